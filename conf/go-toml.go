@@ -13,6 +13,7 @@
 package conf
 
 import (
+	"io"
 	"io/ioutil"
 	"log"
 
@@ -22,14 +23,37 @@ import (
 // Init toml config
 func Init(filePath string, config interface{}) error {
 	confLock.Lock()
+	defer confLock.Unlock()
+
 	fileBytes, err := ioutil.ReadFile(filePath)
 	if err != nil {
 		log.Println("ioutil.ReadFile error: ", err)
 		return err
 	}
 
-	toml.Unmarshal(fileBytes, config)
-	confLock.Unlock()
+	if err := toml.Unmarshal(fileBytes, config); err != nil {
+		log.Println("toml.Unmarshal error: ", err)
+		return err
+	}
+
+	return nil
+}
+
+// Decode decodes the toml of r into config
+func Decode(r io.Reader, config interface{}) error {
+	confLock.Lock()
+	defer confLock.Unlock()
+
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		log.Println("ioutil.ReadAll error: ", err)
+		return err
+	}
+
+	if err := toml.Unmarshal(b, config); err != nil {
+		log.Println("toml.Unmarshal error: ", err)
+		return err
+	}
 
 	return nil
 }
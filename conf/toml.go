@@ -13,19 +13,34 @@
 package conf
 
 import (
+	"io"
 	"log"
 
 	"github.com/BurntSushi/toml"
 )
 
 // Init toml config
-func Init(fliePath string, config interface{}) error {
+func Init(filePath string, config interface{}) error {
 	confLock.Lock()
+	defer confLock.Unlock()
+
 	if _, err := toml.DecodeFile(filePath, config); err != nil {
 		log.Println("toml.DecodeFile error: ", err)
 		return err
 	}
-	confLock.Unlock()
+
+	return nil
+}
+
+// Decode decodes the toml of r into config
+func Decode(r io.Reader, config interface{}) error {
+	confLock.Lock()
+	defer confLock.Unlock()
+
+	if _, err := toml.DecodeReader(r, config); err != nil {
+		log.Println("toml.DecodeReader error: ", err)
+		return err
+	}
 
 	return nil
 }
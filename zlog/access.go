@@ -0,0 +1,176 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"io"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// accessSuffix the suffix of the access files
+const accessSuffix = "_access.json"
+
+// AccessEntry an access log entry
+//
+// Deprecated: use AccessRecord, which has the stable keys.
+type AccessEntry struct {
+	Method     string
+	StatusCode int
+	Req        string
+	IP         string
+	Duration   time.Duration
+}
+
+// Fields returns the fields of the access entry
+func (e AccessEntry) Fields() []zap.Field {
+	return []zap.Field{
+		zap.String("method", e.Method),
+		zap.Int("status_code", e.StatusCode),
+		zap.String("req", e.Req),
+		zap.String("ip", e.IP),
+		zap.Duration("duration", e.Duration),
+	}
+}
+
+// LogAccess info log the access entry
+//
+//	zlog.LogAccess(zlog.AccessEntry{
+//		Method: r.Method, StatusCode: 200, Req: r.URL.Path,
+//		IP: r.RemoteAddr, Duration: time.Since(start),
+//	})
+//
+// Deprecated: use Access.
+func LogAccess(e AccessEntry) {
+	getLogger().Info("access", e.Fields()...)
+}
+
+// AccessRecord a request of the access log, the keys of its fields
+// are stable for the dashboards:
+//
+//	method      the request method, or the full grpc method
+//	path        the url path
+//	query       the raw url query
+//	ip          the client ip
+//	user_agent  the user agent
+//	request_id  the request id, left out if it's empty
+//	status      the response status, or the grpc code
+//	bytes_in    the bytes of the request body
+//	bytes_out   the bytes of the response body
+//	duration    the duration in the duration format of the encoder,
+//	            seconds by default
+type AccessRecord struct {
+	Method, Path, Query, IP, UserAgent, RequestID string
+	Status, BytesIn, BytesOut                     int
+	Duration                                      time.Duration
+}
+
+// Fields returns the fields of the record
+func (r AccessRecord) Fields() []zap.Field {
+	fields := make([]zap.Field, 0, 10)
+	fields = append(fields,
+		zap.String("method", r.Method),
+		zap.String("path", r.Path),
+		zap.String("query", r.Query),
+		zap.String("ip", r.IP),
+		zap.String("user_agent", r.UserAgent),
+	)
+	if r.RequestID != "" {
+		fields = append(fields, zap.String("request_id", r.RequestID))
+	}
+
+	return append(fields,
+		zap.Int("status", r.Status),
+		zap.Int("bytes_in", r.BytesIn),
+		zap.Int("bytes_out", r.BytesOut),
+		zap.Duration("duration", r.Duration),
+	)
+}
+
+// Access info logs the record as the access entry, to
+// Path/<date>/name_access.json with AccessFile or to the main logger.
+//
+//	zlog.Access(zlog.AccessRecord{
+//		Method: r.Method, Path: r.URL.Path, Status: 200,
+//		IP: r.RemoteAddr, Duration: time.Since(start),
+//	})
+func Access(rec AccessRecord) {
+	AccessAt(zap.InfoLevel, "access", rec)
+}
+
+// AccessAt logs the record at lvl with msg and the extra fields like
+// Access, the entries from error also go to the error log with the
+// access file. It is for the middlewares.
+func AccessAt(lvl zapcore.Level, msg string, rec AccessRecord, fields ...zap.Field) {
+	writeAccess(getLogger(), getErrLogger(), getAccess(), lvl, msg, rec, fields)
+}
+
+// Access info logs the record like Access, to the access file of
+// the logger of New with AccessFile
+func (z *Zlog) Access(rec AccessRecord) {
+	z.AccessAt(zap.InfoLevel, "access", rec)
+}
+
+// AccessAt logs the record at lvl with msg and the extra fields
+// like AccessAt, to the access file of the logger of New
+func (z *Zlog) AccessAt(lvl zapcore.Level, msg string, rec AccessRecord,
+	fields ...zap.Field) {
+	logger, errLogger := z.get()
+	writeAccess(logger, errLogger, z.getAccess(), lvl, msg, rec, fields)
+}
+
+// writeAccess writes the record to the access logger, or to logger
+// without it, the records from error always go to errLogger
+func writeAccess(logger, errLogger, access *zap.Logger, lvl zapcore.Level,
+	msg string, rec AccessRecord, fields []zap.Field) {
+	fields = append(rec.Fields(), fields...)
+	if lvl >= zap.ErrorLevel {
+		if ce := errLogger.Check(lvl, msg); ce != nil {
+			ce.Write(fields...)
+		}
+		if access == nil {
+			return
+		}
+	}
+	if access != nil {
+		logger = access
+	}
+
+	if ce := logger.Check(lvl, msg); ce != nil {
+		ce.Write(fields...)
+	}
+}
+
+// getAccess returns the logger of the access file, nil without
+// AccessFile
+func getAccess() *zap.Logger {
+	return load().access
+}
+
+// newAccessLogger returns the logger of the access file, it has the
+// options of logger and only writes the file
+func (c *Config) newAccessLogger(logger *zap.Logger, lvl zap.AtomicLevel,
+	st *state) (*zap.Logger, io.Closer, error) {
+	lpath, name := c.confPath()
+	ws, err := c.openFile(lpath, name+accessSuffix)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// checked by newLogger
+	enc, _ := c.fileEncoder()
+	core := rewriteCore{zapcore.NewCore(enc, hookWriter{ws}, lvl), st}
+	return logger.WithOptions(zap.WrapCore(func(zapcore.Core) zapcore.Core {
+		return core
+	})), ws, nil
+}
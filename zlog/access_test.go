@@ -0,0 +1,181 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vcaesar/tt"
+	"go.uber.org/zap"
+)
+
+func TestLogAccess(t *testing.T) {
+	logs := observe(t)
+
+	LogAccess(AccessEntry{
+		Method:     "GET",
+		StatusCode: 200,
+		Req:        "/index",
+		IP:         "127.0.0.1",
+		Duration:   1500 * time.Millisecond,
+	})
+
+	entries := logs.FilterMessage("access").AllUntimed()
+	tt.Equal(t, 1, len(entries))
+	tt.Equal(t, map[string]interface{}{
+		"method":      "GET",
+		"status_code": int64(200),
+		"req":         "/index",
+		"ip":          "127.0.0.1",
+		"duration":    1500 * time.Millisecond,
+	}, entries[0].ContextMap())
+}
+
+func TestPrintf(t *testing.T) {
+	tt.Equal(t, "", Printf())
+	tt.Equal(t, "method: GET, statusCode: 200", Printf("GET", 200))
+	tt.Equal(t, "method: GET, statusCode: 200, req: /index, ip: 127.0.0.1, time: 1.500000s",
+		Printf("GET", 200, "/index", "127.0.0.1", 1.5))
+	tt.Equal(t, "method: GET, statusCode: 200, req: /index, ip: 127.0.0.1, time: 1.500000s, 512",
+		Printf("GET", 200, "/index", "127.0.0.1", 1.5, 512))
+}
+
+func TestAccess(t *testing.T) {
+	logs, errLogs := observeSplit(t)
+
+	rec := AccessRecord{Method: "GET", Path: "/index", Query: "q=1", IP: "10.0.0.1",
+		UserAgent: "curl", Status: 200, BytesIn: 3, BytesOut: 512,
+		Duration: 1500 * time.Millisecond}
+	Access(rec)
+	AccessAt(zap.ErrorLevel, "failed", AccessRecord{Status: 500, RequestID: "r1"})
+
+	entries := logs.FilterMessage("access").AllUntimed()
+	tt.Equal(t, 1, len(entries))
+	tt.Equal(t, zap.InfoLevel, entries[0].Level)
+	tt.Equal(t, map[string]interface{}{
+		"method":     "GET",
+		"path":       "/index",
+		"query":      "q=1",
+		"ip":         "10.0.0.1",
+		"user_agent": "curl",
+		"status":     int64(200),
+		"bytes_in":   int64(3),
+		"bytes_out":  int64(512),
+		"duration":   1500 * time.Millisecond,
+	}, entries[0].ContextMap())
+
+	errEntries := errLogs.FilterMessage("failed").AllUntimed()
+	tt.Equal(t, 1, len(errEntries))
+	tt.Equal(t, "r1", errEntries[0].ContextMap()["request_id"])
+}
+
+func TestAccessJSON(t *testing.T) {
+	dir := initTest(t)
+	Access(AccessRecord{Method: "GET", Path: "/index", Status: 200})
+	tt.Nil(t, Close())
+
+	entries := msgEntries(readEntries(t, filepath.Join(dir, "*", "test.json")), "access")
+	tt.Equal(t, 1, len(entries))
+
+	var keys []string
+	for key := range entries[0] {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	tt.Equal(t, []string{"bytes_in", "bytes_out", "duration", "ip", "level",
+		"method", "msg", "path", "query", "status", "time", "user_agent"}, keys)
+}
+
+func TestAccessFile(t *testing.T) {
+	dir := initTest(t, "access_file = true", "[fields]", "app = \"shop\"")
+	Access(AccessRecord{Method: "GET", Path: "/index", Status: 200})
+
+	h := RequestIDMiddleware(HTTPMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "fail", 500)
+	})))
+	req := httptest.NewRequest("POST", "/order", strings.NewReader("body"))
+	req.Header.Set(RequestIDHeader, "r1")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	Info("not access")
+	tt.Nil(t, Close())
+
+	entries := readEntries(t, filepath.Join(dir, "*", "test_access.json"))
+	tt.Equal(t, 2, len(entries))
+	tt.Equal(t, "access", entries[0]["msg"])
+	tt.Equal(t, "shop", entries[0]["app"])
+
+	tt.Equal(t, "http request", entries[1]["msg"])
+	tt.Equal(t, "error", entries[1]["level"])
+	tt.Equal(t, "r1", entries[1]["request_id"])
+	tt.Equal(t, float64(4), entries[1]["bytes_in"])
+
+	main := append(readEntries(t, filepath.Join(dir, "*", "test.json")),
+		readEntries(t, filepath.Join(dir, "*", "test_err.json"))...)
+	tt.Equal(t, 0, len(msgEntries(main, "access")))
+	tt.Equal(t, 1, len(msgEntries(main, "not access")))
+
+	// the 5xx still reach the error log
+	errs := msgEntries(readEntries(t, filepath.Join(dir, "*", "test_err.json")), "http request")
+	tt.Equal(t, 1, len(errs))
+	tt.Equal(t, float64(500), errs[0]["status"])
+	tt.Equal(t, "r1", errs[0]["request_id"])
+
+	err := InitWithConfig(Config{Mode: "stdout", AccessFile: true})
+	tt.NotNil(t, err)
+}
+
+func TestNewAccessFile(t *testing.T) {
+	dir := initTest(t, "access_file = true")
+	defer Close()
+
+	dirA, dirB := t.TempDir(), t.TempDir()
+	a, err := New(Config{Path: dirA, Name: "a", AccessFile: true,
+		Fields: FieldsConfig{App: "a"}})
+	tt.Nil(t, err)
+	b, err := New(Config{Path: dirB, Name: "b", AccessFile: true})
+	tt.Nil(t, err)
+
+	a.Access(AccessRecord{Method: "GET", Path: "/a", Status: 200})
+	a.Named("api").With(zap.String("k", "v")).Access(AccessRecord{Path: "/a2", Status: 200})
+	b.AccessAt(zap.ErrorLevel, "http request", AccessRecord{Path: "/b", Status: 500})
+	tt.Nil(t, a.Close())
+	tt.Nil(t, b.Close())
+	// a no-op after Close
+	a.Access(AccessRecord{Path: "/closed"})
+	tt.Nil(t, Close())
+
+	entries := readEntries(t, filepath.Join(dirA, "*", "a_access.json"))
+	tt.Equal(t, 2, len(entries))
+	tt.Equal(t, "/a", entries[0]["path"])
+	tt.Equal(t, "a", entries[0]["app"])
+	tt.Equal(t, "/a2", entries[1]["path"])
+	tt.Equal(t, "api", entries[1]["logger"])
+	tt.Equal(t, "v", entries[1]["k"])
+	tt.Equal(t, 0, len(msgEntries(readEntries(t, filepath.Join(dirA, "*", "a.json")), "access")))
+
+	entries = readEntries(t, filepath.Join(dirB, "*", "b_access.json"))
+	tt.Equal(t, 1, len(entries))
+	tt.Equal(t, "/b", entries[0]["path"])
+	errs := msgEntries(readEntries(t, filepath.Join(dirB, "*", "b_err.json")), "http request")
+	tt.Equal(t, 1, len(errs))
+
+	// nothing in the package access file
+	files, _ := filepath.Glob(filepath.Join(dir, "*", "test_access.json"))
+	for _, f := range files {
+		tt.Equal(t, 0, len(readEntries(t, f)))
+	}
+}
@@ -0,0 +1,197 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	defaultAlertLimit   = 5
+	defaultAlertPer     = 10 * time.Minute
+	defaultAlertTimeout = 5 * time.Second
+)
+
+// AlertConfig the webhook of AddAlertHook
+type AlertConfig struct {
+	// URL the webhook url, the payload is the slack one
+	URL string
+	// Level the levels of the alerts, default fatal
+	Level zapcore.LevelEnabler
+	// Limit and Per at most Limit alerts are sent in Per,
+	// the others are dropped, default 5 in 10m
+	Limit int
+	Per   time.Duration
+	// Timeout the max wait of an alert, default 5s
+	Timeout time.Duration
+	// Client the http client, default http.DefaultClient
+	Client *http.Client
+}
+
+// alerter the webhook alerts of an AddAlertHook
+type alerter struct {
+	cfg AlertConfig
+	now func() time.Time
+
+	mu   sync.Mutex
+	sent []time.Time
+
+	// pending the async alerts, the Fatal entries wait for them
+	pending sync.WaitGroup
+}
+
+// AddAlertHook posts the entries of cfg.Level to the webhook, it stays
+// registered across Init. The Panic and Fatal alerts are sent before
+// the panic or the exit, the lower ones are sent in the background.
+// The failed posts run the OnWriteError funcs.
+//
+//	zlog.AddAlertHook(zlog.AlertConfig{URL: slackURL})
+func AddAlertHook(cfg AlertConfig) error {
+	if cfg.URL == "" {
+		return errors.New("zlog: the alert url is empty")
+	}
+
+	if cfg.Level == nil {
+		cfg.Level = zapcore.FatalLevel
+	}
+	if cfg.Limit <= 0 {
+		cfg.Limit = defaultAlertLimit
+	}
+	if cfg.Per <= 0 {
+		cfg.Per = defaultAlertPer
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultAlertTimeout
+	}
+	if cfg.Client == nil {
+		cfg.Client = http.DefaultClient
+	}
+
+	a := &alerter{cfg: cfg, now: time.Now}
+	OnEntry(a.hook)
+	OnFatal(a.wait)
+	return nil
+}
+
+func (a *alerter) hook(ent zapcore.Entry) error {
+	if !a.cfg.Level.Enabled(ent.Level) || !a.allow() {
+		return nil
+	}
+
+	// the process may exit or panic after the entry
+	if ent.Level > zapcore.ErrorLevel {
+		a.send(ent)
+		return nil
+	}
+
+	a.pending.Add(1)
+	go func() {
+		defer a.pending.Done()
+		a.send(ent)
+	}()
+	return nil
+}
+
+// allow reports whether the limit of the alerts allows one more
+func (a *alerter) allow() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := a.now()
+	i := 0
+	for i < len(a.sent) && now.Sub(a.sent[i]) >= a.cfg.Per {
+		i++
+	}
+	a.sent = a.sent[i:]
+
+	if len(a.sent) >= a.cfg.Limit {
+		return false
+	}
+	a.sent = append(a.sent, now)
+	return true
+}
+
+// wait waits for the async alerts, at most Timeout
+func (a *alerter) wait() {
+	done := make(chan struct{})
+	go func() {
+		a.pending.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(a.cfg.Timeout):
+	}
+}
+
+type alertPayload struct {
+	Text string `json:"text"`
+}
+
+// alertText formats the entry like "*FATAL* msg" and the caller,
+// the logger and the time on the next lines
+func alertText(ent zapcore.Entry) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%s* %s", ent.Level.CapitalString(), ent.Message)
+	if ent.Caller.Defined {
+		fmt.Fprintf(&b, "\ncaller: %s", ent.Caller.TrimmedPath())
+	}
+	if ent.LoggerName != "" {
+		fmt.Fprintf(&b, "\nlogger: %s", ent.LoggerName)
+	}
+	fmt.Fprintf(&b, "\ntime: %s", ent.Time.Format(TimeFormat))
+	return b.String()
+}
+
+func (a *alerter) send(ent zapcore.Entry) {
+	if err := a.post(ent); err != nil {
+		writeFailed("the alert webhook", 1, err)
+	}
+}
+
+func (a *alerter) post(ent zapcore.Entry) error {
+	body, err := json.Marshal(alertPayload{Text: alertText(ent)})
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), a.cfg.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		a.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("zlog: alert: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := a.cfg.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("zlog: alert: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("zlog: alert: %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/vcaesar/tt"
+	"go.uber.org/zap/zapcore"
+)
+
+// fakeWebhook records the texts of the alerts
+type fakeWebhook struct {
+	mu    sync.Mutex
+	texts []string
+	delay time.Duration
+}
+
+func (f *fakeWebhook) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	time.Sleep(f.delay)
+
+	var payload alertPayload
+	if r.Header.Get("Content-Type") != "application/json" ||
+		json.NewDecoder(r.Body).Decode(&payload) != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	f.mu.Lock()
+	f.texts = append(f.texts, payload.Text)
+	f.mu.Unlock()
+}
+
+func (f *fakeWebhook) sent() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.texts...)
+}
+
+// alertServer starts f and removes the hooks of the test at cleanup
+func alertServer(t *testing.T, f *fakeWebhook) string {
+	srv := httptest.NewServer(f)
+	hooks, _ := entryHooks.Load().([]func(zapcore.Entry) error)
+	fatalLock.Lock()
+	funcs := fatalFuncs
+	fatalLock.Unlock()
+
+	t.Cleanup(func() {
+		entryHooks.Store(hooks)
+		fatalLock.Lock()
+		fatalFuncs = funcs
+		fatalLock.Unlock()
+		srv.Close()
+	})
+	return srv.URL
+}
+
+func TestAlertPayload(t *testing.T) {
+	f := &fakeWebhook{}
+	url := alertServer(t, f)
+	tt.NotNil(t, AddAlertHook(AlertConfig{}))
+	tt.Nil(t, AddAlertHook(AlertConfig{URL: url, Level: zapcore.ErrorLevel}))
+
+	initTest(t)
+	Warn("alert warn")
+	Error("alert error", errors.New("e1"))
+	tt.Nil(t, Close())
+
+	waitFor(func() bool { return len(f.sent()) > 0 })
+	var texts []string
+	for _, text := range f.sent() {
+		if strings.Contains(text, "alert ") {
+			texts = append(texts, text)
+		}
+	}
+	tt.Equal(t, 1, len(texts))
+
+	lines := strings.Split(texts[0], "\n")
+	tt.Equal(t, 2, len(lines))
+	tt.Equal(t, "*ERROR* alert error", lines[0])
+	tt.True(t, strings.HasPrefix(lines[1], "time: "))
+
+	tt.Equal(t, "*FATAL* db down\ncaller: zlog/main.go:42\nlogger: api\n"+
+		"time: 2018-05-01 08:30:00", alertText(zapcore.Entry{
+		Level:      zapcore.FatalLevel,
+		Message:    "db down",
+		LoggerName: "api",
+		Time:       time.Date(2018, 5, 1, 8, 30, 0, 0, time.UTC),
+		Caller:     zapcore.NewEntryCaller(0, "/src/zlog/main.go", 42, true),
+	}))
+}
+
+func TestAlertLimit(t *testing.T) {
+	now := time.Date(2018, 5, 1, 8, 0, 0, 0, time.UTC)
+	a := &alerter{cfg: AlertConfig{Limit: 2, Per: time.Minute},
+		now: func() time.Time { return now }}
+
+	tt.True(t, a.allow())
+	now = now.Add(30 * time.Second)
+	tt.True(t, a.allow())
+	tt.False(t, a.allow())
+
+	// the first alert left the window
+	now = now.Add(30 * time.Second)
+	tt.True(t, a.allow())
+	tt.False(t, a.allow())
+
+	f := &fakeWebhook{}
+	url := alertServer(t, f)
+	tt.Nil(t, AddAlertHook(AlertConfig{URL: url, Level: zapcore.ErrorLevel,
+		Limit: 2, Per: time.Hour}))
+
+	initTest(t)
+	for i := 0; i < 5; i++ {
+		Error("alert limit", errors.New("e1"))
+	}
+	tt.Nil(t, Close())
+	waitFor(func() bool { return len(f.sent()) >= 2 })
+	time.Sleep(50 * time.Millisecond)
+	tt.Equal(t, 2, len(f.sent()))
+}
+
+func TestAlertTimeout(t *testing.T) {
+	f := &fakeWebhook{delay: time.Second}
+	url := alertServer(t, f)
+	tt.Nil(t, AddAlertHook(AlertConfig{URL: url, Level: zapcore.PanicLevel,
+		Timeout: 50 * time.Millisecond}))
+
+	var writeErrs int64
+	fns, _ := writeErrFns.Load().([]func(error))
+	t.Cleanup(func() { writeErrFns.Store(fns) })
+	OnWriteError(func(error) {
+		atomic.AddInt64(&writeErrs, 1)
+	})
+
+	initTest(t)
+	defer Close()
+	start := time.Now()
+	func() {
+		defer func() { recover() }()
+		Panic("alert panic", errors.New("e1"))
+	}()
+
+	// the panic waited for the alert, at most the timeout
+	tt.True(t, time.Since(start) < 500*time.Millisecond)
+	tt.Equal(t, int64(1), atomic.LoadInt64(&writeErrs))
+}
+
+// TestAlertFatal runs itself in a subprocess which logs an Error
+// and a Fatal entry, both alerts are sent before the exit
+func TestAlertFatal(t *testing.T) {
+	if url := os.Getenv("ZLOG_ALERT_URL"); url != "" {
+		if err := InitWithConfig(Config{Path: os.Getenv("ZLOG_ALERT_DIR")}); err != nil {
+			os.Exit(2)
+		}
+
+		AddAlertHook(AlertConfig{URL: url, Level: zapcore.ErrorLevel})
+		Error("alert error", errors.New("e1"))
+		Fatal("alert fatal", errors.New("e2"))
+		os.Exit(3)
+	}
+
+	f := &fakeWebhook{delay: 100 * time.Millisecond}
+	url := alertServer(t, f)
+	cmd := exec.Command(os.Args[0], "-test.run=^TestAlertFatal$")
+	cmd.Env = append(os.Environ(), "ZLOG_ALERT_URL="+url,
+		"ZLOG_ALERT_DIR="+t.TempDir())
+	err := cmd.Run()
+
+	var exitErr *exec.ExitError
+	tt.True(t, errors.As(err, &exitErr))
+	tt.Equal(t, 1, exitErr.ExitCode())
+
+	var levels []string
+	for _, text := range f.sent() {
+		levels = append(levels, strings.Fields(text)[0])
+	}
+	tt.Equal(t, 2, len(levels))
+	tt.True(t, strings.Contains(strings.Join(levels, " "), "*FATAL*"))
+	tt.True(t, strings.Contains(strings.Join(levels, " "), "*ERROR*"))
+}
@@ -0,0 +1,165 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// archiveExt the extension of the archives of the daily log dirs
+const archiveExt = ".tar.gz"
+
+// archiveOldLog archives the daily log dirs older than days into
+// <date>.tar.gz and removes the dirs but the audit files, the temp
+// archives left by a crashed run are removed and their dirs are
+// archived again, it returns the number of archived dirs.
+func archiveOldLog(fileDir string, days int64, now time.Time) (archived int, err error) {
+	fis, err := ioutil.ReadDir(fileDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	deadline := today.AddDate(0, 0, -int(days))
+
+	for _, fi := range fis {
+		name := fi.Name()
+		if !fi.IsDir() {
+			if strings.HasSuffix(name, archiveExt+".tmp") {
+				os.Remove(filepath.Join(fileDir, name))
+			}
+			continue
+		}
+
+		day, parseErr := time.ParseInLocation(DayFormat, name, now.Location())
+		if parseErr != nil || !day.Before(deadline) ||
+			onlyAudit(filepath.Join(fileDir, name), fi) {
+			continue
+		}
+
+		if archErr := archiveDir(fileDir, fi); archErr != nil {
+			if err == nil {
+				err = archErr
+			}
+			continue
+		}
+		archived++
+	}
+
+	return
+}
+
+// archiveDir writes the dir day of fileDir to a temp archive and
+// renames it to day.tar.gz before removing the dir, so the archive
+// is never truncated, an archive already renamed is complete and
+// only the dir is removed.
+func archiveDir(fileDir string, day os.FileInfo) error {
+	dir := filepath.Join(fileDir, day.Name())
+	name := dir + archiveExt
+	if _, err := os.Stat(name); err == nil {
+		return removeDay(dir, day)
+	}
+
+	tmp := name + ".tmp"
+	if err := writeArchive(tmp, fileDir, dir); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	if err := os.Rename(tmp, name); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return removeDay(dir, day)
+}
+
+// writeArchive writes the files of dir but the audit files to the
+// tar.gz file name, the names in the archive are relative to root.
+func writeArchive(name, root, dir string) error {
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+
+	err = filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if fi.Mode().IsRegular() && isAudit(fi.Name()) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+
+		hdr, err := tar.FileInfoHeader(fi, "")
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(rel)
+		if fi.IsDir() {
+			hdr.Name += "/"
+		}
+
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+
+		if !fi.Mode().IsRegular() {
+			return nil
+		}
+		return copyFile(tw, path)
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+
+	if err := gz.Close(); err != nil {
+		return err
+	}
+
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+func copyFile(w io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(w, f)
+	return err
+}
@@ -0,0 +1,141 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/vcaesar/tt"
+)
+
+// readArchive returns the contents of the regular files of a tar.gz
+func readArchive(t *testing.T, name string) map[string]string {
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := make(map[string]string)
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return files
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		data, err := ioutil.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[hdr.Name] = string(data)
+	}
+}
+
+func writeFile(t *testing.T, name, content string) {
+	mkdir(t, filepath.Dir(name))
+	if err := ioutil.WriteFile(name, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestArchiveOldLog(t *testing.T) {
+	now := time.Date(2018, 5, 30, 10, 0, 0, 0, time.Local)
+	root := filepath.Join(t.TempDir(), "log")
+
+	writeFile(t, filepath.Join(root, "2018-05-01", "test.json"), "day 1\n")
+	writeFile(t, filepath.Join(root, "2018-05-01", "test_err.json"), "day 1 err\n")
+	writeFile(t, filepath.Join(root, "2018-05-01", "nested", "test.json"), "nested\n")
+	// a crashed run left a truncated temp archive
+	writeFile(t, filepath.Join(root, "2018-05-02", "test.json"), "day 2\n")
+	writeFile(t, filepath.Join(root, "2018-05-02.tar.gz.tmp"), "trunc")
+	// a crashed run renamed the archive but kept the dir
+	writeFile(t, filepath.Join(root, "2018-05-03", "test.json"), "left\n")
+	writeFile(t, filepath.Join(root, "2018-05-03.tar.gz"), "done")
+	writeFile(t, filepath.Join(root, "2018-05-29", "test.json"), "recent\n")
+	mkdir(t, filepath.Join(root, "backup"))
+
+	archived, err := archiveOldLog(root, 1, now)
+	tt.Nil(t, err)
+	tt.Equal(t, 3, archived)
+
+	tt.Equal(t, map[string]string{
+		"2018-05-01/test.json":        "day 1\n",
+		"2018-05-01/test_err.json":    "day 1 err\n",
+		"2018-05-01/nested/test.json": "nested\n",
+	}, readArchive(t, filepath.Join(root, "2018-05-01.tar.gz")))
+	tt.Equal(t, map[string]string{"2018-05-02/test.json": "day 2\n"},
+		readArchive(t, filepath.Join(root, "2018-05-02.tar.gz")))
+
+	data, err := ioutil.ReadFile(filepath.Join(root, "2018-05-03.tar.gz"))
+	tt.Nil(t, err)
+	tt.Equal(t, "done", string(data))
+
+	for _, name := range []string{"2018-05-01", "2018-05-02", "2018-05-03",
+		"2018-05-02.tar.gz.tmp"} {
+		tt.False(t, exists(filepath.Join(root, name)), name)
+	}
+	tt.True(t, exists(filepath.Join(root, "2018-05-29")))
+	tt.True(t, exists(filepath.Join(root, "backup")))
+
+	// the archives are deleted after max days
+	removed, err := deleteOldLog(root, 28, now)
+	tt.Nil(t, err)
+	tt.Equal(t, 1, removed)
+	tt.False(t, exists(filepath.Join(root, "2018-05-01.tar.gz")))
+	tt.True(t, exists(filepath.Join(root, "2018-05-02.tar.gz")))
+
+	archived, err = archiveOldLog(filepath.Join(root, "not_exist"), 1, now)
+	tt.Nil(t, err)
+	tt.Equal(t, 0, archived)
+}
+
+func TestRetentionConf(t *testing.T) {
+	var config Config
+	tt.Equal(t, retention{maxDays: 28}, config.retentionConf())
+
+	config = Config{MaxDays: 7, ArchiveOldDays: true}
+	tt.Equal(t, retention{maxDays: 7, archiveAfter: 1}, config.retentionConf())
+
+	config.CompressAfterDays = 3
+	tt.Equal(t, retention{maxDays: 7, archiveAfter: 3}, config.retentionConf())
+}
+
+func TestCleanerArchive(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "log")
+	old := filepath.Join(root, oldDay(3))
+	writeFile(t, filepath.Join(old, "test.json"), "old\n")
+
+	startCleaner(root, retention{maxDays: 28, archiveAfter: 1}, time.Hour)
+	defer StopCleaner()
+
+	tt.True(t, waitFor(func() bool { return exists(old + archiveExt) }))
+	StopCleaner()
+	tt.False(t, exists(old))
+}
@@ -0,0 +1,282 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/multierr"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	defaultQueueSize     = 4096
+	defaultFlushInterval = time.Second
+	// maxBatchBytes the size of a batch written before FlushInterval
+	maxBatchBytes = 256 << 10
+)
+
+// logFile the writer of a log file, a dailyWriter or an asyncWriter
+type logFile interface {
+	zapcore.WriteSyncer
+	io.Closer
+	rotator
+}
+
+// openFile opens the dailyWriter of lpath/name, with Fallback the
+// failed writes go to the fallback output, with BufferSize they're
+// buffered and with Async it is written by an asyncWriter.
+func (c *Config) openFile(lpath, name string) (logFile, error) {
+	daily := newDailyWriter(lpath, name, c.rotateConf())
+	if err := daily.open(); err != nil {
+		return nil, err
+	}
+
+	var ws logFile = daily
+	// validated by InitWithConfig
+	if fallback, _ := c.fallbackOutput(); fallback != nil {
+		ws = newFallbackWriter(daily, fallback)
+	}
+
+	// validated by InitWithConfig
+	if size, interval, _ := c.bufferConf(); size > 0 {
+		return newBufferedWriter(ws, daily, size, interval), nil
+	}
+
+	if !c.Async {
+		return ws, nil
+	}
+
+	// validated by InitWithConfig
+	queueSize, interval, _ := c.asyncConf()
+	return newAsyncWriter(ws, daily, queueSize, interval), nil
+}
+
+// asyncConf returns the queue size and the flush interval of Async
+func (c *Config) asyncConf() (int, time.Duration, error) {
+	queueSize := c.QueueSize
+	if queueSize < 0 {
+		return 0, 0, fmt.Errorf("zlog: invalid queue_size %d", queueSize)
+	}
+	if queueSize == 0 {
+		queueSize = defaultQueueSize
+	}
+
+	interval, err := durationConf("flush_interval", c.FlushInterval,
+		defaultFlushInterval)
+	return queueSize, interval, err
+}
+
+// asyncWriter writes the entries to the file in its goroutine, the
+// writes only queue the entries and drop them when the queue is full,
+// the queued entries are written by batches. With the daily writer
+// the switch to the file of a new day or period is queued before the
+// first entry of the period.
+type asyncWriter struct {
+	file     logFile
+	interval time.Duration
+	daily    *dailyWriter
+
+	// mu queues the entries of the daily writer in the order of
+	// their period, cur the file of the last queued switch
+	mu  sync.Mutex
+	cur string
+
+	ch         chan asyncWrite
+	syncs      chan chan error
+	stop, done chan struct{}
+	once       sync.Once
+}
+
+// asyncWrite an entry of the queue, or the switch of the daily
+// writer to file
+type asyncWrite struct {
+	p    []byte
+	file string
+}
+
+func newAsyncWriter(file logFile, daily *dailyWriter, queueSize int, interval time.Duration) *asyncWriter {
+	w := &asyncWriter{
+		file:     file,
+		interval: interval,
+		daily:    daily,
+		ch:       make(chan asyncWrite, queueSize),
+		syncs:    make(chan chan error),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+
+	if daily != nil {
+		daily.held = true
+		w.cur = daily.Name()
+	}
+
+	go w.run()
+	return w
+}
+
+// Write queues a copy of p, zap reuses its buffer
+func (w *asyncWriter) Write(p []byte) (int, error) {
+	buf := make([]byte, len(p))
+	copy(buf, p)
+
+	if w.daily == nil {
+		w.queue(asyncWrite{p: buf})
+		return len(p), nil
+	}
+
+	// the period, its switch and the entry are queued together so an
+	// entry never goes before the switch to its file
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if file := w.daily.nowFile(); file != w.cur {
+		if !w.queue(asyncWrite{file: file}) {
+			// a full queue drops the entry with the switch, the next
+			// write retries it
+			atomic.AddInt64(&droppedEntries, 1)
+			return len(p), nil
+		}
+		w.cur = file
+	}
+
+	w.queue(asyncWrite{p: buf})
+	return len(p), nil
+}
+
+// queue queues aw, the entries are dropped when the queue is full
+func (w *asyncWriter) queue(aw asyncWrite) bool {
+	select {
+	case w.ch <- aw:
+		return true
+	default:
+		if aw.file == "" {
+			atomic.AddInt64(&droppedEntries, 1)
+		}
+		return false
+	}
+}
+
+func (w *asyncWriter) run() {
+	defer close(w.done)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	var (
+		batch []byte
+		err   error
+	)
+	for {
+		select {
+		case aw := <-w.ch:
+			batch = w.add(batch, aw)
+			if len(batch) < maxBatchBytes {
+				continue
+			}
+			batch, _ = w.flush(batch)
+		case <-ticker.C:
+			batch, _ = w.flush(batch)
+		case reply := <-w.syncs:
+			batch, err = w.flush(w.drain(batch))
+			reply <- err
+		case <-w.stop:
+			w.flush(w.drain(batch))
+			return
+		}
+	}
+}
+
+// drain appends the queued entries to batch
+func (w *asyncWriter) drain(batch []byte) []byte {
+	for {
+		select {
+		case aw := <-w.ch:
+			batch = w.add(batch, aw)
+		default:
+			return batch
+		}
+	}
+}
+
+// add appends the entry to batch, or writes batch to the file of the
+// previous period and switches the daily writer
+func (w *asyncWriter) add(batch []byte, aw asyncWrite) []byte {
+	if aw.file == "" {
+		return append(batch, aw.p...)
+	}
+
+	batch, _ = w.flush(batch)
+	if err := w.daily.switchTo(aw.file); err != nil {
+		// the old file is kept, the next write queues the switch again
+		w.mu.Lock()
+		if w.cur == aw.file {
+			w.cur = ""
+		}
+		w.mu.Unlock()
+	}
+	return batch
+}
+
+// flush writes batch to the file, the errors run
+// the OnWriteError funcs
+func (w *asyncWriter) flush(batch []byte) ([]byte, error) {
+	if len(batch) == 0 {
+		return batch, nil
+	}
+
+	_, err := w.file.Write(batch)
+	if err != nil {
+		writeFailed(outputName(w.file), int64(bytes.Count(batch, []byte{'\n'})), err)
+	}
+	return batch[:0], err
+}
+
+// Name returns the name of the file
+func (w *asyncWriter) Name() string {
+	return outputName(w.file)
+}
+
+// Sync writes the queued entries, the Fatal entries are
+// flushed by it before the exit
+func (w *asyncWriter) Sync() error {
+	reply := make(chan error, 1)
+	select {
+	case w.syncs <- reply:
+		if err := <-reply; err != nil {
+			return err
+		}
+	case <-w.done:
+	}
+
+	return w.file.Sync()
+}
+
+// Rotate writes the queued entries to the old file and rotates it
+func (w *asyncWriter) Rotate() error {
+	return multierr.Append(w.Sync(), w.file.Rotate())
+}
+
+// Close writes the queued entries, stops the goroutine
+// and closes the file
+func (w *asyncWriter) Close() error {
+	w.once.Do(func() {
+		close(w.stop)
+	})
+	<-w.done
+
+	return w.file.Close()
+}
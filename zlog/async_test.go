@@ -0,0 +1,285 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/vcaesar/tt"
+)
+
+// blockFile a log file whose writes block until release is closed
+type blockFile struct {
+	entered chan struct{}
+	release chan struct{}
+	once    sync.Once
+
+	mu    sync.Mutex
+	lines []string
+}
+
+func newBlockFile() *blockFile {
+	return &blockFile{
+		entered: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+}
+
+func (f *blockFile) Write(p []byte) (int, error) {
+	f.once.Do(func() { close(f.entered) })
+	<-f.release
+
+	f.mu.Lock()
+	f.lines = append(f.lines, strings.Split(strings.TrimSuffix(string(p), "\n"), "\n")...)
+	f.mu.Unlock()
+	return len(p), nil
+}
+
+func (f *blockFile) Sync() error   { return nil }
+func (f *blockFile) Close() error  { return nil }
+func (f *blockFile) Rotate() error { return nil }
+
+func TestAsync(t *testing.T) {
+	dropped := Stats().DroppedEntries
+	dir := initTest(t, "async = true", `flush_interval = "10ms"`)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 250; i++ {
+				Info(fmt.Sprintf("async %d %d", g, i))
+			}
+		}(g)
+	}
+	wg.Wait()
+	Error("async error", errors.New("e1"))
+
+	// Sync writes the queue
+	tt.Nil(t, Sync())
+	entries := readEntries(t, filepath.Join(dir, "*", "test.json"))
+	// and the mirrored error
+	tt.Equal(t, 1001, len(entries)-len(msgEntries(entries, "zlog: delete old log")))
+	errEntries := readEntries(t, filepath.Join(dir, "*", "test_err.json"))
+	tt.Equal(t, 1, len(msgEntries(errEntries, "async error")))
+
+	// the flush interval writes it as well
+	Info("async tick")
+	waitFor(func() bool {
+		entries := readEntries(t, filepath.Join(dir, "*", "test.json"))
+		return len(msgEntries(entries, "async tick")) == 1
+	})
+
+	Info("async close")
+	tt.Nil(t, Close())
+	entries = readEntries(t, filepath.Join(dir, "*", "test.json"))
+	tt.Equal(t, 1, len(msgEntries(entries, "async close")))
+	tt.Equal(t, dropped, Stats().DroppedEntries)
+}
+
+func TestAsyncOverflow(t *testing.T) {
+	dropped := Stats().DroppedEntries
+	f := newBlockFile()
+	w := newAsyncWriter(f, nil, 4, time.Millisecond)
+
+	// the goroutine blocks in the write of the first entry
+	w.Write([]byte("first\n"))
+	<-f.entered
+	for i := 0; i < 10; i++ {
+		w.Write([]byte(fmt.Sprintf("entry %d\n", i)))
+	}
+	tt.Equal(t, dropped+6, Stats().DroppedEntries)
+
+	close(f.release)
+	tt.Nil(t, w.Close())
+	tt.Equal(t, []string{"first", "entry 0", "entry 1", "entry 2", "entry 3"}, f.lines)
+}
+
+func TestAsyncRollover(t *testing.T) {
+	dir := t.TempDir()
+	clock := &fakeClock{tm: time.Date(2018, 5, 1, 23, 59, 59, 0, time.Local)}
+
+	daily := newDailyWriter(dir, "test.json", rotateConfig{maxSize: 500})
+	daily.now = clock.now
+	tt.Nil(t, daily.open())
+	w := newAsyncWriter(daily, daily, 16, time.Hour)
+
+	// the batch of the entries before midnight is written at midnight
+	w.Write([]byte("first\n"))
+	w.Write([]byte("before midnight\n"))
+	clock.set(clock.now().Add(2 * time.Second))
+	w.Write([]byte("after midnight\n"))
+
+	tt.Nil(t, w.Close())
+	tt.Equal(t, []string{"first", "before midnight"},
+		readLines(t, filepath.Join(dir, "2018-05-01", "test.json")))
+	tt.Equal(t, []string{"after midnight"},
+		readLines(t, filepath.Join(dir, "2018-05-02", "test.json")))
+}
+
+func TestAsyncRolloverRace(t *testing.T) {
+	const writers, entries = 8, 200
+	dir := t.TempDir()
+	clock := &fakeClock{tm: time.Date(2018, 5, 1, 23, 59, 59, 0, time.Local)}
+
+	daily := newDailyWriter(dir, "test.json", rotateConfig{maxSize: 500})
+	// the other writers run between the clock and the queue
+	daily.now = func() time.Time {
+		tm := clock.now()
+		runtime.Gosched()
+		return tm
+	}
+	tt.Nil(t, daily.open())
+	w := newAsyncWriter(daily, daily, writers*entries+writers, time.Hour)
+
+	// the clock passes midnight while the writers write
+	half, midnight := make(chan struct{}), make(chan struct{})
+	var wg sync.WaitGroup
+	for g := 0; g < writers; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < entries; i++ {
+				if g == 0 && i == entries/2 {
+					close(half)
+					<-midnight
+				}
+				w.Write([]byte(fmt.Sprintf("%d %d\n", g, i)))
+			}
+		}(g)
+	}
+	<-half
+	clock.set(clock.now().Add(2 * time.Second))
+	close(midnight)
+	wg.Wait()
+	tt.Nil(t, w.Close())
+
+	// the entries of each writer before midnight are all in the old
+	// file, the ones after it in the new file
+	last := make(map[int]int)
+	total := 0
+	for _, day := range []string{"2018-05-01", "2018-05-02"} {
+		lines := readLines(t, filepath.Join(dir, day, "test.json"))
+		tt.True(t, len(lines) > 0, day)
+		for _, line := range lines {
+			var g, i int
+			_, err := fmt.Sscanf(line, "%d %d", &g, &i)
+			tt.Nil(t, err)
+			if prev, ok := last[g]; ok {
+				tt.True(t, i > prev, line)
+			}
+			last[g] = i
+			total++
+		}
+	}
+	tt.Equal(t, writers*entries, total)
+	tt.Equal(t, filepath.Join(dir, "2018-05-02", "test.json"), daily.Name())
+}
+
+func TestAsyncRolloverOrder(t *testing.T) {
+	dir := t.TempDir()
+	clock := &fakeClock{tm: time.Date(2018, 5, 1, 23, 59, 59, 0, time.Local)}
+
+	daily := newDailyWriter(dir, "test.json", rotateConfig{maxSize: 500})
+	daily.now = clock.now
+	tt.Nil(t, daily.open())
+	w := newAsyncWriter(daily, daily, 16, time.Hour)
+
+	// the first writer reads the clock before midnight and waits
+	entered, resume := make(chan struct{}), make(chan struct{})
+	var first int32
+	daily.now = func() time.Time {
+		tm := clock.now()
+		if atomic.CompareAndSwapInt32(&first, 0, 1) {
+			close(entered)
+			<-resume
+		}
+		return tm
+	}
+
+	before := make(chan struct{})
+	go func() {
+		w.Write([]byte("before midnight\n"))
+		close(before)
+	}()
+	<-entered
+
+	// the second one writes after midnight, it waits for the first one
+	clock.set(clock.now().Add(2 * time.Second))
+	after := make(chan struct{})
+	go func() {
+		w.Write([]byte("after midnight\n"))
+		close(after)
+	}()
+	select {
+	case <-after:
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(resume)
+	<-before
+	<-after
+
+	tt.Nil(t, w.Close())
+	tt.Equal(t, []string{"before midnight"},
+		readLines(t, filepath.Join(dir, "2018-05-01", "test.json")))
+	tt.Equal(t, []string{"after midnight"},
+		readLines(t, filepath.Join(dir, "2018-05-02", "test.json")))
+	// never switched back to the file of the previous day
+	tt.Equal(t, filepath.Join(dir, "2018-05-02", "test.json"), daily.Name())
+}
+
+func TestAsyncConf(t *testing.T) {
+	dir := t.TempDir()
+	tt.NotNil(t, InitWithConfig(Config{Path: dir, Async: true, QueueSize: -1}))
+	tt.NotNil(t, InitWithConfig(Config{Path: dir, Async: true, FlushInterval: "soon"}))
+
+	tt.Nil(t, InitWithConfig(Config{Path: dir, Async: true, SplitLevels: true}))
+	Warn("async warn")
+	Error("async error", errors.New("e1"))
+	tt.Nil(t, Rotate())
+	tt.Nil(t, Close())
+
+	entries := readEntries(t, filepath.Join(dir, "*", "log_warn*.json"))
+	tt.Equal(t, 1, len(msgEntries(entries, "async warn")))
+	errEntries := readEntries(t, filepath.Join(dir, "*", "log_err*.json"))
+	tt.Equal(t, 1, len(msgEntries(errEntries, "async error")))
+}
+
+func benchmarkFile(b *testing.B, async bool) {
+	tt.Nil(b, InitWithConfig(Config{Path: b.TempDir(), Async: async,
+		QueueSize: 1 << 16}))
+	defer Close()
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			Info("bench info", "a", "b")
+		}
+	})
+}
+
+func BenchmarkFileSync(b *testing.B) {
+	benchmarkFile(b, false)
+}
+
+func BenchmarkFileAsync(b *testing.B) {
+	benchmarkFile(b, true)
+}
@@ -0,0 +1,246 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// auditSuffix the suffix of the audit files
+const auditSuffix = "_audit.json"
+
+// auditFileMode the mode of the new audit files without FileMode,
+// the same as lumberjack
+const auditFileMode os.FileMode = 0600
+
+var (
+	// auditSync syncs the audit file to the disk, tests swap it
+	auditSync = (*os.File).Sync
+
+	// auditLevel enables every level, Audit is never filtered
+	auditLevel = zap.LevelEnablerFunc(func(zapcore.Level) bool {
+		return true
+	})
+)
+
+// isAudit reports whether name is an audit file
+func isAudit(name string) bool {
+	return strings.HasSuffix(name, auditSuffix)
+}
+
+// Audit writes the audit entry event to name_audit.json of the daily
+// log dir, or stdout without log files, the entries are never
+// sampled, deduplicated, truncated or filtered by the level, only
+// the redacted keys are replaced. With AuditSync the file is synced
+// to the disk before Audit returns.
+//
+//	zlog.Audit("login", zap.String("user", user))
+func Audit(event string, fields ...zap.Field) {
+	if r := defaultState.loadRedaction(); r != nil {
+		fields = r.fields(fields)
+	}
+
+	getAudit().Info(event, fields...)
+}
+
+func getAudit() *zap.Logger {
+	if audit := load().audit; audit != nil {
+		return audit
+	}
+	return nopLogger
+}
+
+// buildAll builds the loggers of the package functions at lvl
+// with the audit logger
+func (c *Config) buildAll(lvl zap.AtomicLevel, st *state) (*loggers, error) {
+	audit, closer, err := c.newAuditLogger()
+	if err != nil {
+		return nil, err
+	}
+
+	built, err := c.build(lvl, st)
+	if err != nil {
+		if closer != nil {
+			closer.Close()
+		}
+		return nil, err
+	}
+
+	built.audit, built.auditCloser = audit, closer
+	return built, nil
+}
+
+// newAuditLogger builds the audit logger of the mode of c, it has
+// none of the options and the wrappers of the other loggers
+func (c *Config) newAuditLogger() (*zap.Logger, io.Closer, error) {
+	var (
+		ws     zapcore.WriteSyncer
+		closer io.Closer
+	)
+
+	enc, err := c.fileEncoder()
+	switch c.Mode {
+	case "dev", "stdout":
+		enc, err = c.stdoutEncoder()
+		ws = lockFile(os.Stdout)
+	case "discard":
+		ws = zapcore.AddSync(ioutil.Discard)
+	default:
+		w := c.newAuditWriter()
+		ws, closer = w, w
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	core := zapcore.NewCore(enc, hookWriter{ws}, auditLevel)
+	return zap.New(core), closer, nil
+}
+
+// auditWriter appends to lpath/<date>/name_audit.json, it is never
+// rotated by the size or the period, with sync each entry is synced
+// to the disk before Write returns.
+type auditWriter struct {
+	mu sync.Mutex
+
+	lpath, name string
+	flat        bool
+	dirMode     os.FileMode
+	fileMode    os.FileMode
+	sync        bool
+	now         func() time.Time
+
+	file string
+	f    *os.File
+}
+
+// newAuditWriter returns the audit writer of the config, the file
+// is opened by the first entry
+func (c *Config) newAuditWriter() *auditWriter {
+	lpath, name := c.confPath()
+	// validated by InitWithConfig
+	dirMode, _ := c.dirMode()
+	fileMode, _ := c.fileMode()
+
+	return &auditWriter{
+		lpath:    lpath,
+		name:     name + auditSuffix,
+		flat:     !c.dailyDirs(),
+		dirMode:  dirMode,
+		fileMode: fileMode,
+		sync:     c.AuditSync,
+		now:      time.Now,
+	}
+}
+
+// filename returns the audit file of tm
+func (w *auditWriter) filename(tm time.Time) string {
+	if w.flat {
+		return filepath.Join(w.lpath, w.name)
+	}
+	return filepath.Join(w.lpath, tm.Format(DayFormat), w.name)
+}
+
+// Write appends the entry to the audit file of the current day
+func (w *auditWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if file := w.filename(w.now()); file != w.file || w.f == nil {
+		if err := w.open(file); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := w.f.Write(p)
+	if err != nil || !w.sync {
+		return n, err
+	}
+	return n, auditSync(w.f)
+}
+
+// open closes the audit file and opens file
+func (w *auditWriter) open(file string) error {
+	if err := mkdirMode(filepath.Dir(file), w.dirMode); err != nil {
+		return err
+	}
+
+	mode := w.fileMode
+	if mode == 0 {
+		mode = auditFileMode
+	}
+
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_APPEND, mode)
+	if err != nil {
+		return err
+	}
+
+	if w.fileMode != 0 {
+		if err := f.Chmod(w.fileMode); err != nil {
+			f.Close()
+			return err
+		}
+	}
+
+	if w.f != nil {
+		w.f.Close()
+	}
+	w.file, w.f = file, f
+	return nil
+}
+
+// Name returns the path of the current audit file
+func (w *auditWriter) Name() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == "" {
+		return w.filename(w.now())
+	}
+	return w.file
+}
+
+// Sync syncs the audit file to the disk
+func (w *auditWriter) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.f == nil {
+		return nil
+	}
+	return auditSync(w.f)
+}
+
+// Close syncs and closes the audit file
+func (w *auditWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.f == nil {
+		return nil
+	}
+
+	err := auditSync(w.f)
+	if closeErr := w.f.Close(); err == nil {
+		err = closeErr
+	}
+	w.file, w.f = "", nil
+	return err
+}
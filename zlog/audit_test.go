@@ -0,0 +1,128 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/vcaesar/tt"
+	"go.uber.org/zap"
+)
+
+// countSyncs swaps in an audit syncer counting the syncs
+func countSyncs(t *testing.T) *int64 {
+	var n int64
+	prev := auditSync
+	auditSync = func(f *os.File) error {
+		atomic.AddInt64(&n, 1)
+		return prev(f)
+	}
+	t.Cleanup(func() { auditSync = prev })
+
+	return &n
+}
+
+func TestAudit(t *testing.T) {
+	syncs := countSyncs(t)
+	dir := initTest(t, "audit_sync = true", "dedup = true", "max_field_len = 4",
+		"[sampling]", "initial = 1", "thereafter = 0")
+
+	// the level doesn't filter the audit entries
+	SetLevel(zap.ErrorLevel)
+	for i := 0; i < 3; i++ {
+		Audit("login", zap.String("user", "admin"))
+	}
+	Info("info")
+	tt.Equal(t, int64(3), atomic.LoadInt64(syncs))
+	tt.Nil(t, Close())
+
+	entries := readEntries(t, filepath.Join(dir, time.Now().Format(DayFormat), "test_audit.json"))
+	tt.Equal(t, 3, len(entries))
+	tt.Equal(t, "login", entries[0]["msg"])
+	tt.Equal(t, "admin", entries[2]["user"])
+	tt.Equal(t, "info", entries[2]["level"])
+
+	entries = readEntries(t, filepath.Join(dir, "*", "test.json"))
+	tt.Equal(t, 0, len(msgEntries(entries, "login")))
+
+	// nothing is written after Close
+	Audit("logout")
+	tt.Equal(t, 3, len(readLines(t,
+		filepath.Join(dir, time.Now().Format(DayFormat), "test_audit.json"))))
+}
+
+func TestAuditNoSync(t *testing.T) {
+	syncs := countSyncs(t)
+	dir := initTest(t, "daily_dirs = false")
+
+	Audit("grant", zap.String("role", "admin"))
+	tt.Equal(t, int64(0), atomic.LoadInt64(syncs))
+	tt.Nil(t, Sync())
+	tt.Equal(t, int64(1), atomic.LoadInt64(syncs))
+	tt.Nil(t, Close())
+
+	entries := readEntries(t, filepath.Join(dir, "test_audit.json"))
+	tt.Equal(t, 1, len(entries))
+	tt.Equal(t, "admin", entries[0]["role"])
+}
+
+func TestDeleteOldAudit(t *testing.T) {
+	now := time.Date(2018, 5, 30, 10, 0, 0, 0, time.Local)
+	root := t.TempDir()
+	for _, name := range []string{
+		"2018-03-01/log_audit.json", "2018-03-01/log.json",
+		"2018-04-20/log_audit.json", "2018-04-20/log_err.json",
+		"2018-05-29/log_audit.json",
+	} {
+		path := filepath.Join(root, name)
+		mkdir(t, filepath.Dir(path))
+		if err := ioutil.WriteFile(path, []byte("test"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the other files only
+	removed, err := deleteOldLog(root, 28, now)
+	tt.Nil(t, err)
+	tt.Equal(t, 2, removed)
+	tt.False(t, exists(filepath.Join(root, "2018-03-01", "log.json")))
+	tt.False(t, exists(filepath.Join(root, "2018-04-20", "log_err.json")))
+	tt.True(t, exists(filepath.Join(root, "2018-04-20", "log_audit.json")))
+
+	removed, err = deleteOldLog(root, 28, now)
+	tt.Nil(t, err)
+	tt.Equal(t, 0, removed)
+
+	removed, err = deleteOldAudit(root, 60, now)
+	tt.Nil(t, err)
+	tt.Equal(t, 1, removed)
+	tt.False(t, exists(filepath.Join(root, "2018-03-01")))
+	tt.True(t, exists(filepath.Join(root, "2018-04-20", "log_audit.json")))
+	tt.True(t, exists(filepath.Join(root, "2018-05-29", "log_audit.json")))
+
+	// not counted by the size
+	removed2, _, err := trimLogSize(root, 1, now)
+	tt.Nil(t, err)
+	tt.Equal(t, 0, len(removed2))
+
+	archived, err := archiveOldLog(root, 1, now)
+	tt.Nil(t, err)
+	tt.Equal(t, 0, archived)
+
+	tt.Equal(t, int64(28), retention{maxDays: 28}.auditMaxDays())
+	cfg := Config{AuditMaxDays: 365}
+	tt.Equal(t, int64(365), cfg.retentionConf().auditMaxDays())
+}
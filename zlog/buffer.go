@@ -0,0 +1,111 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/multierr"
+	"go.uber.org/zap/zapcore"
+)
+
+// bufferConf returns the buffer size and the flush interval of
+// BufferSize, the size is 0 without buffer
+func (c *Config) bufferConf() (int, time.Duration, error) {
+	if c.BufferSize < 0 {
+		return 0, 0, fmt.Errorf("zlog: invalid buffer_size %d", c.BufferSize)
+	}
+
+	if c.BufferSize > 0 && c.Async {
+		return 0, 0, fmt.Errorf("zlog: buffer_size conflicts with async, " +
+			"async batches the writes already")
+	}
+
+	interval, err := durationConf("flush_interval", c.FlushInterval,
+		defaultFlushInterval)
+	return c.BufferSize, interval, err
+}
+
+// bufferedWriter buffers the writes of the file in memory, the
+// buffer is written when it is full, every flush interval, on Sync
+// and before the file is rotated or closed. With the daily writer
+// the buffer is also written before the first entry of a new day
+// or period, so the entries stay in the file of their period.
+type bufferedWriter struct {
+	*zapcore.BufferedWriteSyncer
+	file logFile
+
+	mu    sync.Mutex
+	daily *dailyWriter
+	cur   string
+}
+
+func newBufferedWriter(file logFile, daily *dailyWriter, size int, interval time.Duration) *bufferedWriter {
+	w := &bufferedWriter{
+		BufferedWriteSyncer: &zapcore.BufferedWriteSyncer{
+			WS:            file,
+			Size:          size,
+			FlushInterval: interval,
+		},
+		file:  file,
+		daily: daily,
+	}
+
+	if daily != nil {
+		daily.held = true
+		w.cur = daily.Name()
+	}
+	return w
+}
+
+// Write buffers p, the buffer is written to the file of the previous
+// period first when p is the first entry of a new one.
+func (w *bufferedWriter) Write(p []byte) (int, error) {
+	if w.daily == nil {
+		return w.BufferedWriteSyncer.Write(p)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if file := w.daily.nowFile(); file != w.cur {
+		if err := w.Sync(); err != nil {
+			return 0, err
+		}
+		if err := w.daily.switchTo(file); err != nil {
+			return 0, err
+		}
+		w.cur = file
+	}
+
+	return w.BufferedWriteSyncer.Write(p)
+}
+
+// Name returns the name of the file
+func (w *bufferedWriter) Name() string {
+	return outputName(w.file)
+}
+
+// Rotate writes the buffer to the file before it is rotated
+func (w *bufferedWriter) Rotate() error {
+	if err := w.Sync(); err != nil {
+		return err
+	}
+	return w.file.Rotate()
+}
+
+// Close writes the buffer and closes the file
+func (w *bufferedWriter) Close() error {
+	err := w.Stop()
+	return multierr.Append(err, w.file.Close())
+}
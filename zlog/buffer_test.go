@@ -0,0 +1,133 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/vcaesar/tt"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestBuffer(t *testing.T) {
+	dir := initTest(t, "buffer_size = 65536", `flush_interval = "1h"`)
+	day := filepath.Join(dir, time.Now().Format(DayFormat))
+
+	Info("buffered")
+	tt.Equal(t, 0, len(msgEntries(readEntries(t, filepath.Join(day, "test.json")), "buffered")))
+
+	// the buffer is written to the old file
+	tt.Nil(t, Rotate())
+	Info("after rotate")
+	tt.Nil(t, Sync())
+	files, err := filepath.Glob(filepath.Join(day, "test-*.json"))
+	tt.Nil(t, err)
+	tt.Equal(t, 1, len(files))
+	tt.Equal(t, 1, len(msgEntries(readEntries(t, files[0]), "buffered")))
+	tt.Equal(t, 1, len(msgEntries(readEntries(t, filepath.Join(day, "test.json")), "after rotate")))
+
+	Info("before close")
+	tt.Nil(t, Close())
+	tt.Equal(t, 1, len(msgEntries(readEntries(t, filepath.Join(day, "test.json")), "before close")))
+
+	for _, c := range []Config{
+		{Path: dir, BufferSize: -1},
+		{Path: dir, BufferSize: 1, Async: true},
+		{Path: dir, BufferSize: 1, Mode: "stdout"},
+	} {
+		tt.NotNil(t, InitWithConfig(c))
+	}
+}
+
+func TestBufferRollover(t *testing.T) {
+	dir := t.TempDir()
+	clock := &fakeClock{tm: time.Date(2018, 5, 1, 23, 59, 59, 0, time.Local)}
+
+	daily := newDailyWriter(dir, "test.json", rotateConfig{maxSize: 500})
+	daily.now = clock.now
+	tt.Nil(t, daily.open())
+	w := newBufferedWriter(daily, daily, 65536, time.Hour)
+
+	w.Write([]byte("before midnight\n"))
+	clock.set(clock.now().Add(2 * time.Second))
+	// the entries buffered before midnight stay in the old day
+	tt.Nil(t, w.Sync())
+	w.Write([]byte("late before midnight\n"))
+	tt.Nil(t, w.Close())
+
+	old := filepath.Join(dir, "2018-05-01", "test.json")
+	tt.Equal(t, []string{"before midnight"}, readLines(t, old))
+	tt.Equal(t, []string{"late before midnight"},
+		readLines(t, filepath.Join(dir, "2018-05-02", "test.json")))
+
+	// the buffer is written before the first entry of the new period
+	clock.set(time.Date(2018, 5, 1, 13, 59, 59, 0, time.Local))
+	daily = newDailyWriter(dir, "test.json", rotateConfig{maxSize: 500, every: time.Hour})
+	daily.now = clock.now
+	tt.Nil(t, daily.open())
+	w = newBufferedWriter(daily, daily, 65536, time.Hour)
+
+	w.Write([]byte("13h\n"))
+	clock.set(clock.now().Add(2 * time.Second))
+	w.Write([]byte("14h\n"))
+	tt.Nil(t, w.Close())
+
+	tt.Equal(t, []string{"13h"}, readLines(t, filepath.Join(dir, "2018-05-01", "test-2018-05-01T13.json")))
+	tt.Equal(t, []string{"14h"}, readLines(t, filepath.Join(dir, "2018-05-01", "test-2018-05-01T14.json")))
+}
+
+// countFile a log file counting the writes
+type countFile struct {
+	writes int64
+}
+
+func (f *countFile) Write(p []byte) (int, error) {
+	atomic.AddInt64(&f.writes, 1)
+	return len(p), nil
+}
+
+func (f *countFile) Sync() error   { return nil }
+func (f *countFile) Rotate() error { return nil }
+func (f *countFile) Close() error  { return nil }
+
+func benchmarkBuffer(b *testing.B, size int) {
+	file := &countFile{}
+	var ws zapcore.WriteSyncer = file
+	if size > 0 {
+		w := newBufferedWriter(file, nil, size, time.Second)
+		defer w.Close()
+		ws = w
+	}
+
+	logger := zap.New(zapcore.NewCore(zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()),
+		ws, zap.InfoLevel))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		logger.Info("bench info", zap.String("a", "b"))
+	}
+	b.StopTimer()
+	logger.Sync()
+	b.ReportMetric(float64(atomic.LoadInt64(&file.writes))/float64(b.N), "writes/op")
+}
+
+func BenchmarkUnbuffered(b *testing.B) {
+	benchmarkBuffer(b, 0)
+}
+
+func BenchmarkBuffered(b *testing.B) {
+	benchmarkBuffer(b, 256<<10)
+}
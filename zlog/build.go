@@ -0,0 +1,115 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"runtime/debug"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+)
+
+// shortRevision the length of the vcs.revision field
+const shortRevision = 12
+
+// buildInfo the fields of BuildInfoFields
+type buildInfo struct {
+	revision, time, version string
+	modified                *bool
+}
+
+var (
+	// readBuildInfo reads the build info, tests swap it
+	readBuildInfo = debug.ReadBuildInfo
+
+	buildOnce sync.Once
+	stamped   buildInfo
+	setBuild  atomic.Value // buildInfo
+)
+
+// SetBuildInfo sets the vcs.revision and the module.version fields
+// of BuildInfoFields for the binaries built without the vcs stamping,
+// the empty values keep the ones of the build info. It stays set
+// across Init.
+//
+//	zlog.SetBuildInfo(gitSHA, version) // from the ldflags
+func SetBuildInfo(rev, version string) {
+	setBuild.Store(buildInfo{revision: rev, version: version})
+
+	// the loggers are rebuilt on their bases
+	swap(func(lg *loggers) {})
+}
+
+// stampedBuild returns the build info of the binary, read once,
+// nothing if the binary has none
+func stampedBuild() buildInfo {
+	buildOnce.Do(func() {
+		bi, ok := readBuildInfo()
+		if !ok {
+			return
+		}
+
+		stamped.version = bi.Main.Version
+		for _, s := range bi.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				stamped.revision = s.Value
+			case "vcs.time":
+				stamped.time = s.Value
+			case "vcs.modified":
+				if modified, err := strconv.ParseBool(s.Value); err == nil {
+					stamped.modified = &modified
+				}
+			}
+		}
+	})
+	return stamped
+}
+
+// buildFields returns the fields of the build info with
+// BuildInfoFields, the values of SetBuildInfo override it
+func (c *Config) buildFields() []zap.Field {
+	if !c.BuildInfoFields {
+		return nil
+	}
+
+	info := stampedBuild()
+	if set, ok := setBuild.Load().(buildInfo); ok {
+		if set.revision != "" {
+			info.revision = set.revision
+		}
+		if set.version != "" {
+			info.version = set.version
+		}
+	}
+
+	if len(info.revision) > shortRevision {
+		info.revision = info.revision[:shortRevision]
+	}
+
+	var fields []zap.Field
+	for _, f := range []struct{ key, val string }{
+		{"vcs.revision", info.revision}, {"vcs.time", info.time},
+	} {
+		if f.val != "" {
+			fields = append(fields, zap.String(f.key, f.val))
+		}
+	}
+	if info.modified != nil {
+		fields = append(fields, zap.Bool("vcs.modified", *info.modified))
+	}
+	if info.version != "" {
+		fields = append(fields, zap.String("module.version", info.version))
+	}
+	return fields
+}
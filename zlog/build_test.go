@@ -0,0 +1,94 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"path/filepath"
+	"runtime/debug"
+	"sync"
+	"testing"
+
+	"github.com/vcaesar/tt"
+)
+
+// fakeBuild swaps in the build info bi, nil for a binary without it
+func fakeBuild(t *testing.T, bi *debug.BuildInfo) {
+	reset := func() {
+		buildOnce, stamped = sync.Once{}, buildInfo{}
+		setBuild.Store(buildInfo{})
+	}
+
+	prev := readBuildInfo
+	readBuildInfo = func() (*debug.BuildInfo, bool) {
+		return bi, bi != nil
+	}
+	reset()
+	t.Cleanup(func() {
+		readBuildInfo = prev
+		reset()
+	})
+}
+
+func TestBuildInfoFields(t *testing.T) {
+	fakeBuild(t, &debug.BuildInfo{
+		Main: debug.Module{Path: "example.com/shop", Version: "v1.2.3"},
+		Settings: []debug.BuildSetting{
+			{Key: "vcs.revision", Value: "0123456789abcdef0123456789abcdef01234567"},
+			{Key: "vcs.time", Value: "2018-05-01T08:00:00Z"},
+			{Key: "vcs.modified", Value: "true"},
+		},
+	})
+
+	dir := initTest(t, "build_info_fields = true")
+	Info("build info")
+	tt.Nil(t, Close())
+
+	entries := msgEntries(readEntries(t, filepath.Join(dir, "*", "test.json")), "build info")
+	tt.Equal(t, 1, len(entries))
+	tt.Equal(t, "0123456789ab", entries[0]["vcs.revision"])
+	tt.Equal(t, "2018-05-01T08:00:00Z", entries[0]["vcs.time"])
+	tt.Equal(t, true, entries[0]["vcs.modified"])
+	tt.Equal(t, "v1.2.3", entries[0]["module.version"])
+}
+
+func TestSetBuildInfo(t *testing.T) {
+	fakeBuild(t, nil)
+
+	// no build info doesn't fail Init
+	dir := initTest(t, "build_info_fields = true")
+	Info("no build info")
+	SetBuildInfo("abc1234", "v2.0.0")
+	Info("set build info")
+	tt.Nil(t, Close())
+
+	entries := readEntries(t, filepath.Join(dir, "*", "test.json"))
+	before := msgEntries(entries, "no build info")
+	tt.Equal(t, 1, len(before))
+	_, ok := before[0]["vcs.revision"]
+	tt.False(t, ok)
+
+	after := msgEntries(entries, "set build info")
+	tt.Equal(t, 1, len(after))
+	tt.Equal(t, "abc1234", after[0]["vcs.revision"])
+	tt.Equal(t, "v2.0.0", after[0]["module.version"])
+	_, ok = after[0]["vcs.time"]
+	tt.False(t, ok)
+
+	// the fields need BuildInfoFields
+	dir = initTest(t)
+	Info("without build info")
+	tt.Nil(t, Close())
+
+	entries = msgEntries(readEntries(t, filepath.Join(dir, "*", "test.json")), "without build info")
+	tt.Equal(t, 1, len(entries))
+	_, ok = entries[0]["vcs.revision"]
+	tt.False(t, ok)
+}
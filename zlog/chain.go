@@ -0,0 +1,109 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"errors"
+	"fmt"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// maxChain the max causes of an error chain
+const maxChain = 32
+
+// cause an error of the chain with its message and go type
+type cause struct {
+	msg, typ string
+}
+
+func (c cause) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("msg", c.msg)
+	enc.AddString("type", c.typ)
+	return nil
+}
+
+type causes []cause
+
+func (cs causes) MarshalLogArray(enc zapcore.ArrayEncoder) error {
+	for _, c := range cs {
+		if err := enc.AppendObject(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// errorChain walks the causes of err depth first, the errors of
+// Unwrap() []error are fanned out, it returns the causes and the
+// innermost cause of the first branch.
+func errorChain(err error) (chain causes, root cause) {
+	var walk func(err error)
+	rootFound := false
+
+	walk = func(err error) {
+		if err == nil || len(chain) >= maxChain {
+			return
+		}
+
+		c := cause{msg: err.Error(), typ: fmt.Sprintf("%T", err)}
+		chain = append(chain, c)
+
+		if multi, ok := err.(interface{ Unwrap() []error }); ok {
+			for _, e := range multi.Unwrap() {
+				walk(e)
+			}
+			return
+		}
+
+		next := errors.Unwrap(err)
+		if next == nil && !rootFound {
+			root, rootFound = c, true
+		}
+		walk(next)
+	}
+
+	walk(err)
+	if !rootFound && len(chain) > 0 {
+		// capped before the innermost cause
+		root = chain[len(chain)-1]
+	}
+	return
+}
+
+// ErrorChain error log with the error, the chain of its causes
+// as the "errorChain" array and the innermost cause as "errorRoot",
+// the chain is capped at 32 causes.
+//
+//	zlog.ErrorChain("save user", fmt.Errorf("save user: %w", pgErr))
+func ErrorChain(msg string, err error, fields ...zap.Field) {
+	getErrLogger().Error(msg, chainFields(err, fields)...)
+}
+
+// ErrorChain error log with the error and the chain of its causes
+func (z *Zlog) ErrorChain(msg string, err error, fields ...zap.Field) {
+	_, errLogger := z.get()
+	errLogger.Error(msg, chainFields(err, fields)...)
+}
+
+func chainFields(err error, fields []zap.Field) []zap.Field {
+	if err == nil {
+		return fields
+	}
+
+	chain, root := errorChain(err)
+	return append([]zap.Field{
+		zap.Error(err),
+		zap.Array("errorChain", chain),
+		zap.Object("errorRoot", root),
+	}, fields...)
+}
@@ -0,0 +1,78 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/vcaesar/tt"
+)
+
+// loopErr an error unwrapping to itself
+type loopErr struct{}
+
+func (e *loopErr) Error() string { return "loop" }
+func (e *loopErr) Unwrap() error { return e }
+
+func causeMap(msg, typ string) map[string]interface{} {
+	return map[string]interface{}{"msg": msg, "type": typ}
+}
+
+func TestErrorChain(t *testing.T) {
+	_, errLogs := observeSplit(t)
+
+	root := &os.PathError{Op: "open", Path: "/x", Err: os.ErrNotExist}
+	ErrorChain("wrapped", fmt.Errorf("save user: %w", root), Str("k", "v"))
+
+	entries := errLogs.FilterMessage("wrapped").AllUntimed()
+	tt.Equal(t, 1, len(entries))
+	fields := entries[0].ContextMap()
+	tt.Equal(t, "v", fields["k"])
+	tt.Equal(t, "save user: open /x: file does not exist", fields["error"])
+	tt.Equal(t, []interface{}{
+		causeMap("save user: open /x: file does not exist", "*fmt.wrapError"),
+		causeMap("open /x: file does not exist", "*fs.PathError"),
+		causeMap("file does not exist", "*errors.errorString"),
+	}, fields["errorChain"])
+	tt.Equal(t, causeMap("file does not exist", "*errors.errorString"), fields["errorRoot"])
+}
+
+func TestErrorChainJoined(t *testing.T) {
+	_, errLogs := observeSplit(t)
+
+	e1, e2 := errors.New("e1"), errors.New("e2")
+	ErrorChain("joined", fmt.Errorf("batch: %w", errors.Join(fmt.Errorf("a: %w", e1), e2)))
+
+	fields := errLogs.FilterMessage("joined").AllUntimed()[0].ContextMap()
+	chain := fields["errorChain"].([]interface{})
+	tt.Equal(t, 5, len(chain))
+	tt.Equal(t, "*errors.joinError", chain[1].(map[string]interface{})["type"])
+	tt.Equal(t, causeMap("e1", "*errors.errorString"), chain[3])
+	tt.Equal(t, causeMap("e2", "*errors.errorString"), chain[4])
+	tt.Equal(t, causeMap("e1", "*errors.errorString"), fields["errorRoot"])
+}
+
+func TestErrorChainCap(t *testing.T) {
+	_, errLogs := observeSplit(t)
+
+	ErrorChain("loop", &loopErr{})
+	ErrorChain("nil", nil, Str("k", "v"))
+
+	loop := errLogs.FilterMessage("loop").AllUntimed()[0].ContextMap()
+	tt.Equal(t, maxChain, len(loop["errorChain"].([]interface{})))
+	tt.Equal(t, causeMap("loop", "*zlog.loopErr"), loop["errorRoot"])
+
+	fields := errLogs.FilterMessage("nil").AllUntimed()[0].ContextMap()
+	tt.Equal(t, map[string]interface{}{"k": "v"}, fields)
+}
@@ -0,0 +1,482 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultMaxDays           = 28
+	defaultCompressAfterDays = 1
+	defaultCleanInterval     = 24 * time.Hour
+)
+
+// retention the days to keep and to archive the daily log dirs
+type retention struct {
+	maxDays int64
+	// archiveAfter 0 doesn't archive the dirs
+	archiveAfter int64
+	// maxTotalSize the max bytes of the log path, 0 no limit
+	maxTotalSize int64
+	// auditDays the days to keep the audit files, 0 is maxDays
+	auditDays int64
+}
+
+// cleaner runs the old log sweep every interval until stopped
+type cleaner struct {
+	stop, done chan struct{}
+}
+
+var (
+	cleanLock sync.Mutex
+	logClean  *cleaner
+)
+
+// maxDays returns the retention days of the daily log dirs,
+// it falls back to MaxAge when MaxDays isn't set.
+func (c *Config) maxDays() int64 {
+	if c.MaxDays != 0 {
+		return c.MaxDays
+	}
+
+	if c.MaxAge != 0 {
+		return int64(c.MaxAge)
+	}
+	return defaultMaxDays
+}
+
+// retentionConf returns the retention of the config
+func (c *Config) retentionConf() retention {
+	keep := retention{
+		maxDays:      c.maxDays(),
+		maxTotalSize: c.MaxTotalSizeMB << 20,
+		auditDays:    c.AuditMaxDays,
+	}
+	if c.ArchiveOldDays {
+		keep.archiveAfter = defaultCompressAfterDays
+		if c.CompressAfterDays > 0 {
+			keep.archiveAfter = c.CompressAfterDays
+		}
+	}
+
+	return keep
+}
+
+func (c *Config) cleanInterval() (time.Duration, error) {
+	if c.CleanInterval == "" {
+		return defaultCleanInterval, nil
+	}
+
+	interval, err := time.ParseDuration(c.CleanInterval)
+	if err != nil {
+		return 0, err
+	}
+
+	if interval <= 0 {
+		return 0, fmt.Errorf("zlog: clean_interval %q must be positive",
+			c.CleanInterval)
+	}
+	return interval, nil
+}
+
+// startCleaner stops the running cleaner and starts a new one,
+// the first sweep runs right away.
+func startCleaner(fileDir string, keep retention, interval time.Duration) {
+	StopCleaner()
+
+	c := newCleaner(packageZlog, fileDir, keep, interval)
+	cleanLock.Lock()
+	logClean = c
+	cleanLock.Unlock()
+}
+
+// newCleaner starts the sweep of fileDir every interval, the results
+// are logged to z
+func newCleaner(z *Zlog, fileDir string, keep retention, interval time.Duration) *cleaner {
+	c := &cleaner{
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+
+	go func() {
+		defer close(c.done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			sweep(z, fileDir, keep)
+
+			select {
+			case <-ticker.C:
+			case <-c.stop:
+				return
+			}
+		}
+	}()
+
+	return c
+}
+
+// close stops the sweep and waits for a running sweep to finish
+func (c *cleaner) close() {
+	if c != nil {
+		close(c.stop)
+		<-c.done
+	}
+}
+
+// StopCleaner stop the old log sweep started by Init,
+// it waits for a running sweep to finish.
+func StopCleaner() {
+	cleanLock.Lock()
+	c := logClean
+	logClean = nil
+	cleanLock.Unlock()
+
+	c.close()
+}
+
+// cleaning reports whether the old log sweep is running
+func cleaning() bool {
+	cleanLock.Lock()
+	defer cleanLock.Unlock()
+	return logClean != nil
+}
+
+// sweep delete and archive the old log, then trim the log path
+// to the max total size, and log the results to z
+func sweep(z *Zlog, fileDir string, keep retention) {
+	logger, errLogger := z.get()
+	now := time.Now()
+	removed, err := deleteOldLog(fileDir, keep.maxDays, now)
+	if err != nil {
+		errLogger.Error("zlog: delete old log error",
+			zap.String("path", fileDir), zap.Error(err))
+	}
+
+	logger.Info("zlog: delete old log",
+		zap.String("path", fileDir), zap.Int("removed", removed))
+
+	sweepAudit(logger, errLogger, fileDir, keep.auditMaxDays(), now)
+
+	if keep.archiveAfter > 0 {
+		sweepArchive(logger, errLogger, fileDir, keep.archiveAfter, now)
+	}
+
+	if keep.maxTotalSize > 0 {
+		sweepSize(logger, errLogger, fileDir, keep.maxTotalSize, now)
+	}
+}
+
+// auditMaxDays returns the days to keep the audit files
+func (keep retention) auditMaxDays() int64 {
+	if keep.auditDays > 0 {
+		return keep.auditDays
+	}
+	return keep.maxDays
+}
+
+// sweepAudit deletes the audit files older than days, it only
+// logs when there are some
+func sweepAudit(logger, errLogger *zap.Logger, fileDir string,
+	days int64, now time.Time) {
+	removed, err := deleteOldAudit(fileDir, days, now)
+	if err != nil {
+		errLogger.Error("zlog: delete old audit error",
+			zap.String("path", fileDir), zap.Error(err))
+	}
+
+	if removed > 0 {
+		logger.Info("zlog: delete old audit",
+			zap.String("path", fileDir), zap.Int("removed", removed))
+	}
+}
+
+// sweepArchive archives the daily log dirs older than days
+func sweepArchive(logger, errLogger *zap.Logger, fileDir string,
+	days int64, now time.Time) {
+	archived, err := archiveOldLog(fileDir, days, now)
+	if err != nil {
+		errLogger.Error("zlog: archive old log error",
+			zap.String("path", fileDir), zap.Error(err))
+	}
+
+	logger.Info("zlog: archive old log",
+		zap.String("path", fileDir), zap.Int("archived", archived))
+}
+
+// sweepSize trims the log path to the max total size
+func sweepSize(logger, errLogger *zap.Logger, fileDir string,
+	maxSize int64, now time.Time) {
+	removed, reclaimed, err := trimLogSize(fileDir, maxSize, now)
+	if err != nil {
+		errLogger.Error("zlog: trim log size error",
+			zap.String("path", fileDir), zap.Error(err))
+	}
+
+	logger.Info("zlog: trim log size",
+		zap.String("path", fileDir), zap.Strings("removed", removed),
+		zap.Int64("reclaimed", reclaimed))
+}
+
+// deleteOldLog removes the daily log dirs and their archives older
+// than maxDays, only the children of fileDir named with DayFormat
+// and the <date>.tar.gz files are removed, the audit files are kept
+// for deleteOldAudit, it returns the number of removed dirs and
+// archives.
+func deleteOldLog(fileDir string, maxDays int64, now time.Time) (removed int, err error) {
+	dirs, err := ioutil.ReadDir(fileDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	deadline := today.AddDate(0, 0, -int(maxDays))
+
+	for _, fi := range dirs {
+		name := fi.Name()
+		if !fi.IsDir() {
+			if !strings.HasSuffix(name, archiveExt) {
+				continue
+			}
+			name = strings.TrimSuffix(name, archiveExt)
+		}
+
+		day, parseErr := time.ParseInLocation(DayFormat, name, now.Location())
+		if parseErr != nil || !day.Before(deadline) {
+			continue
+		}
+
+		path := filepath.Join(fileDir, fi.Name())
+		if onlyAudit(path, fi) {
+			continue
+		}
+
+		if rmErr := removeDay(path, fi); rmErr != nil {
+			if err == nil {
+				err = fmt.Errorf("Failed to remove %s: %v", path, rmErr)
+			}
+			continue
+		}
+		removed++
+	}
+
+	return
+}
+
+// removeDay removes the daily log dir or archive path, the audit
+// files of the dir are kept for their own retention
+func removeDay(path string, fi os.FileInfo) error {
+	if !fi.IsDir() {
+		return os.RemoveAll(path)
+	}
+
+	fis, err := ioutil.ReadDir(path)
+	if err != nil {
+		return err
+	}
+
+	kept := false
+	for _, fi := range fis {
+		if isAudit(fi.Name()) {
+			kept = true
+			continue
+		}
+
+		if err := os.RemoveAll(filepath.Join(path, fi.Name())); err != nil {
+			return err
+		}
+	}
+
+	if kept {
+		return nil
+	}
+	return os.Remove(path)
+}
+
+// onlyAudit reports whether the dir path only has audit files left
+func onlyAudit(path string, fi os.FileInfo) bool {
+	if !fi.IsDir() {
+		return false
+	}
+
+	fis, err := ioutil.ReadDir(path)
+	if err != nil || len(fis) == 0 {
+		return false
+	}
+
+	for _, fi := range fis {
+		if !isAudit(fi.Name()) {
+			return false
+		}
+	}
+	return true
+}
+
+// deleteOldAudit removes the audit files of the daily log dirs older
+// than maxDays and the dirs left empty, it returns the number of
+// removed files.
+func deleteOldAudit(fileDir string, maxDays int64, now time.Time) (removed int, err error) {
+	dirs, err := ioutil.ReadDir(fileDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	deadline := today.AddDate(0, 0, -int(maxDays))
+
+	for _, fi := range dirs {
+		if !fi.IsDir() {
+			continue
+		}
+
+		day, parseErr := time.ParseInLocation(DayFormat, fi.Name(), now.Location())
+		if parseErr != nil || !day.Before(deadline) {
+			continue
+		}
+
+		dir := filepath.Join(fileDir, fi.Name())
+		files, globErr := filepath.Glob(filepath.Join(dir, "*"+auditSuffix))
+		if globErr != nil {
+			return removed, globErr
+		}
+
+		for _, file := range files {
+			if rmErr := os.Remove(file); rmErr != nil {
+				if err == nil {
+					err = fmt.Errorf("Failed to remove %s: %v", file, rmErr)
+				}
+				continue
+			}
+			removed++
+		}
+
+		// the dir of the other files is removed by deleteOldLog
+		if rest, _ := ioutil.ReadDir(dir); len(rest) == 0 {
+			os.Remove(dir)
+		}
+	}
+
+	return
+}
+
+// dayEntry a daily log dir or archive of the log path
+type dayEntry struct {
+	name string
+	info os.FileInfo
+	day  time.Time
+	size int64
+}
+
+// trimLogSize removes the oldest daily log dirs and archives until
+// the size of fileDir is under maxSize, the dir of today and the
+// newer ones are never removed, the audit files aren't counted or
+// removed, it returns the removed names and the reclaimed bytes.
+func trimLogSize(fileDir string, maxSize int64, now time.Time) (
+	removed []string, reclaimed int64, err error) {
+	fis, err := ioutil.ReadDir(fileDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, 0, nil
+		}
+		return nil, 0, err
+	}
+
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	var (
+		total int64
+		days  []dayEntry
+	)
+	for _, fi := range fis {
+		path := filepath.Join(fileDir, fi.Name())
+		size, sizeErr := dirSize(path, fi)
+		if sizeErr != nil {
+			return nil, 0, sizeErr
+		}
+		total += size
+
+		name := strings.TrimSuffix(fi.Name(), archiveExt)
+		if !fi.IsDir() && name == fi.Name() {
+			continue
+		}
+
+		day, parseErr := time.ParseInLocation(DayFormat, name, now.Location())
+		if parseErr != nil || !day.Before(today) || onlyAudit(path, fi) {
+			continue
+		}
+		days = append(days, dayEntry{name: fi.Name(), info: fi, day: day, size: size})
+	}
+
+	sort.Slice(days, func(i, j int) bool {
+		if days[i].day.Equal(days[j].day) {
+			return days[i].name < days[j].name
+		}
+		return days[i].day.Before(days[j].day)
+	})
+
+	for _, d := range days {
+		if total <= maxSize {
+			break
+		}
+
+		path := filepath.Join(fileDir, d.name)
+		if rmErr := removeDay(path, d.info); rmErr != nil {
+			if err == nil {
+				err = fmt.Errorf("Failed to remove %s: %v", path, rmErr)
+			}
+			continue
+		}
+
+		total -= d.size
+		reclaimed += d.size
+		removed = append(removed, d.name)
+	}
+
+	return
+}
+
+// dirSize returns the size of the files under path but the audit files
+func dirSize(path string, fi os.FileInfo) (int64, error) {
+	if !fi.IsDir() {
+		return fi.Size(), nil
+	}
+
+	var size int64
+	err := filepath.Walk(path, func(_ string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if fi.Mode().IsRegular() && !isAudit(fi.Name()) {
+			size += fi.Size()
+		}
+		return nil
+	})
+	return size, err
+}
@@ -0,0 +1,161 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vcaesar/tt"
+)
+
+func mkdir(t *testing.T, dir string) {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func oldDay(days int) string {
+	return time.Now().AddDate(0, 0, -days).Format(DayFormat)
+}
+
+func TestDeleteOldLog(t *testing.T) {
+	now := time.Date(2018, 5, 30, 10, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		name    string
+		dir     bool
+		removed bool
+	}{
+		{"2018-04-01", true, true},
+		{"2018-05-01", true, true},
+		{"2018-05-02", true, false},
+		{"2018-05-30", true, false},
+		{"2018-04-01.txt", true, false},
+		{"log_old", true, false},
+		{"backup/2018-04-01", true, false},
+		{"2018-04-02/nested/file.json", false, true},
+		{"2018-04-03", false, false},
+		{"notes.txt", false, false},
+	}
+
+	root := filepath.Join(t.TempDir(), "log")
+	for _, test := range tests {
+		path := filepath.Join(root, test.name)
+		if test.dir {
+			mkdir(t, path)
+			continue
+		}
+
+		mkdir(t, filepath.Dir(path))
+		if err := ioutil.WriteFile(path, []byte("test"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	removed, err := deleteOldLog(root, 28, now)
+	tt.Nil(t, err)
+	tt.Equal(t, 3, removed)
+
+	for _, test := range tests {
+		path := filepath.Join(root, test.name)
+		tt.Equal(t, !test.removed, exists(path), test.name)
+	}
+
+	removed, err = deleteOldLog(filepath.Join(root, "not_exist"), 28, now)
+	tt.Nil(t, err)
+	tt.Equal(t, 0, removed)
+}
+
+func TestCleaner(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "log")
+	old := filepath.Join(root, oldDay(30))
+	mkdir(t, old)
+
+	startCleaner(root, retention{maxDays: 28}, 10*time.Millisecond)
+	defer StopCleaner()
+
+	waitGone := func() bool {
+		for i := 0; i < 100; i++ {
+			if !exists(old) {
+				return true
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		return false
+	}
+	tt.True(t, waitGone())
+
+	// the next tick sweeps again
+	mkdir(t, old)
+	tt.True(t, waitGone())
+
+	StopCleaner()
+	mkdir(t, old)
+	time.Sleep(50 * time.Millisecond)
+	tt.True(t, exists(old))
+	tt.True(t, exists(root))
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func TestTrimLogSize(t *testing.T) {
+	now := time.Date(2018, 5, 30, 10, 0, 0, 0, time.Local)
+	root := filepath.Join(t.TempDir(), "log")
+
+	kb := strings.Repeat("x", 1024)
+	writeFile(t, filepath.Join(root, "2018-05-27", "test.json"), kb)
+	writeFile(t, filepath.Join(root, "2018-05-26.tar.gz"), kb)
+	writeFile(t, filepath.Join(root, "2018-05-28", "test.json"), kb)
+	writeFile(t, filepath.Join(root, "2018-05-28", "test_err.json"), kb)
+	writeFile(t, filepath.Join(root, "2018-05-29", "test.json"), kb)
+	writeFile(t, filepath.Join(root, "2018-05-30", "test.json"), kb+kb)
+	writeFile(t, filepath.Join(root, "notes.txt"), kb)
+
+	// 8k in total, the oldest are removed to 4k
+	removed, reclaimed, err := trimLogSize(root, 4*1024, now)
+	tt.Nil(t, err)
+	tt.Equal(t, []string{"2018-05-26.tar.gz", "2018-05-27", "2018-05-28"}, removed)
+	tt.Equal(t, int64(4*1024), reclaimed)
+	tt.True(t, exists(filepath.Join(root, "2018-05-29")))
+
+	// the dir of today is kept over the limit
+	removed, reclaimed, err = trimLogSize(root, 1024, now)
+	tt.Nil(t, err)
+	tt.Equal(t, []string{"2018-05-29"}, removed)
+	tt.Equal(t, int64(1024), reclaimed)
+	tt.True(t, exists(filepath.Join(root, "2018-05-30", "test.json")))
+
+	removed, _, err = trimLogSize(root, 1024, now)
+	tt.Nil(t, err)
+	tt.Equal(t, 0, len(removed))
+}
+
+func TestSweepSize(t *testing.T) {
+	logs := observe(t)
+	root := filepath.Join(t.TempDir(), "log")
+	writeFile(t, filepath.Join(root, oldDay(1), "test.json"), strings.Repeat("x", 1024))
+
+	sweep(packageZlog, root, retention{maxDays: 28, maxTotalSize: 512})
+	tt.False(t, exists(filepath.Join(root, oldDay(1))))
+
+	entries := logs.FilterMessage("zlog: trim log size").All()
+	tt.Equal(t, 1, len(entries))
+	tt.Equal(t, int64(1024), entries[0].ContextMap()["reclaimed"])
+	tt.Equal(t, []interface{}{oldDay(1)}, entries[0].ContextMap()["removed"])
+}
@@ -0,0 +1,507 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+// Package cloudwatch a zapcore.Core shipping the entries to a stream
+// of amazon cloudwatch logs by PutLogEvents, it is added to zlog by
+// zlog.AddCore. The api is the small CloudWatchAPI interface, an
+// adapter of the aws sdk client implements it.
+package cloudwatch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-vgo/gt/zlog"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// MaxBatchBytes the max size of a PutLogEvents, the size of an
+	// event is its message plus EventOverhead
+	MaxBatchBytes = 1048576
+	// MaxBatchEvents the max events of a PutLogEvents
+	MaxBatchEvents = 10000
+	// EventOverhead the bytes added to the size of each message
+	EventOverhead = 26
+	// maxEventBytes the max size of an event, the longer messages
+	// are cut
+	maxEventBytes = 262144
+
+	defaultFlushInterval = 5 * time.Second
+	defaultMaxRetries    = 3
+	defaultBackoff       = 200 * time.Millisecond
+	defaultTimeout       = 10 * time.Second
+	defaultMaxQueued     = 10 * MaxBatchEvents
+)
+
+// The error codes of the api handled by the core
+const (
+	CodeInvalidSequenceToken  = "InvalidSequenceTokenException"
+	CodeDataAlreadyAccepted   = "DataAlreadyAcceptedException"
+	CodeResourceNotFound      = "ResourceNotFoundException"
+	CodeResourceAlreadyExists = "ResourceAlreadyExistsException"
+	CodeThrottling            = "ThrottlingException"
+	CodeServiceUnavailable    = "ServiceUnavailableException"
+)
+
+// ErrQueueFull the error of the entries dropped because too many
+// entries are queued
+var ErrQueueFull = errors.New("cloudwatch: the queue of the entries is full")
+
+// InputLogEvent an event of PutLogEvents, Timestamp in milliseconds
+type InputLogEvent struct {
+	Message   string
+	Timestamp int64
+}
+
+// PutLogEventsInput the input of PutLogEvents
+type PutLogEventsInput struct {
+	LogGroupName  string
+	LogStreamName string
+	LogEvents     []InputLogEvent
+	// SequenceToken the NextSequenceToken of the previous call,
+	// nil for the first call to a stream
+	SequenceToken *string
+}
+
+// PutLogEventsOutput the output of PutLogEvents
+type PutLogEventsOutput struct {
+	NextSequenceToken *string
+}
+
+// CreateLogStreamInput the input of CreateLogStream
+type CreateLogStreamInput struct {
+	LogGroupName  string
+	LogStreamName string
+}
+
+// CloudWatchAPI the calls of cloudwatch logs used by the core, the
+// errors with a code are an *Error or have the ErrorCode or the Code
+// method of the aws sdk errors
+type CloudWatchAPI interface {
+	PutLogEvents(ctx context.Context, in *PutLogEventsInput) (*PutLogEventsOutput, error)
+	CreateLogStream(ctx context.Context, in *CreateLogStreamInput) error
+}
+
+// Error an error of the api, ExpectedSequenceToken is set for the
+// InvalidSequenceTokenException and the DataAlreadyAcceptedException
+type Error struct {
+	Code                  string
+	Message               string
+	ExpectedSequenceToken *string
+}
+
+func (e *Error) Error() string {
+	return "cloudwatch: " + e.Code + ": " + e.Message
+}
+
+// errorCode returns the api code of err
+func errorCode(err error) string {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr.Code
+	}
+
+	var v2 interface{ ErrorCode() string }
+	if errors.As(err, &v2) {
+		return v2.ErrorCode()
+	}
+	var v1 interface{ Code() string }
+	if errors.As(err, &v1) {
+		return v1.Code()
+	}
+	return ""
+}
+
+// expectedToken returns the expected sequence token of err
+func expectedToken(err error) *string {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr.ExpectedSequenceToken
+	}
+	return nil
+}
+
+type options struct {
+	level         zapcore.LevelEnabler
+	flushInterval time.Duration
+	maxRetries    int
+	backoff       time.Duration
+	timeout       time.Duration
+	maxQueued     int
+	onError       func(n int, err error)
+}
+
+// Option an option of New
+type Option func(*options)
+
+// WithLevel sets the enabled levels, default info
+func WithLevel(l zapcore.LevelEnabler) Option {
+	return func(o *options) { o.level = l }
+}
+
+// WithFlushInterval sets the max wait of an entry before its batch
+// is put, default 5s
+func WithFlushInterval(d time.Duration) Option {
+	return func(o *options) { o.flushInterval = d }
+}
+
+// WithMaxRetries sets the retries of a throttled batch before its
+// entries are dropped, default 3
+func WithMaxRetries(n int) Option {
+	return func(o *options) { o.maxRetries = n }
+}
+
+// WithBackoff sets the first wait before a retry, it doubles on
+// each retry, default 200ms
+func WithBackoff(d time.Duration) Option {
+	return func(o *options) { o.backoff = d }
+}
+
+// WithTimeout sets the timeout of a call of the api, default 10s
+func WithTimeout(d time.Duration) Option {
+	return func(o *options) { o.timeout = d }
+}
+
+// WithMaxQueued sets the max entries queued while the api can't be
+// reached, the others are dropped, default 100000
+func WithMaxQueued(n int) Option {
+	return func(o *options) { o.maxQueued = n }
+}
+
+// WithErrorHandler sets fn to run with the entries which couldn't be
+// put, by default the failures are counted by zlog.Stats and run the
+// zlog.OnWriteError funcs
+func WithErrorHandler(fn func(n int, err error)) Option {
+	return func(o *options) { o.onError = fn }
+}
+
+type event struct {
+	ts  int64
+	msg string
+}
+
+// batcher the events shared by a Core and its With copies
+type batcher struct {
+	opts          options
+	client        CloudWatchAPI
+	group, stream string
+
+	mu      sync.Mutex
+	pending []event
+	size    int
+	closed  bool
+
+	// putLock serializes the puts of Sync and the flush goroutine,
+	// it guards created and token
+	putLock sync.Mutex
+	created bool
+	token   *string
+
+	kick       chan struct{}
+	stop, done chan struct{}
+	once       sync.Once
+
+	dropped int64
+}
+
+// Core a zapcore.Core batching the json entries of the loggers and
+// putting them to a log stream in the background, the stream is
+// created on the first put
+type Core struct {
+	zapcore.LevelEnabler
+	enc zapcore.Encoder
+	b   *batcher
+}
+
+// New returns the core of the log stream and starts its flush
+// goroutine, call Close to put the last entries.
+//
+//	core := cloudwatch.New(client, "/app/api", "api-1")
+//	zlog.AddCore(core)
+//	defer core.Close()
+func New(client CloudWatchAPI, group, stream string, opts ...Option) *Core {
+	o := options{
+		level:         zapcore.InfoLevel,
+		flushInterval: defaultFlushInterval,
+		maxRetries:    defaultMaxRetries,
+		backoff:       defaultBackoff,
+		timeout:       defaultTimeout,
+		maxQueued:     defaultMaxQueued,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.onError == nil {
+		name := fmt.Sprintf("cloudwatch %s/%s", group, stream)
+		o.onError = func(n int, err error) {
+			zlog.ReportWriteError(name, int64(n), err)
+		}
+	}
+
+	b := &batcher{
+		opts:   o,
+		client: client,
+		group:  group,
+		stream: stream,
+		kick:   make(chan struct{}, 1),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go b.run()
+
+	encCfg := zap.NewProductionEncoderConfig()
+	encCfg.EncodeTime = zapcore.ISO8601TimeEncoder
+	encCfg.LineEnding = ""
+
+	return &Core{
+		LevelEnabler: o.level,
+		enc:          zapcore.NewJSONEncoder(encCfg),
+		b:            b,
+	}
+}
+
+// With adds the fields to the events of the returned core
+func (c *Core) With(fields []zapcore.Field) zapcore.Core {
+	enc := c.enc.Clone()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	return &Core{LevelEnabler: c.LevelEnabler, enc: enc, b: c.b}
+}
+
+// Check adds the core to ce if the level is enabled
+func (c *Core) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write encodes the entry and queues it for the next put
+func (c *Core) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+
+	msg := truncate(buf.String(), maxEventBytes-EventOverhead)
+	buf.Free()
+
+	c.b.add(event{ts: ent.Time.UnixNano() / int64(time.Millisecond), msg: msg})
+	return nil
+}
+
+// Sync puts the queued entries
+func (c *Core) Sync() error {
+	return c.b.flush()
+}
+
+// Close puts the queued entries and stops the flush goroutine,
+// the entries written after Close are ignored.
+func (c *Core) Close() error {
+	c.b.once.Do(func() {
+		c.b.mu.Lock()
+		c.b.closed = true
+		c.b.mu.Unlock()
+		close(c.b.stop)
+	})
+	<-c.b.done
+	return c.b.flush()
+}
+
+// Dropped returns the number of the entries dropped after the
+// retries of their put or because too many entries were queued
+func (c *Core) Dropped() int64 {
+	return atomic.LoadInt64(&c.b.dropped)
+}
+
+func (b *batcher) add(e event) {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return
+	}
+	if len(b.pending) >= b.opts.maxQueued {
+		b.mu.Unlock()
+		b.drop(1, ErrQueueFull)
+		return
+	}
+
+	b.pending = append(b.pending, e)
+	b.size += len(e.msg) + EventOverhead
+	full := len(b.pending) >= MaxBatchEvents || b.size >= MaxBatchBytes
+	b.mu.Unlock()
+
+	if full {
+		select {
+		case b.kick <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (b *batcher) drop(n int, err error) {
+	atomic.AddInt64(&b.dropped, int64(n))
+	b.opts.onError(n, err)
+}
+
+func (b *batcher) run() {
+	defer close(b.done)
+
+	ticker := time.NewTicker(b.opts.flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-b.kick:
+		case <-b.stop:
+			return
+		}
+		b.flush()
+	}
+}
+
+// take returns the next batch within the limits of PutLogEvents
+func (b *batcher) take() []event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	n, size := 0, 0
+	for n < len(b.pending) && n < MaxBatchEvents {
+		s := len(b.pending[n].msg) + EventOverhead
+		if size+s > MaxBatchBytes {
+			break
+		}
+		size += s
+		n++
+	}
+
+	batch := b.pending[:n:n]
+	b.pending = b.pending[n:]
+	b.size -= size
+	return batch
+}
+
+// flush puts the queued entries by batches
+func (b *batcher) flush() error {
+	b.putLock.Lock()
+	defer b.putLock.Unlock()
+
+	var err error
+	for {
+		batch := b.take()
+		if len(batch) == 0 {
+			return err
+		}
+
+		if putErr := b.putRetry(batch); putErr != nil {
+			b.drop(len(batch), putErr)
+			err = putErr
+		}
+	}
+}
+
+// putRetry puts batch, the token is refreshed on the invalid
+// sequence tokens, the stream is created again if it was deleted
+// and the throttled puts are retried with backoff
+func (b *batcher) putRetry(batch []event) error {
+	// the events of a put are in chronological order
+	sort.SliceStable(batch, func(i, j int) bool {
+		return batch[i].ts < batch[j].ts
+	})
+	events := make([]InputLogEvent, len(batch))
+	for i, e := range batch {
+		events[i] = InputLogEvent{Message: e.msg, Timestamp: e.ts}
+	}
+
+	backoff := b.opts.backoff
+	for i := 0; ; i++ {
+		err := b.put(events)
+		if err == nil {
+			return nil
+		}
+
+		code := errorCode(err)
+		switch code {
+		case CodeDataAlreadyAccepted:
+			b.token = expectedToken(err)
+			return nil
+		case CodeInvalidSequenceToken:
+			b.token = expectedToken(err)
+		case CodeResourceNotFound:
+			b.created, b.token = false, nil
+		case CodeThrottling, CodeServiceUnavailable:
+		default:
+			return err
+		}
+		if i >= b.opts.maxRetries {
+			return err
+		}
+
+		if code == CodeThrottling || code == CodeServiceUnavailable {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}
+}
+
+// put creates the stream if it isn't created yet and puts events
+func (b *batcher) put(events []InputLogEvent) error {
+	ctx, cancel := context.WithTimeout(context.Background(), b.opts.timeout)
+	defer cancel()
+
+	if !b.created {
+		err := b.client.CreateLogStream(ctx, &CreateLogStreamInput{
+			LogGroupName:  b.group,
+			LogStreamName: b.stream,
+		})
+		if err != nil && errorCode(err) != CodeResourceAlreadyExists {
+			return err
+		}
+		b.created = true
+	}
+
+	out, err := b.client.PutLogEvents(ctx, &PutLogEventsInput{
+		LogGroupName:  b.group,
+		LogStreamName: b.stream,
+		LogEvents:     events,
+		SequenceToken: b.token,
+	})
+	if err != nil {
+		return err
+	}
+
+	if out != nil {
+		b.token = out.NextSequenceToken
+	}
+	return nil
+}
+
+// truncate cuts s to max bytes on a rune boundary, the messages
+// are utf-8
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+
+	for max > 0 && !utf8.RuneStart(s[max]) {
+		max--
+	}
+	return s[:max]
+}
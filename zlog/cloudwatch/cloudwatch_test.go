@@ -0,0 +1,267 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package cloudwatch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/vcaesar/tt"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// fakeAPI a log stream checking the sequence tokens, the errors of
+// putErrs are returned by the next puts
+type fakeAPI struct {
+	mu      sync.Mutex
+	exists  bool
+	token   int
+	creates int
+	calls   int
+	puts    [][]InputLogEvent
+	putErrs []error
+}
+
+func (f *fakeAPI) CreateLogStream(ctx context.Context, in *CreateLogStreamInput) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.creates++
+	if f.exists {
+		return &Error{Code: CodeResourceAlreadyExists}
+	}
+	f.exists = true
+	return nil
+}
+
+func (f *fakeAPI) PutLogEvents(ctx context.Context, in *PutLogEventsInput) (*PutLogEventsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls++
+	if len(f.putErrs) > 0 {
+		err := f.putErrs[0]
+		f.putErrs = f.putErrs[1:]
+		return nil, err
+	}
+	if !f.exists {
+		return nil, &Error{Code: CodeResourceNotFound}
+	}
+
+	expected := f.tokenString()
+	if f.token > 0 && (in.SequenceToken == nil || *in.SequenceToken != *expected) {
+		return nil, &Error{Code: CodeInvalidSequenceToken, ExpectedSequenceToken: expected}
+	}
+
+	f.puts = append(f.puts, append([]InputLogEvent(nil), in.LogEvents...))
+	f.token++
+	return &PutLogEventsOutput{NextSequenceToken: f.tokenString()}, nil
+}
+
+func (f *fakeAPI) tokenString() *string {
+	token := fmt.Sprint("t", f.token)
+	return &token
+}
+
+func (f *fakeAPI) events() []InputLogEvent {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var events []InputLogEvent
+	for _, put := range f.puts {
+		events = append(events, put...)
+	}
+	return events
+}
+
+// maxPut checks the limits of the puts and returns the events
+// of the largest one
+func (f *fakeAPI) maxPut() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	max := 0
+	for _, put := range f.puts {
+		size := 0
+		for _, e := range put {
+			size += len(e.Message) + EventOverhead
+		}
+		if size > MaxBatchBytes || len(put) > MaxBatchEvents {
+			return -1
+		}
+		if len(put) > max {
+			max = len(put)
+		}
+	}
+	return max
+}
+
+func TestBatchLimits(t *testing.T) {
+	api := &fakeAPI{}
+	core := New(api, "/app", "api", WithFlushInterval(time.Hour))
+	logger := zap.New(core)
+
+	// the full batches are also put by the flush goroutine
+	for i := 0; i < MaxBatchEvents+1; i++ {
+		logger.Info("entry", zap.Int("i", i))
+	}
+	tt.Nil(t, core.Sync())
+	tt.Equal(t, MaxBatchEvents+1, len(api.events()))
+	tt.True(t, api.maxPut() == MaxBatchEvents)
+	tt.Equal(t, 1, api.creates)
+
+	// 4 events of the max size fill a put
+	large := strings.Repeat("x", maxEventBytes)
+	for i := 0; i < 5; i++ {
+		logger.Info(large)
+	}
+	tt.Nil(t, core.Sync())
+	tt.Equal(t, MaxBatchEvents+6, len(api.events()))
+	tt.Equal(t, maxEventBytes-EventOverhead, len(api.events()[MaxBatchEvents+1].Message))
+	tt.True(t, api.maxPut() == MaxBatchEvents)
+
+	var m map[string]interface{}
+	tt.Nil(t, json.Unmarshal([]byte(api.events()[0].Message), &m))
+	tt.Equal(t, "info", m["level"])
+	tt.Equal(t, float64(0), m["i"])
+	tt.Nil(t, core.Close())
+}
+
+func TestChronological(t *testing.T) {
+	api := &fakeAPI{}
+	core := New(api, "/app", "api", WithFlushInterval(time.Hour))
+
+	now := time.Now()
+	tt.Nil(t, core.Write(zapcore.Entry{Time: now.Add(time.Second), Message: "b"}, nil))
+	tt.Nil(t, core.Write(zapcore.Entry{Time: now, Message: "a"}, nil))
+	tt.Nil(t, core.Close())
+
+	events := api.events()
+	tt.Equal(t, 2, len(events))
+	tt.True(t, events[0].Timestamp < events[1].Timestamp)
+	tt.Equal(t, now.UnixNano()/int64(time.Millisecond), events[0].Timestamp)
+}
+
+func TestSequenceToken(t *testing.T) {
+	api := &fakeAPI{}
+	core := New(api, "/app", "api", WithFlushInterval(time.Hour))
+	logger := zap.New(core)
+
+	logger.Info("first")
+	tt.Nil(t, core.Sync())
+	logger.Info("second")
+	tt.Nil(t, core.Sync())
+	tt.Equal(t, "t2", *core.b.token)
+
+	// another writer of the stream
+	api.token = 5
+	logger.Info("third")
+	tt.Nil(t, core.Sync())
+	tt.Equal(t, 3, len(api.puts))
+	tt.Equal(t, "t6", *core.b.token)
+	tt.Equal(t, 0, int(core.Dropped()))
+
+	// the batch was put before the error
+	api.putErrs = []error{&Error{Code: CodeDataAlreadyAccepted, ExpectedSequenceToken: api.tokenString()}}
+	logger.Info("accepted")
+	tt.Nil(t, core.Sync())
+	tt.Equal(t, 3, len(api.puts))
+	tt.Equal(t, 0, int(core.Dropped()))
+	tt.Nil(t, core.Close())
+}
+
+func TestRetry(t *testing.T) {
+	api := &fakeAPI{}
+	var (
+		mu      sync.Mutex
+		dropped []error
+	)
+	core := New(api, "/app", "api", WithFlushInterval(time.Hour),
+		WithBackoff(time.Millisecond), WithMaxRetries(2),
+		WithErrorHandler(func(n int, err error) {
+			mu.Lock()
+			dropped = append(dropped, err)
+			mu.Unlock()
+		}))
+	logger := zap.New(core)
+
+	throttled := &Error{Code: CodeThrottling, Message: "rate exceeded"}
+	api.putErrs = []error{throttled, throttled}
+	logger.Info("throttled")
+	tt.Nil(t, core.Sync())
+	tt.Equal(t, 1, len(api.puts))
+	tt.Equal(t, 3, api.calls)
+
+	api.putErrs = []error{throttled, throttled, throttled}
+	logger.Info("dropped")
+	tt.NotNil(t, core.Sync())
+	tt.Equal(t, 1, int(core.Dropped()))
+	tt.Equal(t, 1, len(dropped))
+	tt.True(t, strings.Contains(dropped[0].Error(), "rate exceeded"))
+
+	// the other errors aren't retried
+	api.calls = 0
+	api.putErrs = []error{errors.New("denied")}
+	logger.Info("denied")
+	tt.NotNil(t, core.Sync())
+	tt.Equal(t, 1, api.calls)
+	tt.Equal(t, 2, int(core.Dropped()))
+
+	// the deleted stream is created again
+	api.exists = false
+	logger.Info("created")
+	tt.Nil(t, core.Sync())
+	tt.Equal(t, 2, api.creates)
+	tt.Equal(t, 2, len(api.puts))
+	tt.Nil(t, core.Close())
+}
+
+func TestErrorCode(t *testing.T) {
+	tt.Equal(t, CodeThrottling, errorCode(fmt.Errorf("put: %w", &Error{Code: CodeThrottling})))
+	tt.Equal(t, "", errorCode(errors.New("e1")))
+	tt.Equal(t, "a", truncate("a世", 3))
+}
+
+func TestFlush(t *testing.T) {
+	api := &fakeAPI{}
+	core := New(api, "/app", "api", WithFlushInterval(10*time.Millisecond))
+	logger := zap.New(core)
+
+	logger.Info("timer")
+	for i := 0; i < 200 && len(api.events()) == 0; i++ {
+		time.Sleep(5 * time.Millisecond)
+	}
+	tt.Equal(t, 1, len(api.events()))
+
+	var full error
+	hold := New(api, "/app", "api", WithFlushInterval(time.Hour), WithMaxQueued(2),
+		WithErrorHandler(func(n int, err error) { full = err }))
+	logger = zap.New(hold)
+	for i := 0; i < 3; i++ {
+		logger.Warn("close")
+	}
+	tt.Nil(t, hold.Close())
+	tt.Equal(t, 3, len(api.events()))
+	tt.Equal(t, 1, int(hold.Dropped()))
+	tt.Equal(t, ErrQueueFull, full)
+
+	logger.Warn("after close")
+	tt.Nil(t, hold.Sync())
+	tt.Equal(t, 3, len(api.events()))
+	tt.Nil(t, core.Close())
+}
@@ -0,0 +1,172 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+// Command zlogcat renders the json log files of zlog as the colored
+// console lines, the files or stdin are read to the end, -follow
+// follows the log file of a log dir across the daily dirs.
+//
+//	zlogcat -level warn -since 1h log/2018-05-01/log.json
+//	zlogcat -follow -grep timeout ./log
+package main
+
+import (
+	"compress/gzip"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-vgo/gt/zlog"
+)
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintln(os.Stderr, "zlogcat:", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	var (
+		level      = flag.String("level", "", "the lowest `level` of the entries")
+		since      = flag.String("since", "", "the entries since the `time`, a duration like 1h or a time like 2018-05-01T08:00:00Z")
+		grep       = flag.String("grep", "", "the `regexp` of the lines")
+		follow     = flag.Bool("follow", false, "follow the log file of the log dir or the file like tail -f")
+		file       = flag.String("file", "log.json", "the log `file` followed in the log dir")
+		color      = flag.String("color", "auto", "color the lines, \"auto\", \"always\" or \"never\"")
+		timeFormat = flag.String("time-format", "", "the Go `layout` of the times")
+	)
+	flag.BoolVar(follow, "f", false, "shorthand of -follow")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: zlogcat [flags] [file ...]\n       zlogcat -follow [flags] [dir | file]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	opts := zlog.PrettyOptions{MinLevel: *level, Grep: *grep, TimeFormat: *timeFormat}
+	var err error
+	if opts.Since, err = parseSince(*since, time.Now()); err != nil {
+		return err
+	}
+	if opts.Color, err = useColor(*color); err != nil {
+		return err
+	}
+
+	if *follow {
+		return followLogs(flag.Args(), *file, opts)
+	}
+
+	if flag.NArg() == 0 {
+		return zlog.Pretty(os.Stdin, os.Stdout, opts)
+	}
+	for _, path := range flag.Args() {
+		if err := catFile(path, opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseSince parses a duration before now or a time
+func parseSince(s string, now time.Time) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	for _, layout := range []string{time.RFC3339, zlog.TimeFormat, zlog.DayFormat} {
+		if tm, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return tm, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unknown -since %q", s)
+}
+
+// useColor reports whether the lines are colored, auto colors
+// the terminals
+func useColor(mode string) (bool, error) {
+	switch mode {
+	case "always":
+		return true, nil
+	case "never":
+		return false, nil
+	case "auto":
+		fi, err := os.Stdout.Stat()
+		return err == nil && fi.Mode()&os.ModeCharDevice != 0, nil
+	}
+	return false, fmt.Errorf("unknown -color %q", mode)
+}
+
+// catFile renders the file of path, gunzipped for a .gz path
+func catFile(path string, opts zlog.PrettyOptions) error {
+	if path == "-" {
+		return zlog.Pretty(os.Stdin, os.Stdout, opts)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+	return zlog.Pretty(r, os.Stdout, opts)
+}
+
+// followLogs follows the log file of a log dir, or a log file,
+// until the interrupt
+func followLogs(args []string, file string, opts zlog.PrettyOptions) error {
+	if len(args) > 1 {
+		return errors.New("-follow follows a dir or a file")
+	}
+
+	fo := zlog.FollowOptions{Path: "./log", File: file}
+	if len(args) == 1 {
+		fo.Path = args[0]
+	}
+
+	// a log file or the log file of the dir without the daily dirs
+	flat := false
+	if fi, err := os.Stat(fo.Path); err == nil && !fi.IsDir() {
+		fo.Path, fo.File = filepath.Dir(fo.Path), filepath.Base(fo.Path)
+		flat = true
+	} else if _, err := os.Stat(filepath.Join(fo.Path, fo.File)); err == nil {
+		flat = true
+	}
+	if flat {
+		daily := false
+		fo.DailyDirs = &daily
+	}
+
+	r := zlog.Follow(fo)
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt)
+	go func() {
+		<-sigs
+		r.Close()
+	}()
+
+	return zlog.Pretty(r, os.Stdout, opts)
+}
@@ -0,0 +1,484 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/multierr"
+)
+
+// Config the zlog config, it is decoded from the toml file by Init
+// or passed to InitWithConfig.
+type Config struct {
+	// Mode "dev" logs to stderr with the zap development config,
+	// "stdout" writes the json entries to stdout and stderr without
+	// any file, "discard" encodes the entries and drops them,
+	// other modes write the json log files
+	Mode string
+	// Path the log path, default "./log"
+	Path string
+	// Name the log file name, default "log"
+	Name string
+	// DailyDirs writes the log files to the daily dirs Path/<date>,
+	// false writes Path/Name.json and Path/Name_err.json with
+	// the lumberjack rotation and MaxAge only, default true
+	DailyDirs *bool `toml:"daily_dirs"`
+	// DirMode the octal mode of the created log dirs, default "0755"
+	DirMode string `toml:"dir_mode"`
+	// FileMode the octal mode of the log files like "0600", the
+	// rotated files keep it, default the mode of lumberjack
+	FileMode string `toml:"file_mode"`
+	// MaxDays the days to keep the daily log dirs, default 28
+	MaxDays int64 `toml:"max_days"`
+	// Level the level of the main log, debug, info, warn or error,
+	// default "info"
+	Level string `toml:"level"`
+	// MaxSize the max megabytes of a log file before it is rotated,
+	// default 500
+	MaxSize int `toml:"max_size"`
+	// MaxBackups the max number of rotated files of a day, default 3
+	MaxBackups int `toml:"max_backups"`
+	// MaxAge the max days to keep the rotated files,
+	// default MaxDays or 28
+	MaxAge int `toml:"max_age"`
+	// RotateEvery rotates the log files on each period regardless of
+	// the size, "hour", "day" or a duration from 1m to 24h, the files
+	// are named like name-2018-05-01T14.json, the periods start from
+	// the midnight, default no time rotation
+	RotateEvery string `toml:"rotate_every"`
+	// Compress gzip the rotated files
+	Compress bool `toml:"compress"`
+	// Async writes the log files in a goroutine, the log calls only
+	// queue the entries, they're dropped when the queue is full and
+	// counted by Stats, Sync, Close and Fatal write the queue
+	Async bool `toml:"async"`
+	// QueueSize the entries queued for each log file with Async,
+	// default 4096
+	QueueSize int `toml:"queue_size"`
+	// FlushInterval the max wait of a queued entry with Async or
+	// of a buffered entry with BufferSize, default "1s"
+	FlushInterval string `toml:"flush_interval"`
+	// BufferSize the bytes of the entries buffered in memory before
+	// a write of the log file, the buffer is written every
+	// FlushInterval, on Sync, Close, Rotate and before the Fatal
+	// and Panic exits, it conflicts with Async, default 0 no buffer
+	BufferSize int `toml:"buffer_size"`
+	// ArchiveOldDays tar and gzip the daily log dirs older than
+	// CompressAfterDays into <date>.tar.gz next to them, the
+	// archives are deleted after MaxDays like the dirs
+	ArchiveOldDays bool `toml:"archive_old_days"`
+	// MaxTotalSizeMB the max megabytes of the log path, the oldest
+	// daily log dirs and archives are deleted above it even if they
+	// are newer than MaxDays, the dir of today is kept, 0 no limit
+	MaxTotalSizeMB int64 `toml:"max_total_size_mb"`
+	// CompressAfterDays the days to keep a daily log dir before
+	// it is archived, default 1
+	CompressAfterDays int64 `toml:"compress_after_days"`
+	// CleanInterval the interval of the old log sweep, default "24h"
+	CleanInterval string `toml:"clean_interval"`
+	// AuditSync syncs the audit file to the disk after each entry of
+	// Audit, the entries aren't lost by a crash of the system
+	AuditSync bool `toml:"audit_sync"`
+	// AuditMaxDays the days to keep the audit files of the daily log
+	// dirs, they're never archived or trimmed by MaxTotalSizeMB,
+	// default MaxDays
+	AuditMaxDays int64 `toml:"audit_max_days"`
+	// TenantFiles also writes the entries of the loggers of ForTenant
+	// to Path/<date>/tenants/<id>.json, the file of a tenant is opened
+	// on its first entry and removed with the daily dir
+	TenantFiles bool `toml:"tenant_files"`
+	// MaxTenantFiles the max tenant files open at once, the least
+	// recently used one is closed above it, default 64
+	MaxTenantFiles int `toml:"max_tenant_files"`
+	// AccessFile writes the entries of Access and the middlewares to
+	// Path/<date>/name_access.json instead of the main log file
+	AccessFile bool `toml:"access_file"`
+	// Stdout also writes the json entries of the log files to stdout,
+	// the entries of the error log go to stderr
+	Stdout bool `toml:"stdout"`
+	// SplitLevels writes the debug, info and warn entries to
+	// name_debug.json, name.json and name_warn.json, the error
+	// entries stay in name_err.json
+	SplitLevels bool `toml:"split_levels"`
+	// MirrorErrors also writes the entries of the error log to the
+	// main log file, so it has all the entries in order, it isn't
+	// used with SplitLevels, default true
+	MirrorErrors *bool `toml:"mirror_errors"`
+	// Encoding the encoding of the entries, "json", "console",
+	// "logfmt", "gelf" the graylog messages, "ecs" the elastic
+	// common schema or "gcp" the structured logs of google cloud,
+	// default "json"
+	Encoding string `toml:"encoding"`
+	// FileEncoding the encoding of the log files, default Encoding
+	FileEncoding string `toml:"file_encoding"`
+	// StdoutEncoding the encoding of the stdout and stderr output,
+	// default Encoding
+	StdoutEncoding string `toml:"stdout_encoding"`
+	// StacktraceLevel the level from which the stacktrace is added,
+	// "none" or "disabled" turns it off, default "error"
+	StacktraceLevel string `toml:"stacktrace_level"`
+	// Caller adds the file:line of the caller of the package
+	// functions, the dev mode always adds it
+	Caller bool `toml:"caller"`
+	// RuntimeFieldsOnError adds the goroutines, heap_alloc, heap_sys
+	// and gc_count fields to the error and above entries, the memory
+	// stats are read at most once per second
+	RuntimeFieldsOnError bool `toml:"runtime_fields_on_error"`
+	// Redact the keys of the fields whose values are replaced by
+	// "[REDACTED]" like SetRedactedKeys, empty keeps the keys of
+	// SetRedactedKeys
+	Redact []string `toml:"redact"`
+	// MaxFieldLen the max bytes of the message and the string fields,
+	// the longer ones are cut and get a "...(truncated N bytes)" suffix
+	// and the entry the field truncated: true, default 0 no limit
+	MaxFieldLen int `toml:"max_field_len"`
+	// RingBuffer keeps the last RingBuffer entries in memory for
+	// Recent and RecentHandler, an entry is at most 4KB, default 0 off
+	RingBuffer int `toml:"ring_buffer"`
+	// Fields the fields added to every entry of the loggers, like the
+	// hostname, the pid and the app
+	Fields FieldsConfig `toml:"fields"`
+	// BuildInfoFields adds the vcs.revision, vcs.time, vcs.modified
+	// and module.version fields of the build info of the binary
+	// to every entry, see SetBuildInfo
+	BuildInfoFields bool `toml:"build_info_fields"`
+	// K8sFields adds the pod, namespace, node and container fields
+	// of the env vars of the [k8s] table to every entry, the unset
+	// vars add nothing so it runs outside of kubernetes as well, the
+	// hostname field is left out when it is the pod name
+	K8sFields bool `toml:"k8s_fields"`
+	// K8s the env vars of K8sFields
+	K8s K8sConfig `toml:"k8s"`
+	// Encoder the keys and the formats of the encoder
+	Encoder EncoderConfig `toml:"encoder"`
+	// Syslog also writes the entries of the log files to syslog
+	Syslog SyslogConfig `toml:"syslog"`
+	// Journal also writes the entries of the log files to the systemd
+	// journal with the syslog priority of their level, it is only
+	// supported on linux
+	Journal bool `toml:"journal"`
+	// Outputs the urls of the outputs also writing the entries of the
+	// log files, like "file:///var/log/app.json?maxsize=100", "stdout"
+	// or "tcp://vector:9000", the schemes of RegisterSink are opened
+	// by their factory
+	Outputs []string `toml:"outputs"`
+	// GELF also sends the entries of the log files to graylog
+	GELF GELFConfig `toml:"gelf"`
+	// Remote also sends the entries of the log files as json lines
+	// to a tcp or udp address like the vector or logstash sources
+	Remote RemoteConfig `toml:"remote"`
+	// Sampling samples the entries of the main logger
+	Sampling SamplingConfig `toml:"sampling"`
+	// Dedup writes an entry repeating the level, the message and the
+	// error of the previous one as the "last message repeated N times"
+	// entry, written when another entry comes, after DedupWindow or
+	// on Sync. It isn't used in dev mode.
+	Dedup bool `toml:"dedup"`
+	// DedupWindow the max wait of the repeats before they're written,
+	// default "10s"
+	DedupWindow string `toml:"dedup_window"`
+	// Fallback writes the entries to "stderr" or "stdout" when the
+	// writes of a log file fail, like on a full disk, the file is
+	// tried again every 10s, default "none" drops them
+	Fallback string `toml:"fallback"`
+	// Filters drops the entries below error by their message
+	Filters FilterConfig `toml:"filters"`
+	// Levels the levels of the loggers by their Named name or by the
+	// package path of the caller with Caller, like "db" = "warn", the
+	// longest match wins, they only raise the level and never drop
+	// the Error+ entries
+	Levels map[string]string `toml:"levels"`
+	// SlowThreshold the duration above which TimeTrack and Timed log
+	// at warn, like "500ms", empty always logs at info
+	SlowThreshold string `toml:"slow_threshold"`
+	// Strict makes DPanic panic like in dev mode, the errors which
+	// should never happen fail fast in the tests and the staging
+	Strict bool `toml:"strict"`
+	// StrictConfig returns an error for the unknown keys of the config
+	// file, they're logged at warn by default
+	StrictConfig bool `toml:"strict_config"`
+	// Srv  Server     `toml:"server"`
+}
+
+// SyslogConfig the syslog output, it isn't supported on windows
+type SyslogConfig struct {
+	// Enabled writes the entries to syslog
+	Enabled bool `toml:"enabled"`
+	// Network "unix", "unixgram", "tcp" or "udp", the local syslog
+	// daemon is used if Network and Address are empty
+	Network string `toml:"network"`
+	// Address the address of the syslog daemon
+	Address string `toml:"address"`
+	// Tag the tag of the messages, default the program name
+	Tag string `toml:"tag"`
+	// Facility "kern", "user", "daemon", "local0" to "local7" and
+	// the other syslog facilities, default "user"
+	Facility string `toml:"facility"`
+}
+
+// RemoteConfig the remote output, the entries are queued while the
+// remote can't be reached and dropped when the queue is full
+type RemoteConfig struct {
+	// Address the host:port of the remote, empty disables it
+	Address string `toml:"address"`
+	// Protocol "tcp" or "udp", default "tcp"
+	Protocol string `toml:"protocol"`
+	// DialTimeout the timeout of a dial, default "5s"
+	DialTimeout string `toml:"dial_timeout"`
+	// ReconnectBackoff the first wait before a reconnect, it doubles
+	// on each failure up to 30s, default "1s"
+	ReconnectBackoff string `toml:"reconnect_backoff"`
+	// BufferSize the entries queued for the remote, default 1024
+	BufferSize int `toml:"buffer_size"`
+}
+
+// GELFConfig the gelf udp output of graylog, the messages over the
+// datagram limit are chunked
+type GELFConfig struct {
+	// Address the host:port of the graylog gelf udp input, empty
+	// disables it
+	Address string `toml:"address"`
+	// Compression "gzip" or "none", default "none"
+	Compression string `toml:"compression"`
+	// HostnameOverride the host of the messages, default the hostname
+	HostnameOverride string `toml:"hostname_override"`
+}
+
+// SamplingConfig the zap sampling of the entries below error, each
+// second the first Initial entries of a level and a message are
+// logged, then every Thereafter entry, the error entries are never
+// sampled. It isn't used in dev mode.
+type SamplingConfig struct {
+	// Initial the entries logged each second, 0 disables the sampling
+	Initial int `toml:"initial"`
+	// Thereafter logs every Thereafter entry after Initial,
+	// 0 drops them all
+	Thereafter int `toml:"thereafter"`
+}
+
+var (
+	// configs the *Config of the last Init, it is replaced as a whole
+	// so the readers take no lock
+	configs atomic.Value
+	// configLock serializes the Init and the reloads
+	configLock sync.Mutex
+)
+
+// getConfig returns the config of the last Init, it must not be
+// modified
+func getConfig() *Config {
+	if c, ok := configs.Load().(*Config); ok {
+		return c
+	}
+	return &Config{}
+}
+
+// setConfig replaces the config of the last Init
+func setConfig(c Config) {
+	configs.Store(&c)
+}
+
+// validate checks the values of the config, all the problems
+// are returned at once
+func (c *Config) validate() error {
+	var errs []error
+	check := func(err error) {
+		errs = append(errs, err)
+	}
+
+	for _, n := range []struct {
+		key string
+		val int64
+	}{
+		{"max_days", c.MaxDays},
+		{"max_size", int64(c.MaxSize)},
+		{"max_backups", int64(c.MaxBackups)},
+		{"max_age", int64(c.MaxAge)},
+		{"max_total_size_mb", c.MaxTotalSizeMB},
+		{"compress_after_days", c.CompressAfterDays},
+		{"audit_max_days", c.AuditMaxDays},
+		{"max_tenant_files", int64(c.MaxTenantFiles)},
+	} {
+		if n.val < 0 {
+			check(fmt.Errorf("zlog: invalid %s %d", n.key, n.val))
+		}
+	}
+
+	if c.Name == "" && !c.dailyDirs() {
+		check(fmt.Errorf("zlog: name is required with daily_dirs false"))
+	}
+
+	switch c.Mode {
+	case "dev", "stdout", "discard":
+		// the options of the log files
+		for _, f := range []struct {
+			key string
+			set bool
+		}{
+			{"async", c.Async},
+			{"buffer_size", c.BufferSize > 0},
+			{"split_levels", c.SplitLevels},
+			{"stdout", c.Stdout},
+			{"tenant_files", c.TenantFiles},
+			{"access_file", c.AccessFile},
+		} {
+			if f.set {
+				check(fmt.Errorf("zlog: mode %q writes no log file, it conflicts with %s",
+					c.Mode, f.key))
+			}
+		}
+	}
+
+	_, err := c.cleanInterval()
+	check(err)
+	_, err = c.logLevel()
+	check(err)
+	_, _, err = c.stacktraceLevel()
+	check(err)
+	_, err = c.rotateEvery()
+	check(err)
+	_, err = c.dirMode()
+	check(err)
+	_, err = c.fileMode()
+	check(err)
+	_, _, err = c.asyncConf()
+	check(err)
+	_, _, err = c.bufferConf()
+	check(err)
+	check(c.checkFieldLen())
+	check(c.checkRingBuffer())
+	check(c.checkSampling())
+	_, err = c.dedupWindow()
+	check(err)
+	_, err = c.fallbackOutput()
+	check(err)
+	_, err = c.slowThreshold()
+	check(err)
+	_, err = c.levelsConf()
+	check(err)
+	_, err = c.filtersConf()
+	check(err)
+	check(c.checkOutputs())
+	_, err = c.GELF.gelfCompress()
+	check(err)
+	_, err = c.fileEncoder()
+	check(err)
+	_, err = c.stdoutEncoder()
+	check(err)
+
+	return multierr.Combine(errs...)
+}
+
+func (c *Config) confPath() (string, string) {
+	// var lpath, name string
+	var lpath, name string = "./log", "log"
+
+	if c.Path != "" {
+		lpath = c.Path
+	}
+
+	if c.Name != "" {
+		name = c.Name
+	}
+
+	return lpath, name
+}
+
+// dailyDirs reports whether the log files are in the daily dirs
+func (c *Config) dailyDirs() bool {
+	return c.DailyDirs == nil || *c.DailyDirs
+}
+
+// mirrorErrors reports whether the error entries are also in
+// the main log file
+func (c *Config) mirrorErrors() bool {
+	return (c.MirrorErrors == nil || *c.MirrorErrors) && !c.SplitLevels
+}
+
+// rotateConf returns the lumberjack rotation of the config
+func (c *Config) rotateConf() rotateConfig {
+	rotate := rotateConfig{
+		maxSize:    500,
+		maxBackups: 3,
+		maxAge:     c.MaxAge,
+		compress:   c.Compress,
+	}
+
+	if c.MaxSize != 0 {
+		rotate.maxSize = c.MaxSize
+	}
+
+	if c.MaxBackups != 0 {
+		rotate.maxBackups = c.MaxBackups
+	}
+
+	if rotate.maxAge == 0 {
+		rotate.maxAge = int(c.maxDays())
+	}
+
+	// validated by InitWithConfig
+	rotate.every, _ = c.rotateEvery()
+	rotate.flat = !c.dailyDirs()
+	rotate.dirMode, _ = c.dirMode()
+	rotate.fileMode, _ = c.fileMode()
+	return rotate
+}
+
+// dirMode returns the mode of the log dirs
+func (c *Config) dirMode() (os.FileMode, error) {
+	return parseMode("dir_mode", c.DirMode, defaultDirMode)
+}
+
+// fileMode returns the mode of the log files, 0 if unset
+func (c *Config) fileMode() (os.FileMode, error) {
+	return parseMode("file_mode", c.FileMode, 0)
+}
+
+func parseMode(key, s string, def os.FileMode) (os.FileMode, error) {
+	if s == "" {
+		return def, nil
+	}
+
+	mode, err := strconv.ParseUint(s, 8, 32)
+	if err != nil || mode > 0777 {
+		return 0, fmt.Errorf("zlog: invalid %s %q", key, s)
+	}
+	return os.FileMode(mode), nil
+}
+
+// rotateEvery returns the period of the time rotation, 0 if unset
+func (c *Config) rotateEvery() (time.Duration, error) {
+	switch c.RotateEvery {
+	case "":
+		return 0, nil
+	case "hour":
+		return time.Hour, nil
+	case "day":
+		return 24 * time.Hour, nil
+	}
+
+	every, err := time.ParseDuration(c.RotateEvery)
+	if err != nil {
+		return 0, fmt.Errorf("zlog: invalid rotate_every %q: %v", c.RotateEvery, err)
+	}
+
+	if every < time.Minute || every > 24*time.Hour {
+		return 0, fmt.Errorf("zlog: rotate_every %q must be from 1m to 24h",
+			c.RotateEvery)
+	}
+	return every, nil
+}
@@ -0,0 +1,53 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/vcaesar/tt"
+	"go.uber.org/multierr"
+)
+
+func TestValidate(t *testing.T) {
+	flat := false
+	err := InitWithConfig(Config{
+		Mode:          "stdout",
+		Path:          t.TempDir(),
+		DailyDirs:     &flat,
+		MaxDays:       -1,
+		MaxBackups:    -2,
+		Level:         "loud",
+		CleanInterval: "often",
+		SplitLevels:   true,
+		Async:         true,
+	})
+	tt.NotNil(t, err)
+
+	errs := multierr.Errors(err)
+	tt.Equal(t, 7, len(errs))
+	for i, want := range []string{
+		"zlog: invalid max_days -1",
+		"zlog: invalid max_backups -2",
+		"zlog: name is required with daily_dirs false",
+		`zlog: mode "stdout" writes no log file, it conflicts with async`,
+		`zlog: mode "stdout" writes no log file, it conflicts with split_levels`,
+	} {
+		tt.Equal(t, want, errs[i].Error())
+	}
+	tt.True(t, strings.Contains(errs[5].Error(), `"often"`), errs[5].Error())
+	tt.True(t, strings.HasPrefix(errs[6].Error(), `zlog: invalid level "loud"`))
+
+	// the previous config is kept
+	tt.NotEqual(t, "loud", getConfig().Level)
+	tt.Nil(t, InitWithConfig(Config{Mode: "discard", Path: t.TempDir()}))
+}
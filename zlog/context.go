@@ -0,0 +1,79 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"context"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+)
+
+var ctxFieldFns atomic.Value // []func(context.Context) []zap.Field
+
+// fieldsKey the context key of the fields of NewContext
+type fieldsKey struct{}
+
+// ContextFields registers fn to return the fields of a context for
+// Ctx, like the trace ids of otelzlog, it stays registered across Init.
+func ContextFields(fn func(context.Context) []zap.Field) {
+	hookLock.Lock()
+	fns, _ := ctxFieldFns.Load().([]func(context.Context) []zap.Field)
+	ctxFieldFns.Store(append(fns[:len(fns):len(fns)], fn))
+	hookLock.Unlock()
+}
+
+// NewContext returns a copy of ctx carrying the fields for FromContext,
+// the fields are merged with the fields of the parent contexts, the
+// later keys override the earlier ones.
+//
+//	ctx = zlog.NewContext(ctx, zlog.Str("user", user))
+func NewContext(ctx context.Context, fields ...zap.Field) context.Context {
+	merged := mergeFields(contextFields(ctx), fields)
+	return context.WithValue(ctx, fieldsKey{}, merged)
+}
+
+// FromContext returns a child logger with the fields of the
+// NewContext calls of ctx, or the package loggers if there's none.
+//
+//	zlog.FromContext(ctx).Info("handle request")
+func FromContext(ctx context.Context) *Zlog {
+	if fields := contextFields(ctx); len(fields) > 0 {
+		return With(fields...)
+	}
+	return &Zlog{}
+}
+
+// contextFields returns the fields of the NewContext calls of ctx
+func contextFields(ctx context.Context) []zap.Field {
+	fields, _ := ctx.Value(fieldsKey{}).([]zap.Field)
+	return fields
+}
+
+// Ctx returns a child logger with the fields of ctx, the fields of
+// NewContext then the fields returned by the ContextFields funcs,
+// or the package loggers if there's none.
+//
+//	otelzlog.Enable()
+//	zlog.Ctx(ctx).Info("handle request") // with trace_id and span_id
+func Ctx(ctx context.Context) *Zlog {
+	fns, _ := ctxFieldFns.Load().([]func(context.Context) []zap.Field)
+
+	fields := contextFields(ctx)
+	for _, fn := range fns {
+		fields = append(fields[:len(fields):len(fields)], fn(ctx)...)
+	}
+
+	if len(fields) == 0 {
+		return &Zlog{}
+	}
+	return With(fields...)
+}
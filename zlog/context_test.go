@@ -0,0 +1,79 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/vcaesar/tt"
+	"go.uber.org/zap"
+)
+
+type ctxKey struct{}
+
+func TestCtx(t *testing.T) {
+	logs, errLogs := observeSplit(t)
+
+	// no funcs, the package loggers
+	tt.Equal(t, &Zlog{}, Ctx(context.Background()))
+
+	prev, _ := ctxFieldFns.Load().([]func(context.Context) []zap.Field)
+	t.Cleanup(func() { ctxFieldFns.Store(prev) })
+	ContextFields(func(ctx context.Context) []zap.Field {
+		if id, ok := ctx.Value(ctxKey{}).(string); ok {
+			return []zap.Field{zap.String("request_id", id)}
+		}
+		return nil
+	})
+
+	tt.Equal(t, &Zlog{}, Ctx(context.Background()))
+
+	ctx := context.WithValue(context.Background(), ctxKey{}, "r1")
+	Ctx(ctx).Info("ctx info")
+	Ctx(ctx).Error("ctx error", errors.New("e1"))
+
+	tt.Equal(t, "r1", logs.FilterMessage("ctx info").All()[0].ContextMap()["request_id"])
+	tt.Equal(t, "r1", errLogs.FilterMessage("ctx error").All()[0].ContextMap()["request_id"])
+}
+
+func TestFromContext(t *testing.T) {
+	logs, errLogs := observeSplit(t)
+
+	tt.Equal(t, &Zlog{}, FromContext(context.Background()))
+
+	parent := NewContext(context.Background(), zap.String("request_id", "r1"),
+		zap.String("user", "u1"))
+	ctx := NewContext(parent, zap.String("user", "u2"), zap.Int("try", 1),
+		zap.Int("try", 2))
+	tt.Equal(t, 2, len(contextFields(parent)))
+	tt.Equal(t, 3, len(contextFields(ctx)))
+
+	FromContext(ctx).Info("from ctx")
+	FromContext(ctx).Error("from ctx error", errors.New("e1"))
+	FromContext(parent).Info("from parent")
+
+	fields := logs.FilterMessage("from ctx").All()[0].ContextMap()
+	tt.Equal(t, "r1", fields["request_id"])
+	tt.Equal(t, "u2", fields["user"])
+	tt.Equal(t, int64(2), fields["try"])
+
+	fields = logs.FilterMessage("from parent").All()[0].ContextMap()
+	tt.Equal(t, "u1", fields["user"])
+
+	fields = errLogs.FilterMessage("from ctx error").All()[0].ContextMap()
+	tt.Equal(t, "r1", fields["request_id"])
+
+	// Ctx has the fields of NewContext too
+	Ctx(ctx).Info("ctx fields")
+	tt.Equal(t, "u2", logs.FilterMessage("ctx fields").All()[0].ContextMap()["user"])
+}
@@ -0,0 +1,182 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	crashFileMode os.FileMode = 0600
+	// maxCrashStack the max bytes of the goroutine dump
+	maxCrashStack = 64 << 20
+)
+
+// crashReport the crash file written before a Panic or Fatal
+// entry panics or exits
+type crashReport struct {
+	Time       time.Time         `json:"time"`
+	Entry      json.RawMessage   `json:"entry"`
+	Recent     []json.RawMessage `json:"recent"`
+	Goroutines string            `json:"goroutines"`
+}
+
+// crashHook writes the crash file of the Panic and Fatal entries in
+// the log path, it does nothing without the log files
+func (c *Config) crashHook() (zap.Option, error) {
+	enc, err := c.newEncoder("json")
+	if err != nil {
+		return nil, err
+	}
+
+	lpath, _ := c.confPath()
+	// validated by InitWithConfig
+	dirMode, _ := c.dirMode()
+	w := &crashWriter{lpath: lpath, flat: !c.dailyDirs(), dirMode: dirMode}
+
+	return zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		switch c.Mode {
+		case "dev", "stdout", "discard":
+			return core
+		}
+		return crashCore{Core: core, enc: enc, w: w}
+	}), nil
+}
+
+// crashCore adds the crash writer to the Panic and Fatal entries,
+// after the cores it wraps so the entry is in the ring buffer
+type crashCore struct {
+	zapcore.Core
+	enc zapcore.Encoder
+	w   *crashWriter
+}
+
+// crashWriter the log path of the crash files
+type crashWriter struct {
+	lpath   string
+	flat    bool
+	dirMode os.FileMode
+}
+
+func (c crashCore) With(fields []zapcore.Field) zapcore.Core {
+	enc := c.enc.Clone()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	return crashCore{Core: c.Core.With(fields), enc: enc, w: c.w}
+}
+
+func (c crashCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	ce = c.Core.Check(ent, ce)
+	if ent.Level >= zap.PanicLevel {
+		ce = ce.AddCore(ent, crashEntry(c))
+	}
+	return ce
+}
+
+// crashEntry writes the crash file of an entry, it's only added to
+// the entries by crashCore
+type crashEntry crashCore
+
+func (c crashEntry) Enabled(zapcore.Level) bool {
+	return true
+}
+
+func (c crashEntry) With([]zapcore.Field) zapcore.Core {
+	return c
+}
+
+func (c crashEntry) Check(_ zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return ce
+}
+
+func (c crashEntry) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	defer buf.Free()
+
+	report := crashReport{
+		Time:       ent.Time,
+		Entry:      json.RawMessage(buf.Bytes()),
+		Recent:     []json.RawMessage{},
+		Goroutines: string(goroutines()),
+	}
+	for _, entry := range Recent(0) {
+		report.Recent = append(report.Recent, entry)
+	}
+
+	file := c.w.filename(ent.Time)
+	if err := writeCrash(file, c.w.dirMode, report); err != nil {
+		writeFailed(file, 1, err)
+		return err
+	}
+	return nil
+}
+
+func (crashEntry) Sync() error {
+	return nil
+}
+
+// filename returns the crash file of tm
+func (w *crashWriter) filename(tm time.Time) string {
+	name := "crash-" + tm.Format("20060102T150405.000000000") + ".json"
+	if w.flat {
+		return filepath.Join(w.lpath, name)
+	}
+	return filepath.Join(w.lpath, tm.Format(DayFormat), name)
+}
+
+// writeCrash writes the report to file and syncs it to the disk
+func writeCrash(file string, dirMode os.FileMode, report crashReport) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := mkdirMode(filepath.Dir(file), dirMode); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, crashFileMode)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// goroutines returns the stacks of all the goroutines
+func goroutines() []byte {
+	buf := make([]byte, 64<<10)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) || len(buf) >= maxCrashStack {
+			return buf[:n]
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vcaesar/tt"
+)
+
+// readCrash decodes the crash file of dir
+func readCrash(t *testing.T, dir string) crashReport {
+	files, err := filepath.Glob(filepath.Join(dir, "*", "crash-*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tt.Equal(t, 1, len(files))
+
+	data, err := ioutil.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var report crashReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatal(err)
+	}
+	return report
+}
+
+// msgOf returns the message of an encoded entry
+func msgOf(t *testing.T, data []byte) string {
+	var entry map[string]interface{}
+	if err := json.Unmarshal(data, &entry); err != nil {
+		t.Fatal(err)
+	}
+	msg, _ := entry["msg"].(string)
+	return msg
+}
+
+// TestCrash runs itself in a subprocess which logs a Fatal entry
+func TestCrash(t *testing.T) {
+	if dir := os.Getenv("ZLOG_CRASH_DIR"); dir != "" {
+		ring := 10
+		if os.Getenv("ZLOG_CRASH_NO_RING") != "" {
+			ring = 0
+		}
+		InitWithConfig(Config{Path: dir, Name: "crash", RingBuffer: ring})
+		Info("before crash")
+		Fatal("crash fatal", errors.New("e1"))
+		os.Exit(3)
+	}
+
+	for _, env := range []string{"", "ZLOG_CRASH_NO_RING=1"} {
+		dir := t.TempDir()
+		cmd := exec.Command(os.Args[0], "-test.run=^TestCrash$")
+		cmd.Env = append(os.Environ(), "ZLOG_CRASH_DIR="+dir, env)
+		err := cmd.Run()
+
+		var exitErr *exec.ExitError
+		tt.True(t, errors.As(err, &exitErr))
+		tt.Equal(t, 1, exitErr.ExitCode())
+
+		report := readCrash(t, dir)
+		tt.Equal(t, "crash fatal", msgOf(t, report.Entry))
+		tt.True(t, strings.Contains(report.Goroutines, "goroutine "))
+		tt.True(t, strings.Contains(report.Goroutines, "TestCrash"))
+
+		if env != "" {
+			tt.Equal(t, 0, len(report.Recent))
+			continue
+		}
+		tt.Equal(t, 2, len(report.Recent))
+		tt.Equal(t, "before crash", msgOf(t, report.Recent[0]))
+		tt.Equal(t, "crash fatal", msgOf(t, report.Recent[1]))
+	}
+}
+
+func TestCrashPanic(t *testing.T) {
+	dir := initTest(t)
+	defer Close()
+
+	tt.True(t, func() (panicked bool) {
+		defer func() { panicked = recover() != nil }()
+		With(Str("ctx", "c1")).Panic("crash panic", errors.New("e1"))
+		return
+	}())
+
+	report := readCrash(t, dir)
+	var entry map[string]interface{}
+	tt.Nil(t, json.Unmarshal(report.Entry, &entry))
+	tt.Equal(t, "crash panic", entry["msg"])
+	tt.Equal(t, "c1", entry["ctx"])
+	tt.Equal(t, "e1", entry["error"])
+
+	// no crash file for the other entries
+	Error("crash error", errors.New("e1"))
+	readCrash(t, dir)
+}
@@ -0,0 +1,165 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const defaultDedupWindow = 10 * time.Second
+
+// dedupWindow returns the flush window of Dedup
+func (c *Config) dedupWindow() (time.Duration, error) {
+	return durationConf("dedup_window", c.DedupWindow, defaultDedupWindow)
+}
+
+// dedupKey the level, the message and the error of an entry
+type dedupKey struct {
+	level zapcore.Level
+	msg   string
+	err   string
+}
+
+// dedup the last entry of a logger and its suppressed repeats,
+// it is shared by the With copies of the core
+type dedup struct {
+	mu     sync.Mutex
+	window time.Duration
+	timer  *time.Timer
+
+	last        dedupKey
+	ent         zapcore.Entry
+	core        zapcore.Core
+	repeats     int
+	first, prev time.Time
+}
+
+// dedupCore suppresses the entries repeating the level, the
+// message and the error of the previous one, the repeats are
+// summed up by an entry when another entry comes, after the
+// window or on Sync.
+type dedupCore struct {
+	zapcore.Core
+	d *dedup
+}
+
+// wrapDedup wraps core in a dedupCore with Dedup
+func (c *Config) wrapDedup(core zapcore.Core) zapcore.Core {
+	if !c.Dedup {
+		return core
+	}
+
+	// validated by InitWithConfig
+	window, _ := c.dedupWindow()
+	return newDedupCore(core, window)
+}
+
+func newDedupCore(core zapcore.Core, window time.Duration) dedupCore {
+	return dedupCore{Core: core, d: &dedup{window: window}}
+}
+
+func (c dedupCore) With(fields []zapcore.Field) zapcore.Core {
+	return dedupCore{c.Core.With(fields), c.d}
+}
+
+func (c dedupCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write writes the entry if it isn't a repeat, the wrapped cores
+// are checked again so each of them keeps its own level
+func (c dedupCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	key := dedupKey{level: ent.Level, msg: ent.Message, err: errString(fields)}
+
+	d := c.d
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.core != nil && key == d.last {
+		if d.repeats == 0 {
+			d.first = ent.Time
+			d.timer = time.AfterFunc(d.window, d.flushTimer)
+		}
+		d.repeats++
+		d.prev = ent.Time
+		return nil
+	}
+
+	d.flush()
+	d.last, d.ent, d.core = key, ent, c.Core
+	write(c.Core, ent, fields)
+	return nil
+}
+
+// Sync writes the summary of the repeats
+func (c dedupCore) Sync() error {
+	c.d.mu.Lock()
+	c.d.flush()
+	c.d.mu.Unlock()
+
+	return c.Core.Sync()
+}
+
+func (d *dedup) flushTimer() {
+	d.mu.Lock()
+	d.flush()
+	d.mu.Unlock()
+}
+
+// flush writes the summary of the repeats if there are any
+func (d *dedup) flush() {
+	if d.repeats == 0 {
+		return
+	}
+
+	d.timer.Stop()
+	ent := d.ent
+	ent.Message = fmt.Sprintf("last message repeated %d times", d.repeats)
+	ent.Time = time.Now()
+	ent.Stack = ""
+
+	write(d.core, ent, []zapcore.Field{
+		zap.String("repeated_msg", d.last.msg),
+		zap.Int("repeated", d.repeats),
+		zap.Time("first", d.first),
+		zap.Time("last", d.prev),
+	})
+	d.repeats = 0
+}
+
+// write writes the entry to the cores of core which enable it
+func write(core zapcore.Core, ent zapcore.Entry, fields []zapcore.Field) {
+	if ce := core.Check(ent, nil); ce != nil {
+		ce.Write(fields...)
+	}
+}
+
+// errString returns the message of the first error field
+func errString(fields []zapcore.Field) string {
+	for _, f := range fields {
+		if f.Type != zapcore.ErrorType {
+			continue
+		}
+
+		if err, ok := f.Interface.(error); ok && err != nil {
+			return err.Error()
+		}
+	}
+	return ""
+}
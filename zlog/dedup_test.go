@@ -0,0 +1,119 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/vcaesar/tt"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// dedupLogger returns a logger deduplicating to an observer
+func dedupLogger(window time.Duration) (*zap.Logger, *observer.ObservedLogs) {
+	core, logs := observer.New(zap.DebugLevel)
+	return zap.New(newDedupCore(core, window)), logs
+}
+
+func messages(logs *observer.ObservedLogs) []string {
+	var msgs []string
+	for _, e := range logs.All() {
+		msgs = append(msgs, e.Message)
+	}
+	return msgs
+}
+
+func TestDedupBurst(t *testing.T) {
+	logger, logs := dedupLogger(time.Hour)
+	for i := 0; i < 5; i++ {
+		logger.Error("db refused", zap.Error(errors.New("e1")))
+	}
+	tt.Equal(t, 1, logs.Len())
+
+	logger.Info("other")
+	tt.Equal(t, []string{"db refused", "last message repeated 4 times", "other"},
+		messages(logs))
+
+	summary := logs.All()[1]
+	tt.Equal(t, zap.ErrorLevel, summary.Level)
+	ctx := summary.ContextMap()
+	tt.Equal(t, "db refused", ctx["repeated_msg"])
+	tt.Equal(t, int64(4), ctx["repeated"])
+	tt.False(t, ctx["last"].(time.Time).Before(ctx["first"].(time.Time)))
+
+	// Sync writes the pending repeats once
+	logger.Info("other")
+	tt.Nil(t, logger.Sync())
+	tt.Nil(t, logger.Sync())
+	tt.Equal(t, "last message repeated 1 times", logs.All()[3].Message)
+	tt.Equal(t, 4, logs.Len())
+}
+
+func TestDedupInterleaved(t *testing.T) {
+	logger, logs := dedupLogger(time.Hour)
+	for i := 0; i < 3; i++ {
+		logger.Warn("a")
+		logger.Warn("b")
+	}
+	tt.Equal(t, 6, logs.Len())
+
+	// another level or another error isn't a repeat
+	logger.Info("b")
+	logger.Error("c", zap.Error(errors.New("e1")))
+	logger.Error("c", zap.Error(errors.New("e2")))
+	logger.Error("c", zap.Error(errors.New("e2")))
+	tt.Nil(t, logger.Sync())
+	tt.Equal(t, []string{"a", "b", "a", "b", "a", "b", "b", "c", "c",
+		"last message repeated 1 times"}, messages(logs))
+
+	// the fields of With don't change the key
+	logger.With(zap.String("k", "v")).Error("c", zap.Error(errors.New("e2")))
+	tt.Nil(t, logger.Sync())
+	tt.Equal(t, 11, logs.Len())
+}
+
+func TestDedupWindow(t *testing.T) {
+	logger, logs := dedupLogger(20 * time.Millisecond)
+	for i := 0; i < 3; i++ {
+		logger.Info("tick")
+	}
+	tt.Equal(t, 1, logs.Len())
+
+	tt.True(t, waitFor(func() bool { return logs.Len() == 2 }))
+	tt.Equal(t, "last message repeated 2 times", logs.All()[1].Message)
+
+	// the repeats after the flush start a new summary
+	logger.Info("tick")
+	tt.True(t, waitFor(func() bool { return logs.Len() == 3 }))
+	tt.Nil(t, logger.Sync())
+	tt.Equal(t, 3, logs.Len())
+}
+
+func TestDedupConfig(t *testing.T) {
+	tt.NotNil(t, InitWithConfig(Config{Path: t.TempDir(), Dedup: true,
+		DedupWindow: "soon"}))
+
+	dir := initTest(t, "dedup = true")
+	for i := 0; i < 10; i++ {
+		Error("dedup error", errors.New("e1"))
+	}
+	tt.Nil(t, Close())
+
+	entries := readEntries(t, filepath.Join(dir, "*", "test_err.json"))
+	tt.Equal(t, 1, len(msgEntries(entries, "dedup error")))
+	summary := msgEntries(entries, "last message repeated 9 times")
+	tt.Equal(t, 1, len(summary))
+	tt.Equal(t, float64(9), summary[0]["repeated"])
+}
@@ -0,0 +1,179 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// ECSVersion the ecs.version of the entries of the ecs encoding
+const ECSVersion = "1.6.0"
+
+// ecsKeys the keys of the fields renamed to their ecs field
+var ecsKeys = map[string]string{
+	"error":        "error.message",
+	"errorVerbose": "error.stack_trace",
+	"trace_id":     "trace.id",
+	"span_id":      "span.id",
+}
+
+var ecsBufs = buffer.NewPool()
+
+// ecsEncoder encodes the entries as the documents of the elastic
+// common schema, the dotted keys are nested in objects like
+// log.level in {"log": {"level": "info"}}, the later key wins
+// if a key is both a value and an object.
+type ecsEncoder struct {
+	*zapcore.MapObjectEncoder
+}
+
+func newECSEncoder() zapcore.Encoder {
+	return ecsEncoder{zapcore.NewMapObjectEncoder()}
+}
+
+// Clone copies the fields of With, they aren't modified
+// once they're added
+func (e ecsEncoder) Clone() zapcore.Encoder {
+	clone := zapcore.NewMapObjectEncoder()
+	for k, v := range e.Fields {
+		clone.Fields[k] = v
+	}
+	return ecsEncoder{clone}
+}
+
+func (e ecsEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	doc := make(map[string]interface{})
+	ecsPut(doc, "@timestamp", ent.Time.UTC().Format("2006-01-02T15:04:05.000Z07:00"))
+	ecsPut(doc, "log.level", ent.Level.String())
+	ecsPut(doc, "message", ent.Message)
+	ecsPut(doc, "ecs.version", ECSVersion)
+	if ent.LoggerName != "" {
+		ecsPut(doc, "log.logger", ent.LoggerName)
+	}
+	if ent.Caller.Defined {
+		ecsPut(doc, "log.origin.file.name", callerFile(ent.Caller))
+		ecsPut(doc, "log.origin.file.line", ent.Caller.Line)
+		if ent.Caller.Function != "" {
+			ecsPut(doc, "log.origin.function", ent.Caller.Function)
+		}
+	}
+
+	for k, v := range e.Fields {
+		ecsPut(doc, ecsKey(k), v)
+	}
+
+	m := zapcore.NewMapObjectEncoder()
+	for _, f := range fields {
+		if err, ok := f.Interface.(error); ok && f.Type == zapcore.ErrorType && f.Key == "error" {
+			ecsPut(doc, "error.type", fmt.Sprintf("%T", err))
+		}
+		f.AddTo(m)
+	}
+	for k, v := range m.Fields {
+		ecsPut(doc, ecsKey(k), v)
+	}
+
+	// the stacktrace of the entry over the verbose error
+	if ent.Stack != "" {
+		ecsPut(doc, "error.stack_trace", ent.Stack)
+	}
+
+	buf := ecsBufs.Get()
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(doc); err != nil {
+		buf.Free()
+		return nil, err
+	}
+	return buf, nil
+}
+
+// ecsKey returns the ecs field of the key
+func ecsKey(key string) string {
+	if k, ok := ecsKeys[key]; ok {
+		return k
+	}
+	return key
+}
+
+// ecsPut sets the dotted key of doc to v, the objects on the path
+// are copied so the objects of the fields are never modified
+func ecsPut(doc map[string]interface{}, key string, v interface{}) {
+	path := strings.Split(key, ".")
+	for _, k := range path[:len(path)-1] {
+		obj, ok := doc[k].(map[string]interface{})
+		if !ok {
+			obj = make(map[string]interface{})
+		} else {
+			obj = ecsCopy(obj)
+		}
+		doc[k] = obj
+		doc = obj
+	}
+
+	last := path[len(path)-1]
+	if obj, ok := ecsValue(v).(map[string]interface{}); ok {
+		// merged with the object of the dotted keys before
+		if prev, ok := doc[last].(map[string]interface{}); ok {
+			prev = ecsCopy(prev)
+			for k, v := range obj {
+				ecsPut(prev, k, v)
+			}
+			doc[last] = prev
+			return
+		}
+	}
+	doc[last] = ecsValue(v)
+}
+
+func ecsCopy(obj map[string]interface{}) map[string]interface{} {
+	cp := make(map[string]interface{}, len(obj))
+	for k, v := range obj {
+		cp[k] = v
+	}
+	return cp
+}
+
+// ecsValue returns v with the dotted keys of its objects nested and
+// the values json can't encode as strings
+func ecsValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		obj := make(map[string]interface{}, len(v))
+		for k, val := range v {
+			ecsPut(obj, k, val)
+		}
+		return obj
+	case []interface{}:
+		arr := make([]interface{}, len(v))
+		for i, val := range v {
+			arr[i] = ecsValue(val)
+		}
+		return arr
+	case complex128, complex64:
+		return fmt.Sprint(v)
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return fmt.Sprint(v)
+		}
+	case float32:
+		if math.IsNaN(float64(v)) || math.IsInf(float64(v), 0) {
+			return fmt.Sprint(v)
+		}
+	}
+	return v
+}
@@ -0,0 +1,115 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"errors"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/vcaesar/tt"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestECSGolden(t *testing.T) {
+	tm := time.Date(2018, 5, 1, 8, 30, 0, 123000000, time.UTC)
+	user := zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+		enc.AddString("id", "u1")
+		enc.AddString("name.first", "Ada")
+		return nil
+	})
+
+	tests := []struct {
+		name   string
+		ent    zapcore.Entry
+		with   []zapcore.Field
+		fields []zapcore.Field
+	}{
+		{"info", zapcore.Entry{Level: zapcore.InfoLevel, Time: tm, LoggerName: "http",
+			Message: "handle request",
+			Caller:  zapcore.NewEntryCaller(0, "/src/app/server.go", 42, true)},
+			[]zapcore.Field{zap.String("service.name", "api")},
+			[]zapcore.Field{
+				zap.String("http.request.method", "GET"),
+				zap.Int("http.response.status_code", 200),
+				zap.String("trace_id", "4bf92f3577b34da6a3ce929d0e0e4736"),
+				zap.String("span_id", "00f067aa0ba902b7"),
+			}},
+		{"error", zapcore.Entry{Level: zapcore.ErrorLevel, Time: tm, Message: "query failed",
+			Stack: "main.query\n\t/src/app/db.go:12"},
+			nil,
+			[]zapcore.Field{zap.Error(errors.New("connection refused")),
+				zap.Duration("event.duration", 1500*time.Millisecond)}},
+		{"object", zapcore.Entry{Level: zapcore.WarnLevel, Time: tm, Message: "nested"},
+			[]zapcore.Field{zap.Error(errors.New("with error"))},
+			[]zapcore.Field{
+				zap.Object("user", user),
+				zap.String("user.roles", "admin"),
+				zap.Float64("ratio", math.NaN()),
+				zap.Namespace("labels"),
+				zap.String("env", "prod"),
+			}},
+	}
+
+	for _, test := range tests {
+		enc := newECSEncoder()
+		for _, f := range test.with {
+			f.AddTo(enc)
+		}
+
+		buf, err := enc.Clone().EncodeEntry(test.ent, test.fields)
+		tt.Nil(t, err)
+
+		golden := filepath.Join("..", "testdata", "zlog_ecs_"+test.name+".golden")
+		if *update {
+			tt.Nil(t, ioutil.WriteFile(golden, buf.Bytes(), 0644))
+		}
+
+		want, err := ioutil.ReadFile(golden)
+		tt.Nil(t, err)
+		tt.Equal(t, string(want), buf.String(), test.name)
+		buf.Free()
+	}
+}
+
+func TestECS(t *testing.T) {
+	dir := initTest(t, `encoding = "ecs"`)
+	Named("db").With(zap.String("service.name", "api")).Info("ecs info")
+	Error("ecs error", errors.New("e1"))
+	tt.Nil(t, Close())
+
+	var n int
+	for _, entry := range readEntries(t, filepath.Join(dir, "*", "test.json")) {
+		tt.Equal(t, map[string]interface{}{"version": ECSVersion}, entry["ecs"])
+		_, err := time.Parse(time.RFC3339, entry["@timestamp"].(string))
+		tt.Nil(t, err)
+
+		log := entry["log"].(map[string]interface{})
+		switch entry["message"] {
+		case "ecs info":
+			tt.Equal(t, "info", log["level"])
+			tt.Equal(t, "db", log["logger"])
+			tt.Equal(t, map[string]interface{}{"name": "api"}, entry["service"])
+			n++
+		case "ecs error":
+			e := entry["error"].(map[string]interface{})
+			tt.Equal(t, "e1", e["message"])
+			tt.Equal(t, "*errors.errorString", e["type"])
+			tt.NotNil(t, e["stack_trace"])
+			n++
+		}
+	}
+	tt.Equal(t, 2, n)
+}
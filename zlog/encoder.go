@@ -0,0 +1,200 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// EncoderConfig the keys and the formats of the encoder,
+// it is the [encoder] table of the toml file.
+type EncoderConfig struct {
+	// TimeKey the key of the entry time, default "time"
+	TimeKey string `toml:"time_key"`
+	// MessageKey the key of the message, default "msg"
+	MessageKey string `toml:"message_key"`
+	// LevelKey the key of the level, default "level"
+	LevelKey string `toml:"level_key"`
+	// CallerKey the key of the caller, default "caller"
+	CallerKey string `toml:"caller_key"`
+	// TimeFormat a Go time layout, or "rfc3339", "rfc3339nano",
+	// "iso8601", "epoch", "epochmillis" or "epochnanos",
+	// default TimeFormat
+	TimeFormat string `toml:"time_format"`
+	// LevelFormat "lower" or "capital", default "lower"
+	LevelFormat string `toml:"level_format"`
+}
+
+// encoderConfig returns the production encoder config,
+// which writes the entry time with TimeFormat under the "time" key
+// and the name of the Named loggers under the "logger" key,
+// the keys and the formats of the [encoder] table are applied.
+func (c *Config) encoderConfig() (zapcore.EncoderConfig, error) {
+	encCfg := zap.NewProductionEncoderConfig()
+	encCfg.TimeKey = "time"
+	encCfg.NameKey = "logger"
+	encCfg.EncodeTime = timeEncoder
+
+	return applyEncoder(encCfg, c.Encoder)
+}
+
+// applyEncoder applies the keys and the formats set in ec
+func applyEncoder(encCfg zapcore.EncoderConfig, ec EncoderConfig) (zapcore.EncoderConfig, error) {
+	for _, key := range []struct {
+		dst *string
+		val string
+	}{
+		{&encCfg.TimeKey, ec.TimeKey},
+		{&encCfg.MessageKey, ec.MessageKey},
+		{&encCfg.LevelKey, ec.LevelKey},
+		{&encCfg.CallerKey, ec.CallerKey},
+	} {
+		if key.val != "" {
+			*key.dst = key.val
+		}
+	}
+
+	if ec.TimeFormat != "" {
+		enc, err := timeFormat(ec.TimeFormat)
+		if err != nil {
+			return encCfg, err
+		}
+		encCfg.EncodeTime = enc
+	}
+
+	switch ec.LevelFormat {
+	case "":
+	case "lower":
+		encCfg.EncodeLevel = zapcore.LowercaseLevelEncoder
+	case "capital":
+		encCfg.EncodeLevel = zapcore.CapitalLevelEncoder
+	default:
+		return encCfg, fmt.Errorf("zlog: unknown level_format %q", ec.LevelFormat)
+	}
+
+	return encCfg, nil
+}
+
+// timeFormat returns the time encoder of the format
+func timeFormat(format string) (zapcore.TimeEncoder, error) {
+	switch format {
+	case "rfc3339":
+		return zapcore.RFC3339TimeEncoder, nil
+	case "rfc3339nano":
+		return zapcore.RFC3339NanoTimeEncoder, nil
+	case "iso8601":
+		return zapcore.ISO8601TimeEncoder, nil
+	case "epoch":
+		return zapcore.EpochTimeEncoder, nil
+	case "epochmillis":
+		return zapcore.EpochMillisTimeEncoder, nil
+	case "epochnanos":
+		return zapcore.EpochNanosTimeEncoder, nil
+	}
+
+	// a layout without any element formats to itself
+	ref := time.Date(2018, 5, 1, 8, 30, 0, 0, time.UTC)
+	if ref.Format(format) == format {
+		return nil, fmt.Errorf("zlog: unknown time_format %q", format)
+	}
+	return zapcore.TimeEncoderOfLayout(format), nil
+}
+
+// timeBufs the buffers of timeEncoder
+var timeBufs = sync.Pool{New: func() interface{} {
+	b := make([]byte, 0, 32)
+	return &b
+}}
+
+// timeEncoder appends the time in TimeFormat without allocating,
+// the encoders copy the bytes
+func timeEncoder(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
+	b := timeBufs.Get().(*[]byte)
+	*b = t.AppendFormat((*b)[:0], TimeFormat)
+	enc.AppendByteString(*b)
+	timeBufs.Put(b)
+}
+
+// consoleConfig returns the encoder config of the console encoding,
+// with the ISO8601 time and the capital levels by default.
+func (c *Config) consoleConfig() (zapcore.EncoderConfig, error) {
+	encCfg := zap.NewProductionEncoderConfig()
+	encCfg.TimeKey = "time"
+	encCfg.NameKey = "logger"
+	encCfg.EncodeTime = zapcore.ISO8601TimeEncoder
+	encCfg.EncodeLevel = zapcore.CapitalLevelEncoder
+
+	return applyEncoder(encCfg, c.Encoder)
+}
+
+// newEncoder returns the encoder of the encoding, "json", "console",
+// "logfmt", "gelf", "ecs" or "gcp"
+func (c *Config) newEncoder(encoding string) (zapcore.Encoder, error) {
+	switch encoding {
+	case "", "json":
+		encCfg, err := c.encoderConfig()
+		if err != nil {
+			return nil, err
+		}
+		return zapcore.NewJSONEncoder(encCfg), nil
+	case "console":
+		encCfg, err := c.consoleConfig()
+		if err != nil {
+			return nil, err
+		}
+		return zapcore.NewConsoleEncoder(encCfg), nil
+	case "logfmt":
+		encCfg, err := c.encoderConfig()
+		if err != nil {
+			return nil, err
+		}
+		return newLogfmtEncoder(encCfg), nil
+	case "gelf":
+		return c.newGELFEncoder(), nil
+	case "ecs":
+		return newECSEncoder(), nil
+	case "gcp":
+		return newGCPEncoder(), nil
+	}
+
+	return nil, fmt.Errorf("zlog: unknown encoding %q", encoding)
+}
+
+// fileEncoder returns the encoder of the log files,
+// FileEncoding falls back to Encoding.
+func (c *Config) fileEncoder() (zapcore.Encoder, error) {
+	if c.FileEncoding != "" {
+		return c.newEncoder(c.FileEncoding)
+	}
+	return c.newEncoder(c.Encoding)
+}
+
+// stdoutEncoder returns the encoder of stdout and stderr,
+// StdoutEncoding falls back to Encoding.
+func (c *Config) stdoutEncoder() (zapcore.Encoder, error) {
+	if c.StdoutEncoding != "" {
+		return c.newEncoder(c.StdoutEncoding)
+	}
+	return c.newEncoder(c.Encoding)
+}
+
+// callerFile returns the package/file.go of the caller, the
+// trimmed path without the line
+func callerFile(caller zapcore.EntryCaller) string {
+	return strings.TrimSuffix(caller.TrimmedPath(), ":"+strconv.Itoa(caller.Line))
+}
@@ -0,0 +1,148 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vcaesar/tt"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestConsoleEncoding(t *testing.T) {
+	dir := initTest(t, "encoding = \"console\"")
+	Info("console info", "a")
+	tt.Nil(t, Close())
+
+	files, err := filepath.Glob(filepath.Join(dir, "*", "test.json"))
+	tt.Nil(t, err)
+	tt.Equal(t, 1, len(files))
+
+	var line string
+	for _, l := range readLines(t, files[0]) {
+		if strings.Contains(l, "console info") {
+			line = l
+		}
+	}
+
+	parts := strings.Split(line, "\t")
+	tt.Equal(t, 4, len(parts))
+	tt.Equal(t, "INFO", parts[1])
+	tt.Equal(t, "console info", parts[2])
+	tt.Equal(t, `{"info": "a"}`, parts[3])
+	tt.Equal(t, "T", parts[0][10:11])
+}
+
+func TestMixedEncoding(t *testing.T) {
+	stdout := capture(t, &os.Stdout)
+	dir := initTest(t, "stdout = true", "file_encoding = \"json\"",
+		"stdout_encoding = \"console\"")
+	Info("mixed info")
+	tt.Nil(t, Close())
+
+	entries := readEntries(t, filepath.Join(dir, "*", "test.json"))
+	tt.Equal(t, 1, len(msgEntries(entries, "mixed info")))
+
+	found := false
+	for _, line := range stdout() {
+		if strings.Contains(line, "mixed info") {
+			found = true
+			tt.False(t, json.Valid([]byte(line)))
+			tt.True(t, strings.Contains(line, "\tINFO\t"))
+		}
+	}
+	tt.True(t, found)
+}
+
+func TestUnknownEncoding(t *testing.T) {
+	err := InitWithConfig(Config{Path: t.TempDir(), Encoding: "xml"})
+	tt.NotNil(t, err)
+
+	err = InitWithConfig(Config{Mode: "stdout", StdoutEncoding: "yaml"})
+	tt.NotNil(t, err)
+}
+
+var update = flag.Bool("update", false, "update the golden files")
+
+func TestEncoderGolden(t *testing.T) {
+	var config Config
+
+	tm := time.Date(2018, 5, 1, 8, 30, 0, 123000000, time.UTC)
+	ent := zapcore.Entry{Level: zapcore.WarnLevel, Time: tm, LoggerName: "db",
+		Message: "golden"}
+
+	tests := []struct {
+		name string
+		cfg  Config
+	}{
+		{"default", Config{}},
+		{"elastic", Config{Encoder: EncoderConfig{TimeKey: "@timestamp",
+			MessageKey: "message", LevelKey: "severity", TimeFormat: "rfc3339",
+			LevelFormat: "capital"}}},
+		{"epochmillis", Config{Encoder: EncoderConfig{TimeFormat: "epochmillis"}}},
+		{"layout", Config{Encoder: EncoderConfig{TimeFormat: "02/01/2006 15:04"}}},
+		{"console", Config{Encoding: "console",
+			Encoder: EncoderConfig{LevelFormat: "lower"}}},
+	}
+
+	for _, test := range tests {
+		config = test.cfg
+		enc, err := config.fileEncoder()
+		tt.Nil(t, err)
+
+		buf, err := enc.EncodeEntry(ent, []zapcore.Field{Str("k", "v")})
+		tt.Nil(t, err)
+
+		golden := filepath.Join("..", "testdata", "zlog_encoder_"+test.name+".golden")
+		if *update {
+			tt.Nil(t, ioutil.WriteFile(golden, buf.Bytes(), 0644))
+		}
+
+		want, err := ioutil.ReadFile(golden)
+		tt.Nil(t, err)
+		tt.Equal(t, string(want), buf.String())
+	}
+}
+
+func TestEncoderConf(t *testing.T) {
+	dir := initTest(t, "[encoder]", "time_key = \"@timestamp\"",
+		"message_key = \"message\"", "time_format = \"rfc3339\"")
+	Info("encoder conf")
+	tt.Nil(t, Close())
+
+	entries := readEntries(t, filepath.Join(dir, "*", "test.json"))
+	var entry map[string]interface{}
+	for _, e := range entries {
+		if e["message"] == "encoder conf" {
+			entry = e
+		}
+	}
+	tt.NotNil(t, entry)
+	tt.Nil(t, entry["msg"])
+
+	_, err := time.Parse(time.RFC3339, entry["@timestamp"].(string))
+	tt.Nil(t, err)
+}
+
+func TestEncoderUnknown(t *testing.T) {
+	dir := t.TempDir()
+	tt.NotNil(t, InitWithConfig(Config{Path: dir,
+		Encoder: EncoderConfig{TimeFormat: "unix"}}))
+	tt.NotNil(t, InitWithConfig(Config{Path: dir,
+		Encoder: EncoderConfig{LevelFormat: "upper"}}))
+}
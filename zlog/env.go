@@ -0,0 +1,149 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EnvPrefix the prefix of the environment variables overriding the
+// config file, the key of a field is upper cased like ZLOG_MAX_SIZE
+// and the keys of the tables are joined like ZLOG_SYSLOG_ENABLED.
+const EnvPrefix = "ZLOG_"
+
+// applyEnv sets the fields of cfg from the ZLOG_ environment
+// variables, the empty variables are ignored, the lists are comma
+// separated and the maps are k=v lists
+func applyEnv(cfg *Config) error {
+	return envStruct(reflect.ValueOf(cfg).Elem(), EnvPrefix)
+}
+
+func envStruct(v reflect.Value, prefix string) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		key := prefix + strings.ToUpper(envKey(f))
+
+		if f.Type.Kind() == reflect.Struct {
+			if err := envStruct(v.Field(i), key+"_"); err != nil {
+				return err
+			}
+			continue
+		}
+
+		s, ok := os.LookupEnv(key)
+		if !ok || s == "" {
+			continue
+		}
+
+		if err := setEnv(v.Field(i), s); err != nil {
+			return fmt.Errorf("zlog: invalid %s %q: %v", key, s, err)
+		}
+	}
+	return nil
+}
+
+// envKey returns the toml key of the field, or its lower cased name
+func envKey(f reflect.StructField) string {
+	if tag := strings.Split(f.Tag.Get("toml"), ",")[0]; tag != "" {
+		return tag
+	}
+	return strings.ToLower(f.Name)
+}
+
+func setEnv(v reflect.Value, s string) error {
+	if v.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Ptr:
+		elem := reflect.New(v.Type().Elem())
+		if err := setEnv(elem.Elem(), s); err != nil {
+			return err
+		}
+		v.Set(elem)
+	case reflect.Slice:
+		var list []string
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		v.Set(reflect.ValueOf(list))
+	case reflect.Map:
+		m := make(map[string]string)
+		for _, kv := range strings.Split(s, ",") {
+			i := strings.Index(kv, "=")
+			if i <= 0 {
+				return fmt.Errorf("%q isn't a k=v pair", kv)
+			}
+			m[strings.TrimSpace(kv[:i])] = strings.TrimSpace(kv[i+1:])
+		}
+		v.Set(reflect.ValueOf(m))
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// EffectiveConfig returns the config of the last Init with the
+// environment variables, the defaults of the paths, the level, the
+// retention and the rotation are filled.
+func EffectiveConfig() Config {
+	config := getConfig()
+	cfg := *config
+	cfg.Path, cfg.Name = config.confPath()
+
+	if cfg.Level == "" {
+		cfg.Level = defaultLevel(cfg.Mode)
+	}
+
+	daily, mirror := config.dailyDirs(), config.mirrorErrors()
+	cfg.DailyDirs, cfg.MirrorErrors = &daily, &mirror
+	cfg.MaxDays = config.maxDays()
+
+	rotate := config.rotateConf()
+	cfg.MaxSize, cfg.MaxBackups, cfg.MaxAge = rotate.maxSize, rotate.maxBackups, rotate.maxAge
+	return cfg
+}
+
+// defaultLevel the default level of the mode
+func defaultLevel(mode string) string {
+	if mode == "dev" {
+		return "debug"
+	}
+	return "info"
+}
@@ -0,0 +1,158 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/vcaesar/tt"
+)
+
+// envValue returns an env value of the kind of v and the value it sets
+func envValue(v reflect.Value) (string, interface{}) {
+	switch v.Kind() {
+	case reflect.String:
+		return "v1", "v1"
+	case reflect.Bool:
+		return "true", true
+	case reflect.Int:
+		return "42", 42
+	case reflect.Int64:
+		return "42", int64(42)
+	case reflect.Ptr:
+		b := false
+		return "false", &b
+	case reflect.Slice:
+		return "a, b,", []string{"a", "b"}
+	case reflect.Map:
+		return "k1=v1, k2 = v2", map[string]string{"k1": "v1", "k2": "v2"}
+	}
+	return "", nil
+}
+
+// eachField runs fn for each leaf field of v with its variable
+func eachField(v reflect.Value, prefix string, fn func(key string, f reflect.Value)) {
+	for i := 0; i < v.NumField(); i++ {
+		key := prefix + strings.ToUpper(envKey(v.Type().Field(i)))
+		if v.Field(i).Kind() == reflect.Struct {
+			eachField(v.Field(i), key+"_", fn)
+			continue
+		}
+		fn(key, v.Field(i))
+	}
+}
+
+func TestApplyEnv(t *testing.T) {
+	var keys []string
+	eachField(reflect.ValueOf(&Config{}).Elem(), EnvPrefix, func(key string, f reflect.Value) {
+		keys = append(keys, key)
+	})
+	tt.True(t, len(keys) > 40)
+
+	for _, key := range keys {
+		t.Run(key, func(t *testing.T) {
+			var field reflect.Value
+			eachField(reflect.ValueOf(&Config{}).Elem(), EnvPrefix, func(k string, f reflect.Value) {
+				if k == key {
+					field = f
+				}
+			})
+
+			s, want := envValue(field)
+			tt.NotNil(t, want)
+			t.Setenv(key, s)
+
+			var cfg Config
+			tt.Nil(t, applyEnv(&cfg))
+
+			var got reflect.Value
+			eachField(reflect.ValueOf(&cfg).Elem(), EnvPrefix, func(k string, f reflect.Value) {
+				if k == key {
+					got = f
+				}
+			})
+			tt.True(t, reflect.DeepEqual(want, got.Interface()), key)
+		})
+	}
+}
+
+func TestApplyEnvKeys(t *testing.T) {
+	t.Setenv("ZLOG_MODE", "stdout")
+	t.Setenv("ZLOG_MAX_SIZE", "10")
+	t.Setenv("ZLOG_SYSLOG_ENABLED", "1")
+	t.Setenv("ZLOG_SAMPLING_INITIAL", "100")
+	t.Setenv("ZLOG_FIELDS_STATIC", "team=core")
+	t.Setenv("ZLOG_PATH", "")
+
+	cfg := Config{Path: "./logs", MaxSize: 5}
+	tt.Nil(t, applyEnv(&cfg))
+	tt.Equal(t, "stdout", cfg.Mode)
+	tt.Equal(t, "./logs", cfg.Path)
+	tt.Equal(t, 10, cfg.MaxSize)
+	tt.True(t, cfg.Syslog.Enabled)
+	tt.Equal(t, 100, cfg.Sampling.Initial)
+	tt.Equal(t, "core", cfg.Fields.Static["team"])
+}
+
+func TestApplyEnvError(t *testing.T) {
+	for _, c := range []struct {
+		key, val, err string
+	}{
+		{"ZLOG_MAX_SIZE", "ten", `zlog: invalid ZLOG_MAX_SIZE "ten"`},
+		{"ZLOG_MAX_DAYS", "1.5", `zlog: invalid ZLOG_MAX_DAYS "1.5"`},
+		{"ZLOG_COMPRESS", "maybe", `zlog: invalid ZLOG_COMPRESS "maybe"`},
+		{"ZLOG_DAILY_DIRS", "no way", `zlog: invalid ZLOG_DAILY_DIRS "no way"`},
+		{"ZLOG_FIELDS_STATIC", "team", `zlog: invalid ZLOG_FIELDS_STATIC "team"`},
+	} {
+		t.Run(c.key, func(t *testing.T) {
+			t.Setenv(c.key, c.val)
+
+			var cfg Config
+			err := applyEnv(&cfg)
+			tt.NotNil(t, err)
+			tt.True(t, strings.HasPrefix(err.Error(), c.err), err.Error())
+		})
+	}
+}
+
+func TestEnvOverride(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("ZLOG_LEVEL", "debug")
+	t.Setenv("ZLOG_NAME", "env")
+	t.Setenv("ZLOG_MAX_BACKUPS", "7")
+	tt.Nil(t, Init(writeConf(t, "path = \""+filepath.ToSlash(dir)+
+		"\"\nname = \"file\"\nlevel = \"warn\"\nmax_size = 9\n")))
+	t.Cleanup(func() { Close() })
+
+	cfg := EffectiveConfig()
+	tt.Equal(t, "debug", cfg.Level)
+	tt.Equal(t, "env", cfg.Name)
+	tt.Equal(t, filepath.ToSlash(dir), cfg.Path)
+	tt.Equal(t, 9, cfg.MaxSize)
+	tt.Equal(t, 7, cfg.MaxBackups)
+	tt.Equal(t, int64(28), cfg.MaxDays)
+	tt.Equal(t, 28, cfg.MaxAge)
+	tt.True(t, *cfg.DailyDirs)
+	tt.True(t, *cfg.MirrorErrors)
+
+	Debug("env debug")
+	tt.Nil(t, Close())
+	entries := readEntries(t, filepath.Join(dir, "*", "env.json"))
+	tt.Equal(t, 1, len(msgEntries(entries, "env debug")))
+
+	t.Setenv("ZLOG_MAX_SIZE", "big")
+	err := Init(writeConf(t, "path = \""+filepath.ToSlash(dir)+"\"\n"))
+	tt.NotNil(t, err)
+	tt.Equal(t, "env", EffectiveConfig().Name)
+}
@@ -0,0 +1,127 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// rateBuckets the buckets of the window of an error rate watcher
+const rateBuckets = 10
+
+var rateWatchers atomic.Value // []*rateWatcher
+
+// rateBucket the errors of a bucket of the window, epoch is the
+// index of the bucket since the zero time
+type rateBucket struct {
+	epoch, count int64
+}
+
+// rateWatcher counts the errors of the last window in coarse
+// buckets and calls fn when they're above threshold, the counts
+// are atomics so the loggers never wait on each other
+type rateWatcher struct {
+	threshold int64
+	window    int64
+	bucket    int64
+	fn        func(count int)
+
+	buckets [rateBuckets]rateBucket
+	// fired the time of the last call of fn
+	fired int64
+}
+
+func newRateWatcher(threshold int, window time.Duration, fn func(count int)) *rateWatcher {
+	bucket := int64(window) / rateBuckets
+	if bucket == 0 {
+		bucket = 1
+	}
+
+	return &rateWatcher{
+		threshold: int64(threshold),
+		window:    int64(window),
+		bucket:    bucket,
+		fn:        fn,
+	}
+}
+
+// OnErrorRate calls fn in a goroutine with the count of the errors
+// of the last window when it's above threshold, at most once per
+// window. The errors are the entries at error level or above, the
+// window is counted in buckets of a tenth of it, it stays
+// registered across Init.
+//
+//	zlog.OnErrorRate(100, time.Minute, func(count int) {
+//		breaker.Open()
+//	})
+func OnErrorRate(threshold int, window time.Duration, fn func(count int)) {
+	if threshold < 0 || window <= 0 {
+		getErrLogger().Error("zlog: invalid error rate",
+			zap.Int("threshold", threshold), zap.Duration("window", window))
+		return
+	}
+
+	hookLock.Lock()
+	ws, _ := rateWatchers.Load().([]*rateWatcher)
+	rateWatchers.Store(append(ws[:len(ws):len(ws)], newRateWatcher(threshold, window, fn)))
+	hookLock.Unlock()
+}
+
+// countError counts the error entries for the OnErrorRate funcs,
+// it runs in entryHook
+func countError(ent zapcore.Entry) {
+	if ent.Level < zap.ErrorLevel {
+		return
+	}
+
+	ws, _ := rateWatchers.Load().([]*rateWatcher)
+	for _, w := range ws {
+		if count, ok := w.add(ent.Time); ok {
+			go w.fn(count)
+		}
+	}
+}
+
+// add counts an error at now, it reports whether fn is due with
+// the count of the window
+func (w *rateWatcher) add(now time.Time) (int, bool) {
+	epoch := now.UnixNano() / w.bucket
+	b := &w.buckets[epoch%rateBuckets]
+
+	// a bucket of an older window starts again, the errors counted
+	// by another goroutine in between may be lost
+	if old := atomic.LoadInt64(&b.epoch); old != epoch &&
+		atomic.CompareAndSwapInt64(&b.epoch, old, epoch) {
+		atomic.StoreInt64(&b.count, 0)
+	}
+	atomic.AddInt64(&b.count, 1)
+
+	var count int64
+	for i := range w.buckets {
+		if epoch-atomic.LoadInt64(&w.buckets[i].epoch) < rateBuckets {
+			count += atomic.LoadInt64(&w.buckets[i].count)
+		}
+	}
+	if count <= w.threshold {
+		return 0, false
+	}
+
+	fired := atomic.LoadInt64(&w.fired)
+	if now.UnixNano()-fired < w.window ||
+		!atomic.CompareAndSwapInt64(&w.fired, fired, now.UnixNano()) {
+		return 0, false
+	}
+	return int(count), true
+}
@@ -0,0 +1,95 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/vcaesar/tt"
+)
+
+func TestRateWatcher(t *testing.T) {
+	w := newRateWatcher(3, 10*time.Second, nil)
+	now := time.Date(2018, 5, 1, 8, 0, 0, 0, time.UTC)
+
+	// 3 errors aren't above the threshold
+	for i := 0; i < 3; i++ {
+		_, ok := w.add(now)
+		tt.False(t, ok)
+	}
+	count, ok := w.add(now.Add(time.Second))
+	tt.True(t, ok)
+	tt.Equal(t, 4, count)
+
+	// once per window
+	_, ok = w.add(now.Add(2 * time.Second))
+	tt.False(t, ok)
+	_, ok = w.add(now.Add(10 * time.Second))
+	tt.False(t, ok)
+
+	// the errors of the first 2 buckets are out of the window
+	_, ok = w.add(now.Add(11 * time.Second))
+	tt.False(t, ok)
+	count, ok = w.add(now.Add(11 * time.Second))
+	tt.True(t, ok)
+	tt.Equal(t, 4, count)
+
+	// all the buckets are out of the window
+	_, ok = w.add(now.Add(time.Hour))
+	tt.False(t, ok)
+}
+
+func TestRateWatcherBurst(t *testing.T) {
+	w := newRateWatcher(100, time.Hour, nil)
+	now := time.Now()
+
+	// the first error starts the bucket
+	w.add(now)
+	var fired int64
+	hammer(100, func() {
+		if _, ok := w.add(now); ok {
+			atomic.AddInt64(&fired, 1)
+		}
+	})
+	tt.Equal(t, int64(1), fired)
+	tt.Equal(t, int64(801), w.buckets[now.UnixNano()/w.bucket%rateBuckets].count)
+}
+
+func TestOnErrorRate(t *testing.T) {
+	prev, _ := rateWatchers.Load().([]*rateWatcher)
+	t.Cleanup(func() { rateWatchers.Store(prev) })
+
+	tt.Nil(t, InitWithConfig(Config{Mode: "discard"}))
+	defer Close()
+
+	counts := make(chan int, 4)
+	OnErrorRate(5, time.Hour, func(count int) { counts <- count })
+	OnErrorRate(50, time.Hour, func(count int) { counts <- -count })
+	OnErrorRate(-1, time.Hour, nil)
+
+	for i := 0; i < 10; i++ {
+		Warn("not counted")
+		Error("rate error", errors.New("e1"))
+	}
+
+	select {
+	case count := <-counts:
+		tt.Equal(t, 6, count)
+	case <-time.After(5 * time.Second):
+		t.Fatal("fn not called")
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	tt.Equal(t, 0, len(counts))
+}
@@ -0,0 +1,143 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+// Package eventlog a zapcore.Core writing the warn and the error
+// entries to the windows event log, it is added to zlog by Attach.
+// On the other systems Attach returns an error.
+package eventlog
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-vgo/gt/zlog"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// EventID the id of the events of the entries
+	EventID = 1
+	// maxMessage the max length of an event message
+	maxMessage = 31839
+)
+
+// writer the event log of a source
+type writer interface {
+	Warning(eid uint32, msg string) error
+	Error(eid uint32, msg string) error
+	Close() error
+}
+
+// Core a zapcore.Core writing the warn entries as the Warning events
+// and the error entries and above as the Error events, the message of
+// an event is the json entry
+type Core struct {
+	enc zapcore.Encoder
+	w   writer
+}
+
+// Attach registers the event source if it isn't registered, which
+// needs the administrator rights once, and adds its core to zlog by
+// zlog.AddCore.
+//
+//	if err := eventlog.Attach("my-service"); err != nil {
+//		zlog.Warn("no event log", err.Error())
+//	}
+func Attach(source string) error {
+	core, err := New(source)
+	if err != nil {
+		return err
+	}
+
+	zlog.AddCore(core)
+	return nil
+}
+
+// New returns the core of the event source, like Attach without
+// adding it.
+func New(source string) (*Core, error) {
+	if source == "" || strings.ContainsAny(source, `\/`) {
+		return nil, errors.New("eventlog: invalid source " + source)
+	}
+
+	w, err := open(source)
+	if err != nil {
+		return nil, err
+	}
+	return newCore(w), nil
+}
+
+func newCore(w writer) *Core {
+	encCfg := zap.NewProductionEncoderConfig()
+	encCfg.EncodeTime = zapcore.ISO8601TimeEncoder
+
+	return &Core{enc: zapcore.NewJSONEncoder(encCfg), w: w}
+}
+
+// Enabled reports whether the level is warn or above
+func (c *Core) Enabled(l zapcore.Level) bool {
+	return l >= zapcore.WarnLevel
+}
+
+// With adds the fields to the messages of the returned core
+func (c *Core) With(fields []zapcore.Field) zapcore.Core {
+	enc := c.enc.Clone()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	return &Core{enc: enc, w: c.w}
+}
+
+// Check adds the core to ce if the level is enabled
+func (c *Core) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write writes the event of the entry
+func (c *Core) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	msg := truncate(strings.TrimSuffix(buf.String(), "\n"), maxMessage)
+	buf.Free()
+
+	if ent.Level == zapcore.WarnLevel {
+		return c.w.Warning(EventID, msg)
+	}
+	return c.w.Error(EventID, msg)
+}
+
+// Sync the events are written by Write
+func (c *Core) Sync() error {
+	return nil
+}
+
+// Close closes the event log, the entries written after Close fail
+func (c *Core) Close() error {
+	return c.w.Close()
+}
+
+// truncate cuts s to max bytes on a rune boundary
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+
+	for max > 0 && !utf8.RuneStart(s[max]) {
+		max--
+	}
+	return s[:max]
+}
@@ -0,0 +1,27 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+// +build !windows
+
+package eventlog
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/vcaesar/tt"
+)
+
+func TestAttach(t *testing.T) {
+	err := Attach("zlog-test")
+	tt.NotNil(t, err)
+	tt.True(t, strings.Contains(err.Error(), "not supported on "+runtime.GOOS))
+}
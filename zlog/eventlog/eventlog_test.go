@@ -0,0 +1,81 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package eventlog
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/vcaesar/tt"
+	"go.uber.org/zap"
+)
+
+type event struct {
+	typ string
+	eid uint32
+	msg string
+}
+
+type fakeLog struct {
+	events []event
+}
+
+func (l *fakeLog) Warning(eid uint32, msg string) error {
+	l.events = append(l.events, event{"warning", eid, msg})
+	return nil
+}
+
+func (l *fakeLog) Error(eid uint32, msg string) error {
+	l.events = append(l.events, event{"error", eid, msg})
+	return nil
+}
+
+func (l *fakeLog) Close() error {
+	return nil
+}
+
+func TestCore(t *testing.T) {
+	w := &fakeLog{}
+	logger := zap.New(newCore(w)).With(zap.String("app", "gt"))
+
+	logger.Info("info")
+	logger.Warn("warn", zap.Int("n", 1))
+	logger.Error("error", zap.Error(errors.New("e1")))
+	logger.DPanic("dpanic")
+
+	tt.Equal(t, 3, len(w.events))
+	tt.Equal(t, "warning", w.events[0].typ)
+	tt.Equal(t, "error", w.events[1].typ)
+	tt.Equal(t, "error", w.events[2].typ)
+	tt.Equal(t, uint32(EventID), w.events[1].eid)
+
+	var m map[string]interface{}
+	tt.Nil(t, json.Unmarshal([]byte(w.events[1].msg), &m))
+	tt.Equal(t, "error", m["msg"])
+	tt.Equal(t, "e1", m["error"])
+	tt.Equal(t, "gt", m["app"])
+	tt.False(t, strings.HasSuffix(w.events[0].msg, "\n"))
+}
+
+func TestTruncate(t *testing.T) {
+	tt.Equal(t, "abc", truncate("abc", 3))
+	tt.Equal(t, "ab", truncate("abc", 2))
+	// the 3 bytes of 世 aren't cut
+	tt.Equal(t, "a", truncate("a世", 3))
+}
+
+func TestSource(t *testing.T) {
+	_, err := New(`a\b`)
+	tt.NotNil(t, err)
+	tt.NotNil(t, Attach(""))
+}
@@ -0,0 +1,41 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+// +build windows
+
+package eventlog
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/windows/registry"
+	"golang.org/x/sys/windows/svc/eventlog"
+)
+
+// sourceKey the registry key of the sources of the application log
+const sourceKey = `SYSTEM\CurrentControlSet\Services\EventLog\Application\`
+
+// open registers source with the EventCreate messages if it isn't
+// registered and opens its event log
+func open(source string) (writer, error) {
+	key, err := registry.OpenKey(registry.LOCAL_MACHINE, sourceKey+source, registry.QUERY_VALUE)
+	if err == nil {
+		key.Close()
+	} else if err := eventlog.InstallAsEventCreate(source,
+		eventlog.Error|eventlog.Warning|eventlog.Info); err != nil {
+		return nil, fmt.Errorf("eventlog: register the source %q: %v", source, err)
+	}
+
+	l, err := eventlog.Open(source)
+	if err != nil {
+		return nil, fmt.Errorf("eventlog: open the source %q: %v", source, err)
+	}
+	return l, nil
+}
@@ -0,0 +1,52 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+// +build windows
+
+package eventlog
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-vgo/gt/zlog"
+	"github.com/vcaesar/tt"
+	"go.uber.org/zap/zapcore"
+)
+
+const testSource = "zlog-test"
+
+func TestAttach(t *testing.T) {
+	core, err := New(testSource)
+	if err != nil {
+		// registering the source needs the administrator rights
+		t.Skip(err)
+	}
+	defer core.Close()
+
+	h := zlog.AddCore(core)
+	defer h.Remove()
+
+	msg := fmt.Sprint("eventlog test ", time.Now().UnixNano())
+	zlog.L().Error(msg)
+	tt.Nil(t, core.Write(zapcore.Entry{Level: zapcore.WarnLevel, Message: msg + " warn"}, nil))
+
+	query := fmt.Sprintf("*[System[Provider[@Name='%s']]]", testSource)
+	out, err := exec.Command("wevtutil", "qe", "Application", "/q:"+query,
+		"/c:10", "/rd:true", "/f:text").CombinedOutput()
+	tt.Nil(t, err)
+	tt.True(t, strings.Contains(string(out), msg+`"`))
+	tt.True(t, strings.Contains(string(out), msg+" warn"))
+	tt.True(t, strings.Contains(string(out), "Level: Error"))
+	tt.True(t, strings.Contains(string(out), "Level: Warning"))
+}
@@ -0,0 +1,160 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// maxEveryKeys the keys kept by the limiter of the Every funcs
+const maxEveryKeys = 4096
+
+// everyKey the last entry of a key and the entries suppressed since
+type everyKey struct {
+	last       time.Time
+	interval   time.Duration
+	suppressed int64
+}
+
+// keyLimiter allows an entry per interval and per key, the keys
+// whose interval is over are evicted when it is full.
+type keyLimiter struct {
+	mu   sync.Mutex
+	max  int
+	now  func() time.Time
+	keys map[string]*everyKey
+}
+
+func newKeyLimiter(max int) *keyLimiter {
+	return &keyLimiter{
+		max:  max,
+		now:  time.Now,
+		keys: make(map[string]*everyKey),
+	}
+}
+
+var (
+	everyLimiter = newKeyLimiter(maxEveryKeys)
+	onceKeys     sync.Map
+)
+
+// allow reports whether the entry of key is logged and returns
+// the entries suppressed since the last one
+func (l *keyLimiter) allow(key string, interval time.Duration) (bool, int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	k, ok := l.keys[key]
+	if ok && now.Sub(k.last) < interval {
+		k.suppressed++
+		return false, 0
+	}
+
+	if !ok {
+		if len(l.keys) >= l.max {
+			l.evict(now)
+		}
+		k = &everyKey{}
+		l.keys[key] = k
+	}
+
+	suppressed := k.suppressed
+	k.last, k.interval, k.suppressed = now, interval, 0
+	return true, suppressed
+}
+
+// evict removes the keys whose interval is over, or the
+// oldest key if none is over
+func (l *keyLimiter) evict(now time.Time) {
+	var (
+		oldest string
+		last   time.Time
+	)
+	for key, k := range l.keys {
+		if now.Sub(k.last) >= k.interval {
+			delete(l.keys, key)
+			continue
+		}
+
+		if oldest == "" || k.last.Before(last) {
+			oldest, last = key, k.last
+		}
+	}
+
+	if len(l.keys) >= l.max {
+		delete(l.keys, oldest)
+	}
+}
+
+// ErrorEvery error log at most once per interval for key, the
+// entry has the suppressed field with the entries skipped since
+// the last one.
+//
+//	zlog.ErrorEvery("db retry", time.Minute, "db retry error", err)
+func ErrorEvery(key string, interval time.Duration, msg string, err error) {
+	logger := getErrLogger()
+	if !logger.Core().Enabled(zap.ErrorLevel) {
+		return
+	}
+
+	ok, suppressed := everyLimiter.allow(key, interval)
+	if !ok {
+		return
+	}
+
+	if ce := logger.Check(zap.ErrorLevel, msg); ce != nil {
+		ce.Write(zap.Error(err), suppressedField(suppressed))
+	}
+}
+
+// InfoEvery info log at most once per interval for key,
+// like ErrorEvery
+func InfoEvery(key string, interval time.Duration, msg string, info ...string) {
+	logger := getLogger()
+	if !logger.Core().Enabled(zap.InfoLevel) {
+		return
+	}
+
+	ok, suppressed := everyLimiter.allow(key, interval)
+	if !ok {
+		return
+	}
+
+	if ce := logger.Check(zap.InfoLevel, msg); ce != nil {
+		ce.Write(zap.String("info", strings.Join(info, " ")),
+			suppressedField(suppressed))
+	}
+}
+
+// suppressedField the suppressed field, skipped if there's none
+func suppressedField(n int64) zap.Field {
+	if n == 0 {
+		return zap.Skip()
+	}
+	return zap.Int64("suppressed", n)
+}
+
+// Once runs fn the first time it is called for key, the
+// keys are kept for the life of the process.
+//
+//	zlog.Once("deprecated conf", func() {
+//		zlog.Warn("the server table is deprecated")
+//	})
+func Once(key string, fn func()) {
+	if _, loaded := onceKeys.LoadOrStore(key, struct{}{}); !loaded {
+		fn()
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/vcaesar/tt"
+)
+
+// fakeLimiter swaps in a limiter of max keys on the clock
+func fakeLimiter(t *testing.T, max int) *fakeClock {
+	clock := &fakeClock{tm: time.Date(2018, 5, 1, 8, 0, 0, 0, time.UTC)}
+	prev := everyLimiter
+	everyLimiter = newKeyLimiter(max)
+	everyLimiter.now = clock.now
+	t.Cleanup(func() { everyLimiter = prev })
+
+	return clock
+}
+
+// hammer runs fn n times from 8 goroutines
+func hammer(n int, fn func()) {
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < n; i++ {
+				fn()
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestErrorEvery(t *testing.T) {
+	clock := fakeLimiter(t, maxEveryKeys)
+	_, errLogs := observeSplit(t)
+
+	hammer(100, func() {
+		ErrorEvery("retry", time.Minute, "retry error", errors.New("e1"))
+	})
+	tt.Equal(t, 1, errLogs.Len())
+	_, ok := errLogs.All()[0].ContextMap()["suppressed"]
+	tt.False(t, ok)
+
+	clock.set(clock.now().Add(time.Minute))
+	hammer(100, func() {
+		ErrorEvery("retry", time.Minute, "retry error", errors.New("e1"))
+	})
+	tt.Equal(t, 2, errLogs.Len())
+	entry := errLogs.All()[1].ContextMap()
+	tt.Equal(t, int64(799), entry["suppressed"])
+	tt.Equal(t, "e1", entry["error"])
+}
+
+func TestInfoEvery(t *testing.T) {
+	clock := fakeLimiter(t, maxEveryKeys)
+	logs, _ := observeSplit(t)
+
+	hammer(50, func() {
+		InfoEvery("a", time.Second, "every a", "x")
+		InfoEvery("b", time.Minute, "every b")
+	})
+	tt.Equal(t, 1, logs.FilterMessage("every a").Len())
+	tt.Equal(t, 1, logs.FilterMessage("every b").Len())
+
+	// only the interval of a is over
+	clock.set(clock.now().Add(time.Second))
+	InfoEvery("a", time.Second, "every a", "x")
+	InfoEvery("b", time.Minute, "every b")
+
+	a := logs.FilterMessage("every a").All()
+	tt.Equal(t, 2, len(a))
+	tt.Equal(t, int64(399), a[1].ContextMap()["suppressed"])
+	tt.Equal(t, "x", a[1].ContextMap()["info"])
+	tt.Equal(t, 1, logs.FilterMessage("every b").Len())
+
+	// a disabled level counts nothing
+	current.Store(nopLoggers())
+	InfoEvery("c", time.Minute, "every c")
+	_, ok := everyLimiter.keys["c"]
+	tt.False(t, ok)
+}
+
+func TestEvictKeys(t *testing.T) {
+	clock := fakeLimiter(t, 3)
+	l := everyLimiter
+
+	for i := 0; i < 3; i++ {
+		ok, _ := l.allow(fmt.Sprint(i), time.Minute)
+		tt.True(t, ok)
+		clock.set(clock.now().Add(time.Second))
+	}
+
+	// no interval is over, the oldest key is evicted
+	l.allow("3", time.Minute)
+	tt.Equal(t, 3, len(l.keys))
+	_, ok := l.keys["0"]
+	tt.False(t, ok)
+
+	// all the intervals are over
+	clock.set(clock.now().Add(time.Hour))
+	l.allow("4", time.Minute)
+	tt.Equal(t, 1, len(l.keys))
+
+	hammer(100, func() {
+		l.allow(fmt.Sprint(time.Now().UnixNano()), time.Minute)
+	})
+	tt.True(t, len(l.keys) <= 3)
+}
+
+func TestOnce(t *testing.T) {
+	// the keys stay for the other runs of the test
+	key := fmt.Sprint("once ", time.Now().UnixNano())
+	var n int64
+	hammer(100, func() {
+		Once(key, func() { atomic.AddInt64(&n, 1) })
+	})
+	tt.Equal(t, int64(1), n)
+
+	Once(key+" 2", func() { atomic.AddInt64(&n, 1) })
+	tt.Equal(t, int64(2), n)
+}
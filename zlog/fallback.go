@@ -0,0 +1,98 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// fallbackProbe the wait before a failed log file is tried again
+const fallbackProbe = 10 * time.Second
+
+// fallbackOutput returns the output of Fallback, nil for "none"
+func (c *Config) fallbackOutput() (zapcore.WriteSyncer, error) {
+	switch c.Fallback {
+	case "", "none":
+		return nil, nil
+	case "stderr":
+		return lockFile(os.Stderr), nil
+	case "stdout":
+		return lockFile(os.Stdout), nil
+	}
+	return nil, fmt.Errorf("zlog: invalid fallback %q, want stderr, stdout or none",
+		c.Fallback)
+}
+
+// fallbackWriter writes the entries to the fallback output when
+// the writes of the file fail, the file is tried again by the first
+// entry after fallbackProbe and used again if the write succeeds.
+// Each entry is written once, to the file or to the fallback.
+type fallbackWriter struct {
+	mu       sync.Mutex
+	file     logFile
+	fallback zapcore.WriteSyncer
+	now      func() time.Time
+
+	failed bool
+	retry  time.Time
+}
+
+func newFallbackWriter(file logFile, fallback zapcore.WriteSyncer) *fallbackWriter {
+	return &fallbackWriter{file: file, fallback: fallback, now: time.Now}
+}
+
+// Write writes p to the file, or to the fallback if the file failed,
+// the failures are counted like the other write errors
+func (w *fallbackWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	if w.failed && w.now().Before(w.retry) {
+		defer w.mu.Unlock()
+		return w.fallback.Write(p)
+	}
+
+	n, err := w.file.Write(p)
+	if err == nil {
+		w.failed = false
+		w.mu.Unlock()
+		return n, nil
+	}
+
+	w.failed, w.retry = true, w.now().Add(fallbackProbe)
+	n, fallbackErr := w.fallback.Write(p)
+	w.mu.Unlock()
+
+	// out of the lock, the OnWriteError funcs may log
+	writeFailed(outputName(w.file), int64(bytes.Count(p, []byte{'\n'})), err)
+	return n, fallbackErr
+}
+
+// Name returns the name of the file
+func (w *fallbackWriter) Name() string {
+	return outputName(w.file)
+}
+
+func (w *fallbackWriter) Sync() error {
+	return w.file.Sync()
+}
+
+func (w *fallbackWriter) Rotate() error {
+	return w.file.Rotate()
+}
+
+func (w *fallbackWriter) Close() error {
+	return w.file.Close()
+}
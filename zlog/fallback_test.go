@@ -0,0 +1,124 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/vcaesar/tt"
+)
+
+// flakyFile a log file failing while down
+type flakyFile struct {
+	mu    sync.Mutex
+	down  bool
+	lines []string
+}
+
+func (f *flakyFile) setDown(down bool) {
+	f.mu.Lock()
+	f.down = down
+	f.mu.Unlock()
+}
+
+func (f *flakyFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.down {
+		return 0, errors.New("read-only file system")
+	}
+	f.lines = append(f.lines, strings.TrimSuffix(string(p), "\n"))
+	return len(p), nil
+}
+
+func (f *flakyFile) Sync() error   { return nil }
+func (f *flakyFile) Rotate() error { return nil }
+func (f *flakyFile) Close() error  { return nil }
+
+// lockedBuffer a buffer safe for concurrent use
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) Sync() error { return nil }
+
+func (b *lockedBuffer) lines() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return strings.Split(strings.TrimSuffix(b.buf.String(), "\n"), "\n")
+}
+
+func TestFallbackWriter(t *testing.T) {
+	fakeNotices(t)
+	errs := Stats().WriteErrors
+	clock := &fakeClock{tm: time.Date(2018, 5, 1, 8, 0, 0, 0, time.UTC)}
+	file, fallback := &flakyFile{}, &lockedBuffer{}
+	w := newFallbackWriter(file, fallback)
+	w.now = clock.now
+
+	write := func(from, to int) {
+		for i := from; i < to; i++ {
+			_, err := w.Write([]byte(fmt.Sprintf("entry %d\n", i)))
+			tt.Nil(t, err)
+		}
+	}
+
+	write(0, 3)
+	file.setDown(true)
+	write(3, 6)
+	// only the first failure tries the file
+	tt.Equal(t, errs+1, Stats().WriteErrors)
+
+	// the probe fails, the file is still down
+	clock.set(clock.now().Add(fallbackProbe))
+	write(6, 7)
+	tt.Equal(t, errs+2, Stats().WriteErrors)
+
+	file.setDown(false)
+	write(7, 8)
+	clock.set(clock.now().Add(fallbackProbe))
+	hammer(4, func() {
+		w.Write([]byte("entry recovered\n"))
+	})
+
+	tt.Equal(t, []string{"entry 0", "entry 1", "entry 2"}, file.lines[:3])
+	tt.Equal(t, 32, len(file.lines)-3)
+	tt.Equal(t, []string{"entry 3", "entry 4", "entry 5", "entry 6", "entry 7"},
+		fallback.lines())
+}
+
+func TestFallback(t *testing.T) {
+	err := InitWithConfig(Config{Path: t.TempDir(), Fallback: "syslog"})
+	tt.NotNil(t, err)
+	tt.Equal(t, `zlog: invalid fallback "syslog", want stderr, stdout or none`, err.Error())
+
+	dir := initTest(t, `fallback = "stderr"`)
+	Info("fallback info")
+	tt.Nil(t, Close())
+
+	entries := readEntries(t, filepath.Join(dir, "*", "test.json"))
+	tt.Equal(t, 1, len(msgEntries(entries, "fallback info")))
+}
@@ -0,0 +1,170 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"os"
+	"sort"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+)
+
+// FieldsConfig the fields added to every entry of the loggers
+type FieldsConfig struct {
+	// Hostname adds the hostname field
+	Hostname bool `toml:"hostname"`
+	// PID adds the pid field
+	PID bool `toml:"pid"`
+	// App the app field, empty adds nothing
+	App string `toml:"app"`
+	// Version the version field, empty adds nothing
+	Version string `toml:"version"`
+	// Env the env field like "prod", empty adds nothing
+	Env string `toml:"env"`
+	// Static the other fields, the [fields.static] table
+	Static map[string]string `toml:"static"`
+}
+
+// K8sConfig the env vars of the fields of K8sFields, set by the
+// downward api of the pod spec like
+//
+//	env:
+//	- name: POD_NAME
+//	  valueFrom: {fieldRef: {fieldPath: metadata.name}}
+type K8sConfig struct {
+	// PodEnv the env var of the pod field, default "POD_NAME"
+	PodEnv string `toml:"pod_env"`
+	// NamespaceEnv the env var of the namespace field,
+	// default "POD_NAMESPACE"
+	NamespaceEnv string `toml:"namespace_env"`
+	// NodeEnv the env var of the node field, default "NODE_NAME"
+	NodeEnv string `toml:"node_env"`
+	// ContainerEnv the env var of the container field,
+	// default "CONTAINER_NAME"
+	ContainerEnv string `toml:"container_env"`
+}
+
+var runtimeFields atomic.Value // []zap.Field
+
+// SetGlobalFields sets the fields added to every entry of the loggers
+// after the fields of the config, like the values only known at
+// runtime, it replaces the fields of the previous call and stays set
+// across Init. The loggers taken by L or With before it don't have
+// the fields.
+//
+//	zlog.SetGlobalFields(zlog.Str("region", region))
+func SetGlobalFields(fields ...zap.Field) {
+	runtimeFields.Store(append([]zap.Field(nil), fields...))
+
+	// the loggers are rebuilt on their bases
+	swap(func(lg *loggers) {})
+}
+
+// globalFields returns the fields of the config and SetGlobalFields
+func globalFields() []zap.Field {
+	fields, _ := runtimeFields.Load().([]zap.Field)
+	config := getConfig()
+	return mergeFields(append(config.buildFields(), config.configFields()...), fields)
+}
+
+// configFields returns the fields of the fields table
+func (c *Config) configFields() []zap.Field {
+	conf := c.Fields
+
+	var k8s []zap.Field
+	if c.K8sFields {
+		k8s = c.k8sFields()
+	}
+
+	var fields []zap.Field
+	if conf.Hostname {
+		// the hostname of a pod is its name, already the pod field
+		if host, err := os.Hostname(); err == nil && !hasValue(k8s, "pod", host) {
+			fields = append(fields, zap.String("hostname", host))
+		}
+	}
+	if conf.PID {
+		fields = append(fields, zap.Int("pid", os.Getpid()))
+	}
+	fields = append(fields, k8s...)
+
+	for _, f := range []struct{ key, val string }{
+		{"app", conf.App}, {"version", conf.Version}, {"env", conf.Env},
+	} {
+		if f.val != "" {
+			fields = append(fields, zap.String(f.key, f.val))
+		}
+	}
+
+	keys := make([]string, 0, len(conf.Static))
+	for key := range conf.Static {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fields = append(fields, zap.String(key, conf.Static[key]))
+	}
+
+	return fields
+}
+
+// k8sFields returns the fields of the env vars of K8s which are set
+func (c *Config) k8sFields() []zap.Field {
+	var fields []zap.Field
+	for _, f := range []struct{ key, env, def string }{
+		{"pod", c.K8s.PodEnv, "POD_NAME"},
+		{"namespace", c.K8s.NamespaceEnv, "POD_NAMESPACE"},
+		{"node", c.K8s.NodeEnv, "NODE_NAME"},
+		{"container", c.K8s.ContainerEnv, "CONTAINER_NAME"},
+	} {
+		if f.env == "" {
+			f.env = f.def
+		}
+		if val := os.Getenv(f.env); val != "" {
+			fields = append(fields, zap.String(f.key, val))
+		}
+	}
+	return fields
+}
+
+// mergeFields returns the fields of a then b, the later keys
+// override the earlier ones, a and b aren't modified
+func mergeFields(a, b []zap.Field) []zap.Field {
+	all := append(append(make([]zap.Field, 0, len(a)+len(b)), a...), b...)
+
+	merged := all[:0]
+	for i, f := range all {
+		if !hasKey(all[i+1:], f.Key) {
+			merged = append(merged, f)
+		}
+	}
+	return merged
+}
+
+func hasKey(fields []zap.Field, key string) bool {
+	for _, f := range fields {
+		if f.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// hasValue reports whether the string field key of fields is val
+func hasValue(fields []zap.Field, key, val string) bool {
+	for _, f := range fields {
+		if f.Key == key && f.String == val {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,135 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/vcaesar/tt"
+	"go.uber.org/zap"
+)
+
+func TestGlobalFields(t *testing.T) {
+	t.Cleanup(func() { SetGlobalFields() })
+	dir := initTest(t, "[fields]", "hostname = true", "pid = true",
+		`app = "api"`, `version = "1.2.0"`, `env = "prod"`,
+		"[fields.static]", `team = "core"`, `zone = "a"`)
+
+	host, err := os.Hostname()
+	tt.Nil(t, err)
+
+	Info("global info")
+	SetGlobalFields(zap.String("region", "eu"), zap.String("env", "stage"))
+	db := Named("db")
+	db.Info("named info")
+	Error("global error", errors.New("e1"))
+	tt.Nil(t, Close())
+
+	entries := readEntries(t, filepath.Join(dir, "*", "test.json"))
+	entry := msgEntries(entries, "global info")[0]
+	tt.Equal(t, host, entry["hostname"])
+	tt.Equal(t, float64(os.Getpid()), entry["pid"])
+	tt.Equal(t, "api", entry["app"])
+	tt.Equal(t, "1.2.0", entry["version"])
+	tt.Equal(t, "prod", entry["env"])
+	tt.Equal(t, "core", entry["team"])
+	tt.Equal(t, "a", entry["zone"])
+	_, ok := entry["region"]
+	tt.False(t, ok)
+
+	// the runtime fields override the keys of the config
+	entry = msgEntries(entries, "named info")[0]
+	tt.Equal(t, "db", entry["logger"])
+	tt.Equal(t, "eu", entry["region"])
+	tt.Equal(t, "stage", entry["env"])
+	tt.Equal(t, "api", entry["app"])
+
+	entry = msgEntries(readEntries(t, filepath.Join(dir, "*", "test_err.json")),
+		"global error")[0]
+	tt.Equal(t, "eu", entry["region"])
+	tt.Equal(t, host, entry["hostname"])
+}
+
+func TestSetGlobalFields(t *testing.T) {
+	t.Cleanup(func() { SetGlobalFields() })
+	tt.Nil(t, InitNop())
+	SetGlobalFields(zap.String("region", "eu"))
+	// the no-op loggers stay no-op
+	tt.Equal(t, nopLogger, L())
+
+	logs := NewTestLogger(t)
+	SetGlobalFields(zap.String("region", "us"))
+	Info("set global")
+	With(zap.Int("n", 1)).Error("set global error")
+	SetGlobalFields()
+	Info("no global")
+
+	entries := logs.Entries()
+	tt.Equal(t, 3, len(entries))
+	tt.Equal(t, "us", entries[0].ContextMap()["region"])
+	tt.Equal(t, "us", entries[1].ContextMap()["region"])
+	_, ok := entries[2].ContextMap()["region"]
+	tt.False(t, ok)
+}
+
+func TestMergeFields(t *testing.T) {
+	a := []zap.Field{zap.String("a", "1"), zap.String("b", "1")}
+	b := []zap.Field{zap.String("b", "2"), zap.String("c", "2"), zap.String("c", "3")}
+
+	merged := mergeFields(a, b)
+	tt.Equal(t, []zap.Field{a[0], b[0], b[2]}, merged)
+	tt.Equal(t, "1", a[1].String)
+	tt.Equal(t, 0, len(mergeFields(nil, nil)))
+}
+
+func TestK8sFields(t *testing.T) {
+	host, err := os.Hostname()
+	tt.Nil(t, err)
+
+	t.Setenv("POD_NAME", host)
+	t.Setenv("POD_NAMESPACE", "shop")
+	t.Setenv("NODE_NAME", "node-1")
+	t.Setenv("APP_CONTAINER", "api")
+	dir := initTest(t, "k8s_fields = true", "[fields]", "hostname = true", "pid = true",
+		"[k8s]", `container_env = "APP_CONTAINER"`)
+	Info("k8s info")
+	tt.Nil(t, Close())
+
+	entry := msgEntries(readEntries(t, filepath.Join(dir, "*", "test.json")), "k8s info")[0]
+	tt.Equal(t, host, entry["pod"])
+	tt.Equal(t, "shop", entry["namespace"])
+	tt.Equal(t, "node-1", entry["node"])
+	tt.Equal(t, "api", entry["container"])
+	tt.Equal(t, float64(os.Getpid()), entry["pid"])
+	// the hostname is the pod field
+	_, ok := entry["hostname"]
+	tt.False(t, ok)
+}
+
+func TestK8sFieldsUnset(t *testing.T) {
+	for _, env := range []string{"POD_NAME", "POD_NAMESPACE", "NODE_NAME", "CONTAINER_NAME"} {
+		t.Setenv(env, "")
+	}
+
+	dir := initTest(t, "k8s_fields = true", "[fields]", "hostname = true")
+	Info("no k8s info")
+	tt.Nil(t, Close())
+
+	entry := msgEntries(readEntries(t, filepath.Join(dir, "*", "test.json")), "no k8s info")[0]
+	for _, key := range []string{"pod", "namespace", "node", "container"} {
+		_, ok := entry[key]
+		tt.False(t, ok)
+	}
+	tt.NotNil(t, entry["hostname"])
+}
@@ -0,0 +1,145 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"fmt"
+	"regexp"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// FilterConfig the regexps of the messages of the entries below
+// error, the Error+ entries always pass
+type FilterConfig struct {
+	// Deny drops the entries whose message matches one of them
+	Deny []string `toml:"deny"`
+	// Allow only keeps the entries whose message matches one of them,
+	// empty keeps all of them
+	Allow []string `toml:"allow"`
+}
+
+// filters the compiled regexps of the running config
+type filters struct {
+	deny, allow []*regexp.Regexp
+}
+
+// filtersConf compiles the regexps of the Filters
+func (c *Config) filtersConf() (*filters, error) {
+	deny, err := compileAll("filters.deny", c.Filters.Deny)
+	if err != nil {
+		return nil, err
+	}
+	allow, err := compileAll("filters.allow", c.Filters.Allow)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(deny) == 0 && len(allow) == 0 {
+		return nil, nil
+	}
+	return &filters{deny: deny, allow: allow}, nil
+}
+
+func compileAll(key string, exprs []string) ([]*regexp.Regexp, error) {
+	var res []*regexp.Regexp
+	for _, expr := range exprs {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("zlog: invalid %s %q: %v", key, expr, err)
+		}
+		res = append(res, re)
+	}
+	return res, nil
+}
+
+func (st *state) loadFilters() *filters {
+	f, _ := st.filters.Load().(*filters)
+	return f
+}
+
+func (st *state) setFilters(f *filters) {
+	st.filterLock.Lock()
+	st.filters.Store(f)
+	st.filterLock.Unlock()
+}
+
+// AddDenyFilter drops the entries below error whose message matches
+// re at runtime, like the filters.deny of the config, Init replaces
+// them with the config.
+//
+//	zlog.AddDenyFilter(regexp.MustCompile(`^grpc: addrConn\.createTransport`))
+func AddDenyFilter(re *regexp.Regexp) {
+	defaultState.addDenyFilter(re)
+}
+
+func (st *state) addDenyFilter(re *regexp.Regexp) {
+	st.filterLock.Lock()
+	defer st.filterLock.Unlock()
+
+	next := &filters{}
+	if f := st.loadFilters(); f != nil {
+		*next = *f
+	}
+	next.deny = append(next.deny[:len(next.deny):len(next.deny)], re)
+	st.filters.Store(next)
+}
+
+// pass reports whether the message passes the filters
+func (f *filters) pass(msg string) bool {
+	for _, re := range f.deny {
+		if re.MatchString(msg) {
+			return false
+		}
+	}
+
+	if len(f.allow) == 0 {
+		return true
+	}
+	for _, re := range f.allow {
+		if re.MatchString(msg) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterHook wraps the cores of the loggers with the filters of st
+func (st *state) filterHook() zap.Option {
+	return zap.WrapCore(func(c zapcore.Core) zapcore.Core {
+		return filterCore{c, st}
+	})
+}
+
+// filterCore drops the entries below error filtered out by the
+// messages, they're counted in the FilteredEntries of Stats
+type filterCore struct {
+	zapcore.Core
+	st *state
+}
+
+func (c filterCore) With(fields []zapcore.Field) zapcore.Core {
+	return filterCore{c.Core.With(fields), c.st}
+}
+
+func (c filterCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if ent.Level >= zap.ErrorLevel || !c.Enabled(ent.Level) {
+		return c.Core.Check(ent, ce)
+	}
+
+	if f := c.st.loadFilters(); f != nil && !f.pass(ent.Message) {
+		atomic.AddInt64(&filteredEntries, 1)
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
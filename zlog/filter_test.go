@@ -0,0 +1,113 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"errors"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/vcaesar/tt"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// filterLogger returns a debug logger with the filters
+func filterLogger(t *testing.T, f *filters) (*zap.Logger, *observer.ObservedLogs) {
+	t.Cleanup(func() { defaultState.setFilters(nil) })
+	defaultState.setFilters(f)
+	core, logs := observer.New(zap.DebugLevel)
+	return zap.New(core, defaultState.filterHook()), logs
+}
+
+func TestFilterDeny(t *testing.T) {
+	logger, logs := filterLogger(t, &filters{
+		deny: []*regexp.Regexp{regexp.MustCompile(`deprecated`)},
+	})
+	filtered := Stats().FilteredEntries
+
+	logger.Warn("the api is deprecated")
+	logger.Info("request")
+	logger.With(zap.String("ctx", "c1")).Debug("deprecated option")
+	// the Error+ entries always pass
+	logger.Error("deprecated and failed")
+	tt.Equal(t, filtered+2, Stats().FilteredEntries)
+
+	AddDenyFilter(regexp.MustCompile(`^request$`))
+	logger.Info("request")
+	logger.Info("request 2")
+	tt.Equal(t, filtered+3, Stats().FilteredEntries)
+
+	var msgs []string
+	for _, entry := range logs.All() {
+		msgs = append(msgs, entry.Message)
+	}
+	tt.Equal(t, []string{"request", "deprecated and failed", "request 2"}, msgs)
+}
+
+func TestFilterAllow(t *testing.T) {
+	logger, logs := filterLogger(t, &filters{
+		deny:  []*regexp.Regexp{regexp.MustCompile(`health`)},
+		allow: []*regexp.Regexp{regexp.MustCompile(`^http `), regexp.MustCompile(`^db `)},
+	})
+
+	logger.Info("http request")
+	logger.Info("db query")
+	logger.Info("http health")
+	logger.Info("cache miss")
+	logger.Error("cache error")
+
+	var msgs []string
+	for _, entry := range logs.All() {
+		msgs = append(msgs, entry.Message)
+	}
+	tt.Equal(t, []string{"http request", "db query", "cache error"}, msgs)
+
+	// runtime additions keep the allow list
+	defaultState.setFilters(nil)
+	AddDenyFilter(regexp.MustCompile(`cache`))
+	logger.Info("cache miss")
+	logger.Info("other")
+	tt.Equal(t, "other", logs.All()[logs.Len()-1].Message)
+	tt.Equal(t, 0, len(defaultState.loadFilters().allow))
+}
+
+func TestFilters(t *testing.T) {
+	t.Cleanup(func() { defaultState.setFilters(nil) })
+	err := InitWithConfig(Config{Path: t.TempDir(),
+		Filters: FilterConfig{Deny: []string{"(unclosed"}}})
+	tt.NotNil(t, err)
+	tt.True(t, strings.Contains(err.Error(), `zlog: invalid filters.deny "(unclosed"`))
+
+	dir := initTest(t, "[filters]", `deny = ["^noisy"]`)
+	Warn("noisy warning")
+	Info("kept")
+	Error("noisy error", errors.New("e1"))
+	tt.Nil(t, Close())
+
+	entries := readEntries(t, filepath.Join(dir, "*", "test.json"))
+	tt.Equal(t, 0, len(msgEntries(entries, "noisy warning")))
+	tt.Equal(t, 2, len(msgEntries(entries, "kept", "noisy error")))
+
+	// Init replaces the runtime filters by the config
+	AddDenyFilter(regexp.MustCompile(`kept`))
+	initTest(t)
+	defer Close()
+	tt.True(t, defaultState.loadFilters() == nil)
+
+	cfg, logger := *getConfig(), L()
+	cfg.Filters.Allow = []string{"^http "}
+	tt.Nil(t, apply(cfg))
+	tt.True(t, logger == L())
+	tt.Equal(t, 1, len(defaultState.loadFilters().allow))
+}
@@ -0,0 +1,133 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+const defaultFollowPoll = 250 * time.Millisecond
+
+// FollowOptions the log file of Follow
+type FollowOptions struct {
+	// Path the dir of the log files, default "./log"
+	Path string
+	// File the log file, default "log.json"
+	File string
+	// DailyDirs false follows Path/File, default true
+	DailyDirs *bool
+	// PollInterval the interval of the reads at the end of the
+	// file, default 250ms
+	PollInterval time.Duration
+}
+
+// Follow returns the reader of the lines written to the log file
+// from now on like tail -f, the file is followed across the daily
+// dirs and the rotations. Read waits for the next line until Close.
+//
+//	r := zlog.Follow(zlog.FollowOptions{Path: "./log"})
+//	defer r.Close()
+//	zlog.Pretty(r, os.Stdout, zlog.PrettyOptions{})
+func Follow(opts FollowOptions) io.ReadCloser {
+	return newFollower(opts, time.Now)
+}
+
+// follower reads the stream of the file, the lines are returned
+// whole by Read
+type follower struct {
+	lpath, name string
+	daily       bool
+	poll        time.Duration
+	now         func() time.Time
+
+	mu   sync.Mutex
+	st   shipStream
+	line []byte
+
+	stop chan struct{}
+	once sync.Once
+}
+
+func newFollower(opts FollowOptions, now func() time.Time) *follower {
+	f := &follower{
+		lpath: "./log",
+		name:  "log.json",
+		daily: opts.DailyDirs == nil || *opts.DailyDirs,
+		poll:  defaultFollowPoll,
+		now:   now,
+		stop:  make(chan struct{}),
+	}
+	if opts.Path != "" {
+		f.lpath = opts.Path
+	}
+	if opts.File != "" {
+		f.name = opts.File
+	}
+	if opts.PollInterval > 0 {
+		f.poll = opts.PollInterval
+	}
+
+	// the lines written before are skipped
+	active := f.active()
+	if fi, err := os.Stat(active); err == nil {
+		f.st.file, _ = openTail(active, fi.Size())
+	}
+	return f
+}
+
+func (f *follower) active() string {
+	return activeFile(f.lpath, f.name, f.daily, f.now())
+}
+
+func (f *follower) Read(p []byte) (int, error) {
+	for {
+		f.mu.Lock()
+		select {
+		case <-f.stop:
+			f.mu.Unlock()
+			return 0, io.EOF
+		default:
+		}
+
+		if len(f.line) == 0 {
+			f.line, _ = f.st.next(f.active())
+		}
+		if len(f.line) > 0 {
+			n := copy(p, f.line)
+			f.line = f.line[n:]
+			f.mu.Unlock()
+			return n, nil
+		}
+		f.mu.Unlock()
+
+		select {
+		case <-f.stop:
+		case <-time.After(f.poll):
+		}
+	}
+}
+
+// Close stops the reads, the waiting Read returns io.EOF
+func (f *follower) Close() error {
+	f.once.Do(func() {
+		close(f.stop)
+
+		f.mu.Lock()
+		if f.st.file != nil {
+			f.st.file.f.Close()
+		}
+		f.mu.Unlock()
+	})
+	return nil
+}
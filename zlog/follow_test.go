@@ -0,0 +1,88 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/vcaesar/tt"
+)
+
+// followLines sends the lines of r until its end
+func followLines(r io.Reader) <-chan string {
+	lines := make(chan string, 16)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+	return lines
+}
+
+// nextLine returns the next line of lines, or "timeout"
+func nextLine(lines <-chan string) string {
+	select {
+	case line := <-lines:
+		return line
+	case <-time.After(5 * time.Second):
+		return "timeout"
+	}
+}
+
+func TestFollow(t *testing.T) {
+	dir := t.TempDir()
+	clock := &fakeClock{tm: time.Date(2018, 5, 1, 23, 59, 0, 0, time.Local)}
+	day1 := filepath.Join(dir, "2018-05-01", "test.json")
+	appendLines(t, day1, 0, 1)
+
+	f := newFollower(FollowOptions{Path: dir, File: "test.json",
+		PollInterval: 5 * time.Millisecond}, clock.now)
+	lines := followLines(f)
+
+	appendLines(t, day1, 1, 3)
+	tt.Equal(t, wantLines(1, 3), []string{nextLine(lines), nextLine(lines)})
+
+	// the lines of the old day are read before the new day
+	appendLines(t, day1, 3, 4)
+	appendLines(t, filepath.Join(dir, "2018-05-02", "test.json"), 4, 5)
+	clock.set(clock.now().Add(time.Hour))
+	tt.Equal(t, wantLines(3, 5), []string{nextLine(lines), nextLine(lines)})
+
+	tt.Nil(t, f.Close())
+	_, ok := <-lines
+	tt.False(t, ok)
+}
+
+func TestFollowRotated(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "test.json")
+
+	daily := false
+	r := Follow(FollowOptions{Path: dir, File: "test.json", DailyDirs: &daily,
+		PollInterval: 5 * time.Millisecond})
+	defer r.Close()
+	lines := followLines(r)
+
+	// the file is created after Follow
+	appendLines(t, path, 0, 1)
+	tt.Equal(t, wantLines(0, 1)[0], nextLine(lines))
+
+	tt.Nil(t, os.Rename(path, filepath.Join(dir, "test-2018-05-01T08-00-00.000.json")))
+	appendLines(t, path, 1, 2)
+	tt.Equal(t, wantLines(1, 2)[0], nextLine(lines))
+}
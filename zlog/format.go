@@ -0,0 +1,296 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/go-vgo/gt/conf"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+// InitFromReader init zap log with the config of r in the format
+// "toml", "yaml", "yml" or "json", like an embedded config, the
+// yaml and json keys are the toml keys.
+//
+//	//go:embed zlog.yaml
+//	var zlogConf string
+//
+//	zlog.InitFromReader(strings.NewReader(zlogConf), "yaml")
+func InitFromReader(r io.Reader, format string) error {
+	cfg, unknown, err := decodeConfig(r, format)
+	if err != nil {
+		return err
+	}
+
+	return initConfig(cfg, unknown)
+}
+
+// initConfig init zap log with the decoded config, the unknown
+// keys are an error with StrictConfig or logged at warn
+func initConfig(cfg Config, unknown []string) error {
+	if err := checkUnknown(cfg, unknown); err != nil {
+		return err
+	}
+
+	if err := InitWithConfig(cfg); err != nil {
+		return err
+	}
+
+	warnUnknown(unknown)
+	return nil
+}
+
+// checkUnknown returns the error of the unknown keys with StrictConfig
+func checkUnknown(cfg Config, unknown []string) error {
+	if len(unknown) == 0 || !cfg.StrictConfig {
+		return nil
+	}
+
+	return fmt.Errorf("zlog: unknown config keys %s", strings.Join(unknown, ", "))
+}
+
+func warnUnknown(unknown []string) {
+	if len(unknown) > 0 {
+		getLogger().Warn("zlog: unknown config keys", zap.Strings("keys", unknown))
+	}
+}
+
+// loadConfig decodes the config file in the format of its
+// extension and applies the environment variables over it,
+// the keys of the file not in Config are returned
+func loadConfig(tpath string) (Config, []string, error) {
+	format := strings.TrimPrefix(strings.ToLower(filepath.Ext(tpath)), ".")
+	if !knownFormat(format) {
+		return Config{}, nil, fmt.Errorf("zlog: unknown config format %q of %s, "+
+			"want .toml, .yaml, .yml or .json", filepath.Ext(tpath), tpath)
+	}
+
+	f, err := os.Open(tpath)
+	if err != nil {
+		return Config{}, nil, err
+	}
+	defer f.Close()
+
+	return decodeConfig(f, format)
+}
+
+func knownFormat(format string) bool {
+	switch format {
+	case "toml", "yaml", "yml", "json":
+		return true
+	}
+	return false
+}
+
+// decodeConfig decodes the config of r and applies the
+// environment variables over it
+func decodeConfig(r io.Reader, format string) (Config, []string, error) {
+	m, err := decodeTable(r, format)
+	if err != nil {
+		return Config{}, nil, err
+	}
+
+	cfg, unknown, err := configFromTable(m)
+	if err != nil {
+		return cfg, nil, err
+	}
+	return cfg, unknown, applyEnv(&cfg)
+}
+
+// decodeTable decodes the table of r in the format
+func decodeTable(r io.Reader, format string) (map[string]interface{}, error) {
+	var m map[string]interface{}
+	switch strings.ToLower(format) {
+	case "toml":
+		if err := conf.Decode(r, &m); err != nil {
+			return nil, err
+		}
+	case "yaml", "yml":
+		if err := yaml.NewDecoder(r).Decode(&m); err != nil && err != io.EOF {
+			return nil, fmt.Errorf("zlog: decode yaml config: %v", err)
+		}
+	case "json":
+		dec := json.NewDecoder(r)
+		dec.UseNumber()
+		if err := dec.Decode(&m); err != nil {
+			return nil, fmt.Errorf("zlog: decode json config: %v", err)
+		}
+	default:
+		return nil, fmt.Errorf("zlog: unknown config format %q, "+
+			"want toml, yaml, yml or json", format)
+	}
+
+	return m, nil
+}
+
+// configFromTable returns the config of the table m and
+// its unknown keys
+func configFromTable(m map[string]interface{}) (Config, []string, error) {
+	var cfg Config
+	if err := setMap(reflect.ValueOf(&cfg).Elem(), m, ""); err != nil {
+		return cfg, nil, err
+	}
+
+	unknown := unknownKeys(reflect.TypeOf(cfg), m, "")
+	sort.Strings(unknown)
+	return cfg, unknown, nil
+}
+
+// unknownKeys returns the keys of the table m which aren't fields
+// of the struct t by their toml key or name ignoring the case, the
+// keys of the tables are joined with a dot
+func unknownKeys(t reflect.Type, m map[string]interface{}, prefix string) []string {
+	fields := make(map[string]reflect.Type, 2*t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		fields[strings.ToLower(envKey(f))] = f.Type
+		fields[strings.ToLower(f.Name)] = f.Type
+	}
+
+	var unknown []string
+	for key, val := range m {
+		ft, ok := fields[strings.ToLower(key)]
+		if !ok {
+			unknown = append(unknown, prefix+key)
+			continue
+		}
+
+		if table, ok := val.(map[string]interface{}); ok && ft.Kind() == reflect.Struct {
+			unknown = append(unknown, unknownKeys(ft, table, prefix+key+".")...)
+		}
+	}
+	return unknown
+}
+
+// setMap sets the fields of the struct v from the decoded table m by
+// their toml keys or names ignoring the case like the toml decoding,
+// the unknown keys are ignored
+func setMap(v reflect.Value, m map[string]interface{}, prefix string) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		key := envKey(t.Field(i))
+		val, ok := tableValue(m, key, t.Field(i).Name)
+		if !ok || val == nil {
+			continue
+		}
+
+		if err := setValue(v.Field(i), val, prefix+key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tableValue returns the value of the toml key or the field name in
+// m, the exact key first, then the one matching them ignoring the case
+func tableValue(m map[string]interface{}, key, name string) (interface{}, bool) {
+	if val, ok := m[key]; ok {
+		return val, true
+	}
+
+	for k, val := range m {
+		if strings.EqualFold(k, key) || strings.EqualFold(k, name) {
+			return val, true
+		}
+	}
+	return nil, false
+}
+
+func setValue(v reflect.Value, val interface{}, key string) error {
+	invalid := fmt.Errorf("zlog: invalid %s %v, want %s", key, val, v.Type())
+
+	switch v.Kind() {
+	case reflect.String:
+		s, ok := val.(string)
+		if !ok {
+			return invalid
+		}
+		v.SetString(s)
+	case reflect.Bool:
+		b, ok := val.(bool)
+		if !ok {
+			return invalid
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, ok := intValue(val)
+		if !ok || v.OverflowInt(n) {
+			return invalid
+		}
+		v.SetInt(n)
+	case reflect.Ptr:
+		elem := reflect.New(v.Type().Elem())
+		if err := setValue(elem.Elem(), val, key); err != nil {
+			return err
+		}
+		v.Set(elem)
+	case reflect.Slice:
+		items, ok := val.([]interface{})
+		if !ok {
+			return invalid
+		}
+
+		list := make([]string, len(items))
+		for i, item := range items {
+			if list[i], ok = item.(string); !ok {
+				return invalid
+			}
+		}
+		v.Set(reflect.ValueOf(list))
+	case reflect.Map:
+		table, ok := val.(map[string]interface{})
+		if !ok {
+			return invalid
+		}
+
+		m := make(map[string]string, len(table))
+		for k, item := range table {
+			if m[k], ok = item.(string); !ok {
+				return invalid
+			}
+		}
+		v.Set(reflect.ValueOf(m))
+	case reflect.Struct:
+		table, ok := val.(map[string]interface{})
+		if !ok {
+			return invalid
+		}
+		return setMap(v, table, key+".")
+	default:
+		return invalid
+	}
+	return nil
+}
+
+// intValue returns the integer of a yaml or json number
+func intValue(val interface{}) (int64, bool) {
+	switch n := val.(type) {
+	case int:
+		return int64(n), true
+	case int64:
+		return n, true
+	case uint64:
+		return int64(n), n <= 1<<63-1
+	case json.Number:
+		i, err := n.Int64()
+		return i, err == nil
+	}
+	return 0, false
+}
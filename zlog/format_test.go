@@ -0,0 +1,174 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/vcaesar/tt"
+)
+
+func TestConfigFormats(t *testing.T) {
+	t.Cleanup(func() {
+		Close()
+		SetRedactedKeys()
+		defaultState.setMaxFieldLen(0)
+	})
+
+	var configs []Config
+	for _, ext := range []string{"toml", "yaml", "json"} {
+		dir := t.TempDir()
+		t.Setenv("ZLOG_PATH", dir)
+
+		tt.Nil(t, Init(filepath.Join("..", "testdata", "zlog_conf."+ext)), ext)
+		Debug("conf " + ext)
+		tt.Nil(t, Close())
+
+		entries := readEntries(t, filepath.Join(dir, "conf.json"))
+		entries = msgEntries(entries, "conf "+ext)
+		tt.Equal(t, 1, len(entries))
+		tt.Equal(t, "DEBUG", entries[0]["level"])
+		tt.Equal(t, "api", entries[0]["app"])
+		tt.Equal(t, "core", entries[0]["team"])
+		tt.NotNil(t, entries[0]["ts"])
+
+		cfg := EffectiveConfig()
+		cfg.Path = ""
+		configs = append(configs, cfg)
+	}
+
+	want := configs[0]
+	tt.Equal(t, "debug", want.Level)
+	tt.False(t, *want.DailyDirs)
+	tt.Equal(t, int64(7), want.MaxDays)
+	tt.Equal(t, 100, want.MaxSize)
+	tt.Equal(t, []string{"password", "token"}, want.Redact)
+	tt.Equal(t, 10, want.Sampling.Thereafter)
+	for _, cfg := range configs[1:] {
+		tt.True(t, reflect.DeepEqual(want, cfg))
+	}
+}
+
+func TestInitFromReader(t *testing.T) {
+	t.Cleanup(func() {
+		Close()
+		SetRedactedKeys()
+		defaultState.setMaxFieldLen(0)
+	})
+
+	for _, c := range []struct {
+		format, content string
+	}{
+		{"toml", "mode = \"discard\"\nname = \"reader\"\n[fields]\napp = \"api\"\n"},
+		{"yaml", "mode: discard\nname: reader\nfields:\n  app: api\n"},
+		{"YML", "mode: discard\nname: reader\nfields:\n  app: api\n"},
+		{"json", `{"mode": "discard", "name": "reader", "fields": {"app": "api"}}`},
+	} {
+		tt.Nil(t, InitFromReader(strings.NewReader(c.content), c.format), c.format)
+		cfg := EffectiveConfig()
+		tt.Equal(t, "reader", cfg.Name, c.format)
+		tt.Equal(t, "api", cfg.Fields.App, c.format)
+	}
+
+	// an empty yaml is the default config
+	tt.Nil(t, InitFromReader(strings.NewReader("mode: discard\n"), "yaml"))
+	tt.Equal(t, "log", EffectiveConfig().Name)
+}
+
+func TestConfigFormatError(t *testing.T) {
+	for _, c := range []struct {
+		format, content, err string
+	}{
+		{"ini", "", `zlog: unknown config format "ini"`},
+		{"yaml", "max_size: big\n", "zlog: invalid max_size big, want int"},
+		{"yaml", "encoder: ts\n", "zlog: invalid encoder ts"},
+		{"json", `{"syslog": {"enabled": "yes"}}`,
+			"zlog: invalid syslog.enabled yes, want bool"},
+		{"json", `{"max_size": 1.5}`, "zlog: invalid max_size 1.5"},
+		{"json", `{"redact": ["a", 1]}`, "zlog: invalid redact [a 1]"},
+		{"json", `{"mode": `, "zlog: decode json config"},
+		{"yaml", "mode: [", "zlog: decode yaml config"},
+	} {
+		err := InitFromReader(strings.NewReader(c.content), c.format)
+		tt.NotNil(t, err, c.content)
+		tt.True(t, strings.HasPrefix(err.Error(), c.err), err.Error())
+	}
+
+	tpath := filepath.Join(t.TempDir(), "zlog.conf")
+	tt.Nil(t, ioutil.WriteFile(tpath, []byte("mode = \"discard\"\n"), 0644))
+	err := Init(tpath)
+	tt.NotNil(t, err)
+	tt.True(t, strings.Contains(err.Error(), `unknown config format ".conf"`), err.Error())
+}
+
+func TestUnknownKeys(t *testing.T) {
+	t.Cleanup(func() { Close() })
+	for _, c := range []struct {
+		format, content, strict string
+	}{
+		{"toml", "mode = \"discard\"\nmaxdays_keep = 7\n[syslog]\nenable = true\n" +
+			"[fields.static]\nteam = \"core\"\n", "strict_config = true\n"},
+		{"yaml", "mode: discard\nmaxdays_keep: 7\nsyslog:\n  enable: true\n" +
+			"fields:\n  static:\n    team: core\n", "strict_config: true\n"},
+		{"json", `"mode": "discard", "maxdays_keep": 7, "syslog": {"enable": true},` +
+			`"fields": {"static": {"team": "core"}}}`, `{"strict_config": true, `},
+	} {
+		content := c.content
+		if c.format == "json" {
+			content = "{" + content
+		}
+		tt.Nil(t, InitFromReader(strings.NewReader(content), c.format), c.format)
+
+		_, unknown, err := decodeConfig(strings.NewReader(content), c.format)
+		tt.Nil(t, err)
+		tt.Equal(t, []string{"maxdays_keep", "syslog.enable"}, unknown, c.format)
+
+		err = InitFromReader(strings.NewReader(c.strict+c.content), c.format)
+		tt.NotNil(t, err, c.format)
+		tt.Equal(t, "zlog: unknown config keys maxdays_keep, syslog.enable", err.Error())
+	}
+}
+
+func TestConfigKeysCase(t *testing.T) {
+	for _, c := range []struct {
+		format, content string
+	}{
+		{"toml", "Mode = \"dev\"\nPath = \"/var/log/app\"\nName = \"app\"\n" +
+			"MaxDays = 3\nDIR_MODE = \"0750\"\n[Fields]\nApp = \"web\"\n"},
+		{"yaml", "Mode: dev\nPath: /var/log/app\nName: app\n" +
+			"MaxDays: 3\nDIR_MODE: \"0750\"\nFields:\n  App: web\n"},
+	} {
+		cfg, unknown, err := decodeConfig(strings.NewReader(c.content), c.format)
+		tt.Nil(t, err, c.format)
+		tt.Equal(t, 0, len(unknown), c.format)
+		tt.Equal(t, "dev", cfg.Mode)
+		tt.Equal(t, "/var/log/app", cfg.Path)
+		tt.Equal(t, "app", cfg.Name)
+		tt.Equal(t, int64(3), cfg.MaxDays)
+		tt.Equal(t, "0750", cfg.DirMode)
+		tt.Equal(t, "web", cfg.Fields.App)
+	}
+}
+
+func TestUnknownKeysWarn(t *testing.T) {
+	dir := initTest(t, "maxdays_keep = 7")
+	tt.Nil(t, Close())
+
+	entries := readEntries(t, filepath.Join(dir, "*", "test.json"))
+	entries = msgEntries(entries, "zlog: unknown config keys")
+	tt.Equal(t, 1, len(entries))
+	tt.Equal(t, "warn", entries[0]["level"])
+	tt.Equal(t, []interface{}{"maxdays_keep"}, entries[0]["keys"])
+}
@@ -0,0 +1,110 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"os"
+	"strconv"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	gcpSourceKey = "logging.googleapis.com/sourceLocation"
+	gcpTraceKey  = "logging.googleapis.com/trace"
+	gcpSpanKey   = "logging.googleapis.com/spanId"
+)
+
+// gcpSeverity returns the cloud logging severity of the level
+func gcpSeverity(l zapcore.Level) string {
+	switch l {
+	case zapcore.DebugLevel:
+		return "DEBUG"
+	case zapcore.InfoLevel:
+		return "INFO"
+	case zapcore.WarnLevel:
+		return "WARNING"
+	case zapcore.ErrorLevel:
+		return "ERROR"
+	case zapcore.PanicLevel:
+		return "ALERT"
+	}
+	// dpanic and fatal
+	return "CRITICAL"
+}
+
+// newGCPEncoder returns the encoder of the structured logs of
+// google cloud logging, the trace_id of Ctx is the trace of the
+// project GOOGLE_CLOUD_PROJECT if it's set.
+func newGCPEncoder() zapcore.Encoder {
+	enc := zapcore.NewJSONEncoder(zapcore.EncoderConfig{
+		MessageKey:    "message",
+		LevelKey:      "severity",
+		TimeKey:       "timestamp",
+		NameKey:       "logger",
+		StacktraceKey: "stacktrace",
+		EncodeLevel: func(l zapcore.Level, enc zapcore.PrimitiveArrayEncoder) {
+			enc.AppendString(gcpSeverity(l))
+		},
+		EncodeTime:     zapcore.RFC3339NanoTimeEncoder,
+		EncodeDuration: zapcore.StringDurationEncoder,
+	})
+	return gcpEncoder{Encoder: enc, project: os.Getenv("GOOGLE_CLOUD_PROJECT")}
+}
+
+// gcpEncoder adds the caller as the source location and the
+// trace_id and span_id fields as the trace of the entry
+type gcpEncoder struct {
+	zapcore.Encoder
+	project string
+}
+
+func (e gcpEncoder) Clone() zapcore.Encoder {
+	return gcpEncoder{Encoder: e.Encoder.Clone(), project: e.project}
+}
+
+func (e gcpEncoder) AddString(key, val string) {
+	switch key {
+	case "trace_id":
+		if e.project != "" {
+			val = "projects/" + e.project + "/traces/" + val
+		}
+		key = gcpTraceKey
+	case "span_id":
+		key = gcpSpanKey
+	}
+	e.Encoder.AddString(key, val)
+}
+
+func (e gcpEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	final := e.Clone()
+	if ent.Caller.Defined {
+		final.AddObject(gcpSourceKey, gcpSource(ent.Caller))
+	}
+	for _, f := range fields {
+		f.AddTo(final)
+	}
+	return final.(gcpEncoder).Encoder.EncodeEntry(ent, nil)
+}
+
+// gcpSource the sourceLocation of a caller, the line is a string
+// like the int64 of the LogEntry json
+type gcpSource zapcore.EntryCaller
+
+func (s gcpSource) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("file", callerFile(zapcore.EntryCaller(s)))
+	enc.AddString("line", strconv.Itoa(s.Line))
+	if s.Function != "" {
+		enc.AddString("function", s.Function)
+	}
+	return nil
+}
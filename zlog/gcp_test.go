@@ -0,0 +1,100 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/vcaesar/tt"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestGCPSeverity(t *testing.T) {
+	for l, severity := range map[zapcore.Level]string{
+		zapcore.DebugLevel: "DEBUG", zapcore.InfoLevel: "INFO",
+		zapcore.WarnLevel: "WARNING", zapcore.ErrorLevel: "ERROR",
+		zapcore.DPanicLevel: "CRITICAL", zapcore.PanicLevel: "ALERT",
+		zapcore.FatalLevel: "CRITICAL",
+	} {
+		tt.Equal(t, severity, gcpSeverity(l))
+	}
+}
+
+// TestGCPGolden the entries of the structured logs example of
+// the cloud logging docs
+func TestGCPGolden(t *testing.T) {
+	tm := time.Date(2020, 10, 12, 7, 20, 50, 520000000, time.UTC)
+	tests := []struct {
+		name    string
+		project string
+		ent     zapcore.Entry
+		fields  []zapcore.Field
+	}{
+		{"error", "my-projectid", zapcore.Entry{Level: zapcore.ErrorLevel, Time: tm,
+			Message: "There was an error in the application.",
+			Caller: zapcore.EntryCaller{Defined: true, File: "/src/app/get_data.go",
+				Line: 142, Function: "main.getData"}},
+			[]zapcore.Field{
+				zap.String("trace_id", "06796866738c859f2f19b7cfb3214824"),
+				zap.String("span_id", "000000000000004a"),
+			}},
+		{"info", "", zapcore.Entry{Level: zapcore.InfoLevel, Time: tm,
+			LoggerName: "http", Message: "handle request"},
+			[]zapcore.Field{
+				zap.String("trace_id", "06796866738c859f2f19b7cfb3214824"),
+				zap.Duration("latency", 1500*time.Millisecond),
+			}},
+	}
+
+	for _, test := range tests {
+		t.Setenv("GOOGLE_CLOUD_PROJECT", test.project)
+		buf, err := newGCPEncoder().EncodeEntry(test.ent, test.fields)
+		tt.Nil(t, err)
+
+		golden := filepath.Join("..", "testdata", "zlog_gcp_"+test.name+".golden")
+		if *update {
+			tt.Nil(t, ioutil.WriteFile(golden, buf.Bytes(), 0644))
+		}
+
+		want, err := ioutil.ReadFile(golden)
+		tt.Nil(t, err)
+		tt.Equal(t, string(want), buf.String(), test.name)
+	}
+}
+
+func TestGCP(t *testing.T) {
+	dir := initTest(t, `encoding = "gcp"`, "caller = true")
+	With(zap.String("trace_id", "t1")).Warn("gcp warn")
+	tt.Nil(t, Close())
+
+	var n int
+	for _, entry := range readEntries(t, filepath.Join(dir, "*", "test.json")) {
+		if entry["message"] != "gcp warn" {
+			continue
+		}
+		n++
+
+		tt.Equal(t, "WARNING", entry["severity"])
+		tt.Equal(t, "t1", entry[gcpTraceKey])
+		_, err := time.Parse(time.RFC3339Nano, entry["timestamp"].(string))
+		tt.Nil(t, err)
+
+		source := entry[gcpSourceKey].(map[string]interface{})
+		tt.Equal(t, "zlog/gcp_test.go", source["file"])
+		_, ok := entry["caller"]
+		tt.False(t, ok)
+	}
+	tt.Equal(t, 1, n)
+}
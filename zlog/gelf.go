@@ -0,0 +1,371 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// gelfChunkSize the max size of a datagram, the larger messages
+	// are chunked
+	gelfChunkSize = 1420
+	// gelfChunkHeader the magic bytes, the message id, the sequence
+	// number and the sequence count of a chunk
+	gelfChunkHeader = 12
+	// gelfMaxChunks the max chunks of a message
+	gelfMaxChunks = 128
+)
+
+// gelfMagic the first bytes of the chunks
+var gelfMagic = []byte{0x1e, 0x0f}
+
+// gelfHost returns the host of the gelf messages
+func (c *Config) gelfHost() string {
+	if c.GELF.HostnameOverride != "" {
+		return c.GELF.HostnameOverride
+	}
+
+	host, err := os.Hostname()
+	if err != nil {
+		return "localhost"
+	}
+	return host
+}
+
+// gelfCompress reports whether the gelf messages are gzipped
+func (g GELFConfig) gelfCompress() (bool, error) {
+	switch g.Compression {
+	case "", "none":
+		return false, nil
+	case "gzip":
+		return true, nil
+	}
+	return false, fmt.Errorf("zlog: unknown gelf compression %q", g.Compression)
+}
+
+// gelfLevel the syslog severity of the level, like the syslog output
+func gelfLevel(l zapcore.Level) int64 {
+	switch l {
+	case zapcore.DebugLevel:
+		return 7
+	case zapcore.InfoLevel:
+		return 6
+	case zapcore.WarnLevel:
+		return 4
+	case zapcore.ErrorLevel:
+		return 3
+	}
+	return 2
+}
+
+// newGELFEncoder returns the encoder of the gelf 1.1 messages, the
+// fields are the additional fields prefixed with an underscore
+func (c *Config) newGELFEncoder() zapcore.Encoder {
+	enc := zapcore.NewJSONEncoder(zapcore.EncoderConfig{
+		MessageKey:    "short_message",
+		LevelKey:      "level",
+		TimeKey:       "timestamp",
+		NameKey:       "_logger",
+		CallerKey:     "_caller",
+		StacktraceKey: "full_message",
+		EncodeLevel: func(l zapcore.Level, enc zapcore.PrimitiveArrayEncoder) {
+			enc.AppendInt64(gelfLevel(l))
+		},
+		// the seconds with the millis
+		EncodeTime: func(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
+			enc.AppendFloat64(float64(t.UnixNano()/int64(time.Millisecond)) / 1e3)
+		},
+		EncodeDuration: zapcore.SecondsDurationEncoder,
+		EncodeCaller:   zapcore.ShortCallerEncoder,
+	})
+	enc.AddString("version", "1.1")
+	enc.AddString("host", c.gelfHost())
+
+	return &gelfEncoder{enc: enc}
+}
+
+// gelfEncoder adds the fields as the flat additional fields,
+// the objects and the arrays are added as json strings
+type gelfEncoder struct {
+	enc zapcore.Encoder
+	// ns the prefix of the keys of OpenNamespace
+	ns string
+}
+
+// key returns the additional field of key, the chars other than
+// the letters, the digits, '_', '.' and '-' are replaced by '_',
+// _id is reserved by gelf
+func (e *gelfEncoder) key(key string) string {
+	key = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9',
+			r == '_', r == '.', r == '-':
+			return r
+		}
+		return '_'
+	}, e.ns+key)
+
+	if key == "id" {
+		return "__id"
+	}
+	return "_" + key
+}
+
+func (e *gelfEncoder) Clone() zapcore.Encoder {
+	return &gelfEncoder{enc: e.enc.Clone(), ns: e.ns}
+}
+
+func (e *gelfEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	final := e.Clone().(*gelfEncoder)
+	for _, f := range fields {
+		f.AddTo(final)
+	}
+	return final.enc.EncodeEntry(ent, nil)
+}
+
+func (e *gelfEncoder) OpenNamespace(key string) {
+	e.ns += key + "."
+}
+
+// addJSON adds the json of v as a string
+func (e *gelfEncoder) addJSON(key string, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	e.enc.AddString(e.key(key), string(b))
+	return nil
+}
+
+func (e *gelfEncoder) AddArray(key string, m zapcore.ArrayMarshaler) error {
+	arr := zapcore.NewMapObjectEncoder()
+	if err := arr.AddArray("v", m); err != nil {
+		return err
+	}
+	return e.addJSON(key, arr.Fields["v"])
+}
+
+func (e *gelfEncoder) AddObject(key string, m zapcore.ObjectMarshaler) error {
+	obj := zapcore.NewMapObjectEncoder()
+	if err := m.MarshalLogObject(obj); err != nil {
+		return err
+	}
+	return e.addJSON(key, obj.Fields)
+}
+
+func (e *gelfEncoder) AddReflected(key string, v interface{}) error {
+	if s, ok := v.(string); ok {
+		e.enc.AddString(e.key(key), s)
+		return nil
+	}
+	return e.addJSON(key, v)
+}
+
+func (e *gelfEncoder) AddBinary(key string, v []byte) {
+	e.enc.AddString(e.key(key), base64.StdEncoding.EncodeToString(v))
+}
+
+func (e *gelfEncoder) AddBool(key string, v bool) {
+	e.enc.AddString(e.key(key), fmt.Sprint(v))
+}
+
+func (e *gelfEncoder) AddByteString(key string, v []byte) {
+	e.enc.AddByteString(e.key(key), v)
+}
+
+func (e *gelfEncoder) AddComplex128(key string, v complex128) {
+	e.enc.AddComplex128(e.key(key), v)
+}
+
+func (e *gelfEncoder) AddComplex64(key string, v complex64) {
+	e.enc.AddComplex64(e.key(key), v)
+}
+
+func (e *gelfEncoder) AddDuration(key string, v time.Duration) {
+	e.enc.AddDuration(e.key(key), v)
+}
+
+func (e *gelfEncoder) AddFloat64(key string, v float64) {
+	e.enc.AddFloat64(e.key(key), v)
+}
+
+func (e *gelfEncoder) AddFloat32(key string, v float32) {
+	e.enc.AddFloat32(e.key(key), v)
+}
+
+func (e *gelfEncoder) AddInt(key string, v int) {
+	e.enc.AddInt(e.key(key), v)
+}
+
+func (e *gelfEncoder) AddInt64(key string, v int64) {
+	e.enc.AddInt64(e.key(key), v)
+}
+
+func (e *gelfEncoder) AddInt32(key string, v int32) {
+	e.enc.AddInt32(e.key(key), v)
+}
+
+func (e *gelfEncoder) AddInt16(key string, v int16) {
+	e.enc.AddInt16(e.key(key), v)
+}
+
+func (e *gelfEncoder) AddInt8(key string, v int8) {
+	e.enc.AddInt8(e.key(key), v)
+}
+
+func (e *gelfEncoder) AddString(key, v string) {
+	e.enc.AddString(e.key(key), v)
+}
+
+func (e *gelfEncoder) AddTime(key string, v time.Time) {
+	e.enc.AddTime(e.key(key), v)
+}
+
+func (e *gelfEncoder) AddUint(key string, v uint) {
+	e.enc.AddUint(e.key(key), v)
+}
+
+func (e *gelfEncoder) AddUint64(key string, v uint64) {
+	e.enc.AddUint64(e.key(key), v)
+}
+
+func (e *gelfEncoder) AddUint32(key string, v uint32) {
+	e.enc.AddUint32(e.key(key), v)
+}
+
+func (e *gelfEncoder) AddUint16(key string, v uint16) {
+	e.enc.AddUint16(e.key(key), v)
+}
+
+func (e *gelfEncoder) AddUint8(key string, v uint8) {
+	e.enc.AddUint8(e.key(key), v)
+}
+
+func (e *gelfEncoder) AddUintptr(key string, v uintptr) {
+	e.enc.AddUintptr(e.key(key), v)
+}
+
+// gelfZips the gzip writers of the gelf messages
+var gelfZips = sync.Pool{New: func() interface{} {
+	return gzip.NewWriter(nil)
+}}
+
+// gelfWriter sends each entry as a gelf datagram, the entries over
+// the datagram limit are chunked. The udp writes don't wait for the
+// server so the log calls never block on it.
+type gelfWriter struct {
+	addr string
+	conn net.Conn
+	gzip bool
+}
+
+func newGELFWriter(cfg GELFConfig) (*gelfWriter, error) {
+	compress, err := cfg.gelfCompress()
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := net.Dial("udp", cfg.Address)
+	if err != nil {
+		return nil, fmt.Errorf("zlog: invalid gelf address %q: %v", cfg.Address, err)
+	}
+	return &gelfWriter{addr: cfg.Address, conn: conn, gzip: compress}, nil
+}
+
+// Name returns the address of the gelf server
+func (w *gelfWriter) Name() string {
+	return "gelf udp://" + w.addr
+}
+
+// Write sends the message p without its line ending
+func (w *gelfWriter) Write(p []byte) (int, error) {
+	msg := bytes.TrimSuffix(p, []byte("\n"))
+	if w.gzip {
+		var buf bytes.Buffer
+		zw := gelfZips.Get().(*gzip.Writer)
+		zw.Reset(&buf)
+		zw.Write(msg)
+		zw.Close()
+		gelfZips.Put(zw)
+		msg = buf.Bytes()
+	}
+
+	chunks, err := gelfChunks(msg, gelfChunkSize)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, chunk := range chunks {
+		if _, err := w.conn.Write(chunk); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// gelfChunks returns the datagrams of msg, msg itself if it fits
+// size, its chunks otherwise
+func gelfChunks(msg []byte, size int) ([][]byte, error) {
+	if len(msg) <= size {
+		return [][]byte{msg}, nil
+	}
+
+	data := size - gelfChunkHeader
+	count := (len(msg) + data - 1) / data
+	if count > gelfMaxChunks {
+		return nil, fmt.Errorf("zlog: the gelf message of %d bytes is over %d chunks",
+			len(msg), gelfMaxChunks)
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+
+	chunks := make([][]byte, 0, count)
+	for i := 0; i < count; i++ {
+		end := (i + 1) * data
+		if end > len(msg) {
+			end = len(msg)
+		}
+
+		chunk := make([]byte, 0, gelfChunkHeader+end-i*data)
+		chunk = append(chunk, gelfMagic...)
+		chunk = append(chunk, id...)
+		chunk = append(chunk, byte(i), byte(count))
+		chunks = append(chunks, append(chunk, msg[i*data:end]...))
+	}
+	return chunks, nil
+}
+
+// Sync the datagrams aren't buffered
+func (w *gelfWriter) Sync() error {
+	return nil
+}
+
+// Close closes the socket
+func (w *gelfWriter) Close() error {
+	return w.conn.Close()
+}
@@ -0,0 +1,186 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vcaesar/tt"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// listenGELF listens to the gelf datagrams on a local udp port
+func listenGELF(t *testing.T) net.PacketConn {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { pc.Close() })
+	return pc
+}
+
+// readGELF decodes the next gelf message of pc, the chunks are
+// joined and the gzipped messages are decompressed, it returns
+// the number of the datagrams
+func readGELF(t *testing.T, pc net.PacketConn) (map[string]interface{}, int) {
+	pc.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	var (
+		msg    []byte
+		chunks [][]byte
+		id     []byte
+		n      int
+	)
+	buf := make([]byte, 65536)
+	for {
+		size, _, err := pc.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		n++
+
+		d := append([]byte(nil), buf[:size]...)
+		if !bytes.HasPrefix(d, gelfMagic) {
+			msg = d
+			break
+		}
+
+		tt.True(t, size <= gelfChunkSize)
+		if id == nil {
+			id, chunks = d[2:10], make([][]byte, d[11])
+		}
+		tt.Equal(t, id, d[2:10])
+		tt.Equal(t, len(chunks), int(d[11]))
+		chunks[d[10]] = d[gelfChunkHeader:]
+
+		if n == len(chunks) {
+			msg = bytes.Join(chunks, nil)
+			break
+		}
+	}
+
+	if bytes.HasPrefix(msg, []byte{0x1f, 0x8b}) {
+		zr, err := gzip.NewReader(bytes.NewReader(msg))
+		tt.Nil(t, err)
+		msg, err = ioutil.ReadAll(zr)
+		tt.Nil(t, err)
+	}
+
+	entry := make(map[string]interface{})
+	if err := json.Unmarshal(msg, &entry); err != nil {
+		t.Fatal(err)
+	}
+	return entry, n
+}
+
+func TestGELF(t *testing.T) {
+	pc := listenGELF(t)
+	initTest(t, "[gelf]", `address = "`+pc.LocalAddr().String()+`"`,
+		`hostname_override = "api-1"`)
+
+	L().Named("db").Info("gelf info", zap.String("user", "u1"), zap.Int("n", 3),
+		zap.Bool("ok", true), zap.String("id", "i1"), zap.String("a b", "c"),
+		zap.Namespace("req"), zap.String("path", "/v1"))
+	entry, n := readGELF(t, pc)
+	tt.Equal(t, 1, n)
+	tt.Equal(t, "1.1", entry["version"])
+	tt.Equal(t, "api-1", entry["host"])
+	tt.Equal(t, "gelf info", entry["short_message"])
+	tt.Equal(t, float64(6), entry["level"])
+	tt.Equal(t, "db", entry["_logger"])
+	tt.Equal(t, "u1", entry["_user"])
+	tt.Equal(t, float64(3), entry["_n"])
+	tt.Equal(t, "true", entry["_ok"])
+	tt.Equal(t, "i1", entry["__id"])
+	tt.Equal(t, "c", entry["_a_b"])
+	tt.Equal(t, "/v1", entry["_req.path"])
+	_, ok := entry["user"]
+	tt.False(t, ok)
+
+	ts := entry["timestamp"].(float64)
+	tt.True(t, time.Since(time.Unix(int64(ts), 0)) < time.Minute)
+	tt.Equal(t, ts, float64(int64(ts*1e3))/1e3)
+
+	Error("gelf error", errors.New("e1"))
+	entry, _ = readGELF(t, pc)
+	tt.Equal(t, float64(3), entry["level"])
+	tt.Equal(t, "e1", entry["_error"])
+	tt.True(t, strings.Contains(entry["full_message"].(string), "gelf_test.go"))
+
+	// the message over the datagram limit is chunked
+	long := strings.Repeat("0123456789", 500)
+	Info(long)
+	entry, n = readGELF(t, pc)
+	tt.Equal(t, 4, n)
+	tt.Equal(t, long, entry["short_message"])
+	tt.Nil(t, Close())
+}
+
+func TestGELFGzip(t *testing.T) {
+	pc := listenGELF(t)
+	dir := initTest(t, `file_encoding = "gelf"`, "[gelf]",
+		`address = "`+pc.LocalAddr().String()+`"`, `compression = "gzip"`)
+
+	L().Info("gzipped", zap.Reflect("tags", []string{"a", "b"}),
+		zap.Object("obj", zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+			enc.AddString("k", "v")
+			return nil
+		})))
+	entry, _ := readGELF(t, pc)
+	tt.Equal(t, "gzipped", entry["short_message"])
+	tt.Equal(t, `["a","b"]`, entry["_tags"])
+	tt.Equal(t, `{"k":"v"}`, entry["_obj"])
+	tt.Nil(t, Close())
+
+	// the log files with the gelf encoding
+	var n int
+	for _, entry := range readEntries(t, filepath.Join(dir, "*", "test.json")) {
+		if entry["short_message"] == "gzipped" {
+			tt.Equal(t, "1.1", entry["version"])
+			n++
+		}
+	}
+	tt.Equal(t, 1, n)
+}
+
+func TestGELFChunks(t *testing.T) {
+	msg := bytes.Repeat([]byte("x"), 100)
+	chunks, err := gelfChunks(msg, 100)
+	tt.Nil(t, err)
+	tt.Equal(t, 1, len(chunks))
+
+	chunks, err = gelfChunks(msg, 62)
+	tt.Nil(t, err)
+	tt.Equal(t, 2, len(chunks))
+	tt.Equal(t, 62, len(chunks[0]))
+	tt.Equal(t, []byte{0, 2}, chunks[0][10:12])
+	tt.Equal(t, []byte{1, 2}, chunks[1][10:12])
+
+	_, err = gelfChunks(bytes.Repeat([]byte("x"), 129*10), 22)
+	tt.NotNil(t, err)
+
+	for _, gelf := range []GELFConfig{
+		{Address: "127.0.0.1:12201", Compression: "zip"},
+		{Address: "nohost"},
+	} {
+		tt.NotNil(t, InitWithConfig(Config{Path: t.TempDir(), GELF: gelf}))
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+// Package ginzlog gin middleware logging through zlog,
+// it is a separate package so zlog doesn't depend on gin.
+package ginzlog
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-vgo/gt/zlog"
+	"go.uber.org/zap"
+)
+
+// GinMiddleware logs the zlog.AccessRecord of every request with
+// the route and the errors fields like zlog.AccessAt, the entries
+// of 4xx are logged at warn and 5xx at error.
+//
+//	r := gin.New()
+//	r.Use(ginzlog.GinMiddleware(), ginzlog.GinRecovery())
+func GinMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		rec := zlog.AccessRecord{
+			Method:    c.Request.Method,
+			Path:      c.Request.URL.Path,
+			Query:     c.Request.URL.RawQuery,
+			IP:        c.ClientIP(),
+			UserAgent: c.Request.UserAgent(),
+			RequestID: c.Writer.Header().Get(zlog.RequestIDHeader),
+			Status:    status,
+			Duration:  time.Since(start),
+		}
+		if c.Request.ContentLength > 0 {
+			rec.BytesIn = int(c.Request.ContentLength)
+		}
+		if size := c.Writer.Size(); size > 0 {
+			rec.BytesOut = size
+		}
+
+		fields := []zap.Field{zap.String("route", c.FullPath())}
+		if len(c.Errors) > 0 {
+			fields = append(fields, zap.String("errors", c.Errors.String()))
+		}
+
+		lvl := zap.InfoLevel
+		switch {
+		case status >= http.StatusInternalServerError:
+			lvl = zap.ErrorLevel
+		case status >= http.StatusBadRequest:
+			lvl = zap.WarnLevel
+		}
+		zlog.AccessAt(lvl, "gin request", rec, fields...)
+	}
+}
+
+// GinRecovery recovers the panics of the handlers, logs them with
+// the stack into the error log and responds with a 500.
+func GinRecovery() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			if rec := recover(); rec != nil {
+				if rec == http.ErrAbortHandler {
+					panic(rec)
+				}
+
+				zlog.ErrorF("gin panic",
+					zap.Any("panic", rec),
+					zap.String("path", c.Request.URL.Path),
+					zap.Stack("stack"),
+				)
+				c.AbortWithStatus(http.StatusInternalServerError)
+			}
+		}()
+
+		c.Next()
+	}
+}
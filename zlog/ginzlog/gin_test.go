@@ -0,0 +1,118 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package ginzlog
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-vgo/gt/zlog"
+	"github.com/vcaesar/tt"
+)
+
+func readEntries(t *testing.T, pattern, msg string) []map[string]interface{} {
+	files, err := filepath.Glob(pattern)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var entries []map[string]interface{}
+	for _, name := range files {
+		f, err := os.Open(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			entry := make(map[string]interface{})
+			if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+				t.Fatal(err)
+			}
+			if entry["msg"] == msg {
+				entries = append(entries, entry)
+			}
+		}
+		f.Close()
+	}
+
+	return entries
+}
+
+func TestGin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dir := t.TempDir()
+	tt.Nil(t, zlog.InitWithConfig(zlog.Config{Path: dir, Name: "gin"}))
+
+	r := gin.New()
+	r.Use(GinMiddleware(), GinRecovery())
+	r.GET("/user/:id", func(c *gin.Context) { c.String(200, "ok") })
+	r.GET("/panic", func(c *gin.Context) { panic("boom") })
+
+	for _, path := range []string{"/user/1", "/missing", "/panic"} {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("User-Agent", "zlog-test")
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	tt.Nil(t, zlog.Close())
+
+	// the server error is mirrored in the main log
+	entries := readEntries(t, filepath.Join(dir, "*", "gin.json"), "gin request")
+	tt.Equal(t, 3, len(entries))
+	tt.Equal(t, "info", entries[0]["level"])
+	tt.Equal(t, "/user/:id", entries[0]["route"])
+	tt.Equal(t, "/user/1", entries[0]["path"])
+	tt.Equal(t, float64(200), entries[0]["status"])
+	tt.Equal(t, "zlog-test", entries[0]["user_agent"])
+	tt.Equal(t, "192.0.2.1", entries[0]["ip"])
+	tt.Equal(t, float64(2), entries[0]["bytes_out"])
+	tt.NotNil(t, entries[0]["duration"])
+	tt.Equal(t, "warn", entries[1]["level"])
+	tt.Equal(t, float64(404), entries[1]["status"])
+	tt.Equal(t, "error", entries[2]["level"])
+
+	errEntries := readEntries(t, filepath.Join(dir, "*", "gin_err.json"), "gin request")
+	tt.Equal(t, 1, len(errEntries))
+	tt.Equal(t, float64(500), errEntries[0]["status"])
+
+	panics := readEntries(t, filepath.Join(dir, "*", "gin_err.json"), "gin panic")
+	tt.Equal(t, 1, len(panics))
+	tt.Equal(t, "boom", panics[0]["panic"])
+	tt.True(t, strings.Contains(panics[0]["stack"].(string), "GinRecovery"))
+}
+
+func TestGinAccessFile(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dir := t.TempDir()
+	tt.Nil(t, zlog.InitWithConfig(zlog.Config{Path: dir, Name: "gin", AccessFile: true}))
+
+	r := gin.New()
+	r.Use(GinMiddleware())
+	r.GET("/user/:id", func(c *gin.Context) { c.String(500, "fail") })
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/user/1?v=2", nil))
+	tt.Nil(t, zlog.Close())
+
+	entries := readEntries(t, filepath.Join(dir, "*", "gin_access.json"), "gin request")
+	tt.Equal(t, 1, len(entries))
+	tt.Equal(t, "error", entries[0]["level"])
+	tt.Equal(t, "v=2", entries[0]["query"])
+	// the 5xx still reach the error log, mirrored to the main log
+	tt.Equal(t, 1, len(readEntries(t, filepath.Join(dir, "*", "gin.json"), "gin request")))
+	errEntries := readEntries(t, filepath.Join(dir, "*", "gin_err.json"), "gin request")
+	tt.Equal(t, 1, len(errEntries))
+	tt.Equal(t, float64(500), errEntries[0]["status"])
+}
@@ -0,0 +1,136 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+// Package grpczlog grpc server interceptors logging through zlog,
+// it is a separate package so zlog doesn't depend on grpc.
+package grpczlog
+
+import (
+	"context"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/go-vgo/gt/zlog"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+type options struct {
+	metadata []string
+}
+
+// Option an option of the interceptors
+type Option func(*options)
+
+// WithMetadata logs the values of the metadata keys as fields,
+// the other keys are never logged.
+func WithMetadata(keys ...string) Option {
+	return func(o *options) {
+		for _, key := range keys {
+			o.metadata = append(o.metadata, strings.ToLower(key))
+		}
+	}
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// UnaryServerInterceptor logs the zlog.AccessRecord of every unary
+// rpc with the code and the peer fields like zlog.AccessAt, the rpcs
+// with a non OK status are logged at error.
+//
+//	grpc.NewServer(grpc.UnaryInterceptor(grpczlog.UnaryServerInterceptor()))
+func UnaryServerInterceptor(opts ...Option) grpc.UnaryServerInterceptor {
+	o := newOptions(opts)
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (resp interface{}, err error) {
+		start := time.Now()
+		defer func() {
+			if rec := recover(); rec != nil {
+				err = recovered(info.FullMethod, rec)
+			}
+			o.log(ctx, info.FullMethod, err, time.Since(start))
+		}()
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor logs every stream rpc through zlog,
+// the rpcs with a non OK status are logged at error.
+func StreamServerInterceptor(opts ...Option) grpc.StreamServerInterceptor {
+	o := newOptions(opts)
+
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler) (err error) {
+		start := time.Now()
+		defer func() {
+			if rec := recover(); rec != nil {
+				err = recovered(info.FullMethod, rec)
+			}
+			o.log(ss.Context(), info.FullMethod, err, time.Since(start))
+		}()
+
+		return handler(srv, ss)
+	}
+}
+
+// recovered logs the panic of a handler and returns the Internal error
+func recovered(method string, rec interface{}) error {
+	zlog.ErrorF("grpc panic",
+		zap.String("method", method),
+		zap.Any("panic", rec),
+		zap.Stack("stack"),
+	)
+
+	return status.Errorf(codes.Internal, "panic: %v", rec)
+}
+
+func (o *options) log(ctx context.Context, method string, err error, d time.Duration) {
+	code := status.Code(err)
+	rec := zlog.AccessRecord{Method: method, Status: int(code), Duration: d}
+	fields := []zap.Field{zap.String("code", code.String())}
+
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		rec.IP = p.Addr.String()
+		if host, _, err := net.SplitHostPort(rec.IP); err == nil {
+			rec.IP = host
+		}
+		fields = append(fields, zap.String("peer", p.Addr.String()))
+	}
+
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		rec.UserAgent = strings.Join(md.Get("user-agent"), " ")
+		rec.RequestID = strings.Join(md.Get(zlog.RequestIDHeader), " ")
+		for _, key := range o.metadata {
+			if vals := md.Get(key); len(vals) > 0 {
+				fields = append(fields, zap.Strings("md."+key, vals))
+			}
+		}
+	}
+
+	if code != codes.OK {
+		zlog.AccessAt(zap.ErrorLevel, "grpc request", rec, append(fields, zap.Error(err))...)
+		return
+	}
+	zlog.AccessAt(zap.InfoLevel, "grpc request", rec, fields...)
+}
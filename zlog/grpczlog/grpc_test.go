@@ -0,0 +1,138 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package grpczlog
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/go-vgo/gt/zlog"
+	"github.com/vcaesar/tt"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// observe adds the observer core of all the entries to zlog
+func observe(t *testing.T) *observer.ObservedLogs {
+	if err := zlog.InitWithConfig(zlog.Config{Path: t.TempDir(), Name: "grpc"}); err != nil {
+		t.Fatal(err)
+	}
+
+	core, logs := observer.New(zap.DebugLevel)
+	h := zlog.AddCore(core)
+	t.Cleanup(func() {
+		h.Remove()
+		zlog.Close()
+	})
+
+	return logs
+}
+
+func TestUnary(t *testing.T) {
+	logs := observe(t)
+
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer(
+		grpc.UnaryInterceptor(UnaryServerInterceptor(WithMetadata("X-Request-Id"))),
+	)
+	healthpb.RegisterHealthServer(srv, health.NewServer())
+	go srv.Serve(lis)
+	defer srv.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	tt.Nil(t, err)
+	defer conn.Close()
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(),
+		"x-request-id", "r1", "authorization", "secret")
+	client := healthpb.NewHealthClient(conn)
+	_, err = client.Check(ctx, &healthpb.HealthCheckRequest{})
+	tt.Nil(t, err)
+	_, err = client.Check(ctx, &healthpb.HealthCheckRequest{Service: "missing"})
+	tt.Equal(t, codes.NotFound, status.Code(err))
+
+	entries := logs.FilterMessage("grpc request").AllUntimed()
+	tt.Equal(t, 2, len(entries))
+	tt.Equal(t, zap.InfoLevel, entries[0].Level)
+	fields := entries[0].ContextMap()
+	tt.Equal(t, "/grpc.health.v1.Health/Check", fields["method"])
+	tt.Equal(t, "OK", fields["code"])
+	tt.Equal(t, int64(0), fields["status"])
+	tt.Equal(t, []interface{}{"r1"}, fields["md.x-request-id"])
+	tt.Nil(t, fields["md.authorization"])
+	tt.Equal(t, "bufconn", fields["peer"])
+	tt.Equal(t, "r1", fields["request_id"])
+	d, ok := fields["duration"].(time.Duration)
+	tt.True(t, ok)
+	tt.True(t, d > 0)
+
+	tt.Equal(t, zap.ErrorLevel, entries[1].Level)
+	fields = entries[1].ContextMap()
+	tt.Equal(t, "NotFound", fields["code"])
+	tt.Equal(t, int64(codes.NotFound), fields["status"])
+	tt.Equal(t, status.Error(codes.NotFound, "unknown service").Error(), fields["error"])
+}
+
+// testStream a server stream with only the context
+type testStream struct {
+	grpc.ServerStream
+}
+
+func (testStream) Context() context.Context {
+	return context.Background()
+}
+
+func TestPanic(t *testing.T) {
+	logs := observe(t)
+
+	_, err := UnaryServerInterceptor()(context.Background(), nil,
+		&grpc.UnaryServerInfo{FullMethod: "/test/Unary"},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			panic("unary boom")
+		})
+	tt.Equal(t, codes.Internal, status.Code(err))
+
+	err = StreamServerInterceptor()(nil, testStream{},
+		&grpc.StreamServerInfo{FullMethod: "/test/Stream"},
+		func(srv interface{}, ss grpc.ServerStream) error {
+			panic("stream boom")
+		})
+	tt.Equal(t, codes.Internal, status.Code(err))
+
+	panics := logs.FilterMessage("grpc panic").AllUntimed()
+	tt.Equal(t, 2, len(panics))
+	tt.Equal(t, zap.ErrorLevel, panics[0].Level)
+	tt.Equal(t, "unary boom", panics[0].ContextMap()["panic"])
+	tt.Equal(t, "/test/Unary", panics[0].ContextMap()["method"])
+	tt.Equal(t, "stream boom", panics[1].ContextMap()["panic"])
+	tt.NotNil(t, panics[0].ContextMap()["stack"])
+
+	entries := logs.FilterMessage("grpc request").AllUntimed()
+	tt.Equal(t, 2, len(entries))
+	tt.Equal(t, zap.ErrorLevel, entries[1].Level)
+	tt.Equal(t, "Internal", entries[1].ContextMap()["code"])
+	tt.Equal(t, "/test/Stream", entries[1].ContextMap()["method"])
+}
@@ -0,0 +1,152 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"os"
+	"runtime"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// processStart the start of the process for the uptime
+var processStart = time.Now()
+
+// heartbeat logs the alive entry every interval until stopped
+type heartbeat struct {
+	stop, done chan struct{}
+	once       sync.Once
+}
+
+var (
+	beatLock   sync.Mutex
+	heartbeats = make(map[*heartbeat]struct{})
+)
+
+// StartHeartbeat info logs the alive entry every interval with the
+// uptime, the goroutines, the heap_alloc_mb and the fields of extra,
+// extra may be nil. It runs until stop is called or Close.
+//
+//	stop := zlog.StartHeartbeat(time.Minute, nil)
+//	defer stop()
+func StartHeartbeat(interval time.Duration, extra func() []zap.Field) (stop func()) {
+	if interval <= 0 {
+		getErrLogger().Error("zlog: invalid heartbeat interval",
+			zap.Duration("interval", interval))
+		return func() {}
+	}
+
+	h := &heartbeat{
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	beatLock.Lock()
+	heartbeats[h] = struct{}{}
+	beatLock.Unlock()
+
+	go func() {
+		defer close(h.done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				beat(extra)
+			case <-h.stop:
+				return
+			}
+		}
+	}()
+
+	return h.close
+}
+
+// beat writes the alive entry
+func beat(extra func() []zap.Field) {
+	ce := getLogger().Check(zap.InfoLevel, "alive")
+	if ce == nil {
+		return
+	}
+
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	fields := []zap.Field{
+		zap.Duration("uptime", time.Since(processStart)),
+		zap.Int("goroutines", runtime.NumGoroutine()),
+		zap.Float64("heap_alloc_mb", float64(mem.HeapAlloc)/(1<<20)),
+	}
+	if extra != nil {
+		fields = append(fields, extra()...)
+	}
+	ce.Write(fields...)
+}
+
+// close stops the heartbeat and waits for its goroutine,
+// it can be called more than once
+func (h *heartbeat) close() {
+	h.once.Do(func() {
+		beatLock.Lock()
+		delete(heartbeats, h)
+		beatLock.Unlock()
+
+		close(h.stop)
+		<-h.done
+	})
+}
+
+// stopHeartbeats stops the heartbeats, called by Close
+func stopHeartbeats() {
+	beatLock.Lock()
+	running := make([]*heartbeat, 0, len(heartbeats))
+	for h := range heartbeats {
+		running = append(running, h)
+	}
+	beatLock.Unlock()
+
+	for _, h := range running {
+		h.close()
+	}
+}
+
+// LogStartup info logs the startup entry of the app with the
+// version, the pid, the go version and the summary of the
+// EffectiveConfig.
+//
+//	zlog.LogStartup("api", version)
+//	defer zlog.LogShutdown()
+func LogStartup(appName, version string) {
+	cfg := EffectiveConfig()
+	getLogger().Info("startup",
+		zap.String("app", appName),
+		zap.String("version", version),
+		zap.Int("pid", os.Getpid()),
+		zap.String("go_version", runtime.Version()),
+		zap.String("mode", cfg.Mode),
+		zap.String("level", cfg.Level),
+		zap.String("path", cfg.Path),
+		zap.String("name", cfg.Name),
+		zap.Bool("async", cfg.Async),
+		zap.Int64("max_days", cfg.MaxDays),
+	)
+}
+
+// LogShutdown info logs the shutdown entry with the uptime
+// and flushes the loggers
+func LogShutdown() {
+	getLogger().Info("shutdown",
+		zap.Duration("uptime", time.Since(processStart)))
+	Sync()
+}
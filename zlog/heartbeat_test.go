@@ -0,0 +1,94 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"testing"
+	"time"
+
+	"github.com/vcaesar/tt"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// waitEntries waits for n entries of msg in logs
+func waitEntries(t *testing.T, logs *observer.ObservedLogs, msg string, n int) {
+	deadline := time.Now().Add(5 * time.Second)
+	for logs.FilterMessage(msg).Len() < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d %q entries, want %d", logs.FilterMessage(msg).Len(), msg, n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestHeartbeat(t *testing.T) {
+	logs, errLogs := observeSplit(t)
+
+	stop := StartHeartbeat(10*time.Millisecond, func() []zap.Field {
+		return []zap.Field{Str("node", "n1")}
+	})
+	waitEntries(t, logs, "alive", 2)
+	stop()
+	stop()
+
+	n := logs.FilterMessage("alive").Len()
+	time.Sleep(30 * time.Millisecond)
+	tt.Equal(t, n, logs.FilterMessage("alive").Len())
+
+	entry := logs.FilterMessage("alive").All()[0].ContextMap()
+	tt.True(t, entry["uptime"].(time.Duration) > 0)
+	tt.True(t, entry["goroutines"].(int64) > 0)
+	tt.True(t, entry["heap_alloc_mb"].(float64) > 0)
+	tt.Equal(t, "n1", entry["node"])
+
+	StartHeartbeat(0, nil)
+	tt.Equal(t, 1, errLogs.FilterMessage("zlog: invalid heartbeat interval").Len())
+}
+
+func TestHeartbeatClose(t *testing.T) {
+	logs, _ := observeSplit(t)
+
+	stop := StartHeartbeat(time.Millisecond, nil)
+	waitEntries(t, logs, "alive", 1)
+	stopHeartbeats()
+
+	beatLock.Lock()
+	tt.Equal(t, 0, len(heartbeats))
+	beatLock.Unlock()
+
+	n := logs.FilterMessage("alive").Len()
+	time.Sleep(10 * time.Millisecond)
+	tt.Equal(t, n, logs.FilterMessage("alive").Len())
+	// stopped by Close
+	stop()
+
+	initTest(t)
+	StartHeartbeat(time.Millisecond, nil)
+	tt.Nil(t, Close())
+	beatLock.Lock()
+	tt.Equal(t, 0, len(heartbeats))
+	beatLock.Unlock()
+}
+
+func TestLogStartup(t *testing.T) {
+	logs, _ := observeSplit(t)
+
+	LogStartup("api", "v1.2.0")
+	entry := logs.FilterMessage("startup").All()[0].ContextMap()
+	tt.Equal(t, "api", entry["app"])
+	tt.Equal(t, "v1.2.0", entry["version"])
+	tt.Equal(t, EffectiveConfig().Level, entry["level"])
+
+	LogShutdown()
+	entry = logs.FilterMessage("shutdown").All()[0].ContextMap()
+	tt.True(t, entry["uptime"].(time.Duration) > 0)
+}
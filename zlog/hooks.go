@@ -0,0 +1,294 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+var (
+	hookLock    sync.Mutex
+	entryHooks  atomic.Value // []func(zapcore.Entry) error
+	writeErrFns atomic.Value // []func(error)
+	addedCores  atomic.Value // *coreList
+)
+
+// OnEntry registers fn to run on every entry written by the loggers,
+// it stays registered across Init.
+func OnEntry(fn func(zapcore.Entry) error) {
+	hookLock.Lock()
+	hooks, _ := entryHooks.Load().([]func(zapcore.Entry) error)
+	entryHooks.Store(append(hooks[:len(hooks):len(hooks)], fn))
+	hookLock.Unlock()
+}
+
+// OnWriteError registers fn to run when writing an entry to a log
+// file or to the stdout output fails, it stays registered across Init.
+func OnWriteError(fn func(error)) {
+	hookLock.Lock()
+	fns, _ := writeErrFns.Load().([]func(error))
+	writeErrFns.Store(append(fns[:len(fns):len(fns)], fn))
+	hookLock.Unlock()
+}
+
+// entryHook runs the OnEntry funcs and counts the errors of
+// OnErrorRate, it is added to every logger so the funcs registered
+// after Init run as well.
+var entryHook = zap.Hooks(func(ent zapcore.Entry) error {
+	countError(ent)
+
+	hooks, _ := entryHooks.Load().([]func(zapcore.Entry) error)
+	for _, fn := range hooks {
+		if err := fn(ent); err != nil {
+			return err
+		}
+	}
+	return nil
+})
+
+// addedCore a core of AddCore
+type addedCore struct {
+	core zapcore.Core
+}
+
+// coreList the cores of AddCore, replaced as a whole
+type coreList struct {
+	cores []*addedCore
+}
+
+// noCores the coreList before AddCore
+var noCores = &coreList{}
+
+func loadCores() *coreList {
+	if list, _ := addedCores.Load().(*coreList); list != nil {
+		return list
+	}
+	return noCores
+}
+
+// Handle removes the core of AddCore or the writer of AddWriter
+type Handle struct {
+	added *addedCore
+	once  sync.Once
+}
+
+// Remove removes the core from the outputs of the loggers, it can
+// be called more than once
+func (h *Handle) Remove() {
+	h.once.Do(func() {
+		hookLock.Lock()
+		next := &coreList{}
+		for _, added := range loadCores().cores {
+			if added != h.added {
+				next.cores = append(next.cores, added)
+			}
+		}
+		addedCores.Store(next)
+		hookLock.Unlock()
+	})
+}
+
+// AddCore adds core to the outputs of the loggers, before or after
+// Init, it writes the entries at its own level and stays added
+// across Init until Remove. The entries are written to the outputs
+// of Init first, then to the added cores in the order they're added.
+// The entries logged after AddCore returns reach core, also by the
+// loggers taken by L or With before, and no entry is checked into
+// it after Remove returns.
+//
+//	h := zlog.AddCore(lokiCore)
+//	defer h.Remove()
+func AddCore(core zapcore.Core) *Handle {
+	added := &addedCore{core: core}
+
+	hookLock.Lock()
+	prev := loadCores().cores
+	addedCores.Store(&coreList{cores: append(prev[:len(prev):len(prev)], added)})
+	hookLock.Unlock()
+
+	return &Handle{added: added}
+}
+
+// AddWriter adds the core writing the entries of enab to w with one
+// of the encodings of Encoding and the encoder keys of the config,
+// like AddCore. The writes are serialized and the failed ones run
+// the OnWriteError funcs.
+//
+//	h, err := zlog.AddWriter(kafkaWriter, zap.InfoLevel, "json")
+func AddWriter(w io.Writer, enab zapcore.LevelEnabler, encoding string) (*Handle, error) {
+	enc, err := getConfig().newEncoder(encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	ws := hookWriter{zapcore.Lock(zapcore.AddSync(w))}
+	return AddCore(zapcore.NewCore(enc, ws, enab)), nil
+}
+
+// coreHook adds the cores of AddCore to the loggers, the cores are
+// loaded on each entry so the added and the removed ones apply to
+// the running loggers.
+var coreHook = zap.WrapCore(func(c zapcore.Core) zapcore.Core {
+	return addedTee{Core: c, state: &teeState{}}
+})
+
+// addedTee tees the core of a logger with the cores of AddCore
+type addedTee struct {
+	zapcore.Core
+	state *teeState
+}
+
+// teeState the fields of With and the added cores with them,
+// cached for the current coreList
+type teeState struct {
+	fields []zapcore.Field
+	cache  atomic.Value // *teeCache
+}
+
+type teeCache struct {
+	list  *coreList
+	cores []zapcore.Core
+}
+
+// cores returns the added cores with the fields of With
+func (c addedTee) cores() []zapcore.Core {
+	list := loadCores()
+	if len(list.cores) == 0 {
+		return nil
+	}
+
+	if cached, _ := c.state.cache.Load().(*teeCache); cached != nil && cached.list == list {
+		return cached.cores
+	}
+
+	cores := make([]zapcore.Core, len(list.cores))
+	for i, added := range list.cores {
+		cores[i] = added.core
+		if len(c.state.fields) > 0 {
+			cores[i] = added.core.With(c.state.fields)
+		}
+	}
+	c.state.cache.Store(&teeCache{list: list, cores: cores})
+	return cores
+}
+
+func (c addedTee) Enabled(lvl zapcore.Level) bool {
+	if c.Core.Enabled(lvl) {
+		return true
+	}
+
+	for _, core := range c.cores() {
+		if core.Enabled(lvl) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c addedTee) With(fields []zapcore.Field) zapcore.Core {
+	prev := c.state.fields
+	return addedTee{
+		Core:  c.Core.With(fields),
+		state: &teeState{fields: append(prev[:len(prev):len(prev)], fields...)},
+	}
+}
+
+func (c addedTee) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	ce = c.Core.Check(ent, ce)
+	for _, core := range c.cores() {
+		ce = core.Check(ent, ce)
+	}
+	return ce
+}
+
+func (c addedTee) Sync() error {
+	err := c.Core.Sync()
+	for _, core := range c.cores() {
+		err = multierr.Append(err, core.Sync())
+	}
+	return err
+}
+
+// hookWriter runs the OnWriteError funcs when the write or the
+// sync fails
+type hookWriter struct {
+	zapcore.WriteSyncer
+}
+
+func (w hookWriter) Write(p []byte) (int, error) {
+	n, err := w.WriteSyncer.Write(p)
+	if err != nil {
+		writeFailed(outputName(w.WriteSyncer), 1, err)
+	}
+	return n, err
+}
+
+func (w hookWriter) Sync() error {
+	err := w.WriteSyncer.Sync()
+	if err != nil && syncErr(err) != nil {
+		writeFailed(outputName(w.WriteSyncer), 0, err)
+	}
+	return err
+}
+
+// namer an output with a name like the path of the file
+type namer interface {
+	Name() string
+}
+
+// outputName returns the name of the output ws
+func outputName(ws zapcore.WriteSyncer) string {
+	if n, ok := ws.(namer); ok {
+		return n.Name()
+	}
+	return "the log output"
+}
+
+// lockFile returns the locked writer of the std file f, named
+// like /dev/stdout
+func lockFile(f *os.File) zapcore.WriteSyncer {
+	return lockedFile{zapcore.Lock(f), f}
+}
+
+type lockedFile struct {
+	zapcore.WriteSyncer
+	f *os.File
+}
+
+func (l lockedFile) Name() string {
+	return l.f.Name()
+}
+
+// ReportWriteError reports the failed write of n entries to output
+// like the failures of the log files, it is for the outputs writing
+// in the background such as the sinks of AddWriter: the error is
+// counted by Stats and the OnWriteError funcs are run.
+func ReportWriteError(output string, n int64, err error) {
+	writeFailed(output, n, err)
+}
+
+// writeFailed counts the n entries which couldn't be written to
+// output, writes the notice of stderr and runs the OnWriteError funcs
+func writeFailed(output string, n int64, err error) {
+	countFailure(output, n, err)
+
+	fns, _ := writeErrFns.Load().([]func(error))
+	for _, fn := range fns {
+		fn(err)
+	}
+}
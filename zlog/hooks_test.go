@@ -0,0 +1,176 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/vcaesar/tt"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestHooks(t *testing.T) {
+	var entries, warns, writeErrs int64
+	OnEntry(func(ent zapcore.Entry) error {
+		if !strings.HasPrefix(ent.Message, "hook ") {
+			return nil
+		}
+
+		atomic.AddInt64(&entries, 1)
+		if ent.Level == zapcore.WarnLevel {
+			atomic.AddInt64(&warns, 1)
+		}
+		return nil
+	})
+	OnWriteError(func(error) {
+		atomic.AddInt64(&writeErrs, 1)
+	})
+
+	// registered before Init
+	initTest(t)
+	Info("hook info")
+	Warn("hook warn")
+	Error("hook error", errors.New("e1"))
+	tt.Equal(t, int64(3), atomic.LoadInt64(&entries))
+	tt.Equal(t, int64(1), atomic.LoadInt64(&warns))
+	tt.Equal(t, int64(0), atomic.LoadInt64(&writeErrs))
+	tt.Nil(t, Close())
+
+	// the day dir is replaced by a file, the new files can't be created
+	dir := initTest(t)
+	day := filepath.Join(dir, time.Now().Format(DayFormat))
+	tt.Nil(t, os.RemoveAll(day))
+	tt.Nil(t, ioutil.WriteFile(day, nil, 0644))
+	tt.NotNil(t, Rotate())
+	Info("write error")
+	// the old log sweep may log its own errors
+	tt.True(t, atomic.LoadInt64(&writeErrs) >= 1)
+	tt.Nil(t, Close())
+}
+
+func TestAddCore(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	t.Cleanup(func() { addedCores.Store(&coreList{}) })
+
+	// applied to the running loggers
+	initTest(t)
+	AddCore(core)
+	Debug("added core")
+	Info("added core")
+	Error("added core", errors.New("e1"))
+	tt.Equal(t, 2, logs.FilterMessage("added core").Len())
+
+	// and to the loggers of a new Init
+	dir := initTest(t)
+	Info("added core")
+	tt.Equal(t, 3, logs.FilterMessage("added core").Len())
+	tt.Nil(t, Close())
+
+	Info("added core")
+	tt.Equal(t, 3, logs.FilterMessage("added core").Len())
+	entries := readEntries(t, filepath.Join(dir, "*", "test.json"))
+	tt.Equal(t, 1, len(msgEntries(entries, "added core")))
+
+	tt.Nil(t, InitWithConfig(Config{Mode: "dev", Path: dir}))
+	Warn("added core")
+	tt.Nil(t, Close())
+	tt.Equal(t, 4, logs.FilterMessage("added core").Len())
+}
+
+// orderWriter records the name of the writer of each entry
+type orderWriter struct {
+	name  string
+	order *[]string
+}
+
+func (w orderWriter) Write(p []byte) (int, error) {
+	*w.order = append(*w.order, w.name)
+	return len(p), nil
+}
+
+func TestAddCoreRemove(t *testing.T) {
+	t.Cleanup(func() { addedCores.Store(&coreList{}) })
+	tt.Nil(t, InitWithConfig(Config{Mode: "discard"}))
+	defer Close()
+
+	// the loggers taken before AddCore write to it
+	req := With(Str("request_id", "r1"))
+	logger := L()
+
+	core, added := observer.New(zap.DebugLevel)
+	h := AddCore(core)
+	req.Info("after add")
+	logger.Info("after add")
+	// the debug entries of the added core pass the level of Init
+	Debug("added debug")
+	tt.Equal(t, 2, added.FilterMessage("after add").Len())
+	tt.Equal(t, "r1", added.FilterMessage("after add").All()[0].ContextMap()["request_id"])
+	tt.Equal(t, 1, added.FilterMessage("added debug").Len())
+
+	h.Remove()
+	h.Remove()
+	req.Info("after remove")
+	Info("after remove")
+	tt.Equal(t, 0, added.FilterMessage("after remove").Len())
+	tt.Equal(t, 0, len(loadCores().cores))
+
+	// the cores are written in the order they're added
+	var order []string
+	enc := zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig())
+	for _, name := range []string{"a", "b", "c"} {
+		h := AddCore(zapcore.NewCore(enc, zapcore.AddSync(orderWriter{name, &order}),
+			zap.InfoLevel))
+		defer h.Remove()
+	}
+	Info("ordered")
+	tt.Equal(t, []string{"a", "b", "c"}, order)
+}
+
+func TestAddWriter(t *testing.T) {
+	t.Cleanup(func() { addedCores.Store(&coreList{}) })
+
+	// added before Init
+	var buf bytes.Buffer
+	h, err := AddWriter(&buf, zap.WarnLevel, "json")
+	tt.Nil(t, err)
+	defer h.Remove()
+
+	_, err = AddWriter(&buf, zap.WarnLevel, "xml")
+	tt.NotNil(t, err)
+
+	dir := initTest(t)
+	Info("writer info")
+	Warn("writer warn")
+	Error("writer error", errors.New("e1"))
+	tt.Nil(t, Close())
+
+	var msgs []string
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry map[string]interface{}
+		tt.Nil(t, json.Unmarshal([]byte(line), &entry))
+		msgs = append(msgs, entry["msg"].(string))
+	}
+	tt.Equal(t, []string{"writer warn", "writer error"}, msgs)
+
+	entries := readEntries(t, filepath.Join(dir, "*", "test.json"))
+	tt.Equal(t, 3, len(msgEntries(entries, "writer info", "writer warn", "writer error")))
+}
@@ -0,0 +1,225 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// instance the config, the level, the runtime state, the files and
+// the sweep of a logger of New, it is shared by the children of the
+// logger
+type instance struct {
+	cfg               Config
+	level             zap.AtomicLevel
+	st                *state
+	logger, errLogger *zap.Logger
+	// access the logger of the access file, nil without AccessFile
+	access            *zap.Logger
+	closer, errCloser io.Closer
+	clean             *cleaner
+
+	closed    int32
+	closeOnce sync.Once
+	closeErr  error
+}
+
+var (
+	instLock sync.Mutex
+	// instances the open instances, flushed by Fatal
+	instances = make(map[*instance]struct{})
+)
+
+// New returns a logger built with cfg like InitWithConfig, with its
+// own files, level, old log sweep and the redaction, the truncation,
+// the filters, the levels table, the ring buffer and the slow
+// threshold of cfg, the package loggers and the other loggers of New
+// aren't changed. The fields of the fields table are added, the
+// fields of SetGlobalFields aren't. Close it when it is no longer used.
+//
+//	audit, err := zlog.New(zlog.Config{Path: "./log/audit", MaxDays: 365})
+//	defer audit.Close()
+func New(cfg Config) (*Zlog, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+
+	// validated above
+	lvl, _ := cfg.modeLevel()
+	interval, _ := cfg.cleanInterval()
+
+	inst := &instance{cfg: cfg, level: zap.NewAtomicLevelAt(lvl), st: &state{}}
+	inst.st.set(&cfg)
+	lg, err := cfg.build(inst.level, inst.st)
+	if err != nil {
+		return nil, err
+	}
+
+	inst.logger, inst.errLogger = lg.logger, lg.errLogger
+	inst.access = lg.access
+	inst.closer, inst.errCloser = lg.closer, lg.errCloser
+	if fields := cfg.configFields(); len(fields) > 0 {
+		inst.logger = lg.logger.With(fields...)
+		inst.errLogger = inst.logger
+		if lg.errLogger != lg.logger {
+			inst.errLogger = lg.errLogger.With(fields...)
+		}
+		if lg.access != nil {
+			inst.access = lg.access.With(fields...)
+		}
+	}
+
+	z := &Zlog{logger: inst.logger, errLogger: inst.errLogger,
+		access: inst.access, inst: inst}
+	switch cfg.Mode {
+	case "dev", "stdout", "discard":
+	default:
+		if cfg.dailyDirs() {
+			fileDir, _ := cfg.confPath()
+			inst.clean = newCleaner(z, fileDir, cfg.retentionConf(), interval)
+		}
+	}
+
+	instLock.Lock()
+	instances[inst] = struct{}{}
+	instLock.Unlock()
+
+	return z, nil
+}
+
+// isClosed reports whether the instance is closed
+func (inst *instance) isClosed() bool {
+	return atomic.LoadInt32(&inst.closed) == 1
+}
+
+// sync flushes the loggers of the instance
+func (inst *instance) sync() error {
+	err := syncErr(inst.logger.Sync())
+	if inst.errLogger != inst.logger {
+		err = multierr.Append(err, syncErr(inst.errLogger.Sync()))
+	}
+	if inst.access != nil {
+		err = multierr.Append(err, syncErr(inst.access.Sync()))
+	}
+	return err
+}
+
+// close stops the sweep, flushes the loggers and closes the files,
+// the loggers of the instance do nothing after it
+func (inst *instance) close() error {
+	inst.closeOnce.Do(func() {
+		// a running sweep still logs to the files
+		inst.clean.close()
+		atomic.StoreInt32(&inst.closed, 1)
+		instLock.Lock()
+		delete(instances, inst)
+		instLock.Unlock()
+
+		err := inst.sync()
+		for _, c := range []io.Closer{inst.closer, inst.errCloser} {
+			if c != nil {
+				err = multierr.Append(err, c.Close())
+			}
+		}
+		inst.closeErr = err
+	})
+
+	return inst.closeErr
+}
+
+// syncInstances flushes the open instances
+func syncInstances() {
+	instLock.Lock()
+	open := make([]*instance, 0, len(instances))
+	for inst := range instances {
+		open = append(open, inst)
+	}
+	instLock.Unlock()
+
+	for _, inst := range open {
+		inst.sync()
+	}
+}
+
+// Close flushes the logger of New, stops its old log sweep and
+// closes its files, the logger and its children do nothing after
+// Close. The zero value closes the package loggers like Close,
+// the other children only flush.
+func (z *Zlog) Close() error {
+	if z.inst != nil {
+		return z.inst.close()
+	}
+
+	if z.logger == nil {
+		return Close()
+	}
+	return z.Sync()
+}
+
+// Rotate rotates the log files of the logger of New like Rotate,
+// the package ones for the others
+func (z *Zlog) Rotate() error {
+	if z.inst == nil {
+		return Rotate()
+	}
+
+	if z.inst.isClosed() {
+		return nil
+	}
+	return rotateFiles(z.inst.closer, z.inst.errCloser)
+}
+
+// state returns the runtime state of the logger of New, the
+// default state for the others
+func (z *Zlog) state() *state {
+	if z.inst == nil {
+		return defaultState
+	}
+	return z.inst.st
+}
+
+// Sync flushes the buffered entries of the logger and the error logger
+func (z *Zlog) Sync() error {
+	if z.logger == nil {
+		return Sync()
+	}
+
+	logger, errLogger := z.get()
+	err := syncErr(logger.Sync())
+	if errLogger != logger {
+		err = multierr.Append(err, syncErr(errLogger.Sync()))
+	}
+	return err
+}
+
+// SetLevel changes the level of the main log of the logger of New
+// and its children, the package level for the others
+func (z *Zlog) SetLevel(l zapcore.Level) {
+	if z.inst == nil {
+		SetLevel(l)
+		return
+	}
+	z.inst.level.SetLevel(l)
+}
+
+// Level returns the level of the main log, like SetLevel
+func (z *Zlog) Level() zapcore.Level {
+	if z.inst == nil {
+		return GetLevel()
+	}
+	return z.inst.level.Level()
+}
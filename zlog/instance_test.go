@@ -0,0 +1,152 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/vcaesar/tt"
+	"go.uber.org/zap"
+)
+
+func TestNew(t *testing.T) {
+	dir := initTest(t)
+	dirA, dirB := t.TempDir(), t.TempDir()
+	a, err := New(Config{Path: dirA, Name: "a", DailyDirs: new(bool)})
+	tt.Nil(t, err)
+	b, err := New(Config{Path: dirB, Name: "b", Level: "warn",
+		Fields: FieldsConfig{App: "b"}})
+	tt.Nil(t, err)
+
+	a.Info("a info")
+	a.Named("child").Error("a error", errors.New("e1"))
+	b.Info("b info")
+	b.Warn("b warn")
+	Info("package info")
+
+	// the level of b only
+	b.SetLevel(zap.InfoLevel)
+	tt.Equal(t, zap.InfoLevel, b.With(zap.Int("n", 1)).Level())
+	tt.Equal(t, zap.InfoLevel, a.Level())
+	b.Info("b info 2")
+
+	tt.Nil(t, a.Close())
+	tt.Nil(t, b.Close())
+	tt.Nil(t, b.Close())
+	// a no-op after Close
+	a.Info("a closed")
+	tt.Nil(t, Close())
+
+	entries := readEntries(t, filepath.Join(dirA, "a.json"))
+	tt.Equal(t, 1, len(msgEntries(entries, "a info")))
+	tt.Equal(t, 0, len(msgEntries(entries, "a closed", "b info", "package info")))
+	entries = readEntries(t, filepath.Join(dirA, "a_err.json"))
+	tt.Equal(t, 1, len(msgEntries(entries, "a error")))
+
+	entries = readEntries(t, filepath.Join(dirB, "*", "b.json"))
+	tt.Equal(t, 0, len(msgEntries(entries, "b info")))
+	tt.Equal(t, 1, len(msgEntries(entries, "b info 2")))
+	warn := msgEntries(entries, "b warn")
+	tt.Equal(t, 1, len(warn))
+	tt.Equal(t, "b", warn[0]["app"])
+
+	entries = readEntries(t, filepath.Join(dir, "*", "test.json"))
+	tt.Equal(t, 1, len(msgEntries(entries, "package info")))
+	tt.Equal(t, 0, len(msgEntries(entries, "a info", "b warn")))
+}
+
+func TestNewError(t *testing.T) {
+	z, err := New(Config{Path: t.TempDir(), Level: "loud"})
+	tt.NotNil(t, err)
+	tt.True(t, z == nil)
+
+	// the zero value is the package loggers
+	var zero Zlog
+	tt.Nil(t, zero.Sync())
+	tt.Nil(t, zero.Close())
+}
+
+func TestNewState(t *testing.T) {
+	dir := initTest(t)
+	defer Close()
+
+	// no sweep logging to the ring without the daily dirs
+	z, err := New(Config{Path: t.TempDir(), Name: "inst", RingBuffer: 4,
+		Redact: []string{"password"}, MaxFieldLen: 4, DailyDirs: new(bool)})
+	tt.Nil(t, err)
+	z.InfoF("message long", zap.String("password", "hunter2"),
+		zap.String("k", "abcdefgh"))
+	InfoF("package long", zap.String("password", "hunter2"))
+
+	tt.Equal(t, 1, len(z.Recent(0)))
+	tt.Equal(t, 0, len(Recent(0)))
+	tt.Nil(t, z.Close())
+
+	entries := readEntries(t, filepath.Join(z.inst.cfg.Path, "inst.json"))
+	tt.Equal(t, 1, len(entries))
+	tt.Equal(t, "mess...(truncated 8 bytes)", entries[0]["msg"])
+	// redacted, then truncated
+	tt.Equal(t, "[RED...(truncated 6 bytes)", entries[0]["password"])
+	tt.Equal(t, "abcd...(truncated 4 bytes)", entries[0]["k"])
+	tt.Equal(t, true, entries[0]["truncated"])
+
+	// the package loggers keep their own config
+	tt.Nil(t, Close())
+	entries = msgEntries(readEntries(t, filepath.Join(dir, "*", "test.json")), "package long")
+	tt.Equal(t, 1, len(entries))
+	tt.Equal(t, "hunter2", entries[0]["password"])
+}
+
+func TestNewRotate(t *testing.T) {
+	dir := t.TempDir()
+	z, err := New(Config{Path: dir, Name: "inst"})
+	tt.Nil(t, err)
+
+	z.Info("before rotate")
+	tt.Nil(t, z.Rotate())
+	z.Info("after rotate")
+	tt.Nil(t, z.Close())
+	// a no-op after Close
+	tt.Nil(t, z.Rotate())
+
+	files, err := filepath.Glob(filepath.Join(dir, "*", "inst*.json"))
+	tt.Nil(t, err)
+	// inst.json, inst_err.json and their backups
+	tt.Equal(t, 4, len(files))
+
+	entries := readEntries(t, filepath.Join(dir, "*", "inst.json"))
+	tt.Equal(t, 0, len(msgEntries(entries, "before rotate")))
+	tt.Equal(t, 1, len(msgEntries(entries, "after rotate")))
+}
+
+func TestNewSweep(t *testing.T) {
+	dir := initTest(t)
+	defer Close()
+
+	dirA := t.TempDir()
+	a, err := New(Config{Path: dirA, Name: "a"})
+	tt.Nil(t, err)
+	tt.Nil(t, a.Close())
+	tt.Nil(t, Close())
+
+	// the results of the sweep of a go to the files of a
+	entries := readEntries(t, filepath.Join(dirA, "*", "a.json"))
+	sweeps := msgEntries(entries, "zlog: delete old log")
+	tt.Equal(t, 1, len(sweeps))
+	tt.Equal(t, dirA, sweeps[0]["path"])
+
+	entries = readEntries(t, filepath.Join(dir, "*", "test.json"))
+	for _, e := range msgEntries(entries, "zlog: delete old log") {
+		tt.NotEqual(t, dirA, e["path"])
+	}
+}
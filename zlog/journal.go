@@ -0,0 +1,30 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"github.com/go-vgo/gt/zlog/journal"
+	"go.uber.org/zap/zapcore"
+)
+
+// journalSocket the socket of the Journal output
+var journalSocket = journal.DefaultSocket
+
+// newJournalCore returns the journal core of enab, its failed
+// writes are reported like the ones of the log files
+func newJournalCore(enab zapcore.LevelEnabler) (*journal.Core, error) {
+	return journal.NewCore(enab,
+		journal.WithSocket(journalSocket),
+		journal.WithErrorHandler(func(err error) {
+			writeFailed("journal", 1, err)
+		}),
+	)
+}
@@ -0,0 +1,294 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+// Package journal a zapcore.Core writing the entries to the systemd
+// journal by its native protocol, with the syslog priority of their
+// level and their fields as the journal fields, so journalctl -p err
+// selects the errors. It is used by the Journal option of zlog and
+// only supported on linux.
+package journal
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// DefaultSocket the socket of the native protocol of journald
+const DefaultSocket = "/run/systemd/journal/socket"
+
+// maxName the max length of a journal field name
+const maxName = 64
+
+// entryFields the journal fields of an entry, the entry fields
+// named like them get the FIELD_ prefix
+var entryFields = map[string]bool{
+	"MESSAGE": true, "PRIORITY": true, "SYSLOG_IDENTIFIER": true,
+	"LOGGER": true, "STACKTRACE": true,
+	"CODE_FILE": true, "CODE_LINE": true, "CODE_FUNC": true,
+}
+
+// socket the datagram socket of journald
+type socket interface {
+	send(p []byte) error
+	Close() error
+}
+
+// Option an option of NewCore
+type Option func(*Core)
+
+// WithSocket sets the socket of journald, default DefaultSocket
+func WithSocket(path string) Option {
+	return func(c *Core) {
+		c.path = path
+	}
+}
+
+// WithIdentifier sets the SYSLOG_IDENTIFIER of the entries,
+// default the program name
+func WithIdentifier(id string) Option {
+	return func(c *Core) {
+		c.id = id
+	}
+}
+
+// WithErrorHandler sets fn to run when an entry can't be written,
+// the error is also returned by Write
+func WithErrorHandler(fn func(error)) Option {
+	return func(c *Core) {
+		c.onError = fn
+	}
+}
+
+// Core a zapcore.Core writing the entries of its levels to journald,
+// the entries over the datagram limit are passed in a sealed memfd
+type Core struct {
+	zapcore.LevelEnabler
+	path    string
+	id      string
+	onError func(error)
+
+	sock socket
+	// ctx the journal fields of With
+	ctx []byte
+}
+
+// NewCore returns the core writing the entries of enab to journald,
+// it fails if the socket doesn't exist or on the other systems.
+//
+//	core, err := journal.NewCore(zap.InfoLevel)
+//	if err == nil {
+//		zlog.AddCore(core)
+//	}
+func NewCore(enab zapcore.LevelEnabler, opts ...Option) (*Core, error) {
+	c := &Core{
+		LevelEnabler: enab,
+		path:         DefaultSocket,
+		id:           filepath.Base(os.Args[0]),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	sock, err := openSocket(c.path)
+	if err != nil {
+		return nil, err
+	}
+	c.sock = sock
+	return c, nil
+}
+
+// With adds the fields to the entries of the returned core
+func (c *Core) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.ctx = appendFields(c.ctx[:len(c.ctx):len(c.ctx)], fields)
+	return &clone
+}
+
+// Check adds the core to ce if the level is enabled
+func (c *Core) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write sends the entry to journald
+func (c *Core) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	err := c.sock.send(c.encode(ent, fields))
+	if err != nil && c.onError != nil {
+		c.onError(err)
+	}
+	return err
+}
+
+// Sync the entries are sent by Write
+func (c *Core) Sync() error {
+	return nil
+}
+
+// Close closes the socket, the entries written after Close fail
+func (c *Core) Close() error {
+	return c.sock.Close()
+}
+
+// encode returns the datagram of the entry
+func (c *Core) encode(ent zapcore.Entry, fields []zapcore.Field) []byte {
+	buf := make([]byte, 0, 256+len(c.ctx))
+	buf = appendField(buf, "MESSAGE", ent.Message)
+	buf = appendField(buf, "PRIORITY", strconv.Itoa(Priority(ent.Level)))
+	if c.id != "" {
+		buf = appendField(buf, "SYSLOG_IDENTIFIER", c.id)
+	}
+	if ent.LoggerName != "" {
+		buf = appendField(buf, "LOGGER", ent.LoggerName)
+	}
+	if ent.Caller.Defined {
+		buf = appendField(buf, "CODE_FILE", ent.Caller.File)
+		buf = appendField(buf, "CODE_LINE", strconv.Itoa(ent.Caller.Line))
+		if ent.Caller.Function != "" {
+			buf = appendField(buf, "CODE_FUNC", ent.Caller.Function)
+		}
+	}
+	if ent.Stack != "" {
+		buf = appendField(buf, "STACKTRACE", ent.Stack)
+	}
+
+	buf = append(buf, c.ctx...)
+	return appendFields(buf, fields)
+}
+
+// Priority returns the syslog priority of the level, the levels
+// above error are crit like the syslog output
+func Priority(l zapcore.Level) int {
+	switch {
+	case l <= zapcore.DebugLevel:
+		return 7
+	case l == zapcore.InfoLevel:
+		return 6
+	case l == zapcore.WarnLevel:
+		return 4
+	case l == zapcore.ErrorLevel:
+		return 3
+	}
+	return 2
+}
+
+// appendFields appends the journal fields of fields, the objects
+// are flattened like REQUEST_ID and the arrays are json
+func appendFields(buf []byte, fields []zapcore.Field) []byte {
+	if len(fields) == 0 {
+		return buf
+	}
+
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	return appendMap(buf, "", enc.Fields)
+}
+
+func appendMap(buf []byte, prefix string, m map[string]interface{}) []byte {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if sub, ok := m[k].(map[string]interface{}); ok {
+			buf = appendMap(buf, prefix+k+"_", sub)
+			continue
+		}
+
+		name := fieldName(prefix + k)
+		if name == "" {
+			continue
+		}
+		if prefix == "" && entryFields[name] {
+			name = "FIELD_" + name
+		}
+		buf = appendField(buf, name, fieldValue(m[k]))
+	}
+	return buf
+}
+
+// fieldName returns the journal name of key, uppercase with the
+// chars other than A-Z, 0-9 and _ replaced by _, the leading _ and
+// digits are trimmed, they're invalid
+func fieldName(key string) string {
+	b := make([]byte, 0, len(key))
+	for i := 0; i < len(key) && len(b) < maxName; i++ {
+		ch := key[i]
+		switch {
+		case ch >= 'a' && ch <= 'z':
+			ch -= 'a' - 'A'
+		case ch >= 'A' && ch <= 'Z', ch >= '0' && ch <= '9':
+		default:
+			ch = '_'
+		}
+
+		if len(b) == 0 && (ch == '_' || ch >= '0' && ch <= '9') {
+			continue
+		}
+		b = append(b, ch)
+	}
+	return string(b)
+}
+
+// fieldValue returns the text of a value of the map encoder
+func fieldValue(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case []byte:
+		return base64.StdEncoding.EncodeToString(v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case time.Duration:
+		return v.String()
+	case bool, int, int8, int16, int32, int64, uint, uint8,
+		uint16, uint32, uint64, uintptr, float32, float64:
+		return fmt.Sprint(v)
+	}
+
+	if b, err := json.Marshal(v); err == nil {
+		return string(b)
+	}
+	return fmt.Sprint(v)
+}
+
+// appendField appends name=value, or the name, the little endian
+// length and the value if it has newlines
+func appendField(buf []byte, name, value string) []byte {
+	if !strings.Contains(value, "\n") {
+		buf = append(buf, name...)
+		buf = append(buf, '=')
+		buf = append(buf, value...)
+		return append(buf, '\n')
+	}
+
+	buf = append(buf, name...)
+	buf = append(buf, '\n')
+	var n [8]byte
+	binary.LittleEndian.PutUint64(n[:], uint64(len(value)))
+	buf = append(buf, n[:]...)
+	buf = append(buf, value...)
+	return append(buf, '\n')
+}
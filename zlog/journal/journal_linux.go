@@ -0,0 +1,83 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+// +build linux
+
+package journal
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// unixSocket an unbound datagram socket sending to the journal
+// socket, so a restart of journald needs no redial
+type unixSocket struct {
+	conn *net.UnixConn
+	addr *net.UnixAddr
+}
+
+func openSocket(path string) (socket, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("journal: the journal socket: %v", err)
+	}
+
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Net: "unixgram"})
+	if err != nil {
+		return nil, fmt.Errorf("journal: %v", err)
+	}
+	return &unixSocket{conn: conn, addr: &net.UnixAddr{Name: path, Net: "unixgram"}}, nil
+}
+
+// send sends p in a datagram, or in a memfd if it is too large
+func (s *unixSocket) send(p []byte) error {
+	_, _, err := s.conn.WriteMsgUnix(p, nil, s.addr)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, syscall.EMSGSIZE) && !errors.Is(err, syscall.ENOBUFS) {
+		return fmt.Errorf("journal: %v", err)
+	}
+
+	return s.sendFd(p)
+}
+
+// sendFd writes p to a sealed memfd and passes it to journald
+func (s *unixSocket) sendFd(p []byte) error {
+	fd, err := unix.MemfdCreate("journal-entry", unix.MFD_CLOEXEC|unix.MFD_ALLOW_SEALING)
+	if err != nil {
+		return fmt.Errorf("journal: memfd: %v", err)
+	}
+	f := os.NewFile(uintptr(fd), "journal-entry")
+	defer f.Close()
+
+	if _, err := f.Write(p); err != nil {
+		return fmt.Errorf("journal: memfd: %v", err)
+	}
+	seals := unix.F_SEAL_SHRINK | unix.F_SEAL_GROW | unix.F_SEAL_WRITE | unix.F_SEAL_SEAL
+	if _, err := unix.FcntlInt(f.Fd(), unix.F_ADD_SEALS, seals); err != nil {
+		return fmt.Errorf("journal: memfd seals: %v", err)
+	}
+
+	_, _, err = s.conn.WriteMsgUnix(nil, unix.UnixRights(int(f.Fd())), s.addr)
+	if err != nil {
+		return fmt.Errorf("journal: %v", err)
+	}
+	return nil
+}
+
+func (s *unixSocket) Close() error {
+	return s.conn.Close()
+}
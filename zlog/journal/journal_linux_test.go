@@ -0,0 +1,113 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+// +build linux
+
+package journal
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/vcaesar/tt"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// fakeJournal the socket of a fake journald
+type fakeJournal struct {
+	path string
+	conn *net.UnixConn
+}
+
+func newFakeJournal(t *testing.T) *fakeJournal {
+	dir, err := ioutil.TempDir("", "journal")
+	tt.Nil(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	path := filepath.Join(dir, "socket")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	tt.Nil(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return &fakeJournal{path: path, conn: conn}
+}
+
+// read returns the next datagram, or the content of its memfd
+func (j *fakeJournal) read(t *testing.T) ([]byte, bool) {
+	buf, oob := make([]byte, 1<<16), make([]byte, 64)
+	n, oobn, _, _, err := j.conn.ReadMsgUnix(buf, oob)
+	tt.Nil(t, err)
+	if oobn == 0 {
+		return buf[:n], false
+	}
+
+	tt.Equal(t, 0, n)
+	msgs, err := syscall.ParseSocketControlMessage(oob[:oobn])
+	tt.Nil(t, err)
+	fds, err := syscall.ParseUnixRights(&msgs[0])
+	tt.Nil(t, err)
+
+	f := os.NewFile(uintptr(fds[0]), "memfd")
+	defer f.Close()
+	// the offset is shared with the sender
+	_, err = f.Seek(0, 0)
+	tt.Nil(t, err)
+	b, err := ioutil.ReadAll(f)
+	tt.Nil(t, err)
+	return b, true
+}
+
+func TestWrite(t *testing.T) {
+	j := newFakeJournal(t)
+	var errs []error
+	core, err := NewCore(zapcore.InfoLevel, WithSocket(j.path), WithIdentifier("gt"),
+		WithErrorHandler(func(err error) { errs = append(errs, err) }))
+	tt.Nil(t, err)
+
+	logger := zap.New(core).Named("api")
+	logger.Debug("debug")
+	logger.Warn("slow", zap.String("path", "/a"))
+
+	p, memfd := j.read(t)
+	tt.False(t, memfd)
+	fields := parse(t, p)
+	tt.Equal(t, []string{"slow"}, fields["MESSAGE"])
+	tt.Equal(t, []string{"4"}, fields["PRIORITY"])
+	tt.Equal(t, []string{"gt"}, fields["SYSLOG_IDENTIFIER"])
+	tt.Equal(t, []string{"api"}, fields["LOGGER"])
+	tt.Equal(t, []string{"/a"}, fields["PATH"])
+
+	// over the datagram limit
+	large := strings.Repeat("x\n", 1<<20)
+	logger.Error("large", zap.String("body", large))
+	p, memfd = j.read(t)
+	tt.True(t, memfd)
+	fields = parse(t, p)
+	tt.Equal(t, []string{"large"}, fields["MESSAGE"])
+	tt.Equal(t, []string{"3"}, fields["PRIORITY"])
+	tt.True(t, len(fields["BODY"]) == 1 && fields["BODY"][0] == large)
+	tt.Equal(t, 0, len(errs))
+
+	tt.Nil(t, core.Close())
+	tt.NotNil(t, core.Write(zapcore.Entry{Level: zapcore.ErrorLevel}, nil))
+	tt.Equal(t, 1, len(errs))
+}
+
+func TestNoSocket(t *testing.T) {
+	_, err := NewCore(zapcore.InfoLevel, WithSocket(filepath.Join(os.TempDir(), "no-journal")))
+	tt.NotNil(t, err)
+	tt.True(t, strings.Contains(err.Error(), "journal socket"))
+}
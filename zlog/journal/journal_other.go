@@ -0,0 +1,22 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+// +build !linux
+
+package journal
+
+import (
+	"errors"
+	"runtime"
+)
+
+func openSocket(path string) (socket, error) {
+	return nil, errors.New("journal: the systemd journal is not supported on " + runtime.GOOS)
+}
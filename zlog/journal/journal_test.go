@@ -0,0 +1,116 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package journal
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/vcaesar/tt"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// parse returns the fields of a datagram of the native protocol
+func parse(t *testing.T, p []byte) map[string][]string {
+	fields := make(map[string][]string)
+	for len(p) > 0 {
+		i := 0
+		for i < len(p) && p[i] != '=' && p[i] != '\n' {
+			i++
+		}
+		if i == len(p) {
+			t.Fatalf("no end of the field %q", p)
+		}
+
+		name := string(p[:i])
+		if p[i] == '=' {
+			end := i + 1
+			for p[end] != '\n' {
+				end++
+			}
+			fields[name] = append(fields[name], string(p[i+1:end]))
+			p = p[end+1:]
+			continue
+		}
+
+		n := int(binary.LittleEndian.Uint64(p[i+1 : i+9]))
+		value := p[i+9 : i+9+n]
+		tt.Equal(t, byte('\n'), p[i+9+n])
+		fields[name] = append(fields[name], string(value))
+		p = p[i+10+n:]
+	}
+	return fields
+}
+
+func TestAppendField(t *testing.T) {
+	tt.Equal(t, "MESSAGE=hi\n", string(appendField(nil, "MESSAGE", "hi")))
+	tt.Equal(t, "STACKTRACE\n\x03\x00\x00\x00\x00\x00\x00\x00a\nb\n",
+		string(appendField(nil, "STACKTRACE", "a\nb")))
+}
+
+func TestFieldName(t *testing.T) {
+	tt.Equal(t, "REQUEST_ID", fieldName("request-id"))
+	tt.Equal(t, "USER_NAME", fieldName("user.name"))
+	tt.Equal(t, "CURSOR", fieldName("__cursor"))
+	tt.Equal(t, "A1", fieldName("1a1"))
+	tt.Equal(t, "", fieldName("_"))
+	tt.Equal(t, maxName, len(fieldName(string(make([]byte, 100))+"a"+string(make([]byte, 100)))))
+}
+
+func TestPriority(t *testing.T) {
+	tt.Equal(t, 7, Priority(zapcore.DebugLevel))
+	tt.Equal(t, 6, Priority(zapcore.InfoLevel))
+	tt.Equal(t, 4, Priority(zapcore.WarnLevel))
+	tt.Equal(t, 3, Priority(zapcore.ErrorLevel))
+	tt.Equal(t, 2, Priority(zapcore.DPanicLevel))
+	tt.Equal(t, 2, Priority(zapcore.FatalLevel))
+}
+
+func TestEncode(t *testing.T) {
+	c := &Core{LevelEnabler: zapcore.DebugLevel, id: "app"}
+	core := c.With([]zapcore.Field{zap.String("app-env", "prod")}).(*Core)
+
+	ent := zapcore.Entry{
+		Level:      zapcore.ErrorLevel,
+		Message:    "db error",
+		LoggerName: "db",
+		Caller:     zapcore.NewEntryCaller(0, "db/conn.go", 12, true),
+		Stack:      "main.main\n\tmain.go:3",
+	}
+	fields := parse(t, core.encode(ent, []zapcore.Field{
+		zap.Error(errors.New("e1")),
+		zap.Int("priority", 1),
+		zap.Duration("took", time.Second),
+		zap.Strings("hosts", []string{"a", "b"}),
+		zap.Namespace("req"),
+		zap.String("id", "r1"),
+	}))
+
+	tt.Equal(t, []string{"db error"}, fields["MESSAGE"])
+	tt.Equal(t, []string{"3"}, fields["PRIORITY"])
+	tt.Equal(t, []string{"app"}, fields["SYSLOG_IDENTIFIER"])
+	tt.Equal(t, []string{"db"}, fields["LOGGER"])
+	tt.Equal(t, []string{"db/conn.go"}, fields["CODE_FILE"])
+	tt.Equal(t, []string{"12"}, fields["CODE_LINE"])
+	tt.Equal(t, []string{"main.main\n\tmain.go:3"}, fields["STACKTRACE"])
+	tt.Equal(t, []string{"prod"}, fields["APP_ENV"])
+	tt.Equal(t, []string{"e1"}, fields["ERROR"])
+	tt.Equal(t, []string{"1"}, fields["FIELD_PRIORITY"])
+	tt.Equal(t, []string{"1s"}, fields["TOOK"])
+	tt.Equal(t, []string{`["a","b"]`}, fields["HOSTS"])
+	tt.Equal(t, []string{"r1"}, fields["REQ_ID"])
+
+	// the fields of With aren't shared by the parent
+	tt.Equal(t, 0, len(c.ctx))
+}
@@ -0,0 +1,62 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+// +build linux
+
+package zlog
+
+import (
+	"errors"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vcaesar/tt"
+)
+
+func TestJournal(t *testing.T) {
+	addr := filepath.Join(t.TempDir(), "journal.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: addr, Net: "unixgram"})
+	tt.Nil(t, err)
+	defer conn.Close()
+
+	prev := journalSocket
+	journalSocket = addr
+	defer func() { journalSocket = prev }()
+
+	dir := t.TempDir()
+	tt.Nil(t, InitWithConfig(Config{Path: dir, Name: "test", Level: "debug", Journal: true}))
+	Debug("journal debug")
+	Error("journal error", errors.New("e1"))
+	tt.Nil(t, Close())
+
+	priorities := map[string]string{"journal debug": "PRIORITY=7\n", "journal error": "PRIORITY=3\n"}
+	buf := make([]byte, 4096)
+	for len(priorities) > 0 {
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		n, err := conn.Read(buf)
+		if err != nil {
+			t.Fatal(err, priorities)
+		}
+
+		msg := string(buf[:n])
+		for text, priority := range priorities {
+			if strings.HasPrefix(msg, "MESSAGE="+text+"\n") {
+				tt.True(t, strings.Contains(msg, priority), msg)
+				delete(priorities, text)
+			}
+		}
+	}
+
+	journalSocket = filepath.Join(dir, "not_exist.sock")
+	tt.NotNil(t, InitWithConfig(Config{Path: dir, Journal: true}))
+}
@@ -0,0 +1,335 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+// Package kafkasink a zapcore.WriteSyncer producing the entries to a
+// kafka topic in batches, it is added to zlog by zlog.AddWriter.
+package kafkasink
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-vgo/gt/zlog"
+)
+
+const (
+	defaultBatchBytes = 1 << 20
+	defaultLinger     = 100 * time.Millisecond
+	defaultMaxBatches = 16
+	defaultKeyField   = "logger"
+)
+
+// ErrQueueFull the error of the batches dropped because the
+// producer is too slow
+var ErrQueueFull = errors.New("kafkasink: the queue of the batches is full")
+
+// Message a message of the topic, Key is nil if the entry has
+// no key field
+type Message struct {
+	Key, Value []byte
+}
+
+// Producer produces the batches of messages to the topics, it is
+// the adapter of a kafka client like sarama or franz-go and it is
+// called by one goroutine of the sink.
+type Producer interface {
+	Produce(topic string, msgs []Message) error
+	Close() error
+}
+
+// NewProducer returns the producer of the brokers for the sinks
+// without WithProducer, set it to the adapter of the kafka client
+//
+//	kafkasink.NewProducer = func(brokers []string) (kafkasink.Producer, error) {
+//		p, err := sarama.NewSyncProducer(brokers, nil)
+//		return saramaProducer{p}, err
+//	}
+var NewProducer func(brokers []string) (Producer, error)
+
+type options struct {
+	producer   Producer
+	batchBytes int
+	linger     time.Duration
+	maxBatches int
+	keyField   string
+	onError    func(n int, err error)
+}
+
+// Option an option of New
+type Option func(*options)
+
+// WithProducer sets the producer of the sink instead of NewProducer
+func WithProducer(p Producer) Option {
+	return func(o *options) { o.producer = p }
+}
+
+// WithBatchBytes sets the size of the values of a batch, a batch
+// is produced once it is full, default 1MB
+func WithBatchBytes(n int) Option {
+	return func(o *options) { o.batchBytes = n }
+}
+
+// WithLinger sets the max wait of an entry before its batch
+// is produced, default 100ms
+func WithLinger(d time.Duration) Option {
+	return func(o *options) { o.linger = d }
+}
+
+// WithMaxBatches sets the batches queued for the producer, the
+// batches are dropped when it is full, default 16
+func WithMaxBatches(n int) Option {
+	return func(o *options) { o.maxBatches = n }
+}
+
+// WithKeyField sets the field of the json entries which is the key
+// of the messages, default the logger name "logger"
+func WithKeyField(field string) Option {
+	return func(o *options) { o.keyField = field }
+}
+
+// WithErrorHandler sets fn to run with the messages which couldn't be
+// delivered, by default the failures are counted by zlog.Stats and run
+// the zlog.OnWriteError funcs
+func WithErrorHandler(fn func(n int, err error)) Option {
+	return func(o *options) { o.onError = fn }
+}
+
+// batch the messages of a Produce, done is closed once it's produced
+type batch struct {
+	msgs []Message
+	done chan error
+}
+
+// Sink a zapcore.WriteSyncer batching the entries and producing them
+// to a topic in the background, the writes never wait for kafka
+type Sink struct {
+	topic string
+	opts  options
+
+	mu      sync.Mutex
+	pending []Message
+	size    int
+	// gen the batch of the linger timer
+	gen    uint64
+	timer  *time.Timer
+	closed bool
+
+	batches chan batch
+	// sending the Sync waiting for room in batches
+	sending sync.WaitGroup
+	done    chan struct{}
+	once    sync.Once
+}
+
+// New returns the sink of the topic and starts its producer
+// goroutine, call Close to produce the last entries.
+//
+//	ws, err := kafkasink.New([]string{"kafka:9092"}, "logs")
+//	h, err := zlog.AddWriter(ws, zap.InfoLevel, "json")
+//	defer ws.Close()
+func New(brokers []string, topic string, opts ...Option) (*Sink, error) {
+	if topic == "" {
+		return nil, errors.New("kafkasink: the topic is empty")
+	}
+
+	o := options{
+		batchBytes: defaultBatchBytes,
+		linger:     defaultLinger,
+		maxBatches: defaultMaxBatches,
+		keyField:   defaultKeyField,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if o.batchBytes <= 0 || o.linger <= 0 || o.maxBatches <= 0 {
+		return nil, errors.New("kafkasink: invalid batch options")
+	}
+
+	if o.producer == nil {
+		if len(brokers) == 0 {
+			return nil, errors.New("kafkasink: no brokers")
+		}
+		if NewProducer == nil {
+			return nil, errors.New("kafkasink: no producer, set NewProducer or WithProducer")
+		}
+
+		p, err := NewProducer(brokers)
+		if err != nil {
+			return nil, fmt.Errorf("kafkasink: %v", err)
+		}
+		o.producer = p
+	}
+
+	s := &Sink{
+		topic:   topic,
+		opts:    o,
+		batches: make(chan batch, o.maxBatches),
+		done:    make(chan struct{}),
+	}
+	if s.opts.onError == nil {
+		s.opts.onError = func(n int, err error) {
+			zlog.ReportWriteError(s.Name(), int64(n), err)
+		}
+	}
+
+	go s.run()
+	return s, nil
+}
+
+// Name returns the name of the sink in the write errors
+func (s *Sink) Name() string {
+	return "kafka topic " + s.topic
+}
+
+// Write queues a copy of the entry p, keyed by the key field
+func (s *Sink) Write(p []byte) (int, error) {
+	msg := Message{Key: s.key(p), Value: append([]byte(nil), p...)}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return len(p), nil
+	}
+
+	s.pending = append(s.pending, msg)
+	s.size += len(msg.Value)
+	if s.size >= s.opts.batchBytes {
+		s.queue(s.take())
+	} else if len(s.pending) == 1 {
+		gen := s.gen
+		s.timer = time.AfterFunc(s.opts.linger, func() { s.lingered(gen) })
+	}
+	return len(p), nil
+}
+
+// Sync produces the queued entries and waits for them, it returns
+// the error of the last batch
+func (s *Sink) Sync() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	b := s.take()
+	s.sending.Add(1)
+	s.mu.Unlock()
+
+	return s.wait(b)
+}
+
+// Close produces the queued entries and closes the producer, the
+// entries written after Close are ignored.
+func (s *Sink) Close() error {
+	var err error
+	s.once.Do(func() {
+		s.mu.Lock()
+		b := s.take()
+		s.closed = true
+		s.sending.Add(1)
+		s.mu.Unlock()
+
+		err = s.wait(b)
+		s.sending.Wait()
+		close(s.batches)
+		<-s.done
+
+		if closeErr := s.opts.producer.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	})
+	return err
+}
+
+// key returns the string of the key field of the json entry p
+func (s *Sink) key(p []byte) []byte {
+	var entry map[string]json.RawMessage
+	if json.Unmarshal(p, &entry) != nil {
+		return nil
+	}
+
+	raw, ok := entry[s.opts.keyField]
+	if !ok {
+		return nil
+	}
+
+	var str string
+	if json.Unmarshal(raw, &str) == nil {
+		return []byte(str)
+	}
+	return raw
+}
+
+// lingered queues the batch gen when its linger is over
+func (s *Sink) lingered(gen uint64) {
+	s.mu.Lock()
+	if !s.closed && s.gen == gen && len(s.pending) > 0 {
+		s.queue(s.take())
+	}
+	s.mu.Unlock()
+}
+
+// take returns the pending batch and starts the next one,
+// it is called with mu locked
+func (s *Sink) take() batch {
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+
+	b := batch{msgs: s.pending}
+	s.pending, s.size = nil, 0
+	s.gen++
+	return b
+}
+
+// queue hands b to the producer goroutine, it is dropped if the
+// queue is full so the writes never wait, it is called with mu locked
+func (s *Sink) queue(b batch) {
+	select {
+	case s.batches <- b:
+	default:
+		s.opts.onError(len(b.msgs), ErrQueueFull)
+	}
+}
+
+// wait hands b to the producer goroutine once there's room in the
+// queue and returns the error of its Produce, it is called without
+// mu after sending.Add
+func (s *Sink) wait(b batch) error {
+	b.done = make(chan error, 1)
+	s.batches <- b
+	s.sending.Done()
+	return <-b.done
+}
+
+// run produces the batches in order
+func (s *Sink) run() {
+	defer close(s.done)
+
+	var err error
+	for b := range s.batches {
+		if len(b.msgs) > 0 {
+			err = s.opts.producer.Produce(s.topic, b.msgs)
+			if err != nil {
+				s.opts.onError(len(b.msgs), err)
+			}
+		}
+
+		if b.done != nil {
+			b.done <- err
+			err = nil
+		}
+	}
+}
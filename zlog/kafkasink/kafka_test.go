@@ -0,0 +1,256 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package kafkasink
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-vgo/gt/zlog"
+	"github.com/vcaesar/tt"
+	"go.uber.org/zap"
+)
+
+// fakeProducer records the batches, fail returns the error of the
+// nth batch and block holds the batches until it is closed
+type fakeProducer struct {
+	mu      sync.Mutex
+	batches [][]Message
+	topics  []string
+	closed  bool
+	fail    func(n int) error
+	block   chan struct{}
+	entered chan struct{}
+}
+
+func (f *fakeProducer) Produce(topic string, msgs []Message) error {
+	if f.block != nil {
+		f.entered <- struct{}{}
+		<-f.block
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.topics = append(f.topics, topic)
+	if f.fail != nil {
+		if err := f.fail(len(f.topics)); err != nil {
+			return err
+		}
+	}
+	f.batches = append(f.batches, msgs)
+	return nil
+}
+
+func (f *fakeProducer) Close() error {
+	f.mu.Lock()
+	f.closed = true
+	f.mu.Unlock()
+	return nil
+}
+
+func (f *fakeProducer) produced() [][]Message {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([][]Message(nil), f.batches...)
+}
+
+func newSink(t *testing.T, f *fakeProducer, opts ...Option) *Sink {
+	s, err := New(nil, "logs", append([]Option{WithProducer(f)}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestNew(t *testing.T) {
+	_, err := New([]string{"kafka:9092"}, "", WithProducer(&fakeProducer{}))
+	tt.NotNil(t, err)
+	_, err = New([]string{"kafka:9092"}, "logs")
+	tt.NotNil(t, err)
+	_, err = New(nil, "logs", WithProducer(&fakeProducer{}), WithLinger(0))
+	tt.NotNil(t, err)
+
+	f := &fakeProducer{}
+	NewProducer = func(brokers []string) (Producer, error) {
+		tt.Equal(t, []string{"kafka:9092"}, brokers)
+		return f, nil
+	}
+	t.Cleanup(func() { NewProducer = nil })
+
+	_, err = New(nil, "logs")
+	tt.NotNil(t, err)
+	s, err := New([]string{"kafka:9092"}, "logs")
+	tt.Nil(t, err)
+	tt.Equal(t, "kafka topic logs", s.Name())
+	tt.Nil(t, s.Close())
+	tt.True(t, f.closed)
+}
+
+func TestBatchBytes(t *testing.T) {
+	f := &fakeProducer{}
+	s := newSink(t, f, WithBatchBytes(30), WithLinger(time.Hour))
+
+	for i := 0; i < 7; i++ {
+		s.Write([]byte("0123456789"))
+	}
+	// the writes after Close are ignored
+	tt.Nil(t, s.Close())
+	s.Write([]byte("0123456789"))
+	tt.Nil(t, s.Sync())
+	tt.Nil(t, s.Close())
+
+	batches := f.produced()
+	tt.Equal(t, 3, len(batches))
+	for i, n := range []int{3, 3, 1} {
+		tt.Equal(t, n, len(batches[i]))
+	}
+	tt.Equal(t, []string{"logs", "logs", "logs"}, f.topics)
+	tt.True(t, f.closed)
+}
+
+func TestLinger(t *testing.T) {
+	f := &fakeProducer{}
+	s := newSink(t, f, WithLinger(10*time.Millisecond))
+	defer s.Close()
+
+	s.Write([]byte("lingered"))
+	for i := 0; i < 200 && len(f.produced()) == 0; i++ {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	batches := f.produced()
+	tt.Equal(t, 1, len(batches))
+	tt.Equal(t, "lingered", string(batches[0][0].Value))
+
+	// Sync produces without the linger
+	s.Write([]byte("synced"))
+	tt.Nil(t, s.Sync())
+	tt.Equal(t, 2, len(f.produced()))
+}
+
+func TestKey(t *testing.T) {
+	f := &fakeProducer{}
+	s := newSink(t, f)
+	s.Write([]byte(`{"logger":"db","msg":"a"}` + "\n"))
+	s.Write([]byte("console line\n"))
+	tt.Nil(t, s.Close())
+
+	msgs := f.produced()[0]
+	tt.Equal(t, "db", string(msgs[0].Key))
+	tt.Equal(t, 0, len(msgs[1].Key))
+
+	f = &fakeProducer{}
+	s = newSink(t, f, WithKeyField("user_id"))
+	s.Write([]byte(`{"logger":"db","user_id":42}`))
+	s.Write([]byte(`{"logger":"db"}`))
+	tt.Nil(t, s.Close())
+
+	msgs = f.produced()[0]
+	tt.Equal(t, "42", string(msgs[0].Key))
+	tt.Equal(t, 0, len(msgs[1].Key))
+}
+
+func TestFailures(t *testing.T) {
+	errDown := errors.New("broker down")
+	f := &fakeProducer{fail: func(n int) error {
+		if n == 1 {
+			return errDown
+		}
+		return nil
+	}}
+	prev := zlog.Stats().WriteErrors
+	s := newSink(t, f, WithLinger(time.Hour))
+
+	s.Write([]byte("lost"))
+	s.Write([]byte("lost"))
+	tt.Equal(t, errDown, s.Sync())
+	tt.Equal(t, prev+1, zlog.Stats().WriteErrors)
+	tt.Equal(t, "broker down", zlog.Stats().LastError)
+
+	s.Write([]byte("delivered"))
+	tt.Nil(t, s.Close())
+	tt.Equal(t, 1, len(f.produced()))
+}
+
+func TestQueueFull(t *testing.T) {
+	f := &fakeProducer{block: make(chan struct{}), entered: make(chan struct{}, 8)}
+	var (
+		mu      sync.Mutex
+		dropped int
+	)
+	s := newSink(t, f, WithBatchBytes(1), WithMaxBatches(1),
+		WithErrorHandler(func(n int, err error) {
+			tt.Equal(t, ErrQueueFull, err)
+			mu.Lock()
+			dropped += n
+			mu.Unlock()
+		}))
+
+	// the first batch is held by the producer, the second is
+	// queued and the others are dropped without waiting
+	s.Write([]byte("1"))
+	<-f.entered
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 4; i++ {
+			s.Write([]byte("2"))
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the writes wait for the producer")
+	}
+	mu.Lock()
+	tt.Equal(t, 3, dropped)
+	mu.Unlock()
+
+	close(f.block)
+	tt.Nil(t, s.Close())
+	tt.Equal(t, 2, len(f.produced()))
+}
+
+func TestAddWriter(t *testing.T) {
+	f := &fakeProducer{}
+	s := newSink(t, f, WithLinger(time.Hour))
+
+	tt.Nil(t, zlog.InitWithConfig(zlog.Config{Path: t.TempDir()}))
+	h, err := zlog.AddWriter(s, zap.InfoLevel, "json")
+	tt.Nil(t, err)
+
+	zlog.Named("db").Info("to kafka")
+	zlog.Debug("not to kafka")
+	zlog.Error("kafka error", errors.New("e1"))
+	h.Remove()
+	zlog.Info("removed")
+	tt.Nil(t, zlog.Close())
+	tt.Nil(t, s.Close())
+
+	var msgs []Message
+	for _, b := range f.produced() {
+		msgs = append(msgs, b...)
+	}
+	tt.Equal(t, 2, len(msgs))
+	tt.Equal(t, "db", string(msgs[0].Key))
+	tt.Equal(t, 0, len(msgs[1].Key))
+
+	entry := make(map[string]interface{})
+	tt.Nil(t, json.Unmarshal(msgs[0].Value, &entry))
+	tt.Equal(t, "to kafka", entry["msg"])
+	tt.True(t, strings.Contains(string(msgs[1].Value), `"error":"e1"`))
+}
@@ -0,0 +1,89 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+var (
+	// packageZlog the Zlog of the package loggers returned by If
+	packageZlog = &Zlog{}
+	// nopZlog the Zlog dropping the entries returned by If
+	nopZlog = &Zlog{logger: nopLogger, errLogger: nopLogger}
+)
+
+// If returns the package loggers if cond is true, or a Zlog
+// dropping the entries without any cost if it's false.
+//
+//	zlog.If(verbose).Info("request body", body)
+func If(cond bool) *Zlog {
+	if cond {
+		return packageZlog
+	}
+	return nopZlog
+}
+
+// If returns z if cond is true, or a Zlog dropping the entries
+func (z *Zlog) If(cond bool) *Zlog {
+	if cond {
+		return z
+	}
+	return nopZlog
+}
+
+// DebugLazy debug log with the fields of fn, fn is only called
+// if the debug level is enabled.
+//
+//	zlog.DebugLazy("config diff", func() []zap.Field {
+//		return []zap.Field{zlog.Str("diff", diff(old, cfg))}
+//	})
+func DebugLazy(msg string, fn func() []zap.Field) {
+	writeLazy(getLogger(), zap.DebugLevel, msg, fn)
+}
+
+// InfoLazy info log with the fields of fn, like DebugLazy
+func InfoLazy(msg string, fn func() []zap.Field) {
+	writeLazy(getLogger(), zap.InfoLevel, msg, fn)
+}
+
+// WarnLazy warn log with the fields of fn, like DebugLazy
+func WarnLazy(msg string, fn func() []zap.Field) {
+	writeLazy(getLogger(), zap.WarnLevel, msg, fn)
+}
+
+// DebugLazy debug log with the fields of fn, like DebugLazy
+func (z *Zlog) DebugLazy(msg string, fn func() []zap.Field) {
+	logger, _ := z.get()
+	writeLazy(logger, zap.DebugLevel, msg, fn)
+}
+
+// InfoLazy info log with the fields of fn, like DebugLazy
+func (z *Zlog) InfoLazy(msg string, fn func() []zap.Field) {
+	logger, _ := z.get()
+	writeLazy(logger, zap.InfoLevel, msg, fn)
+}
+
+// WarnLazy warn log with the fields of fn, like DebugLazy
+func (z *Zlog) WarnLazy(msg string, fn func() []zap.Field) {
+	logger, _ := z.get()
+	writeLazy(logger, zap.WarnLevel, msg, fn)
+}
+
+// writeLazy writes the entry with the fields of fn, the level is
+// checked by the cores so SetLevel applies at once
+func writeLazy(logger *zap.Logger, lvl zapcore.Level, msg string,
+	fn func() []zap.Field) {
+	if ce := logger.Check(lvl, msg); ce != nil {
+		ce.Write(fn()...)
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"testing"
+
+	"github.com/vcaesar/tt"
+	"go.uber.org/zap"
+)
+
+func TestLazy(t *testing.T) {
+	logs, _ := observeSplit(t)
+	lvl := zap.NewAtomicLevelAt(zap.InfoLevel)
+	logger := load().logger.WithOptions(zap.IncreaseLevel(lvl))
+	current.Store(&loggers{logger: logger, errLogger: logger})
+
+	calls := 0
+	fields := func() []zap.Field {
+		calls++
+		return []zap.Field{zap.Int("calls", calls)}
+	}
+
+	DebugLazy("lazy debug", fields)
+	tt.Equal(t, 0, calls)
+	InfoLazy("lazy info", fields)
+	WarnLazy("lazy warn", fields)
+	tt.Equal(t, 2, calls)
+	tt.Equal(t, int64(1), logs.FilterMessage("lazy info").All()[0].ContextMap()["calls"])
+
+	// the level is checked on every call
+	lvl.SetLevel(zap.WarnLevel)
+	InfoLazy("lazy info", fields)
+	tt.Equal(t, 2, calls)
+	tt.Equal(t, 1, logs.FilterMessage("lazy info").Len())
+
+	With(Str("ctx", "c1")).WarnLazy("lazy with", fields)
+	entry := logs.FilterMessage("lazy with").All()[0].ContextMap()
+	tt.Equal(t, "c1", entry["ctx"])
+	tt.Equal(t, int64(3), entry["calls"])
+}
+
+func TestIf(t *testing.T) {
+	logs, errLogs := observeSplit(t)
+
+	If(true).Info("if true")
+	If(false).Info("if false")
+	If(false).Error("if false error")
+	If(false).InfoLazy("if false lazy", func() []zap.Field {
+		t.Fatal("fn called")
+		return nil
+	})
+
+	req := With(Str("request_id", "r1"))
+	tt.True(t, req.If(true) == req)
+	req.If(false).Warn("req if false")
+	req.If(true).Warn("req if true")
+
+	tt.Equal(t, 1, logs.FilterMessage("if true").Len())
+	tt.Equal(t, 1, logs.FilterMessage("req if true").Len())
+	tt.Equal(t, 2, logs.Len())
+	tt.Equal(t, 0, errLogs.Len())
+
+	tt.Equal(t, float64(0), testing.AllocsPerRun(100, func() {
+		If(false).Info("if false", "a")
+	}))
+}
+
+func TestLazyAllocs(t *testing.T) {
+	tt.Nil(t, InitWithConfig(Config{Mode: "discard", Level: "info"}))
+	defer Close()
+
+	calls := 0
+	fields := func() []zap.Field {
+		calls++
+		return nil
+	}
+	tt.Equal(t, float64(0), testing.AllocsPerRun(100, func() {
+		DebugLazy("disabled debug", fields)
+	}))
+	tt.Equal(t, 0, calls)
+}
+
+func BenchmarkDebugLazyDisabled(b *testing.B) {
+	benchmarkDiscard(b, "info", func() {
+		DebugLazy("bench debug", func() []zap.Field {
+			b.Fatal("fn called")
+			return nil
+		})
+	})
+}
+
+func BenchmarkIfFalse(b *testing.B) {
+	benchmarkDiscard(b, "debug", func() {
+		If(false).Info("bench info", "a", "b")
+	})
+}
@@ -0,0 +1,80 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"fmt"
+	"net/http"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// level the level of the main log, the error log keeps Error+
+var level = zap.NewAtomicLevel()
+
+// logLevel parses the level of the config, default zap.InfoLevel
+func (c *Config) logLevel() (zapcore.Level, error) {
+	if c.Level == "" {
+		return zap.InfoLevel, nil
+	}
+
+	lvl, err := zapcore.ParseLevel(c.Level)
+	if err != nil {
+		return lvl, fmt.Errorf("zlog: invalid level %q: %v", c.Level, err)
+	}
+	return lvl, nil
+}
+
+// modeLevel returns the level of the config, default
+// zap.DebugLevel in dev mode
+func (c *Config) modeLevel() (zapcore.Level, error) {
+	if c.Mode == "dev" && c.Level == "" {
+		return zap.DebugLevel, nil
+	}
+	return c.logLevel()
+}
+
+// stacktraceLevel returns the level from which the stacktrace is
+// added, default error, ok is false for "none" or "disabled".
+func (c *Config) stacktraceLevel() (lvl zapcore.Level, ok bool, err error) {
+	switch c.StacktraceLevel {
+	case "":
+		return zap.ErrorLevel, true, nil
+	case "none", "disabled":
+		return lvl, false, nil
+	}
+
+	lvl, err = zapcore.ParseLevel(c.StacktraceLevel)
+	if err != nil {
+		return lvl, false, fmt.Errorf("zlog: invalid stacktrace_level %q: %v",
+			c.StacktraceLevel, err)
+	}
+	return lvl, true, nil
+}
+
+// SetLevel changes the level of the main log at runtime
+func SetLevel(l zapcore.Level) {
+	level.SetLevel(l)
+}
+
+// GetLevel returns the level of the main log
+func GetLevel() zapcore.Level {
+	return level.Level()
+}
+
+// LevelHandler returns a http.Handler which serves the level as
+// json on GET and changes it on PUT, see zap.AtomicLevel.ServeHTTP.
+//
+//	mux.Handle("/debug/level", zlog.LevelHandler())
+func LevelHandler() http.Handler {
+	return level
+}
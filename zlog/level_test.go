@@ -0,0 +1,110 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/vcaesar/tt"
+	"go.uber.org/zap"
+)
+
+func TestSetLevel(t *testing.T) {
+	dir := initTest(t)
+	tt.Equal(t, zap.InfoLevel, GetLevel())
+
+	Debug("hidden")
+	SetLevel(zap.DebugLevel)
+	tt.Equal(t, zap.DebugLevel, GetLevel())
+	Debug("shown")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				Debug("race")
+				Info("race")
+			}
+		}()
+	}
+
+	for i := 0; i < 100; i++ {
+		if i%2 == 0 {
+			SetLevel(zap.WarnLevel)
+		} else {
+			SetLevel(zap.DebugLevel)
+		}
+	}
+	wg.Wait()
+	tt.Nil(t, Close())
+
+	entries := readEntries(t, filepath.Join(dir, "*", "test.json"))
+	tt.Equal(t, 0, len(msgEntries(entries, "hidden")))
+	tt.Equal(t, 1, len(msgEntries(entries, "shown")))
+}
+
+func TestLevelHandler(t *testing.T) {
+	SetLevel(zap.InfoLevel)
+	h := LevelHandler()
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	tt.Equal(t, http.StatusOK, w.Code)
+	tt.True(t, strings.Contains(w.Body.String(), `"info"`))
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/",
+		strings.NewReader(`{"level":"debug"}`)))
+	tt.Equal(t, http.StatusOK, w.Code)
+	tt.Equal(t, zap.DebugLevel, GetLevel())
+
+	SetLevel(zap.InfoLevel)
+}
+
+func TestStacktraceLevel(t *testing.T) {
+	dir := initTest(t, "level = \"debug\"")
+	Info("stack info")
+	Warn("stack warn")
+	Error("stack error", errors.New("e1"))
+	tt.Nil(t, Close())
+
+	entries := readEntries(t, filepath.Join(dir, "*", "test*.json"))
+	tt.Nil(t, msgEntries(entries, "stack info")[0]["stacktrace"])
+	tt.Nil(t, msgEntries(entries, "stack warn")[0]["stacktrace"])
+	tt.NotNil(t, msgEntries(entries, "stack error")[0]["stacktrace"])
+
+	dir = initTest(t, "stacktrace_level = \"warn\"")
+	Info("stack info")
+	Warn("stack warn")
+	tt.Nil(t, Close())
+
+	entries = readEntries(t, filepath.Join(dir, "*", "test.json"))
+	tt.Nil(t, msgEntries(entries, "stack info")[0]["stacktrace"])
+	tt.NotNil(t, msgEntries(entries, "stack warn")[0]["stacktrace"])
+
+	dir = initTest(t, "stacktrace_level = \"none\"")
+	Error("stack error", errors.New("e1"))
+	tt.Nil(t, Close())
+
+	entries = readEntries(t, filepath.Join(dir, "*", "test_err.json"))
+	tt.Nil(t, msgEntries(entries, "stack error")[0]["stacktrace"])
+
+	err := Init(writeConf(t, "stacktrace_level = \"often\"\n"))
+	tt.NotNil(t, err)
+}
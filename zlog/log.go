@@ -13,185 +13,615 @@ package zlog
 import (
 	// "errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"log"
 	"os"
-	"path/filepath"
 	"strings"
-	"time"
+	"sync"
 
-	"github.com/go-vgo/gt/conf"
+	"go.uber.org/multierr"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
-	"gopkg.in/natefinch/lumberjack.v2"
 )
 
-// Zlog zlog struct
-type Zlog struct {
-	// log.Logger
-}
-
-type logConfig struct {
-	Mode    string
-	Path    string
-	Name    string
-	MaxDays int64 `toml:"max_days"`
-	// Srv  Server     `toml:"server"`
-}
-
 var (
-	logger, errLogger *zap.Logger
-	sugar, errSugar   *zap.SugaredLogger
-	config            logConfig
-
-	zapErr error
 	// ZlogTime zlog time, zapcore.Field
-	ZlogTime = zap.String("time", time.Now().Format("2006-01-02 15:04:05"))
+	//
+	// Deprecated: the time is written by the encoder on every entry,
+	// ZlogTime is a no-op field kept for compatibility.
+	ZlogTime = zap.Skip()
+)
+
+const (
+	// TimeFormat the time format of the log entries
+	TimeFormat = "2006-01-02 15:04:05"
 )
 
-// Init zap log and config
-func Init(tpath string) {
+// Init zap log and config, the config file is toml, yaml or json by
+// its extension, it returns an error if the config file can't be read
+// or decoded, or if the loggers can't be built, the ZLOG_ environment
+// variables override the file like ZLOG_LEVEL, the unknown keys are
+// logged at warn or an error with StrictConfig, see Watch to apply
+// the changes of the config file.
+func Init(tpath string) error {
 	// if _, err := toml.DecodeFile(tpath, &config); err != nil {
 	// 	fmt.Println(err)
 	// 	return
 	// }
-	conf.Init(tpath, &config)
-	go conf.Watch(tpath, &config)
+	cfg, unknown, err := loadConfig(tpath)
+	if err != nil {
+		return err
+	}
 
-	go deleteOldLog()
+	return initConfig(cfg, unknown)
+}
 
-	if config.Mode == "dev" {
-		InitDev()
-		ZlogTime = zap.Error(nil)
-	} else {
-		InitLog()
-		// InitErrLog()
-		go InitErrLog()
-	}
+// InitWithConfig init zap log with the config, the fields
+// not set use the same defaults as Init, the previous config
+// and loggers are kept if it returns an error.
+func InitWithConfig(cfg Config) error {
+	configLock.Lock()
+	defer configLock.Unlock()
+
+	return initWithConfig(cfg)
 }
 
-func deleteOldLog() {
-	fileDir, _ := confPath()
-	var maxDays int64 = 28
+// initWithConfig is InitWithConfig with configLock held
+func initWithConfig(cfg Config) (err error) {
+	config := &cfg
+	prev := *getConfig()
+	// the global fields of the new loggers are the ones of config
+	setConfig(cfg)
+	defer func() {
+		if err != nil {
+			setConfig(prev)
+		}
+	}()
 
-	if config.MaxDays != 0 {
-		maxDays = config.MaxDays
+	if err := config.validate(); err != nil {
+		return err
 	}
 
-	filepath.Walk(fileDir, func(path string, info os.FileInfo, err error) (
-		returnErr error) {
-		defer func() {
-			if r := recover(); r != nil {
-				returnErr = fmt.Errorf("Unable to delete old log '%s', error: %+v",
-					path, r)
-			}
-		}()
+	// validated above
+	interval, _ := config.cleanInterval()
+	lvl, _ := config.modeLevel()
 
-		if info.IsDir() && info.ModTime().Unix() < (time.Now().Unix()-60*60*24*maxDays) {
+	built, err := config.buildAll(level, defaultState)
+	if err != nil {
+		return err
+	}
+	// the state is shared with the old loggers, it's only set once
+	// the new ones are built
+	defaultState.set(config)
+	setLoggers(built)
+	SetLevel(lvl)
+
+	switch config.Mode {
+	case "dev", "stdout", "discard":
+		// no files to sweep
+		StopCleaner()
+		return nil
+	}
 
-			if strings.HasPrefix(filepath.Base(path), filepath.Base(fileDir)) {
-				// if err := os.Remove(path); err != nil {
-				if err := os.RemoveAll(path); err != nil {
-					returnErr = fmt.Errorf("Failed to remove %s: %v", path, err)
-				}
-			}
-		}
-		return returnErr
-	})
+	if !config.dailyDirs() {
+		// lumberjack removes the files after MaxAge
+		StopCleaner()
+		return nil
+	}
+
+	fileDir, _ := config.confPath()
+	startCleaner(fileDir, config.retentionConf(), interval)
+
+	return nil
 }
 
 // InitDev init dev mode
-func InitDev() {
+func InitDev() error {
+	return initMode("dev")
+}
+
+// InitStdout init stdout mode, the entries are written to
+// stdout and the entries of the error log to stderr, no log file
+// is created.
+func InitStdout() error {
+	return initMode("stdout")
+}
+
+// InitDiscard init discard mode, the entries are encoded with
+// the file encoder and dropped, no log file is created, it
+// measures the encoding cost without the I/O in benchmarks.
+func InitDiscard() error {
+	return initMode("discard")
+}
+
+// initMode swaps in the loggers of mode built with the config
+func initMode(mode string) error {
+	cfg := *getConfig()
+	lvl, err := cfg.modeLevel()
+	if err != nil {
+		return err
+	}
+
+	cfg.Mode = mode
+	built, err := cfg.buildAll(level, defaultState)
+	if err != nil {
+		return err
+	}
+
+	setLoggers(built)
+	SetLevel(lvl)
+	return nil
+}
+
+// build builds the loggers of the mode of c at lvl, their cores
+// read the runtime state st
+func (c *Config) build(lvl zap.AtomicLevel, st *state) (*loggers, error) {
+	switch c.Mode {
+	case "dev":
+		return c.devLoggers(lvl, st)
+	case "stdout":
+		return c.stdoutLoggers(lvl, st)
+	case "discard":
+		return c.discardLoggers(lvl, st)
+	}
+	return c.fileLoggers(lvl, st)
+}
+
+// devLoggers builds the logger of the zap development config,
+// it is also the error logger
+func (c *Config) devLoggers(lvl zap.AtomicLevel, st *state) (*loggers, error) {
 	// logger, _ = zap.NewProduction()
 	logCfg := zap.NewDevelopmentConfig()
 	logCfg.Sampling = nil
-	logger, zapErr = logCfg.Build()
-	if zapErr != nil {
-		log.Fatal("zap.NewDevelopmentConfig error: ", zapErr)
+	logCfg.Level = lvl
+	ring, err := c.ringHook(st)
+	if err != nil {
+		return nil, err
 	}
 
-	errLogger = logger
+	logger, err := logCfg.Build(fatalHook, entryHook, coreHook, ring, st.levelHook(),
+		st.filterHook(), st.rewriteHook(), zap.AddCallerSkip(1))
+	if err != nil {
+		log.Println("zap.NewDevelopmentConfig error: ", err)
+		return nil, err
+	}
 
-	defer logger.Sync() // flushes buffer, if any
-	sugar = logger.Sugar()
-	errSugar = sugar
+	return &loggers{logger: logger, errLogger: logger}, nil
 }
 
-func confPath() (string, string) {
-	// var lpath, name string
-	var lpath, name string = "./log", "log"
+// stdoutLoggers builds the loggers of stdout and stderr
+func (c *Config) stdoutLoggers(lvl zap.AtomicLevel, st *state) (*loggers, error) {
+	enc, err := c.stdoutEncoder()
+	if err != nil {
+		return nil, err
+	}
 
-	if config.Path != "" {
-		lpath = config.Path
+	opts, err := c.options(st)
+	if err != nil {
+		return nil, err
 	}
 
-	if config.Name != "" {
-		name = config.Name
+	logger := zap.New(c.sampleCore(c.wrapDedup(zapcore.NewCore(
+		enc,
+		hookWriter{lockFile(os.Stdout)},
+		lvl,
+	))), opts...)
+
+	errLogger := zap.New(c.wrapDedup(zapcore.NewCore(
+		enc,
+		hookWriter{lockFile(os.Stderr)},
+		highPriority,
+	)), opts...)
+
+	return &loggers{logger: logger, errLogger: errLogger}, nil
+}
+
+// discardLoggers builds the loggers encoding the entries
+// with the file encoder and dropping them
+func (c *Config) discardLoggers(lvl zap.AtomicLevel, st *state) (*loggers, error) {
+	enc, err := c.fileEncoder()
+	if err != nil {
+		return nil, err
 	}
 
-	return lpath, name
+	opts, err := c.options(st)
+	if err != nil {
+		return nil, err
+	}
+
+	ws := zapcore.AddSync(ioutil.Discard)
+	logger := zap.New(c.sampleCore(c.wrapDedup(zapcore.NewCore(enc, ws, lvl))), opts...)
+	errLogger := zap.New(c.wrapDedup(zapcore.NewCore(enc, ws, highPriority)), opts...)
+
+	return &loggers{logger: logger, errLogger: errLogger}, nil
 }
 
-// InitLog init log lumberjack
-func InitLog() {
-	lpath, name := confPath()
-	maxDays := 28
-	if config.MaxDays != 0 {
-		maxDays = int(config.MaxDays)
-	}
-
-	logTime := time.Now().Format("2006-01-02")
-	logPath := lpath + "/" + logTime + "/" + name + ".json"
-	ws := zapcore.AddSync(&lumberjack.Logger{
-		Filename:   logPath,
-		MaxSize:    500, // megabytes
-		MaxBackups: 3,
-		MaxAge:     maxDays, // days
+// fileLoggers builds the loggers of the log files, with MirrorErrors
+// the error logger also writes to the main log file
+func (c *Config) fileLoggers(lvl zap.AtomicLevel, st *state) (*loggers, error) {
+	enc, err := c.fileEncoder()
+	if err != nil {
+		return nil, err
+	}
+
+	// the sinks are shared by the loggers, closed with the main logger
+	sinks, sinksCloser, err := c.openSinks(enc, zap.LevelEnablerFunc(func(l zapcore.Level) bool {
+		return lvl.Enabled(l) || highPriority(l)
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	var mainWs logFile
+	if c.mirrorErrors() {
+		lpath, name := c.confPath()
+		ws, err := c.openFile(lpath, name+".json")
+		if err != nil {
+			sinksCloser.Close()
+			return nil, err
+		}
+		mainWs = ws
+	}
+
+	errLogger, errWs, errCloser, err := c.newErrLogger(mainWs, sinks, st)
+	if err != nil {
+		if mainWs != nil {
+			mainWs.Close()
+		}
+		sinksCloser.Close()
+		return nil, err
+	}
+
+	logger, closer, err := c.newLogger(errWs, mainWs, sinks, lvl, st)
+	if err != nil {
+		errCloser.Close()
+		sinksCloser.Close()
+		return nil, err
+	}
+
+	if sinks != nil {
+		closer = &closers{closer, sinksCloser}
+	}
+
+	var access *zap.Logger
+	if c.AccessFile {
+		var accessCloser io.Closer
+		if access, accessCloser, err = c.newAccessLogger(logger, lvl, st); err != nil {
+			closer.Close()
+			errCloser.Close()
+			return nil, err
+		}
+		closer = &closers{closer, accessCloser}
+	}
+
+	if c.TenantFiles {
+		// checked by newLogger
+		enc, _ := c.fileEncoder()
+		tenants := c.newTenantFiles(st)
+		logger = logger.WithOptions(tenants.hook(enc, lvl))
+		errLogger = errLogger.WithOptions(tenants.hook(enc, highPriority))
+		closer = &closers{closer, tenants}
+	}
+	return &loggers{
+		logger: logger, errLogger: errLogger,
+		closer: closer, errCloser: errCloser,
+		access: access,
+	}, nil
+}
+
+// InitNop init the no-op loggers, nothing is encoded or written
+// and the old log sweep is stopped, the log files are closed.
+func InitNop() error {
+	StopCleaner()
+
+	return swap(func(lg *loggers) {
+		*lg = *nopLoggers()
 	})
-	core := zapcore.NewCore(
-		zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()),
-		ws,
-		zap.InfoLevel,
+}
+
+// options returns the options of the loggers, the stacktrace is
+// added from StacktraceLevel, the caller is added with Caller and
+// skips the frame of the package functions, DPanic panics with Strict.
+func (c *Config) options(st *state) ([]zap.Option, error) {
+	ring, err := c.ringHook(st)
+	if err != nil {
+		return nil, err
+	}
+	crash, err := c.crashHook()
+	if err != nil {
+		return nil, err
+	}
+	opts := []zap.Option{fatalHook, entryHook, coreHook, ring, crash,
+		st.levelHook(), st.filterHook(), c.runtimeHook(), st.rewriteHook()}
+
+	stack, ok, err := c.stacktraceLevel()
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		opts = append(opts, zap.AddStacktrace(stack))
+	}
+
+	if c.Caller {
+		opts = append(opts, zap.AddCaller(), zap.AddCallerSkip(1))
+	}
+
+	if c.Strict {
+		opts = append(opts, zap.Development())
+	}
+
+	return opts, nil
+}
+
+// highPriority enables the levels of the error log
+var highPriority = zap.LevelEnablerFunc(func(lvl zapcore.Level) bool {
+	return lvl >= zapcore.ErrorLevel
+})
+
+// InitLog init log lumberjack
+func InitLog() error {
+	config := getConfig()
+	lvl, err := config.logLevel()
+	if err != nil {
+		return err
+	}
+
+	enc, err := config.fileEncoder()
+	if err != nil {
+		return err
+	}
+
+	sinks, sinksCloser, err := config.openSinks(enc, level)
+	if err != nil {
+		return err
+	}
+
+	logger, closer, err := config.newLogger(nil, nil, sinks, level, defaultState)
+	if err != nil {
+		sinksCloser.Close()
+		return err
+	}
+	if sinks != nil {
+		closer = &closers{closer, sinksCloser}
+	}
+
+	setLogger(logger, closer)
+	SetLevel(lvl)
+	return nil
+}
+
+// newLogger builds the main logger of lvl, with SplitLevels the error
+// entries of the main logger are written to errWs if it isn't nil,
+// the main file is ws if it isn't nil, the entries of lvl are also
+// written to the sinks if they aren't nil.
+func (c *Config) newLogger(errWs zapcore.WriteSyncer, ws logFile,
+	sinks zapcore.Core, lvl zap.AtomicLevel, st *state) (*zap.Logger, io.Closer, error) {
+	lpath, name := c.confPath()
+	enc, err := c.fileEncoder()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	stdEnc, err := c.stdoutEncoder()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	opts, err := c.options(st)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		core   zapcore.Core
+		closer io.Closer
 	)
-	// logger = zap.New(core).WithOptions(zap.AddCaller())
-	logger = zap.New(core).WithOptions(zap.AddStacktrace(zap.InfoLevel))
+	if c.SplitLevels {
+		if core, closer, err = c.splitCore(lpath, name, enc, errWs, lvl); err != nil {
+			return nil, nil, err
+		}
+	} else {
+		if ws == nil {
+			if ws, err = c.openFile(lpath, name+".json"); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		core = zapcore.NewCore(
+			enc,
+			hookWriter{ws},
+			lvl,
+		)
+		closer = ws
+	}
+
+	if c.Stdout {
+		core = zapcore.NewTee(core, zapcore.NewCore(
+			stdEnc,
+			hookWriter{lockFile(os.Stdout)},
+			lvl,
+		))
+	}
 
-	defer logger.Sync() // flushes buffer, if any
-	sugar = logger.Sugar()
+	if sinks != nil {
+		core = zapcore.NewTee(core, levelSinks{sinks, lvl})
+	}
+	return zap.New(c.sampleCore(c.wrapDedup(core)), opts...), closer, nil
+}
+
+// openSinks opens the outputs, the syslog, the journal, the remote
+// and the gelf cores of enab, they're opened once for the main and
+// the error logger which tee them with their own level. The core is
+// nil without any of them.
+func (c *Config) openSinks(enc zapcore.Encoder,
+	enab zapcore.LevelEnabler) (zapcore.Core, io.Closer, error) {
+	outs, outsCloser, err := c.openOutputs()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var cores []zapcore.Core
+	cs := closers{outsCloser}
+	for _, ws := range outs {
+		cores = append(cores, zapcore.NewCore(enc, hookWriter{ws}, enab))
+	}
+
+	if c.Syslog.Enabled {
+		sc, sink, err := newSyslogCore(c.Syslog, enc, enab)
+		if err != nil {
+			cs.Close()
+			return nil, nil, err
+		}
+		cores, cs = append(cores, sc), append(cs, sink)
+	}
+
+	if c.Journal {
+		jc, err := newJournalCore(enab)
+		if err != nil {
+			cs.Close()
+			return nil, nil, err
+		}
+		cores, cs = append(cores, jc), append(cs, jc)
+	}
+
+	if c.Remote.Address != "" {
+		// the remote always reads json lines
+		jsonEnc, err := c.newEncoder("json")
+		if err != nil {
+			cs.Close()
+			return nil, nil, err
+		}
+
+		rw, err := newRemoteWriter(c.Remote)
+		if err != nil {
+			cs.Close()
+			return nil, nil, err
+		}
+		cores = append(cores, zapcore.NewCore(jsonEnc, hookWriter{rw}, enab))
+		cs = append(cs, rw)
+	}
+
+	if c.GELF.Address != "" {
+		gw, err := newGELFWriter(c.GELF)
+		if err != nil {
+			cs.Close()
+			return nil, nil, err
+		}
+		cores = append(cores, zapcore.NewCore(c.newGELFEncoder(), hookWriter{gw}, enab))
+		cs = append(cs, gw)
+	}
+
+	if len(cores) == 0 {
+		return nil, &cs, nil
+	}
+	return zapcore.NewTee(cores...), &cs, nil
+}
+
+// levelSinks the sinks of a logger, the entries of the other
+// levels are left to the other logger
+type levelSinks struct {
+	zapcore.Core
+	enab zapcore.LevelEnabler
+}
+
+func (c levelSinks) Enabled(lvl zapcore.Level) bool {
+	return c.enab.Enabled(lvl) && c.Core.Enabled(lvl)
+}
+
+func (c levelSinks) With(fields []zapcore.Field) zapcore.Core {
+	return levelSinks{c.Core.With(fields), c.enab}
+}
+
+func (c levelSinks) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.enab.Enabled(ent.Level) {
+		return c.Core.Check(ent, ce)
+	}
+	return ce
 }
 
 // InitErrLog init error log and lumberjack
-func InitErrLog() {
+func InitErrLog() error {
+	config := getConfig()
+	enc, err := config.fileEncoder()
+	if err != nil {
+		return err
+	}
+
+	sinks, sinksCloser, err := config.openSinks(enc, highPriority)
+	if err != nil {
+		return err
+	}
+
+	errLogger, _, closer, err := config.newErrLogger(nil, sinks, defaultState)
+	if err != nil {
+		sinksCloser.Close()
+		return err
+	}
+	if sinks != nil {
+		closer = &closers{closer, sinksCloser}
+	}
+
+	setErrLogger(errLogger, closer)
+	return nil
+}
+
+// newErrLogger builds the error logger, the entries are also written
+// to mainWs if it isn't nil and to the sinks if they aren't nil, it
+// returns the writer and the closer of the error file.
+func (c *Config) newErrLogger(mainWs zapcore.WriteSyncer,
+	sinks zapcore.Core, st *state) (*zap.Logger, logFile, io.Closer, error) {
 	// lumberjack.Logger is already safe for concurrent use, so we don't need to
 	// lock it.
-	lpath, name := confPath()
-	maxDays := 28
-	if config.MaxDays != 0 {
-		maxDays = int(config.MaxDays)
-	}
-
-	logTime := time.Now().Format("2006-01-02")
-	logPath := lpath + "/" + logTime + "/" + name + "_err.json"
-	ws := zapcore.AddSync(&lumberjack.Logger{
-		Filename:   logPath,
-		MaxSize:    500, // megabytes
-		MaxBackups: 3,
-		MaxAge:     maxDays, // days
-	})
+	lpath, name := c.confPath()
 
-	highPriority := zap.LevelEnablerFunc(func(lvl zapcore.Level) bool {
-		return lvl >= zapcore.ErrorLevel
-	})
+	enc, err := c.fileEncoder()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	stdEnc, err := c.stdoutEncoder()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	opts, err := c.options(st)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	ws, err := c.openFile(lpath, name+"_err.json")
+	if err != nil {
+		return nil, nil, nil, err
+	}
 
 	core := zapcore.NewCore(
-		zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()),
-		ws,
+		enc,
+		hookWriter{ws},
 		// zap.ErrorLevel,
 		highPriority,
 	)
 
-	errLogger = zap.New(core).WithOptions(zap.AddStacktrace(zap.ErrorLevel))
-	defer logger.Sync() // flushes buffer, if any
-	errSugar = errLogger.Sugar()
+	if mainWs != nil {
+		core = zapcore.NewTee(core, zapcore.NewCore(
+			enc,
+			hookWriter{mainWs},
+			highPriority,
+		))
+	}
+
+	if c.Stdout {
+		core = zapcore.NewTee(core, zapcore.NewCore(
+			stdEnc,
+			hookWriter{lockFile(os.Stderr)},
+			highPriority,
+		))
+	}
+
+	if sinks != nil {
+		core = zapcore.NewTee(core, levelSinks{sinks, highPriority})
+	}
+	return zap.New(c.wrapDedup(core), opts...), ws, ws, nil
 }
 
 // Err zap.Error
@@ -219,238 +649,345 @@ func Bool(key string, val bool) zapcore.Field {
 	return zap.Bool(key, val)
 }
 
-// Print fmt.Sprintf
+// Print fmt.Sprintf, a string arg skips the formatting
 func Print(args ...interface{}) string {
 	if len(args) == 0 {
 		return ""
 	}
+
+	if s, ok := args[0].(string); ok {
+		return s + " "
+	}
 	return fmt.Sprintf("%v ", args[0])
 }
 
-// Printf fmt.Sprintf
+// printfLabels the labels of the Printf args
+var printfLabels = []string{
+	"method: %v", "statusCode: %v", "req: %s", "ip: %s", "time: %fs",
+}
+
+// Printf formats the method, statusCode, req, ip and time args,
+// the args after them are appended as they are.
+//
+// Deprecated: use Access, which writes the AccessRecord
+// as fields and the duration as a time.Duration.
 func Printf(args ...interface{}) string {
-	if len(args) < 5 {
-		return ""
+	parts := make([]string, 0, len(args))
+	for i, arg := range args {
+		if i < len(printfLabels) {
+			parts = append(parts, fmt.Sprintf(printfLabels[i], arg))
+			continue
+		}
+		parts = append(parts, fmt.Sprint(arg))
 	}
 
-	return fmt.Sprintf(
-		"method: %v, statusCode: %v, req: %s, ip: %s, time: %fs",
-		args[0],
-		args[1],
-		args[2],
-		args[3],
-		args[4])
+	return strings.Join(parts, ", ")
 }
 
-func (z *Zlog) Error(msg string, err error) {
-	errLogger.Error(msg,
-		ZlogTime,
-		zap.Error(err),
-	)
+// LogInfo info log, the info strings are joined with a space
+func LogInfo(msg string, info ...string) {
+	if ce := getErrLogger().Check(zap.InfoLevel, msg); ce != nil {
+		writeField(ce, zap.String("info", strings.Join(info, " ")))
+	}
 }
 
-// LogInfo info log
-func LogInfo(msg string, info ...string) {
-	var logInfo string
-	if len(info) > 0 {
-		logInfo = info[0]
+// Error error log, the errors are combined into one error field
+func Error(msg string, err ...error) {
+	if ce := getErrLogger().Check(zap.ErrorLevel, msg); ce != nil {
+		writeField(ce, zap.Error(multierr.Combine(err...)))
 	}
+}
 
-	errLogger.Info(msg,
-		ZlogTime,
-		zap.String("info", logInfo),
-	)
+// ErrorF error log with the fields
+func ErrorF(msg string, fields ...zap.Field) {
+	getErrLogger().Error(msg, fields...)
 }
 
-// Error error log
-func Error(msg string, err ...error) {
-	var logErr error
-	if len(err) > 0 {
-		logErr = err[0]
+// ErrorWith error log with the error and the fields,
+// the error key is left out when err is nil.
+//
+//	zlog.ErrorWith("save order", err, zlog.Str("order_id", id))
+func ErrorWith(msg string, err error, fields ...zap.Field) {
+	if ce := getErrLogger().Check(zap.ErrorLevel, msg); ce != nil {
+		writeWithErr(ce, err, fields)
+	}
+}
+
+// WarnWith warn log with the error and the fields
+func WarnWith(msg string, err error, fields ...zap.Field) {
+	if ce := getLogger().Check(zap.WarnLevel, msg); ce != nil {
+		writeWithErr(ce, err, fields)
 	}
-	errLogger.Error(msg,
-		ZlogTime,
-		zap.Error(logErr),
-	)
+}
+
+// FatalWith fatal log with the error and the fields
+func FatalWith(msg string, err error, fields ...zap.Field) {
+	getErrLogger().Fatal(msg, withErr(err, fields)...)
+}
+
+// PanicWith panic log with the error and the fields
+func PanicWith(msg string, err error, fields ...zap.Field) {
+	defer Sync()
+	getErrLogger().Panic(msg, withErr(err, fields)...)
+}
+
+// DPanicWith dpanic log with the error and the fields, like DPanic
+func DPanicWith(msg string, err error, fields ...zap.Field) {
+	defer Sync()
+	if ce := getErrLogger().Check(zap.DPanicLevel, msg); ce != nil {
+		writeWithErr(ce, err, fields)
+	}
+}
+
+// withErr returns the fields with the error field in front
+func withErr(err error, fields []zap.Field) []zap.Field {
+	if err == nil {
+		return fields
+	}
+
+	return append([]zap.Field{zap.Error(err)}, fields...)
+}
+
+// fieldSlices the field slices of the package functions, the cores
+// encode or copy the fields in Write so the slices are reused
+var fieldSlices = sync.Pool{New: func() interface{} {
+	fs := make([]zap.Field, 0, 8)
+	return &fs
+}}
+
+// maxPooledFields the max fields of a slice put back in fieldSlices
+const maxPooledFields = 64
+
+// writeField writes ce with the field f in a pooled slice
+func writeField(ce *zapcore.CheckedEntry, f zap.Field) {
+	p := fieldSlices.Get().(*[]zap.Field)
+	putFields(p, ce, append((*p)[:0], f))
+}
+
+// writeWithErr writes ce with the error field in front of the
+// fields, they're copied to a pooled slice so the slice of the
+// caller doesn't escape
+func writeWithErr(ce *zapcore.CheckedEntry, err error, fields []zap.Field) {
+	p := fieldSlices.Get().(*[]zap.Field)
+	fs := (*p)[:0]
+	if err != nil {
+		fs = append(fs, zap.Error(err))
+	}
+	putFields(p, ce, append(fs, fields...))
+}
+
+// putFields writes ce with fs and puts the slice back in p
+func putFields(p *[]zap.Field, ce *zapcore.CheckedEntry, fs []zap.Field) {
+	ce.Write(fs...)
+	if cap(fs) > maxPooledFields {
+		return
+	}
+
+	for i := range fs {
+		fs[i] = zap.Field{}
+	}
+	*p = fs[:0]
+	fieldSlices.Put(p)
 }
 
 // Errorm more
 func Errorm(msg string, fields ...zapcore.Field) {
-	errLogger.Error(msg,
+	getErrLogger().Error(msg,
 		fields...,
 	)
 }
 
 // SugarErrorm more
 func SugarErrorm(msg string, fields ...zapcore.Field) {
-	errSugar.Error(msg,
+	getErrSugar().Error(msg,
 		fields,
 	)
 }
 
-// Fatal fatal log
+// Fatal fatal log, the OnFatal funcs run and the loggers
+// are flushed before it exits, the log files modes write
+// the crash file lpath/<date>/crash-<time>.json first
 func Fatal(msg string, err ...error) {
-	var logErr error
-	if len(err) > 0 {
-		logErr = err[0]
-	}
-	errLogger.Fatal(msg,
-		ZlogTime,
-		zap.Error(logErr),
+	getErrLogger().Fatal(msg,
+		zap.Error(multierr.Combine(err...)),
 	)
 }
 
-// Panic panic log
+// Panic panic log, the loggers are flushed before it panics,
+// the crash file is written like Fatal
 func Panic(msg string, err ...error) {
-	var logErr error
-	if len(err) > 0 {
-		logErr = err[0]
-	}
-	errLogger.Panic(msg,
-		ZlogTime,
-		zap.Error(logErr),
+	defer Sync()
+	getErrLogger().Panic(msg,
+		zap.Error(multierr.Combine(err...)),
 	)
 }
 
+// DPanic dpanic log for the errors which should never happen, it
+// panics in dev mode and with Strict after the loggers are flushed,
+// the other modes only log it at dpanic.
+func DPanic(msg string, err ...error) {
+	defer Sync()
+	if ce := getErrLogger().Check(zap.DPanicLevel, msg); ce != nil {
+		writeField(ce, zap.Error(multierr.Combine(err...)))
+	}
+}
+
 // LogsError sugar error log
 func LogsError(msg string, err error) {
-	errSugar.Error(msg,
-		ZlogTime,
+	getErrSugar().Error(msg,
 		zap.Error(err),
 	)
 }
 
 // SugarError sugar error log
 func SugarError(msg string, err error) {
-	errSugar.Error(msg,
-		ZlogTime,
+	getErrSugar().Error(msg,
 		zap.Error(err),
 	)
 }
 
 // SugarFatal sugar fatal log
 func SugarFatal(msg string, err error) {
-	errSugar.Fatal(msg,
-		ZlogTime,
+	getErrSugar().Fatal(msg,
 		zap.Error(err),
 	)
 }
 
 // SugarPanic sugar panic log
 func SugarPanic(msg string, err error) {
-	errSugar.Panic(msg,
-		ZlogTime,
+	defer Sync()
+	getErrSugar().Panic(msg,
 		zap.Error(err),
 	)
 }
 
-// Info info log
+// Info info log, the info strings are joined with a space
 func Info(msg string, info ...string) {
-	var logInfo string
-	if len(info) > 0 {
-		logInfo = info[0]
-	}
-	logger.Info(msg,
-		ZlogTime,
-		zap.String("info", logInfo),
-	// fields,
-	)
+	// checked first so a disabled entry doesn't build the field
+	if ce := getLogger().Check(zap.InfoLevel, msg); ce != nil {
+		writeField(ce, zap.String("info", strings.Join(info, " ")))
+	}
+}
+
+// InfoF info log with the fields
+func InfoF(msg string, fields ...zap.Field) {
+	getLogger().Info(msg, fields...)
 }
 
 // Infom more
 func Infom(msg string, fields ...zapcore.Field) {
-	logger.Info(msg, fields...)
+	getLogger().Info(msg, fields...)
 }
 
 // SugarInfom more
 func SugarInfom(msg string, fields ...zapcore.Field) {
-	sugar.Info(msg, fields)
+	getSugar().Info(msg, fields)
 }
 
-// Warn warn log
+// Warn warn log, the warn strings are joined with a space
 func Warn(msg string, warn ...string) {
-	var logWarn string
-	if len(warn) > 0 {
-		logWarn = warn[0]
+	if ce := getLogger().Check(zap.WarnLevel, msg); ce != nil {
+		writeField(ce, zap.String("warn", strings.Join(warn, " ")))
 	}
-	logger.Warn(msg,
-		ZlogTime,
-		zap.String("warn", logWarn),
-	)
 }
 
-// Debug debug log
+// WarnF warn log with the fields
+func WarnF(msg string, fields ...zap.Field) {
+	getLogger().Warn(msg, fields...)
+}
+
+// Debug debug log, the debug strings are joined with a space
 func Debug(msg string, debug ...string) {
-	var logDebug string
-	if len(debug) > 0 {
-		logDebug = debug[0]
+	if ce := getLogger().Check(zap.DebugLevel, msg); ce != nil {
+		writeField(ce, zap.String("debug", strings.Join(debug, " ")))
 	}
-	logger.Debug(msg,
-		ZlogTime,
-		zap.String("debug", logDebug),
-	)
 }
 
-// Infoff info log
+// DebugF debug log with the fields
+func DebugF(msg string, fields ...zap.Field) {
+	getLogger().Debug(msg, fields...)
+}
+
+// Infoff info log with all the fields, it's the same as InfoF
 func Infoff(msg string, fields ...zapcore.Field) {
-	logger.Info(msg,
-		ZlogTime,
-		fields[0],
-	)
+	getLogger().Info(msg, fields...)
 }
 
 // LogError error log
 func LogError(msg string, err error) {
-	logger.Error(msg,
-		ZlogTime,
+	getLogger().Error(msg,
 		zap.Error(err),
 	)
 }
 
 // LogPanic panic log
 func LogPanic(msg string, err error) {
-	logger.Panic(msg,
-		ZlogTime,
+	defer Sync()
+	getLogger().Panic(msg,
 		zap.Error(err),
 	)
 }
 
 // LogFatal fatal log
 func LogFatal(msg string, err error) {
-	logger.Fatal(msg,
-		ZlogTime,
+	getLogger().Fatal(msg,
 		zap.Error(err),
 	)
 }
 
-// Infof infof log
-func Infof(msg, info string) {
-	sugar.Infof(msg,
-		ZlogTime,
-		zap.String("info", info),
-	)
+// Infof info log formatted with fmt.Sprintf.
+//
+// Before, the args were logged as zap fields after the message,
+// the message is now formatted with the args.
+func Infof(format string, args ...interface{}) {
+	getSugar().Infof(format, args...)
 }
 
-// InfoW infow log
-func InfoW(msg, info string) {
-	sugar.Infow(msg,
-		ZlogTime,
-		"info", info,
-	)
+// Warnf warn log formatted with fmt.Sprintf
+func Warnf(format string, args ...interface{}) {
+	getSugar().Warnf(format, args...)
 }
 
-// Errorf errorf log
-func Errorf(msg string, err error) {
-	sugar.Errorf(msg,
-		ZlogTime,
-		zap.Error(err),
-	)
+// Debugf debug log formatted with fmt.Sprintf
+func Debugf(format string, args ...interface{}) {
+	getSugar().Debugf(format, args...)
 }
 
-// Warnf warnf log
-func Warnf(msg, warn string) {
-	sugar.Warnf(msg,
-		ZlogTime,
-		zap.String("warn", warn),
-	)
+// Errorf error log formatted with fmt.Sprintf into the main log as before
+func Errorf(format string, args ...interface{}) {
+	getSugar().Errorf(format, args...)
+}
+
+// Fatalf fatal log formatted with fmt.Sprintf into the error log
+func Fatalf(format string, args ...interface{}) {
+	getErrSugar().Fatalf(format, args...)
+}
+
+// Infow info log with the key value pairs
+//
+//	zlog.Infow("user login", "user_id", id, "ip", ip)
+func Infow(msg string, kvs ...interface{}) {
+	getSugar().Infow(msg, kvs...)
+}
+
+// Warnw warn log with the key value pairs
+func Warnw(msg string, kvs ...interface{}) {
+	getSugar().Warnw(msg, kvs...)
+}
+
+// Debugw debug log with the key value pairs
+func Debugw(msg string, kvs ...interface{}) {
+	getSugar().Debugw(msg, kvs...)
+}
+
+// Errorw error log with the key value pairs into the error log
+func Errorw(msg string, kvs ...interface{}) {
+	getErrSugar().Errorw(msg, kvs...)
+}
+
+// InfoW infow log
+//
+// Deprecated: use Infow.
+func InfoW(msg, info string) {
+	getSugar().Infow(msg, "info", info)
 }
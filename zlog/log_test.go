@@ -0,0 +1,721 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vcaesar/tt"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func writeConf(t *testing.T, content string) string {
+	tpath := filepath.Join(t.TempDir(), "zlog.toml")
+	if err := ioutil.WriteFile(tpath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return tpath
+}
+
+// initTest init the prod mode logger into a temp dir
+// and returns the log path.
+func initTest(t *testing.T, extra ...string) string {
+	dir := t.TempDir()
+	content := "mode = \"prod\"\npath = \"" + filepath.ToSlash(dir) + "\"\nname = \"test\"\n"
+	for _, line := range extra {
+		content += line + "\n"
+	}
+
+	if err := Init(writeConf(t, content)); err != nil {
+		t.Fatal(err)
+	}
+
+	return dir
+}
+
+// readEntries decodes the json entries of the log files matching pattern.
+func readEntries(t *testing.T, pattern string) []map[string]interface{} {
+	files, err := filepath.Glob(pattern)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var entries []map[string]interface{}
+	for _, name := range files {
+		f, err := os.Open(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			entry := make(map[string]interface{})
+			if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+				t.Fatal(err)
+			}
+			entries = append(entries, entry)
+		}
+		f.Close()
+	}
+
+	return entries
+}
+
+// msgEntries returns the entries with one of the msgs
+func msgEntries(entries []map[string]interface{}, msgs ...string) []map[string]interface{} {
+	var res []map[string]interface{}
+	for _, entry := range entries {
+		for _, msg := range msgs {
+			if entry["msg"] == msg {
+				res = append(res, entry)
+			}
+		}
+	}
+
+	return res
+}
+
+// observe swaps an observer core in as the logger and the error
+// logger, the previous loggers are restored by the test cleanup.
+func observe(t *testing.T) *observer.ObservedLogs {
+	core, logs := observer.New(zap.DebugLevel)
+	logger := zap.New(core)
+
+	prev := load()
+	current.Store(&loggers{
+		logger:    logger,
+		errLogger: logger,
+		sugar:     logger.Sugar(),
+		errSugar:  logger.Sugar(),
+	})
+	t.Cleanup(func() { current.Store(prev) })
+
+	return logs
+}
+
+func TestInitMissingFile(t *testing.T) {
+	err := Init(filepath.Join(t.TempDir(), "not_exist.toml"))
+	tt.NotNil(t, err)
+}
+
+func TestInitMalformed(t *testing.T) {
+	tpath := writeConf(t, "mode = \"prod\nname = ")
+	err := Init(tpath)
+	tt.NotNil(t, err)
+}
+
+func TestInit(t *testing.T) {
+	dir := initTest(t)
+
+	Info("init test")
+	Error("init test", errors.New("init error"))
+	getLogger().Sync()
+	getErrLogger().Sync()
+
+	files, err := filepath.Glob(filepath.Join(dir, "*", "test*.json"))
+	tt.Nil(t, err)
+	tt.Equal(t, 2, len(files))
+}
+
+func TestEntryTime(t *testing.T) {
+	dir := initTest(t)
+
+	Info("first")
+	time.Sleep(time.Second)
+	Info("second")
+
+	entries := msgEntries(readEntries(t, filepath.Join(dir, "*", "test.json")),
+		"first", "second")
+	tt.Equal(t, 2, len(entries))
+	tt.NotEqual(t, entries[0]["time"], entries[1]["time"])
+
+	_, err := time.ParseInLocation(TimeFormat, entries[1]["time"].(string), time.Local)
+	tt.Nil(t, err)
+}
+
+func TestNoInit(t *testing.T) {
+	current.Store(nopLoggers())
+	err := errors.New("no init")
+
+	Info("no init")
+	Infom("no init", Str("key", "val"))
+	SugarInfom("no init", Int("key", 1))
+	Infoff("no init", Bool("key", true))
+	Infof("no init %s", "info")
+	InfoW("no init", "info")
+	Infow("no init", "key", 1)
+	Debugf("no init %d", 1)
+	LogInfo("no init", "info")
+	Warn("no init", "warn")
+	Warnf("no init %s", "warn")
+	Debug("no init", "debug")
+	InfoF("no init", Str("key", "val"))
+	WarnF("no init", Str("key", "val"))
+	DebugF("no init", Str("key", "val"))
+	ErrorF("no init", Err(err))
+	Infoff("no init")
+	Error("no init", err)
+	Errorm("no init", Err(err), Any("key", 1))
+	Errorf("no init %v", err)
+	SugarErrorm("no init", Err(err))
+	SugarError("no init", err)
+	LogsError("no init", err)
+	LogError("no init", err)
+
+	z := &Zlog{}
+	z.Error("no init", err)
+
+	tt.Equal(t, "no init ", Print("no init"))
+}
+
+func TestClose(t *testing.T) {
+	dir := initTest(t)
+
+	Info("before close")
+	tt.Nil(t, Close())
+	Info("after close")
+
+	entries := readEntries(t, filepath.Join(dir, "*", "test.json"))
+	tt.Equal(t, 1, len(msgEntries(entries, "before close")))
+	tt.Equal(t, 0, len(msgEntries(entries, "after close")))
+}
+
+func TestLevel(t *testing.T) {
+	dir := initTest(t, "level = \"debug\"")
+	Debug("debug test")
+	tt.Nil(t, Close())
+
+	entries := readEntries(t, filepath.Join(dir, "*", "test.json"))
+	tt.Equal(t, 1, len(msgEntries(entries, "debug test")))
+
+	dir = initTest(t, "level = \"warn\"")
+	Info("info test")
+	Warn("warn test")
+	tt.Nil(t, Close())
+
+	entries = readEntries(t, filepath.Join(dir, "*", "test.json"))
+	tt.Equal(t, 0, len(msgEntries(entries, "info test")))
+	tt.Equal(t, 1, len(msgEntries(entries, "warn test")))
+
+	err := Init(writeConf(t, "mode = \"prod\"\nlevel = \"verbose\"\n"))
+	tt.NotNil(t, err)
+}
+
+func TestInitWithConfig(t *testing.T) {
+	dir := t.TempDir()
+	tt.Nil(t, InitWithConfig(Config{Path: dir, Name: "conf"}))
+	Info("with config")
+	tt.Nil(t, Close())
+
+	entries := readEntries(t, filepath.Join(dir, "*", "conf.json"))
+	tt.Equal(t, 1, len(msgEntries(entries, "with config")))
+
+	// the defaults of the fields not set
+	tt.Nil(t, InitWithConfig(Config{Mode: "dev"}))
+	lpath, name := getConfig().confPath()
+	tt.Equal(t, "./log", lpath)
+	tt.Equal(t, "log", name)
+	tt.Equal(t, int64(28), getConfig().maxDays())
+
+	err := InitWithConfig(Config{Level: "verbose"})
+	tt.NotNil(t, err)
+	tt.Equal(t, "dev", getConfig().Mode)
+	tt.Nil(t, Close())
+}
+
+func TestInitBadPath(t *testing.T) {
+	// the path is a file, the log dirs can't be created
+	file := filepath.Join(t.TempDir(), "file")
+	tt.Nil(t, ioutil.WriteFile(file, nil, 0644))
+	tt.NotNil(t, InitWithConfig(Config{Path: file}))
+	tt.NotNil(t, InitWithConfig(Config{Path: file, SplitLevels: true}))
+
+	tt.NotNil(t, InitWithConfig(Config{Path: t.TempDir(), DirMode: "0999"}))
+
+	if os.Geteuid() == 0 {
+		t.Skip("root can write the read-only dir")
+	}
+
+	parent := t.TempDir()
+	tt.Nil(t, os.Chmod(parent, 0555))
+	defer os.Chmod(parent, 0755)
+	tt.NotNil(t, InitWithConfig(Config{Path: filepath.Join(parent, "log")}))
+}
+
+func TestDirMode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no unix mode on windows")
+	}
+
+	dir := filepath.Join(t.TempDir(), "log") + string(filepath.Separator)
+	tt.Nil(t, InitWithConfig(Config{Path: dir, Name: "test", DirMode: "0750"}))
+	tt.Nil(t, Close())
+
+	fi, err := os.Stat(filepath.Join(dir, time.Now().Format(DayFormat)))
+	tt.Nil(t, err)
+	tt.Equal(t, os.FileMode(0750), fi.Mode().Perm())
+
+	// the created parents have the mode too, not the existing ones
+	root := t.TempDir()
+	tt.Nil(t, os.Chmod(root, 0711))
+	dir = filepath.Join(root, "a", "b", "log")
+	// a mode the umask changes
+	tt.Nil(t, InitWithConfig(Config{Path: dir, Name: "test", DirMode: "0770"}))
+	tt.Nil(t, Close())
+
+	for _, d := range []string{"a", filepath.Join("a", "b"), filepath.Join("a", "b", "log"),
+		filepath.Join("a", "b", "log", time.Now().Format(DayFormat))} {
+		fi, err := os.Stat(filepath.Join(root, d))
+		tt.Nil(t, err)
+		tt.Equal(t, os.FileMode(0770), fi.Mode().Perm(), d)
+	}
+	fi, err = os.Stat(root)
+	tt.Nil(t, err)
+	tt.Equal(t, os.FileMode(0711), fi.Mode().Perm())
+}
+
+func TestFileMode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no unix mode on windows")
+	}
+
+	dir := t.TempDir()
+	tt.Nil(t, InitWithConfig(Config{Path: dir, Name: "test", DirMode: "0700",
+		FileMode: "0640"}))
+	Info("file mode")
+	tt.Nil(t, Rotate())
+	Info("file mode")
+	tt.Nil(t, Close())
+
+	day := filepath.Join(dir, time.Now().Format(DayFormat))
+	fi, err := os.Stat(day)
+	tt.Nil(t, err)
+	tt.Equal(t, os.FileMode(0700), fi.Mode().Perm())
+
+	// test.json, test_err.json and their backups
+	files, err := filepath.Glob(filepath.Join(day, "*.json"))
+	tt.Nil(t, err)
+	tt.Equal(t, 4, len(files))
+	for _, name := range files {
+		fi, err := os.Stat(name)
+		tt.Nil(t, err)
+		tt.Equal(t, os.FileMode(0640), fi.Mode().Perm(), name)
+	}
+
+	tt.NotNil(t, InitWithConfig(Config{Path: dir, FileMode: "rw"}))
+}
+
+func TestMirrorErrors(t *testing.T) {
+	dir := initTest(t)
+	Info("mirror info")
+	Error("mirror error", errors.New("e1"))
+	tt.Nil(t, Close())
+
+	entries := readEntries(t, filepath.Join(dir, "*", "test.json"))
+	mirrored := msgEntries(entries, "mirror info", "mirror error")
+	tt.Equal(t, 2, len(mirrored))
+	tt.Equal(t, "mirror info", mirrored[0]["msg"])
+	tt.Equal(t, "e1", mirrored[1]["error"])
+	entries = readEntries(t, filepath.Join(dir, "*", "test_err.json"))
+	tt.Equal(t, 1, len(msgEntries(entries, "mirror error")))
+
+	dir = initTest(t, "mirror_errors = false")
+	Error("mirror error", errors.New("e1"))
+	tt.Nil(t, Close())
+
+	entries = readEntries(t, filepath.Join(dir, "*", "test.json"))
+	tt.Equal(t, 0, len(msgEntries(entries, "mirror error")))
+	entries = readEntries(t, filepath.Join(dir, "*", "test_err.json"))
+	tt.Equal(t, 1, len(msgEntries(entries, "mirror error")))
+}
+
+func TestDailyDirs(t *testing.T) {
+	dir := initTest(t, "daily_dirs = true")
+	Info("daily dirs")
+	Error("daily dirs", errors.New("e1"))
+	tt.Nil(t, Close())
+
+	entries := readEntries(t, filepath.Join(dir, time.Now().Format(DayFormat), "test.json"))
+	tt.Equal(t, 2, len(msgEntries(entries, "daily dirs")))
+	entries = readEntries(t, filepath.Join(dir, time.Now().Format(DayFormat), "test_err.json"))
+	tt.Equal(t, 1, len(msgEntries(entries, "daily dirs")))
+
+	dir = initTest(t, "daily_dirs = false", "split_levels = true")
+	tt.Nil(t, logClean)
+	Info("flat")
+	Warn("flat")
+	Error("flat", errors.New("e1"))
+	tt.Nil(t, Close())
+
+	for _, name := range []string{"test.json", "test_warn.json", "test_err.json"} {
+		entries := readEntries(t, filepath.Join(dir, name))
+		tt.Equal(t, 1, len(msgEntries(entries, "flat")), name)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*", "*.json"))
+	tt.Nil(t, err)
+	tt.Equal(t, 0, len(files))
+}
+
+func TestFields(t *testing.T) {
+	logs := observe(t)
+
+	InfoF("info", Str("a", "1"), Int("b", 2))
+	WarnF("warn", Bool("c", true))
+	DebugF("debug", Any("d", []int{1}))
+	ErrorF("error", Str("e", "5"), Str("f", "6"))
+	Infoff("infoff", Str("a", "1"), Str("b", "2"), Str("c", "3"))
+	Infoff("empty")
+
+	entries := logs.AllUntimed()
+	tt.Equal(t, 6, len(entries))
+	tt.Equal(t, map[string]interface{}{"a": "1", "b": int64(2)}, entries[0].ContextMap())
+	tt.Equal(t, zap.WarnLevel, entries[1].Level)
+	tt.Equal(t, map[string]interface{}{"c": true}, entries[1].ContextMap())
+	tt.Equal(t, zap.DebugLevel, entries[2].Level)
+	tt.Equal(t, zap.ErrorLevel, entries[3].Level)
+	tt.Equal(t, 2, len(entries[3].Context))
+	tt.Equal(t, map[string]interface{}{"a": "1", "b": "2", "c": "3"}, entries[4].ContextMap())
+	tt.Equal(t, 0, len(entries[5].Context))
+}
+
+func TestJoinStrings(t *testing.T) {
+	logs := observe(t)
+
+	Info("info", "a", "b", "c")
+	Warn("warn", "a", "b")
+	Debug("debug")
+	LogInfo("loginfo", "a", "b")
+	Error("error", errors.New("e1"), errors.New("e2"))
+
+	entries := logs.AllUntimed()
+	tt.Equal(t, 5, len(entries))
+	tt.Equal(t, "a b c", entries[0].ContextMap()["info"])
+	tt.Equal(t, "a b", entries[1].ContextMap()["warn"])
+	tt.Equal(t, "", entries[2].ContextMap()["debug"])
+	tt.Equal(t, "a b", entries[3].ContextMap()["info"])
+	tt.Equal(t, "e1; e2", entries[4].ContextMap()["error"])
+}
+
+// capture replaces *f with a pipe until the returned
+// func is called, which returns the written lines.
+func capture(t *testing.T, f **os.File) func() []string {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prev := *f
+	*f = w
+
+	done := make(chan []string)
+	go func() {
+		var lines []string
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+		done <- lines
+	}()
+
+	return func() []string {
+		*f = prev
+		w.Close()
+		lines := <-done
+		r.Close()
+		return lines
+	}
+}
+
+func TestStdout(t *testing.T) {
+	stdout := capture(t, &os.Stdout)
+	stderr := capture(t, &os.Stderr)
+
+	dir := initTest(t, "stdout = true")
+	Info("tee info")
+	Error("tee error", errors.New("e1"))
+	tt.Nil(t, Close())
+
+	outLines, errLines := stdout(), stderr()
+	entries := readEntries(t, filepath.Join(dir, "*", "test*.json"))
+	tt.Equal(t, 1, len(msgEntries(entries, "tee info")))
+	// and the mirrored error
+	tt.Equal(t, 2, len(msgEntries(entries, "tee error")))
+
+	decode := func(lines []string) []map[string]interface{} {
+		var res []map[string]interface{}
+		for _, line := range lines {
+			entry := make(map[string]interface{})
+			if json.Unmarshal([]byte(line), &entry) == nil {
+				res = append(res, entry)
+			}
+		}
+		return res
+	}
+
+	tt.Equal(t, 1, len(msgEntries(decode(outLines), "tee info")))
+	tt.Equal(t, 0, len(msgEntries(decode(outLines), "tee error")))
+	tt.Equal(t, 1, len(msgEntries(decode(errLines), "tee error")))
+}
+
+func TestStdoutMode(t *testing.T) {
+	stdout := capture(t, &os.Stdout)
+	stderr := capture(t, &os.Stderr)
+
+	dir := t.TempDir()
+	err := Init(writeConf(t, "mode = \"stdout\"\npath = \""+filepath.ToSlash(dir)+
+		"\"\nlevel = \"debug\"\n"))
+	tt.Nil(t, err)
+
+	Debug("stdout debug")
+	Info("stdout info")
+	Error("stdout error", errors.New("e1"))
+	tt.Nil(t, Close())
+
+	outLines, errLines := stdout(), stderr()
+	files, err := ioutil.ReadDir(dir)
+	tt.Nil(t, err)
+	tt.Equal(t, 0, len(files))
+
+	tt.Equal(t, 2, len(outLines))
+	for i, msg := range []string{"stdout debug", "stdout info"} {
+		entry := make(map[string]interface{})
+		tt.Nil(t, json.Unmarshal([]byte(outLines[i]), &entry))
+		tt.Equal(t, msg, entry["msg"])
+	}
+
+	var errEntries []map[string]interface{}
+	for _, line := range errLines {
+		entry := make(map[string]interface{})
+		if json.Unmarshal([]byte(line), &entry) == nil {
+			errEntries = append(errEntries, entry)
+		}
+	}
+	tt.Equal(t, 1, len(msgEntries(errEntries, "stdout error")))
+}
+
+func TestDiscardMode(t *testing.T) {
+	dir := t.TempDir()
+	err := Init(writeConf(t, "mode = \"discard\"\npath = \""+filepath.ToSlash(dir)+"\"\n"))
+	tt.Nil(t, err)
+	defer Close()
+
+	Info("discard info")
+	Error("discard error", errors.New("e1"))
+
+	files, err := ioutil.ReadDir(dir)
+	tt.Nil(t, err)
+	tt.Equal(t, 0, len(files))
+	tt.True(t, L().Core().Enabled(zap.InfoLevel))
+	tt.Nil(t, logClean)
+}
+
+func TestInitNop(t *testing.T) {
+	dir := initTest(t)
+	Info("before nop")
+
+	tt.Nil(t, InitNop())
+	tt.Nil(t, logClean)
+	tt.False(t, L().Core().Enabled(zap.ErrorLevel))
+
+	err := errors.New("nop")
+	allocs := testing.AllocsPerRun(100, func() {
+		Info("nop info", "a", "b")
+		Warn("nop warn")
+		Debug("nop debug")
+		Error("nop error", err)
+	})
+	tt.Equal(t, float64(0), allocs)
+
+	entries := readEntries(t, filepath.Join(dir, "*", "test.json"))
+	tt.Equal(t, 1, len(msgEntries(entries, "before nop")))
+	tt.Equal(t, 0, len(msgEntries(entries, "nop info")))
+}
+
+func BenchmarkNop(b *testing.B) {
+	InitNop()
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		Info("bench info", "a", "b")
+	}
+}
+
+func BenchmarkDiscard(b *testing.B) {
+	tt.Nil(b, InitWithConfig(Config{Mode: "discard"}))
+	defer Close()
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		Info("bench info", "a", "b")
+	}
+}
+
+// benchmarkDiscard runs fn with the discard loggers at level
+func benchmarkDiscard(b *testing.B, level string, fn func()) {
+	tt.Nil(b, InitWithConfig(Config{Mode: "discard", Level: level}))
+	defer Close()
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		fn()
+	}
+}
+
+func TestAllocs(t *testing.T) {
+	tt.Nil(t, InitWithConfig(Config{Mode: "discard", Level: "warn"}))
+	defer Close()
+
+	tt.Equal(t, float64(0), testing.AllocsPerRun(100, func() {
+		Info("disabled info", "a", "b")
+		Debug("disabled debug", "a")
+	}))
+
+	// the pools may miss
+	SetLevel(zap.InfoLevel)
+	tt.True(t, testing.AllocsPerRun(100, func() {
+		Info("enabled info", "a")
+	}) <= 2)
+}
+
+func BenchmarkInfo(b *testing.B) {
+	benchmarkDiscard(b, "info", func() {
+		Info("bench info", "a", "b")
+	})
+}
+
+func BenchmarkInfoDisabledLevel(b *testing.B) {
+	benchmarkDiscard(b, "warn", func() {
+		Info("bench info", "a", "b")
+	})
+}
+
+func BenchmarkErrorWith(b *testing.B) {
+	err := errors.New("e1")
+	benchmarkDiscard(b, "info", func() {
+		ErrorWith("bench error", err, Str("k", "v"))
+	})
+}
+
+func BenchmarkPrint(b *testing.B) {
+	benchmarkDiscard(b, "warn", func() {
+		Info("bench print", Print("a"))
+	})
+}
+
+func TestPrintfFuncs(t *testing.T) {
+	logs, errLogs := observeSplit(t)
+
+	Infof("user %s logged in %d times", "bob", 3)
+	Warnf("disk %d%% full", 90)
+	Debugf("no args")
+	Infow("kv info", "user", "bob", "n", 1)
+	Warnw("kv warn", "k", "v")
+	Debugw("kv debug")
+	Errorw("kv error", "id", 2)
+	Errorf("save user: %v", errors.New("e1"))
+
+	golden := []string{
+		"user bob logged in 3 times",
+		"disk 90% full",
+		"no args",
+		"kv info",
+		"kv warn",
+		"kv debug",
+		"save user: e1",
+	}
+	var msgs []string
+	for _, entry := range logs.AllUntimed() {
+		msgs = append(msgs, entry.Message)
+	}
+	tt.Equal(t, golden, msgs)
+	tt.Equal(t, map[string]interface{}{"user": "bob", "n": int64(1)},
+		logs.FilterMessage("kv info").AllUntimed()[0].ContextMap())
+
+	errEntries := errLogs.AllUntimed()
+	tt.Equal(t, 1, len(errEntries))
+	tt.Equal(t, int64(2), errEntries[0].ContextMap()["id"])
+	tt.Equal(t, 0, len(logs.FilterMessage("save user: e1").AllUntimed()[0].Context))
+}
+
+func TestErrorWith(t *testing.T) {
+	logs, errLogs := observeSplit(t)
+
+	ErrorWith("save order", errors.New("e1"), Str("order_id", "o1"), Int("n", 2))
+	ErrorWith("nil error", nil, Str("order_id", "o2"))
+	WarnWith("retry", errors.New("e2"), Int("attempt", 1))
+	With(Str("request_id", "r1")).ErrorWith("child", nil)
+
+	tt.Equal(t, true, func() (panicked bool) {
+		defer func() { panicked = recover() != nil }()
+		PanicWith("panic with", errors.New("e3"), Str("k", "v"))
+		return
+	}())
+
+	errEntries := errLogs.AllUntimed()
+	tt.Equal(t, 4, len(errEntries))
+	tt.Equal(t, map[string]interface{}{"error": "e1", "order_id": "o1", "n": int64(2)},
+		errEntries[0].ContextMap())
+	tt.Equal(t, map[string]interface{}{"order_id": "o2"}, errEntries[1].ContextMap())
+	tt.Equal(t, map[string]interface{}{"request_id": "r1"}, errEntries[2].ContextMap())
+	tt.Equal(t, zap.PanicLevel, errEntries[3].Level)
+	tt.Equal(t, "e3", errEntries[3].ContextMap()["error"])
+
+	entries := logs.AllUntimed()
+	tt.Equal(t, 1, len(entries))
+	tt.Equal(t, zap.WarnLevel, entries[0].Level)
+	tt.Equal(t, map[string]interface{}{"error": "e2", "attempt": int64(1)},
+		entries[0].ContextMap())
+}
+
+// logHelper a helper wrapping zlog, its caller is logged
+func logHelper(msg string) {
+	WithCallerSkip(1).Info(msg)
+}
+
+func TestCaller(t *testing.T) {
+	dir := initTest(t, "caller = true")
+
+	Info("caller info")
+	Infof("caller %s", "infof")
+	With(Str("k", "v")).Warn("caller with")
+	logHelper("caller helper")
+	Error("caller error", errors.New("e1"))
+	tt.Nil(t, Close())
+
+	entries := readEntries(t, filepath.Join(dir, "*", "test*.json"))
+	msgs := []string{"caller info", "caller infof", "caller with", "caller helper", "caller error"}
+	// and the mirrored error
+	tt.Equal(t, len(msgs)+1, len(msgEntries(entries, msgs...)))
+	for _, entry := range msgEntries(entries, msgs...) {
+		tt.True(t, strings.HasPrefix(entry["caller"].(string), "zlog/log_test.go:"))
+	}
+
+	dir = initTest(t)
+	Info("no caller")
+	tt.Nil(t, Close())
+
+	entries = msgEntries(readEntries(t, filepath.Join(dir, "*", "test.json")), "no caller")
+	tt.Equal(t, 1, len(entries))
+	tt.Nil(t, entries[0]["caller"])
+}
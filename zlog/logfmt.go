@@ -0,0 +1,549 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"math"
+	"strconv"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+const hexDigits = "0123456789abcdef"
+
+var logfmtBufs = buffer.NewPool()
+
+// logfmtEncoder encodes the entries as logfmt lines like
+// time="2018-05-01 08:30:00" level=info msg="handle request" k=v,
+// the objects have dotted keys like user.id=1 and the arrays
+// indexed keys like tags.0=a.
+type logfmtEncoder struct {
+	cfg *zapcore.EncoderConfig
+	buf *buffer.Buffer
+	// prefix the dotted prefix of the keys of the objects
+	// and OpenNamespace
+	prefix string
+}
+
+func newLogfmtEncoder(cfg zapcore.EncoderConfig) zapcore.Encoder {
+	return &logfmtEncoder{cfg: &cfg, buf: logfmtBufs.Get()}
+}
+
+func (e *logfmtEncoder) Clone() zapcore.Encoder {
+	clone := &logfmtEncoder{cfg: e.cfg, buf: logfmtBufs.Get(), prefix: e.prefix}
+	clone.buf.Write(e.buf.Bytes())
+	return clone
+}
+
+func (e *logfmtEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	final := &logfmtEncoder{cfg: e.cfg, buf: logfmtBufs.Get()}
+	cfg := e.cfg
+
+	if cfg.TimeKey != "" && cfg.EncodeTime != nil {
+		final.AddTime(cfg.TimeKey, ent.Time)
+	}
+	if cfg.LevelKey != "" && cfg.EncodeLevel != nil {
+		final.key(cfg.LevelKey)
+		cfg.EncodeLevel(ent.Level, &logfmtValue{e: final})
+	}
+	if ent.LoggerName != "" && cfg.NameKey != "" {
+		final.key(cfg.NameKey)
+		encodeName := cfg.EncodeName
+		if encodeName == nil {
+			encodeName = zapcore.FullNameEncoder
+		}
+		encodeName(ent.LoggerName, &logfmtValue{e: final})
+	}
+	if ent.Caller.Defined {
+		if cfg.CallerKey != "" && cfg.EncodeCaller != nil {
+			final.key(cfg.CallerKey)
+			cfg.EncodeCaller(ent.Caller, &logfmtValue{e: final})
+		}
+		if cfg.FunctionKey != "" {
+			final.AddString(cfg.FunctionKey, ent.Caller.Function)
+		}
+	}
+	if cfg.MessageKey != "" {
+		final.AddString(cfg.MessageKey, ent.Message)
+	}
+
+	if e.buf.Len() > 0 {
+		if final.buf.Len() > 0 {
+			final.buf.AppendByte(' ')
+		}
+		final.buf.Write(e.buf.Bytes())
+	}
+
+	final.prefix = e.prefix
+	for _, f := range fields {
+		f.AddTo(final)
+	}
+	final.prefix = ""
+
+	if ent.Stack != "" && cfg.StacktraceKey != "" {
+		final.AddString(cfg.StacktraceKey, ent.Stack)
+	}
+
+	if cfg.LineEnding != "" {
+		final.buf.AppendString(cfg.LineEnding)
+	} else {
+		final.buf.AppendString(zapcore.DefaultLineEnding)
+	}
+	return final.buf, nil
+}
+
+// key writes the key of the next value, the spaces, the '=',
+// the quotes and the control chars of key are replaced by '_'
+func (e *logfmtEncoder) key(key string) {
+	if e.buf.Len() > 0 {
+		e.buf.AppendByte(' ')
+	}
+
+	if e.prefix == "" && key == "" {
+		e.buf.AppendByte('_')
+	}
+	e.appendKey(e.prefix)
+	e.appendKey(key)
+	e.buf.AppendByte('=')
+}
+
+func (e *logfmtEncoder) appendKey(s string) {
+	for i, r := range s {
+		if r <= ' ' || r == '=' || r == '"' || r == utf8.RuneError || !unicode.IsPrint(r) {
+			e.buf.AppendByte('_')
+			continue
+		}
+		e.buf.AppendString(s[i : i+utf8.RuneLen(r)])
+	}
+}
+
+// child returns the encoder of the fields of the object key
+func (e *logfmtEncoder) child(key string) *logfmtEncoder {
+	return &logfmtEncoder{cfg: e.cfg, buf: e.buf, prefix: e.prefix + key + "."}
+}
+
+func (e *logfmtEncoder) OpenNamespace(key string) {
+	e.prefix += key + "."
+}
+
+func (e *logfmtEncoder) AddObject(key string, m zapcore.ObjectMarshaler) error {
+	return m.MarshalLogObject(e.child(key))
+}
+
+func (e *logfmtEncoder) AddArray(key string, m zapcore.ArrayMarshaler) error {
+	return m.MarshalLogArray(&logfmtArray{e: e, key: key})
+}
+
+func (e *logfmtEncoder) AddReflected(key string, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	e.key(key)
+	e.appendString(string(b))
+	return nil
+}
+
+func (e *logfmtEncoder) AddBinary(key string, v []byte) {
+	e.AddString(key, base64.StdEncoding.EncodeToString(v))
+}
+
+func (e *logfmtEncoder) AddByteString(key string, v []byte) {
+	e.key(key)
+	e.appendString(string(v))
+}
+
+func (e *logfmtEncoder) AddBool(key string, v bool) {
+	e.key(key)
+	e.buf.AppendBool(v)
+}
+
+func (e *logfmtEncoder) AddComplex128(key string, v complex128) {
+	e.key(key)
+	e.appendComplex(v, 64)
+}
+
+func (e *logfmtEncoder) AddComplex64(key string, v complex64) {
+	e.key(key)
+	e.appendComplex(complex128(v), 32)
+}
+
+func (e *logfmtEncoder) AddDuration(key string, v time.Duration) {
+	e.key(key)
+	encode := e.cfg.EncodeDuration
+	if encode == nil {
+		encode = zapcore.StringDurationEncoder
+	}
+	encode(v, &logfmtValue{e: e})
+}
+
+func (e *logfmtEncoder) AddFloat64(key string, v float64) {
+	e.key(key)
+	e.appendFloat(v, 64)
+}
+
+func (e *logfmtEncoder) AddFloat32(key string, v float32) {
+	e.key(key)
+	e.appendFloat(float64(v), 32)
+}
+
+func (e *logfmtEncoder) AddInt(key string, v int) {
+	e.AddInt64(key, int64(v))
+}
+
+func (e *logfmtEncoder) AddInt64(key string, v int64) {
+	e.key(key)
+	e.buf.AppendInt(v)
+}
+
+func (e *logfmtEncoder) AddInt32(key string, v int32) {
+	e.AddInt64(key, int64(v))
+}
+
+func (e *logfmtEncoder) AddInt16(key string, v int16) {
+	e.AddInt64(key, int64(v))
+}
+
+func (e *logfmtEncoder) AddInt8(key string, v int8) {
+	e.AddInt64(key, int64(v))
+}
+
+func (e *logfmtEncoder) AddString(key, v string) {
+	e.key(key)
+	e.appendString(v)
+}
+
+func (e *logfmtEncoder) AddTime(key string, v time.Time) {
+	e.key(key)
+	encode := e.cfg.EncodeTime
+	if encode == nil {
+		encode = zapcore.RFC3339NanoTimeEncoder
+	}
+	encode(v, &logfmtValue{e: e})
+}
+
+func (e *logfmtEncoder) AddUint(key string, v uint) {
+	e.AddUint64(key, uint64(v))
+}
+
+func (e *logfmtEncoder) AddUint64(key string, v uint64) {
+	e.key(key)
+	e.buf.AppendUint(v)
+}
+
+func (e *logfmtEncoder) AddUint32(key string, v uint32) {
+	e.AddUint64(key, uint64(v))
+}
+
+func (e *logfmtEncoder) AddUint16(key string, v uint16) {
+	e.AddUint64(key, uint64(v))
+}
+
+func (e *logfmtEncoder) AddUint8(key string, v uint8) {
+	e.AddUint64(key, uint64(v))
+}
+
+func (e *logfmtEncoder) AddUintptr(key string, v uintptr) {
+	e.AddUint64(key, uint64(v))
+}
+
+func (e *logfmtEncoder) appendFloat(v float64, bits int) {
+	switch {
+	case math.IsNaN(v):
+		e.buf.AppendString("NaN")
+	case math.IsInf(v, 1):
+		e.buf.AppendString("+Inf")
+	case math.IsInf(v, -1):
+		e.buf.AppendString("-Inf")
+	default:
+		e.buf.AppendFloat(v, bits)
+	}
+}
+
+// appendComplex writes v like 1+2i
+func (e *logfmtEncoder) appendComplex(v complex128, bits int) {
+	r, i := real(v), imag(v)
+	e.buf.AppendFloat(r, bits)
+	if i >= 0 {
+		e.buf.AppendByte('+')
+	}
+	e.buf.AppendFloat(i, bits)
+	e.buf.AppendByte('i')
+}
+
+// appendString writes s, quoted if it is empty or has the spaces,
+// the '=', the quotes, the non printable chars or the invalid utf8
+func (e *logfmtEncoder) appendString(s string) {
+	if !logfmtQuote(s) {
+		e.buf.AppendString(s)
+		return
+	}
+
+	e.buf.AppendByte('"')
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c >= utf8.RuneSelf {
+			r, size := utf8.DecodeRuneInString(s[i:])
+			if r == utf8.RuneError && size == 1 {
+				e.buf.AppendString(`�`)
+			} else {
+				e.buf.AppendString(s[i : i+size])
+			}
+			i += size
+			continue
+		}
+
+		switch c {
+		case '"', '\\':
+			e.buf.AppendByte('\\')
+			e.buf.AppendByte(c)
+		case '\n':
+			e.buf.AppendString(`\n`)
+		case '\r':
+			e.buf.AppendString(`\r`)
+		case '\t':
+			e.buf.AppendString(`\t`)
+		default:
+			if c < ' ' || c == 0x7f {
+				e.buf.AppendString(`\u00`)
+				e.buf.AppendByte(hexDigits[c>>4])
+				e.buf.AppendByte(hexDigits[c&0xf])
+			} else {
+				e.buf.AppendByte(c)
+			}
+		}
+		i++
+	}
+	e.buf.AppendByte('"')
+}
+
+func logfmtQuote(s string) bool {
+	if s == "" {
+		return true
+	}
+
+	for _, r := range s {
+		if r <= ' ' || r == '=' || r == '"' || r == '\\' ||
+			r == utf8.RuneError || !unicode.IsPrint(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// logfmtArray writes the elements of an array with the keys
+// key.0, key.1...
+type logfmtArray struct {
+	e   *logfmtEncoder
+	key string
+	i   int
+}
+
+// next returns the key of the next element
+func (a *logfmtArray) next() string {
+	key := a.key + "." + strconv.Itoa(a.i)
+	a.i++
+	return key
+}
+
+func (a *logfmtArray) AppendArray(m zapcore.ArrayMarshaler) error {
+	return m.MarshalLogArray(&logfmtArray{e: a.e, key: a.next()})
+}
+
+func (a *logfmtArray) AppendObject(m zapcore.ObjectMarshaler) error {
+	return m.MarshalLogObject(a.e.child(a.next()))
+}
+
+func (a *logfmtArray) AppendReflected(v interface{}) error {
+	return a.e.AddReflected(a.next(), v)
+}
+
+func (a *logfmtArray) AppendBool(v bool) {
+	a.e.AddBool(a.next(), v)
+}
+
+func (a *logfmtArray) AppendByteString(v []byte) {
+	a.e.AddByteString(a.next(), v)
+}
+
+func (a *logfmtArray) AppendComplex128(v complex128) {
+	a.e.AddComplex128(a.next(), v)
+}
+
+func (a *logfmtArray) AppendComplex64(v complex64) {
+	a.e.AddComplex64(a.next(), v)
+}
+
+func (a *logfmtArray) AppendDuration(v time.Duration) {
+	a.e.AddDuration(a.next(), v)
+}
+
+func (a *logfmtArray) AppendFloat64(v float64) {
+	a.e.AddFloat64(a.next(), v)
+}
+
+func (a *logfmtArray) AppendFloat32(v float32) {
+	a.e.AddFloat32(a.next(), v)
+}
+
+func (a *logfmtArray) AppendInt(v int) {
+	a.e.AddInt64(a.next(), int64(v))
+}
+
+func (a *logfmtArray) AppendInt64(v int64) {
+	a.e.AddInt64(a.next(), v)
+}
+
+func (a *logfmtArray) AppendInt32(v int32) {
+	a.e.AddInt64(a.next(), int64(v))
+}
+
+func (a *logfmtArray) AppendInt16(v int16) {
+	a.e.AddInt64(a.next(), int64(v))
+}
+
+func (a *logfmtArray) AppendInt8(v int8) {
+	a.e.AddInt64(a.next(), int64(v))
+}
+
+func (a *logfmtArray) AppendString(v string) {
+	a.e.AddString(a.next(), v)
+}
+
+func (a *logfmtArray) AppendTime(v time.Time) {
+	a.e.AddTime(a.next(), v)
+}
+
+func (a *logfmtArray) AppendUint(v uint) {
+	a.e.AddUint64(a.next(), uint64(v))
+}
+
+func (a *logfmtArray) AppendUint64(v uint64) {
+	a.e.AddUint64(a.next(), v)
+}
+
+func (a *logfmtArray) AppendUint32(v uint32) {
+	a.e.AddUint64(a.next(), uint64(v))
+}
+
+func (a *logfmtArray) AppendUint16(v uint16) {
+	a.e.AddUint64(a.next(), uint64(v))
+}
+
+func (a *logfmtArray) AppendUint8(v uint8) {
+	a.e.AddUint64(a.next(), uint64(v))
+}
+
+func (a *logfmtArray) AppendUintptr(v uintptr) {
+	a.e.AddUint64(a.next(), uint64(v))
+}
+
+// logfmtValue writes the value of a key for the time, level, name,
+// caller and duration encoders of the config, the values appended
+// after the first one are joined with ','
+type logfmtValue struct {
+	e *logfmtEncoder
+	n int
+}
+
+func (v *logfmtValue) sep() {
+	if v.n > 0 {
+		v.e.buf.AppendByte(',')
+	}
+	v.n++
+}
+
+func (v *logfmtValue) AppendBool(b bool) {
+	v.sep()
+	v.e.buf.AppendBool(b)
+}
+
+func (v *logfmtValue) AppendByteString(b []byte) {
+	v.sep()
+	v.e.appendString(string(b))
+}
+
+func (v *logfmtValue) AppendComplex128(c complex128) {
+	v.sep()
+	v.e.appendComplex(c, 64)
+}
+
+func (v *logfmtValue) AppendComplex64(c complex64) {
+	v.sep()
+	v.e.appendComplex(complex128(c), 32)
+}
+
+func (v *logfmtValue) AppendFloat64(f float64) {
+	v.sep()
+	v.e.appendFloat(f, 64)
+}
+
+func (v *logfmtValue) AppendFloat32(f float32) {
+	v.sep()
+	v.e.appendFloat(float64(f), 32)
+}
+
+func (v *logfmtValue) AppendInt(i int) {
+	v.AppendInt64(int64(i))
+}
+
+func (v *logfmtValue) AppendInt64(i int64) {
+	v.sep()
+	v.e.buf.AppendInt(i)
+}
+
+func (v *logfmtValue) AppendInt32(i int32) {
+	v.AppendInt64(int64(i))
+}
+
+func (v *logfmtValue) AppendInt16(i int16) {
+	v.AppendInt64(int64(i))
+}
+
+func (v *logfmtValue) AppendInt8(i int8) {
+	v.AppendInt64(int64(i))
+}
+
+func (v *logfmtValue) AppendString(s string) {
+	v.sep()
+	v.e.appendString(s)
+}
+
+func (v *logfmtValue) AppendUint(i uint) {
+	v.AppendUint64(uint64(i))
+}
+
+func (v *logfmtValue) AppendUint64(i uint64) {
+	v.sep()
+	v.e.buf.AppendUint(i)
+}
+
+func (v *logfmtValue) AppendUint32(i uint32) {
+	v.AppendUint64(uint64(i))
+}
+
+func (v *logfmtValue) AppendUint16(i uint16) {
+	v.AppendUint64(uint64(i))
+}
+
+func (v *logfmtValue) AppendUint8(i uint8) {
+	v.AppendUint64(uint64(i))
+}
+
+func (v *logfmtValue) AppendUintptr(i uintptr) {
+	v.AppendUint64(uint64(i))
+}
@@ -0,0 +1,170 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-logfmt/logfmt"
+	"github.com/vcaesar/tt"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// decodeLogfmt decodes the logfmt lines of b, the values of
+// the keys without a value are empty
+func decodeLogfmt(t *testing.T, b []byte) []map[string]string {
+	var lines []map[string]string
+	dec := logfmt.NewDecoder(bytes.NewReader(b))
+	for dec.ScanRecord() {
+		line := make(map[string]string)
+		for dec.ScanKeyval() {
+			_, dup := line[string(dec.Key())]
+			tt.False(t, dup, string(dec.Key()))
+			line[string(dec.Key())] = string(dec.Value())
+		}
+		lines = append(lines, line)
+	}
+	if err := dec.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return lines
+}
+
+type logfmtUser struct {
+	id   int
+	tags []string
+}
+
+func (u logfmtUser) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddInt("id", u.id)
+	return enc.AddArray("tags", zapcore.ArrayMarshalerFunc(func(arr zapcore.ArrayEncoder) error {
+		for _, tag := range u.tags {
+			arr.AppendString(tag)
+		}
+		return nil
+	}))
+}
+
+func TestLogfmtRoundTrip(t *testing.T) {
+	enc, err := (&Config{}).newEncoder("logfmt")
+	tt.Nil(t, err)
+	zap.String("request_id", "r 1").AddTo(enc)
+
+	tm := time.Date(2018, 5, 1, 8, 30, 0, 0, time.UTC)
+	ent := zapcore.Entry{Level: zapcore.ErrorLevel, Time: tm, LoggerName: "db",
+		Message: `say "hi"`, Stack: "main.f\n\tmain.go:1"}
+	users := zapcore.ArrayMarshalerFunc(func(arr zapcore.ArrayEncoder) error {
+		arr.AppendObject(logfmtUser{id: 2})
+		arr.AppendInt(3)
+		return nil
+	})
+
+	buf, err := enc.Clone().EncodeEntry(ent, []zapcore.Field{
+		zap.String("space", "a b"),
+		zap.String("quote", `a"b`),
+		zap.String("newline", "a\nb\r\tc"),
+		zap.String("eq", "a=b"),
+		zap.String("empty", ""),
+		zap.String("backslash", `a\b`),
+		zap.String("unicode", "héllo 日本"),
+		zap.String("control", "a\x01b"),
+		zap.String("invalid", "a\xffb"),
+		zap.String("bad key", "v"),
+		zap.Int("int", -3),
+		zap.Uint8("uint", 4),
+		zap.Float64("float", 1.5),
+		zap.Bool("bool", true),
+		zap.Duration("duration", 1500*time.Millisecond),
+		zap.Complex128("complex", complex(1, -2)),
+		zap.Error(errors.New("read: eof")),
+		zap.Binary("binary", []byte("hi")),
+		zap.Object("user", logfmtUser{id: 1, tags: []string{"a", "b c"}}),
+		zap.Array("users", users),
+		zap.Strings("strs", []string{"x"}),
+		zap.Reflect("reflect", map[string]int{"n": 1}),
+		zap.Namespace("ns"),
+		zap.String("k", "v"),
+	})
+	tt.Nil(t, err)
+	tt.True(t, strings.HasSuffix(buf.String(), "\n"))
+	tt.Equal(t, 1, strings.Count(buf.String(), "\n"))
+
+	lines := decodeLogfmt(t, buf.Bytes())
+	tt.Equal(t, 1, len(lines))
+	tt.Equal(t, map[string]string{
+		"time":        tm.Format(TimeFormat),
+		"level":       "error",
+		"logger":      "db",
+		"msg":         `say "hi"`,
+		"request_id":  "r 1",
+		"space":       "a b",
+		"quote":       `a"b`,
+		"newline":     "a\nb\r\tc",
+		"eq":          "a=b",
+		"empty":       "",
+		"backslash":   `a\b`,
+		"unicode":     "héllo 日本",
+		"control":     "a\x01b",
+		"invalid":     "a�b",
+		"bad_key":     "v",
+		"int":         "-3",
+		"uint":        "4",
+		"float":       "1.5",
+		"bool":        "true",
+		"duration":    "1.5",
+		"complex":     "1-2i",
+		"error":       "read: eof",
+		"binary":      "aGk=",
+		"user.id":     "1",
+		"user.tags.0": "a",
+		"user.tags.1": "b c",
+		"users.0.id":  "2",
+		"users.1":     "3",
+		"strs.0":      "x",
+		"reflect":     `{"n":1}`,
+		"ns.k":        "v",
+		"stacktrace":  "main.f\n\tmain.go:1",
+	}, lines[0])
+}
+
+func TestLogfmt(t *testing.T) {
+	dir := initTest(t, `encoding = "logfmt"`, "[encoder]", `time_format = "rfc3339"`)
+	With(zap.String("svc", "api")).Info("logfmt info", "a b")
+	Error("logfmt error", errors.New("e1"))
+	tt.Nil(t, Close())
+
+	files, err := filepath.Glob(filepath.Join(dir, "*", "test.json"))
+	tt.Nil(t, err)
+	tt.Equal(t, 1, len(files))
+
+	var n int
+	for _, line := range decodeLogfmt(t, []byte(strings.Join(readLines(t, files[0]), "\n"))) {
+		switch line["msg"] {
+		case "logfmt info":
+			tt.Equal(t, "a b", line["info"])
+			tt.Equal(t, "api", line["svc"])
+			_, err := time.Parse(time.RFC3339, line["time"])
+			tt.Nil(t, err)
+			n++
+		case "logfmt error":
+			tt.Equal(t, "e1", line["error"])
+			tt.NotEqual(t, "", line["stacktrace"])
+			n++
+		}
+	}
+	tt.Equal(t, 2, n)
+}
@@ -0,0 +1,271 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"syscall"
+
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// loggers holds the loggers used by the package functions,
+// it is replaced as a whole so readers never see a half init.
+type loggers struct {
+	logger, errLogger *zap.Logger
+	sugar, errSugar   *zap.SugaredLogger
+
+	// closer and errCloser close the files of the loggers
+	closer, errCloser io.Closer
+
+	// audit the logger of Audit, auditCloser closes its file
+	audit       *zap.Logger
+	auditCloser io.Closer
+
+	// access the logger of the access file, closed with closer
+	access *zap.Logger
+
+	// base, errBase, auditBase and accessBase the loggers without
+	// the global fields
+	base, errBase, auditBase, accessBase *zap.Logger
+}
+
+var (
+	current  atomic.Value // *loggers
+	swapLock sync.Mutex
+)
+
+func init() {
+	current.Store(nopLoggers())
+}
+
+// nopLogger the logger of the package functions before Init
+var nopLogger = zap.NewNop()
+
+// nopLoggers returns the no-op loggers used before Init
+func nopLoggers() *loggers {
+	nop := nopLogger
+	return &loggers{
+		logger:    nop,
+		errLogger: nop,
+		sugar:     nop.Sugar(),
+		errSugar:  nop.Sugar(),
+		audit:     nop,
+	}
+}
+
+func load() *loggers {
+	return current.Load().(*loggers)
+}
+
+func getLogger() *zap.Logger {
+	return load().logger
+}
+
+func getErrLogger() *zap.Logger {
+	return load().errLogger
+}
+
+func getSugar() *zap.SugaredLogger {
+	return load().sugar
+}
+
+func getErrSugar() *zap.SugaredLogger {
+	return load().errSugar
+}
+
+// L returns the live main logger, a no-op logger before Init,
+// call it again after a re-Init to get the new logger.
+//
+//	db.SetLogger(zlog.L())
+func L() *zap.Logger {
+	return getLogger()
+}
+
+// ErrL returns the live error logger, a no-op logger before Init
+func ErrL() *zap.Logger {
+	return getErrLogger()
+}
+
+// S returns the live sugared main logger, a no-op logger before Init
+func S() *zap.SugaredLogger {
+	return getSugar()
+}
+
+// ErrS returns the live sugared error logger, a no-op logger before Init
+func ErrS() *zap.SugaredLogger {
+	return getErrSugar()
+}
+
+// swap copies the current loggers, applies fn to the loggers without
+// the global fields, adds them back and stores the result, the files
+// which are no longer used are closed.
+func swap(fn func(lg *loggers)) error {
+	swapLock.Lock()
+	old := load()
+	lg := *old
+	if lg.base != nil {
+		lg.logger, lg.errLogger, lg.audit = lg.base, lg.errBase, lg.auditBase
+		lg.access = lg.accessBase
+	}
+	fn(&lg)
+	lg.addGlobalFields()
+	current.Store(&lg)
+	swapLock.Unlock()
+
+	var err error
+	if old.closer != nil && old.closer != lg.closer {
+		err = multierr.Append(err, old.closer.Close())
+	}
+
+	if old.errCloser != nil && old.errCloser != lg.errCloser {
+		err = multierr.Append(err, old.errCloser.Close())
+	}
+
+	if old.auditCloser != nil && old.auditCloser != lg.auditCloser {
+		err = multierr.Append(err, old.auditCloser.Close())
+	}
+	return err
+}
+
+// addGlobalFields keeps the loggers as the bases and adds the
+// global fields to them, the no-op loggers stay no-op
+func (lg *loggers) addGlobalFields() {
+	lg.base, lg.errBase, lg.auditBase = lg.logger, lg.errLogger, lg.audit
+	lg.accessBase = lg.access
+
+	if fields := globalFields(); len(fields) > 0 {
+		lg.audit = withFields(lg.audit, fields)
+		lg.access = withFields(lg.access, fields)
+		same := lg.errLogger == lg.logger
+		lg.logger = withFields(lg.logger, fields)
+		if same {
+			lg.errLogger = lg.logger
+		} else {
+			lg.errLogger = withFields(lg.errLogger, fields)
+		}
+	}
+
+	lg.sugar, lg.errSugar = lg.logger.Sugar(), lg.errLogger.Sugar()
+}
+
+func withFields(l *zap.Logger, fields []zap.Field) *zap.Logger {
+	if l == nil || l == nopLogger {
+		return l
+	}
+	return l.With(fields...)
+}
+
+// setLoggers swaps in the loggers and the closers of built
+func setLoggers(built *loggers) {
+	swap(func(lg *loggers) {
+		lg.logger, lg.closer = built.logger, built.closer
+		lg.errLogger, lg.errCloser = built.errLogger, built.errCloser
+		lg.audit, lg.auditCloser = built.audit, built.auditCloser
+		lg.access = built.access
+	})
+}
+
+func setLogger(l *zap.Logger, c io.Closer) {
+	swap(func(lg *loggers) {
+		// the access file is closed with the closer
+		lg.logger, lg.sugar, lg.closer, lg.access = l, l.Sugar(), c, nil
+	})
+}
+
+func setErrLogger(l *zap.Logger, c io.Closer) {
+	swap(func(lg *loggers) {
+		lg.errLogger, lg.errSugar, lg.errCloser = l, l.Sugar(), c
+	})
+}
+
+// Sync flushes the buffered entries of the logger, the error
+// logger and the access file and syncs the audit file
+func Sync() error {
+	lg := load()
+	err := syncErr(lg.logger.Sync())
+	if lg.errLogger != lg.logger {
+		err = multierr.Append(err, syncErr(lg.errLogger.Sync()))
+	}
+
+	if lg.audit != nil {
+		err = multierr.Append(err, syncErr(lg.audit.Sync()))
+	}
+
+	if lg.access != nil {
+		err = multierr.Append(err, syncErr(lg.access.Sync()))
+	}
+	return err
+}
+
+// syncErr drops the error of syncing stdout or stderr,
+// a terminal or a pipe can't be synced on most systems.
+func syncErr(err error) error {
+	if errors.Is(err, syscall.EINVAL) || errors.Is(err, syscall.ENOTTY) {
+		return nil
+	}
+	return err
+}
+
+// Close flushes the loggers, stops the old log sweep and the
+// heartbeats, closes the log files and the loggers of InitMulti,
+// the package functions do nothing after Close.
+//
+//	defer zlog.Close()
+func Close() error {
+	StopCleaner()
+	stopHeartbeats()
+
+	err := Sync()
+	err = multierr.Append(err, resetProfiles())
+	return multierr.Append(err, swap(func(lg *loggers) {
+		*lg = *nopLoggers()
+	}))
+}
+
+var (
+	// fatalHook runs the OnFatal funcs and flushes
+	// the loggers before the Fatal entry exits
+	fatalHook = zap.WithFatalHook(exitHook{})
+
+	fatalLock  sync.Mutex
+	fatalFuncs []func()
+)
+
+// OnFatal registers fn to run after a Fatal entry is written and
+// before the process exits, like flushing the metrics or traces.
+func OnFatal(fn func()) {
+	fatalLock.Lock()
+	fatalFuncs = append(fatalFuncs, fn)
+	fatalLock.Unlock()
+}
+
+type exitHook struct{}
+
+func (exitHook) OnWrite(ce *zapcore.CheckedEntry, fields []zapcore.Field) {
+	fatalLock.Lock()
+	funcs := fatalFuncs
+	fatalLock.Unlock()
+
+	for _, fn := range funcs {
+		fn()
+	}
+
+	Sync()
+	syncInstances()
+	os.Exit(1)
+}
@@ -0,0 +1,129 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/vcaesar/tt"
+	"go.uber.org/zap"
+)
+
+func TestAccessors(t *testing.T) {
+	current.Store(nopLoggers())
+	for _, l := range []*zap.Logger{L(), ErrL(), S().Desugar(), ErrS().Desugar()} {
+		tt.NotNil(t, l)
+		tt.False(t, l.Core().Enabled(zap.ErrorLevel))
+	}
+
+	tt.Nil(t, InitWithConfig(Config{Path: t.TempDir()}))
+	first, firstErr := L(), ErrL()
+	tt.True(t, first.Core().Enabled(zap.InfoLevel))
+	tt.False(t, firstErr.Core().Enabled(zap.InfoLevel))
+	tt.True(t, firstErr.Core().Enabled(zap.ErrorLevel))
+	tt.True(t, S().Desugar().Core() == first.Core())
+	tt.True(t, ErrS().Desugar().Core() == firstErr.Core())
+
+	tt.Nil(t, InitWithConfig(Config{Path: t.TempDir()}))
+	tt.False(t, L() == first)
+	tt.False(t, ErrL() == firstErr)
+	tt.Nil(t, Close())
+}
+
+// TestFatal runs itself in a subprocess which logs a Fatal entry
+func TestFatal(t *testing.T) {
+	if dir := os.Getenv("ZLOG_FATAL_DIR"); dir != "" {
+		cfg := Config{Path: dir, Name: "fatal",
+			Async: os.Getenv("ZLOG_FATAL_ASYNC") != "", FlushInterval: "1h"}
+		if os.Getenv("ZLOG_FATAL_BUFFER") != "" {
+			cfg.BufferSize = 1 << 20
+		}
+		if err := InitWithConfig(cfg); err != nil {
+			os.Exit(2)
+		}
+
+		OnFatal(func() {
+			Info("on fatal")
+		})
+		Info("before fatal")
+		Fatal("fatal", errors.New("e1"))
+		os.Exit(3)
+	}
+
+	// the queue of Async and the buffer are written before the exit
+	for _, env := range []string{"", "ZLOG_FATAL_ASYNC=1", "ZLOG_FATAL_BUFFER=1"} {
+		testFatal(t, env)
+	}
+}
+
+func testFatal(t *testing.T, env string) {
+	dir := t.TempDir()
+	cmd := exec.Command(os.Args[0], "-test.run=^TestFatal$")
+	cmd.Env = append(os.Environ(), "ZLOG_FATAL_DIR="+dir, env)
+	err := cmd.Run()
+
+	var exitErr *exec.ExitError
+	tt.True(t, errors.As(err, &exitErr))
+	tt.Equal(t, 1, exitErr.ExitCode())
+
+	entries := readEntries(t, filepath.Join(dir, "*", "fatal.json"))
+	tt.Equal(t, 1, len(msgEntries(entries, "before fatal")))
+	tt.Equal(t, 1, len(msgEntries(entries, "on fatal")))
+
+	errEntries := readEntries(t, filepath.Join(dir, "*", "fatal_err.json"))
+	tt.Equal(t, 1, len(msgEntries(errEntries, "fatal")))
+}
+
+func TestPanicSync(t *testing.T) {
+	logs, errLogs := observeSplit(t)
+
+	tt.Equal(t, true, func() (panicked bool) {
+		defer func() { panicked = recover() != nil }()
+		Info("before panic")
+		Panic("panic", errors.New("e1"))
+		return
+	}())
+
+	tt.Equal(t, 1, logs.FilterMessage("before panic").Len())
+	tt.Equal(t, 1, errLogs.FilterMessage("panic").Len())
+}
+
+// dpanics reports whether DPanic panics
+func dpanics() (panicked bool) {
+	defer func() { panicked = recover() != nil }()
+	DPanic("dpanic", errors.New("e1"))
+	return
+}
+
+func TestDPanic(t *testing.T) {
+	dir := initTest(t)
+	tt.False(t, dpanics())
+	DPanicWith("dpanic with", errors.New("e2"), zap.Int("n", 1))
+	tt.Nil(t, Close())
+
+	entries := readEntries(t, filepath.Join(dir, "*", "test_err.json"))
+	dpanic := msgEntries(entries, "dpanic", "dpanic with")
+	tt.Equal(t, 2, len(dpanic))
+	tt.Equal(t, "dpanic", dpanic[0]["level"])
+	tt.Equal(t, "e1", dpanic[0]["error"])
+	tt.Equal(t, float64(1), dpanic[1]["n"])
+
+	initTest(t, "strict = true")
+	tt.True(t, dpanics())
+	tt.Nil(t, Close())
+
+	tt.Nil(t, InitDev())
+	tt.True(t, dpanics())
+}
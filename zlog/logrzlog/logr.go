@@ -0,0 +1,87 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+// Package logrzlog a logr sink logging through zlog,
+// it is a separate package so zlog doesn't depend on logr.
+package logrzlog
+
+import (
+	"fmt"
+
+	"github.com/go-logr/logr"
+	"github.com/go-vgo/gt/zlog"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// sink the logr.LogSink of zlog
+type sink struct {
+	z *zlog.Zlog
+}
+
+// Logr returns a logr logger logging through zlog, the V levels
+// log at debug and Error logs into the error log.
+//
+//	ctrl.SetLogger(logrzlog.Logr())
+func Logr() logr.Logger {
+	return logr.New(&sink{z: &zlog.Zlog{}})
+}
+
+// level maps the V level to the zap level
+func level(v int) zapcore.Level {
+	if v > 0 {
+		return zapcore.DebugLevel
+	}
+	return zapcore.InfoLevel
+}
+
+func (s *sink) Init(logr.RuntimeInfo) {}
+
+func (s *sink) Enabled(v int) bool {
+	return zlog.GetLevel().Enabled(level(v))
+}
+
+func (s *sink) Info(v int, msg string, kvs ...interface{}) {
+	if level(v) == zapcore.DebugLevel {
+		s.z.DebugF(msg, fields(kvs)...)
+		return
+	}
+	s.z.InfoF(msg, fields(kvs)...)
+}
+
+func (s *sink) Error(err error, msg string, kvs ...interface{}) {
+	s.z.ErrorF(msg, append(fields(kvs), zap.Error(err))...)
+}
+
+func (s *sink) WithValues(kvs ...interface{}) logr.LogSink {
+	return &sink{z: s.z.With(fields(kvs)...)}
+}
+
+func (s *sink) WithName(name string) logr.LogSink {
+	return &sink{z: s.z.Named(name)}
+}
+
+// fields returns the fields of the key value pairs,
+// the key without a value is kept as the "ignored key" field.
+func fields(kvs []interface{}) []zap.Field {
+	res := make([]zap.Field, 0, len(kvs)/2+1)
+	for i := 0; i+1 < len(kvs); i += 2 {
+		key, ok := kvs[i].(string)
+		if !ok {
+			key = fmt.Sprint(kvs[i])
+		}
+		res = append(res, zap.Any(key, kvs[i+1]))
+	}
+
+	if len(kvs)%2 == 1 {
+		res = append(res, zap.Any("ignored key", kvs[len(kvs)-1]))
+	}
+	return res
+}
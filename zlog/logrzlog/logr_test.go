@@ -0,0 +1,104 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package logrzlog
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-vgo/gt/zlog"
+	"github.com/vcaesar/tt"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// observe adds the observer core of all the entries to zlog
+func observe(t *testing.T, level string) *observer.ObservedLogs {
+	cfg := zlog.Config{Path: t.TempDir(), Name: "logr", Level: level}
+	if err := zlog.InitWithConfig(cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	core, logs := observer.New(zap.DebugLevel)
+	h := zlog.AddCore(core)
+	t.Cleanup(func() {
+		h.Remove()
+		zlog.Close()
+	})
+
+	return logs
+}
+
+func TestLogr(t *testing.T) {
+	logs := observe(t, "debug")
+
+	logger := Logr().WithName("ctrl").WithValues("app", "gt")
+	logger.Info("logr info", "n", 1)
+	logger.V(1).Info("logr debug", "odd")
+	logger.V(3).Info("logr v3")
+	logger.WithName("reconcile").Error(errors.New("e1"), "logr error", "id", "x")
+
+	entries := logs.AllUntimed()
+	tt.Equal(t, 4, len(entries))
+
+	tt.Equal(t, zap.InfoLevel, entries[0].Level)
+	tt.Equal(t, "logr info", entries[0].Message)
+	tt.Equal(t, "ctrl", entries[0].LoggerName)
+	tt.Equal(t, map[string]interface{}{"app": "gt", "n": int64(1)}, entries[0].ContextMap())
+
+	tt.Equal(t, zap.DebugLevel, entries[1].Level)
+	tt.Equal(t, map[string]interface{}{"app": "gt", "ignored key": "odd"}, entries[1].ContextMap())
+	tt.Equal(t, zap.DebugLevel, entries[2].Level)
+	tt.Equal(t, "logr v3", entries[2].Message)
+
+	tt.Equal(t, zap.ErrorLevel, entries[3].Level)
+	tt.Equal(t, "ctrl.reconcile", entries[3].LoggerName)
+	tt.Equal(t, map[string]interface{}{"app": "gt", "id": "x", "error": "e1"},
+		entries[3].ContextMap())
+}
+
+func TestLogrValues(t *testing.T) {
+	logs := observe(t, "debug")
+
+	parent := Logr().WithValues("a", 1)
+	child := parent.WithValues("b", 2).WithName("child")
+	child.Info("child")
+	parent.Info("parent")
+
+	entries := logs.AllUntimed()
+	tt.Equal(t, 2, len(entries))
+	tt.Equal(t, "child", entries[0].LoggerName)
+	tt.Equal(t, map[string]interface{}{"a": int64(1), "b": int64(2)}, entries[0].ContextMap())
+	// the values and the name of the child don't leak into the parent
+	tt.Equal(t, "", entries[1].LoggerName)
+	tt.Equal(t, map[string]interface{}{"a": int64(1)}, entries[1].ContextMap())
+}
+
+func TestLogrLevel(t *testing.T) {
+	logs := observe(t, "info")
+
+	logger := Logr()
+	tt.True(t, logger.V(0).Enabled())
+	tt.False(t, logger.V(1).Enabled())
+	logger.V(1).Info("dropped")
+	logger.Info("kept")
+
+	zlog.SetLevel(zap.WarnLevel)
+	tt.False(t, logger.V(0).Enabled())
+	logger.Info("dropped")
+	// the errors are logged at any V level
+	logger.V(2).Error(errors.New("e1"), "failed")
+
+	entries := logs.AllUntimed()
+	tt.Equal(t, 2, len(entries))
+	tt.Equal(t, "kept", entries[0].Message)
+	tt.Equal(t, zap.ErrorLevel, entries[1].Level)
+}
@@ -0,0 +1,377 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+// Package lokizlog a zapcore.Core pushing the entries to the
+// grafana loki push api, it is added to zlog by zlog.AddCore.
+package lokizlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// PushPath the path of the loki push api
+const PushPath = "/loki/api/v1/push"
+
+const (
+	defaultBatchSize     = 100
+	defaultFlushInterval = time.Second
+	defaultMaxRetries    = 3
+	defaultBackoff       = 500 * time.Millisecond
+	defaultTimeout       = 10 * time.Second
+	// maxBatches the batches kept while loki can't be reached
+	maxBatches = 10
+)
+
+// Config the loki output
+type Config struct {
+	// URL the base url of loki like http://loki:3100
+	URL string
+	// Labels the labels of the streams like app and env
+	Labels map[string]string
+	// LevelLabel adds the level as the label "level", by default
+	// it is sent as the structured metadata of the entries
+	LevelLabel bool
+	// Level the enabled levels, default info
+	Level zapcore.LevelEnabler
+	// BatchSize the entries of a push, default 100
+	BatchSize int
+	// FlushInterval the max wait of an entry before the push,
+	// default 1s
+	FlushInterval time.Duration
+	// MaxRetries the retries of a failed push before its entries
+	// are dropped, default 3
+	MaxRetries int
+	// Backoff the first wait before a retry, it doubles on each
+	// retry, default 500ms
+	Backoff time.Duration
+	// Username and Password the basic auth of the pushes
+	Username, Password string
+	// Client the http client, default a client with a 10s timeout
+	Client *http.Client
+}
+
+type entry struct {
+	ts    time.Time
+	level zapcore.Level
+	line  string
+}
+
+// batcher the entries shared by a Core and its With copies
+type batcher struct {
+	cfg Config
+	url string
+
+	mu      sync.Mutex
+	pending []entry
+
+	// pushLock serializes the pushes of Sync and the flush goroutine
+	pushLock sync.Mutex
+
+	kick       chan struct{}
+	stop, done chan struct{}
+	once       sync.Once
+	closed     bool
+
+	dropped, failures int64
+}
+
+// Core a zapcore.Core batching the entries of the loggers and
+// pushing them to loki in the background
+type Core struct {
+	zapcore.LevelEnabler
+	enc zapcore.Encoder
+	b   *batcher
+}
+
+// New returns the core of cfg and starts its flush goroutine,
+// call Close to push the last entries.
+//
+//	core, err := lokizlog.New(lokizlog.Config{URL: "http://loki:3100",
+//		Labels: map[string]string{"app": "api"}})
+//	zlog.AddCore(core)
+//	defer core.Close()
+func New(cfg Config) (*Core, error) {
+	if cfg.URL == "" {
+		return nil, errors.New("lokizlog: the url is empty")
+	}
+
+	if cfg.Level == nil {
+		cfg.Level = zapcore.InfoLevel
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaultBatchSize
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = defaultFlushInterval
+	}
+	if cfg.MaxRetries < 0 {
+		cfg.MaxRetries = 0
+	} else if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = defaultMaxRetries
+	}
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = defaultBackoff
+	}
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: defaultTimeout}
+	}
+
+	b := &batcher{
+		cfg:  cfg,
+		url:  strings.TrimSuffix(cfg.URL, "/") + PushPath,
+		kick: make(chan struct{}, 1),
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	go b.run()
+
+	encCfg := zap.NewProductionEncoderConfig()
+	encCfg.EncodeTime = zapcore.ISO8601TimeEncoder
+	encCfg.LineEnding = ""
+
+	return &Core{
+		LevelEnabler: cfg.Level,
+		enc:          zapcore.NewJSONEncoder(encCfg),
+		b:            b,
+	}, nil
+}
+
+// With adds the fields to the lines of the returned core
+func (c *Core) With(fields []zapcore.Field) zapcore.Core {
+	enc := c.enc.Clone()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	return &Core{LevelEnabler: c.LevelEnabler, enc: enc, b: c.b}
+}
+
+// Check adds the core to ce if the level is enabled
+func (c *Core) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write encodes the entry and queues it for the next push
+func (c *Core) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+
+	c.b.add(entry{ts: ent.Time, level: ent.Level, line: buf.String()})
+	buf.Free()
+	return nil
+}
+
+// Sync pushes the queued entries
+func (c *Core) Sync() error {
+	return c.b.flush()
+}
+
+// Close pushes the queued entries and stops the flush goroutine,
+// the entries written after Close are ignored.
+func (c *Core) Close() error {
+	c.b.once.Do(func() {
+		c.b.mu.Lock()
+		c.b.closed = true
+		c.b.mu.Unlock()
+		close(c.b.stop)
+	})
+	<-c.b.done
+	return c.b.flush()
+}
+
+// Dropped returns the number of the entries dropped after the
+// retries of their push or because too many entries were queued
+func (c *Core) Dropped() int64 {
+	return atomic.LoadInt64(&c.b.dropped)
+}
+
+// Failures returns the number of the failed pushes
+func (c *Core) Failures() int64 {
+	return atomic.LoadInt64(&c.b.failures)
+}
+
+func (b *batcher) add(e entry) {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return
+	}
+	if len(b.pending) >= b.cfg.BatchSize*maxBatches {
+		b.mu.Unlock()
+		atomic.AddInt64(&b.dropped, 1)
+		return
+	}
+
+	b.pending = append(b.pending, e)
+	full := len(b.pending) >= b.cfg.BatchSize
+	b.mu.Unlock()
+
+	if full {
+		select {
+		case b.kick <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (b *batcher) run() {
+	defer close(b.done)
+
+	ticker := time.NewTicker(b.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-b.kick:
+		case <-b.stop:
+			return
+		}
+		b.flush()
+	}
+}
+
+// flush pushes the queued entries by batches
+func (b *batcher) flush() error {
+	b.pushLock.Lock()
+	defer b.pushLock.Unlock()
+
+	var err error
+	for {
+		b.mu.Lock()
+		n := len(b.pending)
+		if n > b.cfg.BatchSize {
+			n = b.cfg.BatchSize
+		}
+		batch := b.pending[:n:n]
+		b.pending = b.pending[n:]
+		b.mu.Unlock()
+
+		if len(batch) == 0 {
+			return err
+		}
+
+		if pushErr := b.pushRetry(batch); pushErr != nil {
+			atomic.AddInt64(&b.dropped, int64(len(batch)))
+			err = pushErr
+		}
+	}
+}
+
+// pushRetry pushes batch and retries with backoff on the errors
+// which may pass later
+func (b *batcher) pushRetry(batch []entry) error {
+	backoff := b.cfg.Backoff
+	for i := 0; ; i++ {
+		retry, err := b.push(batch)
+		if err == nil {
+			return nil
+		}
+
+		atomic.AddInt64(&b.failures, 1)
+		if !retry || i >= b.cfg.MaxRetries {
+			return err
+		}
+
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+type stream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][]interface{}   `json:"values"`
+}
+
+type pushRequest struct {
+	Streams []stream `json:"streams"`
+}
+
+// payload returns the push request of batch, the entries are
+// grouped by level if the level is a label
+func (b *batcher) payload(batch []entry) pushRequest {
+	var req pushRequest
+	index := make(map[string]int)
+
+	for _, e := range batch {
+		key, labels := "", b.cfg.Labels
+		if b.cfg.LevelLabel {
+			key = e.level.String()
+			labels = make(map[string]string, len(b.cfg.Labels)+1)
+			for k, v := range b.cfg.Labels {
+				labels[k] = v
+			}
+			labels["level"] = key
+		}
+
+		i, ok := index[key]
+		if !ok {
+			if labels == nil {
+				labels = map[string]string{}
+			}
+			i = len(req.Streams)
+			index[key] = i
+			req.Streams = append(req.Streams, stream{Stream: labels})
+		}
+
+		value := []interface{}{strconv.FormatInt(e.ts.UnixNano(), 10), e.line}
+		if !b.cfg.LevelLabel {
+			value = append(value, map[string]string{"level": e.level.String()})
+		}
+		req.Streams[i].Values = append(req.Streams[i].Values, value)
+	}
+
+	return req
+}
+
+func (b *batcher) push(batch []entry) (retry bool, err error) {
+	body, err := json.Marshal(b.payload(batch))
+	if err != nil {
+		return false, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, b.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if b.cfg.Username != "" {
+		req.SetBasicAuth(b.cfg.Username, b.cfg.Password)
+	}
+
+	resp, err := b.cfg.Client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode/100 == 2 {
+		return false, nil
+	}
+
+	err = fmt.Errorf("lokizlog: push status %s", resp.Status)
+	return resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests, err
+}
@@ -0,0 +1,221 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package lokizlog
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-vgo/gt/zlog"
+	"github.com/vcaesar/tt"
+	"go.uber.org/zap"
+)
+
+// fakeLoki records the push requests, status returns the
+// status of the nth request
+type fakeLoki struct {
+	mu     sync.Mutex
+	reqs   []pushRequest
+	calls  int
+	status func(n int) int
+}
+
+func (f *fakeLoki) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls++
+	if f.status != nil {
+		if code := f.status(f.calls); code != http.StatusNoContent {
+			w.WriteHeader(code)
+			return
+		}
+	}
+
+	user, pass, _ := r.BasicAuth()
+	if r.URL.Path != PushPath || user != "user" || pass != "pass" ||
+		r.Header.Get("Content-Type") != "application/json" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	var req pushRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	f.reqs = append(f.reqs, req)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (f *fakeLoki) requests() []pushRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]pushRequest(nil), f.reqs...)
+}
+
+func newLoki(t *testing.T, f *fakeLoki, cfg Config) *Core {
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+
+	cfg.URL = srv.URL + "/"
+	cfg.Username, cfg.Password = "user", "pass"
+	core, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return core
+}
+
+func TestBatch(t *testing.T) {
+	f := &fakeLoki{}
+	core := newLoki(t, f, Config{
+		Labels:    map[string]string{"app": "api", "env": "test"},
+		BatchSize: 2, FlushInterval: time.Hour,
+	})
+
+	logger := zap.New(core).With(zap.String("request_id", "r1"))
+	for i := 0; i < 5; i++ {
+		logger.Info("loki info", zap.Int("n", i))
+	}
+	logger.Debug("loki debug")
+	tt.Nil(t, core.Close())
+
+	reqs := f.requests()
+	tt.True(t, len(reqs) >= 3)
+
+	total := 0
+	for _, req := range reqs {
+		tt.Equal(t, 1, len(req.Streams))
+		tt.Equal(t, map[string]string{"app": "api", "env": "test"}, req.Streams[0].Stream)
+
+		values := req.Streams[0].Values
+		tt.True(t, len(values) <= 2)
+		for _, value := range values {
+			tt.Equal(t, 3, len(value))
+			_, isTs := value[0].(string)
+			tt.True(t, isTs)
+
+			line := make(map[string]interface{})
+			tt.Nil(t, json.Unmarshal([]byte(value[1].(string)), &line))
+			tt.Equal(t, "loki info", line["msg"])
+			tt.Equal(t, "r1", line["request_id"])
+			tt.Equal(t, map[string]interface{}{"level": "info"}, value[2])
+		}
+		total += len(values)
+	}
+	tt.Equal(t, 5, total)
+	tt.Equal(t, int64(0), core.Dropped())
+}
+
+func TestLevelLabel(t *testing.T) {
+	f := &fakeLoki{}
+	core := newLoki(t, f, Config{
+		Labels: map[string]string{"app": "api"}, LevelLabel: true,
+		Level: zap.DebugLevel, FlushInterval: time.Hour,
+	})
+
+	logger := zap.New(core)
+	logger.Debug("loki debug")
+	logger.Error("loki error")
+	logger.Error("loki error")
+	tt.Nil(t, logger.Sync())
+
+	reqs := f.requests()
+	tt.Equal(t, 1, len(reqs))
+	tt.Equal(t, 2, len(reqs[0].Streams))
+
+	for _, s := range reqs[0].Streams {
+		tt.Equal(t, "api", s.Stream["app"])
+		want := map[string]int{"debug": 1, "error": 2}[s.Stream["level"]]
+		tt.Equal(t, want, len(s.Values))
+		tt.Equal(t, 2, len(s.Values[0]))
+	}
+	tt.Nil(t, core.Close())
+}
+
+func TestRetry(t *testing.T) {
+	// the pushes pass after 2 failures
+	f := &fakeLoki{status: func(n int) int {
+		if n <= 2 {
+			return http.StatusServiceUnavailable
+		}
+		return http.StatusNoContent
+	}}
+	core := newLoki(t, f, Config{FlushInterval: time.Hour, Backoff: time.Millisecond})
+	zap.New(core).Info("loki retry")
+	tt.Nil(t, core.Close())
+	tt.Equal(t, 1, len(f.requests()))
+	tt.Equal(t, int64(2), core.Failures())
+	tt.Equal(t, int64(0), core.Dropped())
+
+	// the entries are dropped after the retries
+	f = &fakeLoki{status: func(int) int { return http.StatusInternalServerError }}
+	core = newLoki(t, f, Config{FlushInterval: time.Hour, Backoff: time.Millisecond,
+		MaxRetries: 1})
+	zap.New(core).Info("loki drop")
+	zap.New(core).Info("loki drop")
+	tt.NotNil(t, core.Close())
+	tt.Equal(t, int64(2), core.Failures())
+	tt.Equal(t, int64(2), core.Dropped())
+
+	// a bad request isn't retried
+	f = &fakeLoki{status: func(int) int { return http.StatusBadRequest }}
+	core = newLoki(t, f, Config{FlushInterval: time.Hour, Backoff: time.Millisecond})
+	zap.New(core).Info("loki bad")
+	tt.NotNil(t, core.Close())
+	tt.Equal(t, int64(1), core.Failures())
+}
+
+func TestFlushInterval(t *testing.T) {
+	f := &fakeLoki{}
+	core := newLoki(t, f, Config{FlushInterval: 10 * time.Millisecond})
+	defer core.Close()
+
+	zap.New(core).Info("loki tick")
+	for i := 0; i < 500 && len(f.requests()) == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	tt.Equal(t, 1, len(f.requests()))
+}
+
+func TestAddCore(t *testing.T) {
+	f := &fakeLoki{}
+	core := newLoki(t, f, Config{FlushInterval: time.Hour})
+	defer core.Close()
+
+	tt.Nil(t, zlog.InitWithConfig(zlog.Config{Path: t.TempDir()}))
+	zlog.AddCore(core)
+	zlog.Info("via zlog")
+	zlog.Error("via zlog", errors.New("e1"))
+	tt.Nil(t, zlog.Close())
+
+	var lines []string
+	for _, req := range f.requests() {
+		for _, s := range req.Streams {
+			for _, value := range s.Values {
+				if line := value[1].(string); strings.Contains(line, `"msg":"via zlog"`) {
+					lines = append(lines, line)
+				}
+			}
+		}
+	}
+	tt.Equal(t, 2, len(lines))
+
+	_, err := New(Config{})
+	tt.NotNil(t, err)
+}
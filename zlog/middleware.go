@@ -0,0 +1,251 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// statusWriter records the status and the written bytes of a response
+type statusWriter struct {
+	http.ResponseWriter
+
+	status int
+	bytes  int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += n
+	return n, err
+}
+
+// Flush flushes the response if the wrapped writer is a http.Flusher,
+// for the streamed responses
+func (w *statusWriter) Flush() {
+	f, ok := w.ResponseWriter.(http.Flusher)
+	if !ok {
+		return
+	}
+
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	f.Flush()
+}
+
+// Hijack hijacks the connection if the wrapped writer is a
+// http.Hijacker, for the websockets
+func (w *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("zlog: the response writer can't be hijacked")
+	}
+
+	conn, rw, err := h.Hijack()
+	if err == nil && w.status == 0 {
+		w.status = http.StatusSwitchingProtocols
+	}
+	return conn, rw, err
+}
+
+// ReadFrom copies r with the io.ReaderFrom of the wrapped writer, or
+// with Write without it
+func (w *statusWriter) ReadFrom(r io.Reader) (int64, error) {
+	rf, ok := w.ResponseWriter.(io.ReaderFrom)
+	if !ok {
+		// the struct hides ReadFrom from io.Copy
+		return io.Copy(struct{ io.Writer }{w}, r)
+	}
+
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := rf.ReadFrom(r)
+	w.bytes += int(n)
+	return n, err
+}
+
+// Unwrap returns the wrapped writer for http.ResponseController
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// HTTPMiddleware logs the AccessRecord of every request like
+// AccessAt, the entries of 4xx are logged at warn and 5xx at error.
+// A panic of next is recovered, logged with the stack and the
+// response is a 500. The entries have the fields of NewContext
+// of the request context, the 5xx also go to the error log with
+// the access file.
+//
+//	http.ListenAndServe(":8080", zlog.HTTPMiddleware(mux))
+func HTTPMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: w}
+		_, errLogger := FromContext(r.Context()).get()
+
+		defer func() {
+			if rec := recover(); rec != nil {
+				if rec == http.ErrAbortHandler {
+					panic(rec)
+				}
+
+				errLogger.Error("http panic",
+					zap.Any("panic", rec),
+					zap.String("path", r.URL.Path),
+					zap.Stack("stack"),
+				)
+
+				if sw.status == 0 {
+					sw.WriteHeader(http.StatusInternalServerError)
+				} else {
+					sw.status = http.StatusInternalServerError
+				}
+			}
+
+			logRequest(r, sw, time.Since(start))
+		}()
+
+		next.ServeHTTP(sw, r)
+	})
+}
+
+func logRequest(r *http.Request, sw *statusWriter, d time.Duration) {
+	status := sw.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	rec := AccessRecord{
+		Method:    r.Method,
+		Path:      r.URL.Path,
+		Query:     r.URL.RawQuery,
+		IP:        clientIP(r),
+		UserAgent: r.UserAgent(),
+		Status:    status,
+		BytesOut:  sw.bytes,
+		Duration:  d,
+	}
+	if r.ContentLength > 0 {
+		rec.BytesIn = int(r.ContentLength)
+	}
+
+	lvl := zap.InfoLevel
+	switch {
+	case status >= http.StatusInternalServerError:
+		lvl = zap.ErrorLevel
+	case status >= http.StatusBadRequest:
+		lvl = zap.WarnLevel
+	}
+
+	// the fields of the context also go to the access file
+	writeAccess(getLogger(), getErrLogger(), getAccess(), lvl, "http request",
+		rec, contextFields(r.Context()))
+}
+
+// RequestIDHeader the header of the request id of RequestIDMiddleware
+const RequestIDHeader = "X-Request-ID"
+
+// requestIDKey the context key of the request id
+type requestIDKey struct{}
+
+// RequestIDMiddleware takes the request id of the X-Request-ID header,
+// or generates a random one, sets it on the response header and adds
+// it to the request context with NewContext as the request_id field.
+// Wrap HTTPMiddleware with it so the access entries have the id.
+//
+//	h := zlog.RequestIDMiddleware(zlog.HTTPMiddleware(mux))
+//	zlog.FromContext(r.Context()).Info("handle request") // with request_id
+func RequestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(RequestIDHeader, id)
+
+		ctx := context.WithValue(r.Context(), requestIDKey{}, id)
+		ctx = NewContext(ctx, zap.String("request_id", id))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// RequestIDFromContext returns the request id of RequestIDMiddleware,
+// "" if there's none.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// validRequestID reports whether the id of a header is kept, it is
+// at most 128 printable ascii characters
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+
+	for i := 0; i < len(id); i++ {
+		if id[i] < '!' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID returns a random 16 bytes hex id
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(b)
+}
+
+// clientIP returns the first address of X-Forwarded-For,
+// or the host of the remote address.
+func clientIP(r *http.Request) string {
+	if fwd := r.Header.Get("X-Forwarded-For"); fwd != "" {
+		ip := strings.TrimSpace(strings.Split(fwd, ",")[0])
+		if ip != "" {
+			return ip
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
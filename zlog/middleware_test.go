@@ -0,0 +1,229 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vcaesar/tt"
+	"go.uber.org/zap"
+)
+
+func testHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello"))
+	})
+	mux.HandleFunc("/panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+
+	return HTTPMiddleware(mux)
+}
+
+func TestHTTPMiddleware(t *testing.T) {
+	logs, errLogs := observeSplit(t)
+	h := testHandler()
+
+	req := httptest.NewRequest("GET", "/ok", nil)
+	req.Header.Set("X-Forwarded-For", "10.0.0.1, 10.0.0.2")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	tt.Equal(t, 200, rec.Code)
+
+	entries := logs.FilterMessage("http request").AllUntimed()
+	tt.Equal(t, 1, len(entries))
+	tt.Equal(t, zap.InfoLevel, entries[0].Level)
+
+	fields := entries[0].ContextMap()
+	tt.Equal(t, "GET", fields["method"])
+	tt.Equal(t, "/ok", fields["path"])
+	tt.Equal(t, int64(200), fields["status"])
+	tt.Equal(t, "10.0.0.1", fields["ip"])
+	tt.Equal(t, int64(5), fields["bytes_out"])
+	tt.NotNil(t, fields["duration"])
+	tt.Equal(t, 0, errLogs.Len())
+}
+
+func TestHTTPMiddlewareFlush(t *testing.T) {
+	logs := observe(t)
+	read := make(chan struct{})
+	srv := httptest.NewServer(HTTPMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("first\n"))
+		w.(http.Flusher).Flush()
+		// the first line reached the client before the end
+		<-read
+		w.Write([]byte("second\n"))
+	})))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/stream")
+	tt.Nil(t, err)
+	defer resp.Body.Close()
+
+	r := bufio.NewReader(resp.Body)
+	line, err := r.ReadString('\n')
+	tt.Nil(t, err)
+	tt.Equal(t, "first\n", line)
+	close(read)
+	line, err = r.ReadString('\n')
+	tt.Nil(t, err)
+	tt.Equal(t, "second\n", line)
+
+	tt.True(t, waitFor(func() bool { return logs.FilterMessage("http request").Len() == 1 }))
+	fields := logs.FilterMessage("http request").All()[0].ContextMap()
+	tt.Equal(t, int64(200), fields["status"])
+	tt.Equal(t, int64(13), fields["bytes_out"])
+}
+
+func TestHTTPMiddlewareHijack(t *testing.T) {
+	logs := observe(t)
+	srv := httptest.NewServer(HTTPMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		defer conn.Close()
+		rw.WriteString("HTTP/1.1 418 I'm a teapot\r\nContent-Length: 0\r\n\r\n")
+		rw.Flush()
+	})))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/upgrade")
+	tt.Nil(t, err)
+	resp.Body.Close()
+	tt.Equal(t, 418, resp.StatusCode)
+
+	tt.True(t, waitFor(func() bool { return logs.FilterMessage("http request").Len() == 1 }))
+	fields := logs.FilterMessage("http request").All()[0].ContextMap()
+	tt.Equal(t, int64(http.StatusSwitchingProtocols), fields["status"])
+
+	// the recorder can't be hijacked
+	var hijackErr error
+	HTTPMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _, hijackErr = w.(http.Hijacker).Hijack()
+	})).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	tt.NotNil(t, hijackErr)
+}
+
+func TestHTTPMiddlewareClientError(t *testing.T) {
+	logs, errLogs := observeSplit(t)
+
+	rec := httptest.NewRecorder()
+	testHandler().ServeHTTP(rec, httptest.NewRequest("POST", "/missing", nil))
+	tt.Equal(t, 404, rec.Code)
+
+	entries := logs.FilterMessage("http request").AllUntimed()
+	tt.Equal(t, 1, len(entries))
+	tt.Equal(t, zap.WarnLevel, entries[0].Level)
+	tt.Equal(t, int64(404), entries[0].ContextMap()["status"])
+	tt.Equal(t, "192.0.2.1", entries[0].ContextMap()["ip"])
+	tt.Equal(t, 0, errLogs.Len())
+}
+
+func TestHTTPMiddlewarePanic(t *testing.T) {
+	_, errLogs := observeSplit(t)
+
+	rec := httptest.NewRecorder()
+	testHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/panic", nil))
+	tt.Equal(t, 500, rec.Code)
+
+	panics := errLogs.FilterMessage("http panic").AllUntimed()
+	tt.Equal(t, 1, len(panics))
+	tt.Equal(t, "boom", panics[0].ContextMap()["panic"])
+	tt.True(t, strings.Contains(panics[0].ContextMap()["stack"].(string), "ServeHTTP"))
+
+	entries := errLogs.FilterMessage("http request").AllUntimed()
+	tt.Equal(t, 1, len(entries))
+	tt.Equal(t, zap.ErrorLevel, entries[0].Level)
+	tt.Equal(t, int64(500), entries[0].ContextMap()["status"])
+}
+
+func TestHTTPMiddlewareAccessFile(t *testing.T) {
+	dir := initTest(t, "access_file = true")
+
+	rec := httptest.NewRecorder()
+	testHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/panic", nil))
+	tt.Equal(t, 500, rec.Code)
+	testHandler().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/ok", nil))
+	tt.Nil(t, Close())
+
+	entries := readEntries(t, filepath.Join(dir, "*", "test_access.json"))
+	tt.Equal(t, 2, len(entries))
+	tt.Equal(t, "error", entries[0]["level"])
+	tt.Equal(t, "info", entries[1]["level"])
+
+	// only the 5xx go to the error log
+	errEntries := msgEntries(readEntries(t, filepath.Join(dir, "*", "test_err.json")), "http request")
+	tt.Equal(t, 1, len(errEntries))
+	tt.Equal(t, float64(500), errEntries[0]["status"])
+	tt.Equal(t, "/panic", errEntries[0]["path"])
+}
+
+func requestIDHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/deep", func(w http.ResponseWriter, r *http.Request) {
+		FromContext(r.Context()).Info("deep in handler")
+		w.Write([]byte(RequestIDFromContext(r.Context())))
+	})
+
+	return RequestIDMiddleware(HTTPMiddleware(mux))
+}
+
+func TestRequestIDMiddleware(t *testing.T) {
+	logs, _ := observeSplit(t)
+
+	req := httptest.NewRequest("GET", "/deep", nil)
+	req.Header.Set("X-Request-ID", "req-1")
+	rec := httptest.NewRecorder()
+	requestIDHandler().ServeHTTP(rec, req)
+
+	tt.Equal(t, "req-1", rec.Header().Get("X-Request-ID"))
+	tt.Equal(t, "req-1", rec.Body.String())
+	for _, msg := range []string{"deep in handler", "http request"} {
+		entries := logs.FilterMessage(msg).AllUntimed()
+		tt.Equal(t, 1, len(entries))
+		tt.Equal(t, "req-1", entries[0].ContextMap()["request_id"])
+	}
+}
+
+func TestRequestIDGenerated(t *testing.T) {
+	logs, _ := observeSplit(t)
+	h := requestIDHandler()
+
+	ids := make(map[string]bool)
+	// a header id too long or with spaces is replaced
+	for _, header := range []string{"", strings.Repeat("x", 129), "a b"} {
+		req := httptest.NewRequest("GET", "/deep", nil)
+		if header != "" {
+			req.Header.Set("X-Request-ID", header)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		id := rec.Header().Get("X-Request-ID")
+		tt.Equal(t, 32, len(id))
+		tt.Equal(t, id, rec.Body.String())
+		ids[id] = true
+	}
+	tt.Equal(t, 3, len(ids))
+
+	entries := logs.FilterMessage("deep in handler").AllUntimed()
+	tt.Equal(t, 3, len(entries))
+	tt.True(t, ids[entries[0].ContextMap()["request_id"].(string)])
+	tt.Equal(t, "", RequestIDFromContext(httptest.NewRequest("GET", "/", nil).Context()))
+}
@@ -0,0 +1,166 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"go.uber.org/multierr"
+)
+
+var (
+	profileLock sync.Mutex
+	profiles    map[string]*Zlog
+)
+
+// InitMulti init the package loggers with the top-level keys of the
+// config file like Init, and the loggers of its [loggers.<name>]
+// tables taken by Get. Each table is a whole config with its own
+// files, retention, level, encoder, redaction, truncation, filters
+// and ring buffer like New, the file name defaults to the name of
+// the table, the environment variables only override the top-level
+// keys. The previous loggers of Get are closed.
+//
+//	[loggers.access]
+//	path = "./log/access"
+//	level = "info"
+//
+//	zlog.InitMulti("zlog.toml")
+//	zlog.Get("access").Info("GET /")
+func InitMulti(tpath string) error {
+	format := strings.TrimPrefix(strings.ToLower(filepath.Ext(tpath)), ".")
+	if !knownFormat(format) {
+		return fmt.Errorf("zlog: unknown config format %q of %s, "+
+			"want .toml, .yaml, .yml or .json", filepath.Ext(tpath), tpath)
+	}
+
+	f, err := os.Open(tpath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	m, err := decodeTable(f, format)
+	if err != nil {
+		return err
+	}
+
+	tables, ok := m["loggers"].(map[string]interface{})
+	if !ok && m["loggers"] != nil {
+		return fmt.Errorf("zlog: invalid loggers %v, want tables", m["loggers"])
+	}
+	delete(m, "loggers")
+
+	cfg, unknown, err := configFromTable(m)
+	if err != nil {
+		return err
+	}
+	if err := applyEnv(&cfg); err != nil {
+		return err
+	}
+
+	cfgs := make(map[string]Config, len(tables))
+	for name, t := range tables {
+		table, ok := t.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("zlog: invalid loggers.%s %v, want a table", name, t)
+		}
+
+		pcfg, punknown, err := configFromTable(table)
+		if err != nil {
+			return fmt.Errorf("%v of loggers.%s", err, name)
+		}
+		if pcfg.Name == "" {
+			pcfg.Name = name
+		}
+
+		for _, key := range punknown {
+			unknown = append(unknown, "loggers."+name+"."+key)
+		}
+		cfgs[name] = pcfg
+	}
+	sort.Strings(unknown)
+
+	if err := checkUnknown(cfg, unknown); err != nil {
+		return err
+	}
+
+	built, err := buildProfiles(cfgs)
+	if err != nil {
+		return err
+	}
+
+	if err := InitWithConfig(cfg); err != nil {
+		closeProfiles(built)
+		return err
+	}
+
+	profileLock.Lock()
+	prev := profiles
+	profiles = built
+	profileLock.Unlock()
+
+	warnUnknown(unknown)
+	return closeProfiles(prev)
+}
+
+// buildProfiles builds the loggers of cfgs with New, the built
+// ones are closed if one fails
+func buildProfiles(cfgs map[string]Config) (map[string]*Zlog, error) {
+	built := make(map[string]*Zlog, len(cfgs))
+	for name, cfg := range cfgs {
+		z, err := New(cfg)
+		if err != nil {
+			closeProfiles(built)
+			return nil, fmt.Errorf("%v of loggers.%s", err, name)
+		}
+		built[name] = z
+	}
+	return built, nil
+}
+
+func closeProfiles(ps map[string]*Zlog) error {
+	var err error
+	for _, z := range ps {
+		err = multierr.Append(err, z.Close())
+	}
+	return err
+}
+
+// resetProfiles closes the profiles of InitMulti
+func resetProfiles() error {
+	profileLock.Lock()
+	prev := profiles
+	profiles = nil
+	profileLock.Unlock()
+
+	return closeProfiles(prev)
+}
+
+// Get returns the logger of the [loggers.<name>] table of InitMulti,
+// or the package loggers if there's none.
+//
+//	access := zlog.Get("access")
+func Get(name string) *Zlog {
+	profileLock.Lock()
+	z := profiles[name]
+	profileLock.Unlock()
+
+	if z == nil {
+		return &Zlog{}
+	}
+	return z
+}
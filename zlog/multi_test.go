@@ -0,0 +1,122 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vcaesar/tt"
+)
+
+func TestInitMulti(t *testing.T) {
+	dir := filepath.ToSlash(t.TempDir())
+	tt.Nil(t, InitMulti(writeConf(t, `path = "`+dir+`/app"
+name = "test"
+
+[loggers.access]
+path = "`+dir+`/access"
+level = "warn"
+daily_dirs = false
+
+[loggers.audit]
+path = "`+dir+`/audit"
+level = "debug"
+encoding = "console"
+
+[loggers.audit.fields]
+app = "audit"
+`)))
+
+	Info("app info")
+	access, audit := Get("access"), Get("audit")
+	access.Info("access info")
+	access.Warn("access warn")
+	access.Error("access error", errors.New("e1"))
+	audit.Debug("audit debug")
+	tt.Equal(t, &Zlog{}, Get("missing"))
+	tt.Nil(t, Close())
+	// closed with the package loggers
+	tt.Equal(t, &Zlog{}, Get("access"))
+
+	entries := readEntries(t, filepath.Join(dir, "app", "*", "test.json"))
+	tt.Equal(t, 1, len(msgEntries(entries, "app info")))
+	tt.Equal(t, 0, len(msgEntries(entries, "access warn")))
+
+	entries = readEntries(t, filepath.Join(dir, "access", "access.json"))
+	tt.Equal(t, 0, len(msgEntries(entries, "access info")))
+	tt.Equal(t, 1, len(msgEntries(entries, "access warn")))
+	entries = readEntries(t, filepath.Join(dir, "access", "access_err.json"))
+	tt.Equal(t, 1, len(msgEntries(entries, "access error")))
+
+	// the sweep of audit logs to its own file too
+	var lines []string
+	for _, line := range readLines(t, filepath.Join(dir, "audit", time.Now().Format(DayFormat), "audit.json")) {
+		if strings.Contains(line, "audit debug") {
+			lines = append(lines, line)
+		}
+	}
+	tt.Equal(t, 1, len(lines))
+	tt.True(t, strings.Contains(lines[0], "DEBUG\taudit debug"), lines[0])
+	tt.True(t, strings.Contains(lines[0], `"app": "audit"`), lines[0])
+}
+
+func TestInitMultiError(t *testing.T) {
+	dir := filepath.ToSlash(t.TempDir())
+	for _, c := range []struct {
+		content, err string
+	}{
+		{"loggers = 1\n", "zlog: invalid loggers 1"},
+		{"[loggers]\naccess = 1\n", "zlog: invalid loggers.access 1"},
+		{"[loggers.access]\nlevel = \"loud\"\n", `zlog: invalid level "loud"`},
+		{"strict_config = true\n[loggers.access]\nlvl = \"info\"\n",
+			"zlog: unknown config keys loggers.access.lvl"},
+		{"level = \"loud\"\n[loggers.access]\npath = \"" + dir + "\"\n",
+			`zlog: invalid level "loud"`},
+	} {
+		err := InitMulti(writeConf(t, c.content))
+		tt.NotNil(t, err, c.content)
+		tt.True(t, strings.HasPrefix(err.Error(), c.err), err.Error())
+	}
+	tt.Equal(t, &Zlog{}, Get("access"))
+}
+
+func TestInitMultiState(t *testing.T) {
+	dir := filepath.ToSlash(t.TempDir())
+	tt.Nil(t, InitMulti(writeConf(t, `path = "`+dir+`/app"
+name = "test"
+daily_dirs = false
+
+[loggers.audit]
+path = "`+dir+`/audit"
+daily_dirs = false
+redact = ["password"]
+max_field_len = 12
+`)))
+
+	Get("audit").InfoF("audit login", Str("password", "hunter2"),
+		Str("user", "a-very-long-user"))
+	InfoF("app login", Str("password", "hunter2"))
+	tt.Nil(t, Close())
+
+	entries := msgEntries(readEntries(t, filepath.Join(dir, "audit", "audit.json")), "audit login")
+	tt.Equal(t, 1, len(entries))
+	tt.Equal(t, Redacted, entries[0]["password"])
+	tt.Equal(t, "a-very-long-...(truncated 4 bytes)", entries[0]["user"])
+
+	// the package loggers keep their own config
+	entries = msgEntries(readEntries(t, filepath.Join(dir, "app", "test.json")), "app login")
+	tt.Equal(t, 1, len(entries))
+	tt.Equal(t, "hunter2", entries[0]["password"])
+}
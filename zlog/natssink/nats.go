@@ -0,0 +1,238 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+// Package natssink a zapcore.Core publishing the entries to the nats
+// subject of their level, it is added to zlog by Attach.
+package natssink
+
+import (
+	"errors"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-vgo/gt/zlog"
+	"github.com/nats-io/nats.go"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// HeaderLevel and HeaderLogger the headers of the level and
+	// the logger name of the messages
+	HeaderLevel  = "Zlog-Level"
+	HeaderLogger = "Zlog-Logger"
+)
+
+const (
+	defaultAckTimeout = 5 * time.Second
+	defaultMaxPending = 256
+	// stallWait the max wait of a jetstream publish when maxPending
+	// acks are pending, the entry is dropped after it
+	stallWait   = time.Millisecond
+	syncTimeout = time.Second
+)
+
+type options struct {
+	level      zapcore.LevelEnabler
+	jetStream  bool
+	ackTimeout time.Duration
+	maxPending int
+}
+
+// Option an option of New and Attach
+type Option func(*options)
+
+// WithLevel sets the enabled levels, default info
+func WithLevel(enab zapcore.LevelEnabler) Option {
+	return func(o *options) { o.level = enab }
+}
+
+// WithJetStream publishes the entries of Error and above with the
+// jetstream ack, a stream has to capture their subjects
+func WithJetStream() Option {
+	return func(o *options) { o.jetStream = true }
+}
+
+// WithAckTimeout sets the wait of the jetstream acks, the entries
+// without an ack are dropped, default 5s
+func WithAckTimeout(d time.Duration) Option {
+	return func(o *options) { o.ackTimeout = d }
+}
+
+// WithMaxPending sets the jetstream publishes waiting for their ack,
+// the entries over it are dropped, default 256
+func WithMaxPending(n int) Option {
+	return func(o *options) { o.maxPending = n }
+}
+
+// publisher the connection shared by a Core and its With copies
+type publisher struct {
+	nc      *nats.Conn
+	js      nats.JetStreamContext
+	prefix  string
+	timeout time.Duration
+	dropped int64
+}
+
+// Core a zapcore.Core publishing the entries to the subjects
+// prefix.level like logs.api.error, the publishes never wait
+// for the connection
+type Core struct {
+	zapcore.LevelEnabler
+	enc zapcore.Encoder
+	p   *publisher
+}
+
+// Attach adds the core of nc and subjectPrefix to zlog by
+// zlog.AddCore, it stays added across zlog.Init.
+//
+//	err := natssink.Attach(nc, "logs.api", natssink.WithJetStream())
+func Attach(nc *nats.Conn, subjectPrefix string, opts ...Option) error {
+	core, err := New(nc, subjectPrefix, opts...)
+	if err != nil {
+		return err
+	}
+
+	zlog.AddCore(core)
+	return nil
+}
+
+// New returns the core publishing to the subjects of subjectPrefix,
+// like Attach without adding it.
+func New(nc *nats.Conn, subjectPrefix string, opts ...Option) (*Core, error) {
+	if nc == nil {
+		return nil, errors.New("natssink: the connection is nil")
+	}
+	if subjectPrefix == "" || strings.HasSuffix(subjectPrefix, ".") ||
+		strings.ContainsAny(subjectPrefix, " \t\r\n*>") {
+		return nil, errors.New("natssink: invalid subject prefix " + subjectPrefix)
+	}
+
+	o := options{
+		level:      zapcore.InfoLevel,
+		ackTimeout: defaultAckTimeout,
+		maxPending: defaultMaxPending,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if o.level == nil || o.ackTimeout <= 0 || o.maxPending <= 0 {
+		return nil, errors.New("natssink: invalid options")
+	}
+
+	p := &publisher{nc: nc, prefix: subjectPrefix, timeout: o.ackTimeout}
+	if o.jetStream {
+		js, err := nc.JetStream(
+			nats.PublishAsyncMaxPending(o.maxPending),
+			nats.PublishAsyncTimeout(o.ackTimeout),
+			nats.PublishAsyncErrHandler(func(_ nats.JetStream, _ *nats.Msg, err error) {
+				p.drop(err)
+			}),
+		)
+		if err != nil {
+			return nil, err
+		}
+		p.js = js
+	}
+
+	encCfg := zap.NewProductionEncoderConfig()
+	encCfg.EncodeTime = zapcore.ISO8601TimeEncoder
+	encCfg.LineEnding = ""
+
+	return &Core{
+		LevelEnabler: o.level,
+		enc:          zapcore.NewJSONEncoder(encCfg),
+		p:            p,
+	}, nil
+}
+
+// With adds the fields to the payloads of the returned core
+func (c *Core) With(fields []zapcore.Field) zapcore.Core {
+	enc := c.enc.Clone()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	return &Core{LevelEnabler: c.LevelEnabler, enc: enc, p: c.p}
+}
+
+// Check adds the core to ce if the level is enabled
+func (c *Core) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write publishes the encoded entry, while the connection is lost
+// it is kept by the reconnect buffer of the client or dropped
+func (c *Core) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+
+	msg := &nats.Msg{
+		Subject: c.p.prefix + "." + ent.Level.String(),
+		Data:    append([]byte(nil), buf.Bytes()...),
+		Header:  nats.Header{},
+	}
+	buf.Free()
+
+	msg.Header.Set(HeaderLevel, ent.Level.String())
+	if ent.LoggerName != "" {
+		msg.Header.Set(HeaderLogger, ent.LoggerName)
+	}
+
+	if c.p.js != nil && ent.Level >= zapcore.ErrorLevel {
+		_, err = c.p.js.PublishMsgAsync(msg, nats.StallWait(stallWait))
+	} else {
+		err = c.p.nc.PublishMsg(msg)
+	}
+
+	if err != nil {
+		c.p.drop(err)
+	}
+	return nil
+}
+
+// Sync flushes the connection and waits for the jetstream acks,
+// it doesn't wait while the connection is lost
+func (c *Core) Sync() error {
+	if !c.p.nc.IsConnected() {
+		return nil
+	}
+
+	if err := c.p.nc.FlushTimeout(syncTimeout); err != nil {
+		return err
+	}
+
+	if c.p.js != nil {
+		select {
+		case <-c.p.js.PublishAsyncComplete():
+		case <-time.After(c.p.timeout):
+		}
+	}
+	return nil
+}
+
+// Dropped returns the number of the entries which couldn't be
+// published or weren't acked by jetstream
+func (c *Core) Dropped() int64 {
+	return atomic.LoadInt64(&c.p.dropped)
+}
+
+// drop counts a dropped entry, it is counted by zlog.Stats and
+// runs the zlog.OnWriteError funcs
+func (p *publisher) drop(err error) {
+	atomic.AddInt64(&p.dropped, 1)
+	zlog.ReportWriteError("nats "+p.prefix, 1, err)
+}
@@ -0,0 +1,209 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package natssink
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-vgo/gt/zlog"
+	"github.com/nats-io/nats-server/v2/server"
+	natstest "github.com/nats-io/nats-server/v2/test"
+	"github.com/nats-io/nats.go"
+	"github.com/vcaesar/tt"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// runServer runs an in-process server with jetstream
+func runServer(t *testing.T) *server.Server {
+	opts := natstest.DefaultTestOptions
+	opts.Port = -1
+	opts.JetStream = true
+	opts.StoreDir = t.TempDir()
+
+	s := natstest.RunServer(&opts)
+	t.Cleanup(s.Shutdown)
+	return s
+}
+
+func connect(t *testing.T, s *server.Server, opts ...nats.Option) *nats.Conn {
+	nc, err := nats.Connect(s.ClientURL(), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(nc.Close)
+	return nc
+}
+
+func subscribe(t *testing.T, nc *nats.Conn, subject string) *nats.Subscription {
+	sub, err := nc.SubscribeSync(subject)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tt.Nil(t, nc.Flush())
+	return sub
+}
+
+func next(t *testing.T, sub *nats.Subscription) *nats.Msg {
+	msg, err := sub.NextMsg(5 * time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return msg
+}
+
+// zapEntry an entry of level for the Write of a core
+func zapEntry(level zapcore.Level) zapcore.Entry {
+	return zapcore.Entry{Level: level, Time: time.Now(), Message: "entry"}
+}
+
+func TestNew(t *testing.T) {
+	nc := connect(t, runServer(t))
+	for _, prefix := range []string{"", "logs.", "logs.*", "logs >", "a b"} {
+		_, err := New(nc, prefix)
+		tt.NotNil(t, err, prefix)
+	}
+
+	_, err := New(nil, "logs")
+	tt.NotNil(t, err)
+	_, err = New(nc, "logs", WithMaxPending(0))
+	tt.NotNil(t, err)
+	_, err = New(nc, "logs", WithLevel(nil))
+	tt.NotNil(t, err)
+}
+
+func TestSubjects(t *testing.T) {
+	s := runServer(t)
+	nc := connect(t, s)
+	sub := subscribe(t, nc, "logs.app.>")
+
+	core, err := New(nc, "logs.app", WithLevel(zap.DebugLevel))
+	tt.Nil(t, err)
+	logger := zap.New(core).Named("db").With(zap.String("request_id", "r1"))
+
+	logger.Debug("nats debug")
+	logger.Info("nats info")
+	logger.Warn("nats warn")
+	logger.Error("nats error", zap.Error(errors.New("e1")))
+	tt.Nil(t, logger.Sync())
+
+	for _, level := range []string{"debug", "info", "warn", "error"} {
+		msg := next(t, sub)
+		tt.Equal(t, "logs.app."+level, msg.Subject)
+		tt.Equal(t, level, msg.Header.Get(HeaderLevel))
+		tt.Equal(t, "db", msg.Header.Get(HeaderLogger))
+
+		entry := make(map[string]interface{})
+		tt.Nil(t, json.Unmarshal(msg.Data, &entry))
+		tt.Equal(t, "nats "+level, entry["msg"])
+		tt.Equal(t, "r1", entry["request_id"])
+	}
+	tt.Equal(t, int64(0), core.Dropped())
+
+	// the logger without a name has no logger header
+	zap.New(core).Info("no name")
+	msg := next(t, sub)
+	_, ok := msg.Header[HeaderLogger]
+	tt.False(t, ok)
+}
+
+func TestJetStream(t *testing.T) {
+	s := runServer(t)
+	nc := connect(t, s)
+	js, err := nc.JetStream()
+	tt.Nil(t, err)
+	_, err = js.AddStream(&nats.StreamConfig{Name: "LOGS", Subjects: []string{"logs.app.error"}})
+	tt.Nil(t, err)
+	sub := subscribe(t, nc, "logs.app.info")
+
+	core, err := New(nc, "logs.app", WithJetStream())
+	tt.Nil(t, err)
+	logger := zap.New(core)
+	logger.Info("core info")
+	logger.Error("acked error")
+	logger.Error("acked error")
+	tt.Nil(t, core.Sync())
+
+	info, err := js.StreamInfo("LOGS")
+	tt.Nil(t, err)
+	tt.Equal(t, uint64(2), info.State.Msgs)
+	tt.Equal(t, "logs.app.info", next(t, sub).Subject)
+	tt.Equal(t, int64(0), core.Dropped())
+
+	// no stream captures the fatal subject, the entry isn't acked
+	core, err = New(nc, "logs.app", WithJetStream(), WithLevel(zap.ErrorLevel),
+		WithAckTimeout(50*time.Millisecond))
+	tt.Nil(t, err)
+	core.Write(zapEntry(zap.DPanicLevel), nil)
+	tt.Nil(t, core.Sync())
+	for i := 0; i < 100 && core.Dropped() == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	tt.Equal(t, int64(1), core.Dropped())
+}
+
+func TestDisconnected(t *testing.T) {
+	s := runServer(t)
+	disconnected := make(chan struct{})
+	nc := connect(t, s, nats.ReconnectBufSize(-1), nats.MaxReconnects(-1),
+		nats.DisconnectErrHandler(func(*nats.Conn, error) { close(disconnected) }))
+
+	core, err := New(nc, "logs.app")
+	tt.Nil(t, err)
+	prev := zlog.Stats().WriteErrors
+
+	s.Shutdown()
+	<-disconnected
+
+	done := make(chan struct{})
+	go func() {
+		logger := zap.New(core)
+		for i := 0; i < 100; i++ {
+			logger.Info("lost")
+		}
+		tt.Nil(t, logger.Sync())
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the logs wait for the connection")
+	}
+	tt.Equal(t, int64(100), core.Dropped())
+	tt.Equal(t, prev+100, zlog.Stats().WriteErrors)
+
+	nc.Close()
+	zap.New(core).Info("closed")
+	tt.Equal(t, int64(101), core.Dropped())
+}
+
+func TestAttach(t *testing.T) {
+	s := runServer(t)
+	nc := connect(t, s)
+	sub := subscribe(t, nc, "logs.zlog.>")
+
+	tt.Nil(t, zlog.InitWithConfig(zlog.Config{Path: t.TempDir()}))
+	tt.NotNil(t, Attach(nc, ""))
+	tt.Nil(t, Attach(nc, "logs.zlog"))
+
+	zlog.Named("api").Info("attached")
+	zlog.Error("attached error", errors.New("e1"))
+	tt.Nil(t, zlog.Close())
+
+	msg := next(t, sub)
+	tt.Equal(t, "logs.zlog.info", msg.Subject)
+	tt.Equal(t, "api", msg.Header.Get(HeaderLogger))
+	tt.Equal(t, "logs.zlog.error", next(t, sub).Subject)
+}
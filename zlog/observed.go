@@ -0,0 +1,90 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// Observed the in-memory entries of the loggers of NewTestLogger
+type Observed struct {
+	logs *observer.ObservedLogs
+}
+
+// NewTestLogger swaps the main and the error logger for an in-memory
+// logger till the end of t, the previous loggers are restored by
+// t.Cleanup so it can be nested, the entries of both the loggers
+// are recorded at debug level.
+//
+//	obs := zlog.NewTestLogger(t)
+//	doWork()
+//	tt.Equal(t, 1, obs.FilterLevel(zap.ErrorLevel).Len())
+func NewTestLogger(t testing.TB) *Observed {
+	core, logs := observer.New(zap.DebugLevel)
+	logger := zap.New(core, zap.AddCaller(), zap.AddCallerSkip(1))
+
+	swapLock.Lock()
+	prev := load()
+	current.Store(&loggers{
+		logger:    logger,
+		errLogger: logger,
+		sugar:     logger.Sugar(),
+		errSugar:  logger.Sugar(),
+	})
+	swapLock.Unlock()
+
+	t.Cleanup(func() {
+		swapLock.Lock()
+		current.Store(prev)
+		swapLock.Unlock()
+	})
+
+	return &Observed{logs: logs}
+}
+
+// Entries returns a copy of the observed entries
+func (o *Observed) Entries() []observer.LoggedEntry {
+	return o.logs.All()
+}
+
+// Len returns the number of the observed entries
+func (o *Observed) Len() int {
+	return o.logs.Len()
+}
+
+// Messages returns the messages of the observed entries
+func (o *Observed) Messages() []string {
+	entries := o.logs.All()
+	msgs := make([]string, len(entries))
+	for i, ent := range entries {
+		msgs[i] = ent.Message
+	}
+	return msgs
+}
+
+// FilterLevel filters the entries of the level
+func (o *Observed) FilterLevel(l zapcore.Level) *Observed {
+	return &Observed{logs: o.logs.FilterLevelExact(l)}
+}
+
+// FilterMessage filters the entries of the message
+func (o *Observed) FilterMessage(msg string) *Observed {
+	return &Observed{logs: o.logs.FilterMessage(msg)}
+}
+
+// FilterField filters the entries with the field
+func (o *Observed) FilterField(field zap.Field) *Observed {
+	return &Observed{logs: o.logs.FilterField(field)}
+}
@@ -0,0 +1,57 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/vcaesar/tt"
+	"go.uber.org/zap"
+)
+
+func TestNewTestLogger(t *testing.T) {
+	obs := NewTestLogger(t)
+
+	Info("test info")
+	Warn("test warn")
+	Error("test error", errors.New("disk full"))
+	With(Str("request_id", "r1")).ErrorF("test error", Int("n", 1))
+
+	tt.Equal(t, 4, obs.Len())
+	tt.Equal(t, []string{"test info", "test warn", "test error", "test error"},
+		obs.Messages())
+	tt.Equal(t, 1, obs.FilterMessage("test warn").Len())
+
+	errs := obs.FilterLevel(zap.ErrorLevel)
+	tt.Equal(t, 2, errs.Len())
+	tt.Equal(t, "disk full", errs.Entries()[0].ContextMap()["error"])
+
+	req := errs.FilterField(Str("request_id", "r1"))
+	tt.Equal(t, 1, req.Len())
+	tt.Equal(t, int64(1), req.Entries()[0].ContextMap()["n"])
+	tt.Equal(t, "observed_test.go", filepath.Base(req.Entries()[0].Caller.File))
+}
+
+func TestNewTestLoggerNested(t *testing.T) {
+	outer := NewTestLogger(t)
+	Info("outer info")
+
+	t.Run("inner", func(t *testing.T) {
+		inner := NewTestLogger(t)
+		Info("inner info")
+		tt.Equal(t, []string{"inner info"}, inner.Messages())
+	})
+
+	Info("outer again")
+	tt.Equal(t, []string{"outer info", "outer again"}, outer.Messages())
+}
@@ -0,0 +1,50 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+// Package otelzlog adds the opentelemetry trace and span ids to
+// the zlog entries, it is a separate package so zlog doesn't depend
+// on opentelemetry.
+package otelzlog
+
+import (
+	"context"
+	"sync"
+
+	"github.com/go-vgo/gt/zlog"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+var once sync.Once
+
+// Enable adds the trace_id and the span_id of the span of the
+// context to the loggers of zlog.Ctx, it is registered once.
+//
+//	otelzlog.Enable()
+//	zlog.Ctx(ctx).Error("query error", err)
+func Enable() {
+	once.Do(func() {
+		zlog.ContextFields(Fields)
+	})
+}
+
+// Fields returns the hex trace_id and span_id of the span of ctx,
+// nil if ctx has no valid span.
+func Fields(ctx context.Context) []zap.Field {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return nil
+	}
+
+	return []zap.Field{
+		zap.String("trace_id", sc.TraceID().String()),
+		zap.String("span_id", sc.SpanID().String()),
+	}
+}
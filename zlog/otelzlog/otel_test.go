@@ -0,0 +1,100 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package otelzlog
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-vgo/gt/zlog"
+	"github.com/vcaesar/tt"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	spanID  = "00f067aa0ba902b7"
+)
+
+// spanCtx returns a context with a span of traceID and spanID
+func spanCtx(t *testing.T) context.Context {
+	tid, err := trace.TraceIDFromHex(traceID)
+	tt.Nil(t, err)
+	sid, err := trace.SpanIDFromHex(spanID)
+	tt.Nil(t, err)
+
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    tid,
+		SpanID:     sid,
+		TraceFlags: trace.FlagsSampled,
+	})
+	return trace.ContextWithSpanContext(context.Background(), sc)
+}
+
+func readEntries(t *testing.T, pattern string) map[string]map[string]interface{} {
+	paths, err := filepath.Glob(pattern)
+	tt.Nil(t, err)
+	tt.Equal(t, 1, len(paths))
+
+	f, err := os.Open(paths[0])
+	tt.Nil(t, err)
+	defer f.Close()
+
+	entries := make(map[string]map[string]interface{})
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		var entry map[string]interface{}
+		tt.Nil(t, json.Unmarshal(s.Bytes(), &entry))
+		entries[entry["msg"].(string)] = entry
+	}
+	return entries
+}
+
+func TestFields(t *testing.T) {
+	tt.Equal(t, 0, len(Fields(context.Background())))
+
+	fields := Fields(spanCtx(t))
+	tt.Equal(t, 2, len(fields))
+	tt.Equal(t, "trace_id", fields[0].Key)
+	tt.Equal(t, traceID, fields[0].String)
+	tt.Equal(t, "span_id", fields[1].Key)
+	tt.Equal(t, spanID, fields[1].String)
+}
+
+func TestEnable(t *testing.T) {
+	Enable()
+	// enabled twice
+	Enable()
+
+	dir := t.TempDir()
+	tt.Nil(t, zlog.InitWithConfig(zlog.Config{Path: dir, Name: "otel"}))
+
+	ctx := spanCtx(t)
+	zlog.Ctx(ctx).Info("otel info")
+	zlog.Ctx(ctx).Error("otel error", errors.New("e1"))
+	zlog.Ctx(context.Background()).Info("no span")
+	tt.Nil(t, zlog.Close())
+
+	entries := readEntries(t, filepath.Join(dir, "*", "otel.json"))
+	tt.Equal(t, traceID, entries["otel info"]["trace_id"])
+	tt.Equal(t, spanID, entries["otel info"]["span_id"])
+	_, ok := entries["no span"]["trace_id"]
+	tt.False(t, ok)
+
+	errEntries := readEntries(t, filepath.Join(dir, "*", "otel_err.json"))
+	tt.Equal(t, traceID, errEntries["otel error"]["trace_id"])
+	tt.Equal(t, spanID, errEntries["otel error"]["span_id"])
+}
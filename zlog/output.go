@@ -0,0 +1,217 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// SinkFactory opens the output of an url of Outputs
+type SinkFactory func(u *url.URL) (zapcore.WriteSyncer, error)
+
+var (
+	sinkLock sync.Mutex
+	sinks    = map[string]SinkFactory{
+		"file": openFileSink,
+		"tcp":  openRemoteSink,
+		"udp":  openRemoteSink,
+	}
+)
+
+// RegisterSink registers the factory of the outputs of scheme, the
+// Outputs like "scheme://host/path?k=v" are opened by factory with
+// the parsed url. The sink is closed with the log files if it's an
+// io.Closer.
+//
+//	zlog.RegisterSink("kafka", func(u *url.URL) (zapcore.WriteSyncer, error) {
+//		return newKafkaWriter(u.Host, u.Query().Get("topic"))
+//	})
+func RegisterSink(scheme string, factory SinkFactory) error {
+	if scheme == "" || factory == nil {
+		return fmt.Errorf("zlog: invalid sink %q", scheme)
+	}
+
+	sinkLock.Lock()
+	defer sinkLock.Unlock()
+
+	if _, ok := sinks[scheme]; ok {
+		return fmt.Errorf("zlog: sink %q already registered", scheme)
+	}
+	sinks[scheme] = factory
+	return nil
+}
+
+// parseOutput returns the url of an output and the factory of its
+// scheme, "stdout" and "stderr" have no factory
+func parseOutput(output string) (*url.URL, SinkFactory, error) {
+	u, err := url.Parse(output)
+	if err != nil {
+		return nil, nil, fmt.Errorf("zlog: invalid output %q: %v", output, err)
+	}
+
+	if u.Scheme == "" {
+		switch output {
+		case "stdout", "stderr":
+			return u, nil, nil
+		}
+		return nil, nil, fmt.Errorf("zlog: invalid output %q, want an url", output)
+	}
+
+	sinkLock.Lock()
+	factory, ok := sinks[u.Scheme]
+	sinkLock.Unlock()
+	if !ok {
+		return nil, nil, fmt.Errorf("zlog: unknown output scheme %q", u.Scheme)
+	}
+	return u, factory, nil
+}
+
+// checkOutputs returns the error of the invalid Outputs
+func (c *Config) checkOutputs() error {
+	for _, output := range c.Outputs {
+		if _, _, err := parseOutput(output); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// openOutputs opens the Outputs, the closer closes the
+// opened ones
+func (c *Config) openOutputs() ([]zapcore.WriteSyncer, io.Closer, error) {
+	var (
+		outs []zapcore.WriteSyncer
+		cs   closers
+	)
+	for _, output := range c.Outputs {
+		ws, err := openOutput(output)
+		if err != nil {
+			cs.Close()
+			return nil, nil, err
+		}
+
+		if closer, ok := ws.(io.Closer); ok {
+			cs = append(cs, closer)
+		}
+		outs = append(outs, ws)
+	}
+	return outs, &cs, nil
+}
+
+func openOutput(output string) (zapcore.WriteSyncer, error) {
+	u, factory, err := parseOutput(output)
+	if err != nil {
+		return nil, err
+	}
+
+	if factory == nil {
+		if output == "stderr" {
+			return lockFile(os.Stderr), nil
+		}
+		return lockFile(os.Stdout), nil
+	}
+
+	ws, err := factory(u)
+	if err != nil {
+		return nil, fmt.Errorf("zlog: open output %q: %v", output, err)
+	}
+	return ws, nil
+}
+
+// fileSink the lumberjack file of a file output
+type fileSink struct {
+	*lumberjack.Logger
+}
+
+// Sync lumberjack writes to the file directly
+func (fileSink) Sync() error {
+	return nil
+}
+
+// Name returns the path of the file
+func (s fileSink) Name() string {
+	return s.Filename
+}
+
+// openFileSink opens file:///path/name.json, rotated by lumberjack
+// with the maxsize, maxbackups, maxage and compress queries
+func openFileSink(u *url.URL) (zapcore.WriteSyncer, error) {
+	if u.Host != "" && u.Host != "localhost" {
+		return nil, fmt.Errorf("the host %q of a file", u.Host)
+	}
+	if u.Path == "" {
+		return nil, fmt.Errorf("no file path")
+	}
+
+	q := u.Query()
+	lj := &lumberjack.Logger{Filename: u.Path}
+	for key, n := range map[string]*int{
+		"maxsize":    &lj.MaxSize,
+		"maxbackups": &lj.MaxBackups,
+		"maxage":     &lj.MaxAge,
+	} {
+		if err := queryInt(q, key, n); err != nil {
+			return nil, err
+		}
+	}
+
+	if s := q.Get("compress"); s != "" {
+		compress, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid compress %q", s)
+		}
+		lj.Compress = compress
+	}
+	return fileSink{lj}, nil
+}
+
+// openRemoteSink opens tcp://host:port or udp://host:port like Remote
+// with the dial_timeout, reconnect_backoff and buffer_size queries
+func openRemoteSink(u *url.URL) (zapcore.WriteSyncer, error) {
+	q := u.Query()
+	cfg := RemoteConfig{
+		Address:          u.Host,
+		Protocol:         u.Scheme,
+		DialTimeout:      q.Get("dial_timeout"),
+		ReconnectBackoff: q.Get("reconnect_backoff"),
+	}
+	if cfg.Address == "" {
+		return nil, fmt.Errorf("no address")
+	}
+
+	if err := queryInt(q, "buffer_size", &cfg.BufferSize); err != nil {
+		return nil, err
+	}
+	return newRemoteWriter(cfg)
+}
+
+// queryInt sets n to the non-negative int of the query key if it's set
+func queryInt(q url.Values, key string, n *int) error {
+	s := q.Get(key)
+	if s == "" {
+		return nil
+	}
+
+	v, err := strconv.Atoi(s)
+	if err != nil || v < 0 {
+		return fmt.Errorf("invalid %s %q", key, s)
+	}
+	*n = v
+	return nil
+}
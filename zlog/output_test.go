@@ -0,0 +1,128 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"bytes"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/vcaesar/tt"
+	"go.uber.org/zap/zapcore"
+)
+
+// fakeSink the sink of the fake scheme
+type fakeSink struct {
+	mu     sync.Mutex
+	buf    bytes.Buffer
+	closed bool
+}
+
+func (s *fakeSink) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.buf.Write(p)
+}
+
+func (s *fakeSink) Sync() error {
+	return nil
+}
+
+func (s *fakeSink) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *fakeSink) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.buf.String()
+}
+
+var (
+	fakeSinks = make(chan *fakeSink, 1)
+	fakeURLs  = make(chan *url.URL, 1)
+	fakeOnce  sync.Once
+)
+
+func registerFake(t *testing.T) {
+	fakeOnce.Do(func() {
+		tt.Nil(t, RegisterSink("fake", func(u *url.URL) (zapcore.WriteSyncer, error) {
+			s := &fakeSink{}
+			fakeURLs <- u
+			fakeSinks <- s
+			return s, nil
+		}))
+	})
+}
+
+func TestRegisterSink(t *testing.T) {
+	registerFake(t)
+	tt.NotNil(t, RegisterSink("fake", func(*url.URL) (zapcore.WriteSyncer, error) {
+		return nil, nil
+	}))
+	tt.NotNil(t, RegisterSink("", nil))
+
+	initTest(t, `outputs = ["fake://collector/app?topic=logs"]`)
+	u, sink := <-fakeURLs, <-fakeSinks
+	tt.Equal(t, "collector", u.Host)
+	tt.Equal(t, "logs", u.Query().Get("topic"))
+
+	Info("to fake")
+	Debug("not to fake")
+	Error("fake error", os.ErrNotExist)
+	tt.Nil(t, Close())
+
+	out := sink.String()
+	tt.True(t, strings.Contains(out, `"msg":"to fake"`))
+	tt.True(t, strings.Contains(out, `"msg":"fake error"`))
+	tt.False(t, strings.Contains(out, "not to fake"))
+	tt.Equal(t, 1, strings.Count(out, "fake error"))
+	tt.True(t, sink.closed)
+}
+
+func TestOutputs(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out", "app.json")
+	initTest(t, `outputs = ["file://`+filepath.ToSlash(out)+`?maxsize=1&maxbackups=2&compress=true"]`)
+	Info("to file")
+	tt.Nil(t, Close())
+
+	entries := msgEntries(readEntries(t, out), "to file")
+	tt.Equal(t, 1, len(entries))
+
+	sink, err := openOutput("file://" + filepath.ToSlash(out) + "?maxsize=1&maxage=3")
+	tt.Nil(t, err)
+	lj := sink.(fileSink).Logger
+	tt.Equal(t, 1, lj.MaxSize)
+	tt.Equal(t, 3, lj.MaxAge)
+	tt.Equal(t, out, outputName(sink))
+	tt.Nil(t, lj.Close())
+
+	for _, output := range []string{
+		"nope://x", "app.json", "file://host/x.json",
+		"file:///x.json?maxsize=big", "file:///x.json?compress=2",
+		"tcp://", "tcp://localhost:1?buffer_size=-1",
+	} {
+		_, err := openOutput(output)
+		tt.NotNil(t, err, output)
+	}
+
+	dir := t.TempDir()
+	err = InitWithConfig(Config{Path: dir, Outputs: []string{"nope://x"}})
+	tt.NotNil(t, err)
+	tt.True(t, strings.Contains(err.Error(), `unknown output scheme "nope"`))
+}
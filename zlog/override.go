@@ -0,0 +1,153 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// levelsConf parses the Levels of the config
+func (c *Config) levelsConf() (map[string]zapcore.Level, error) {
+	levels := make(map[string]zapcore.Level, len(c.Levels))
+	for key, s := range c.Levels {
+		lvl, err := zapcore.ParseLevel(s)
+		if err != nil {
+			return nil, fmt.Errorf("zlog: invalid levels.%s %q: %v", key, s, err)
+		}
+		levels[key] = lvl
+	}
+	return levels, nil
+}
+
+// setOverrides replaces the level overrides
+func (st *state) setOverrides(levels map[string]zapcore.Level) {
+	st.overrideLock.Lock()
+	st.overrides.Store(levels)
+	st.overrideLock.Unlock()
+}
+
+func (st *state) loadOverrides() map[string]zapcore.Level {
+	levels, _ := st.overrides.Load().(map[string]zapcore.Level)
+	return levels
+}
+
+// SetLevelFor sets the level of the loggers named name or the
+// callers in the package path name at runtime, like the levels
+// table of the config, Init replaces them with the table.
+//
+//	zlog.SetLevelFor("db", zapcore.WarnLevel)
+func SetLevelFor(name string, l zapcore.Level) {
+	defaultState.setLevelFor(name, l)
+}
+
+func (st *state) setLevelFor(name string, l zapcore.Level) {
+	st.overrideLock.Lock()
+	defer st.overrideLock.Unlock()
+
+	prev := st.loadOverrides()
+	levels := make(map[string]zapcore.Level, len(prev)+1)
+	for key, lvl := range prev {
+		levels[key] = lvl
+	}
+	levels[name] = l
+	st.overrides.Store(levels)
+}
+
+// overrideLevel returns the level of the longest key matching the
+// logger name or the package of the caller
+func overrideLevel(levels map[string]zapcore.Level, ent zapcore.Entry) (zapcore.Level, bool) {
+	pkg := callerPackage(ent.Caller)
+
+	var (
+		lvl  zapcore.Level
+		best = -1
+	)
+	for key, l := range levels {
+		if len(key) <= best {
+			continue
+		}
+		if hasPrefix(ent.LoggerName, key, '.') || hasPrefix(pkg, key, '/') {
+			lvl, best = l, len(key)
+		}
+	}
+	return lvl, best >= 0
+}
+
+// hasPrefix reports whether s is prefix or in prefix, the segments
+// of s are separated by sep
+func hasPrefix(s, prefix string, sep byte) bool {
+	if !strings.HasPrefix(s, prefix) {
+		return false
+	}
+	return len(s) == len(prefix) || s[len(prefix)] == sep
+}
+
+// callerPackage returns the package path of the caller,
+// empty without the caller
+func callerPackage(caller zapcore.EntryCaller) string {
+	fn := caller.Function
+	if !caller.Defined || fn == "" {
+		return ""
+	}
+
+	slash := strings.LastIndexByte(fn, '/') + 1
+	if dot := strings.IndexByte(fn[slash:], '.'); dot >= 0 {
+		return fn[:slash+dot]
+	}
+	return fn
+}
+
+// levelHook wraps the cores of the loggers with the level overrides
+// of st
+func (st *state) levelHook() zap.Option {
+	return zap.WrapCore(func(c zapcore.Core) zapcore.Core {
+		return levelCore{c, st}
+	})
+}
+
+// levelCore drops the entries below the level of their logger name
+// or caller package, the overrides can only raise the level of the
+// cores and never drop the Error+ entries
+type levelCore struct {
+	zapcore.Core
+	st *state
+}
+
+func (c levelCore) With(fields []zapcore.Field) zapcore.Core {
+	return levelCore{c.Core.With(fields), c.st}
+}
+
+func (c levelCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if ent.Level >= zap.ErrorLevel || len(c.st.loadOverrides()) == 0 {
+		return c.Core.Check(ent, ce)
+	}
+
+	// the caller is only set after Check
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write drops the entries below their override, the wrapped cores
+// are checked again so each of them keeps its own level
+func (c levelCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if lvl, ok := overrideLevel(c.st.loadOverrides(), ent); ok && ent.Level < lvl {
+		return nil
+	}
+
+	write(c.Core, ent, fields)
+	return nil
+}
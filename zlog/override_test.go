@@ -0,0 +1,111 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/vcaesar/tt"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// overrideLogger returns a debug logger with the level overrides
+// and the caller
+func overrideLogger(t *testing.T) (*zap.Logger, *observer.ObservedLogs) {
+	t.Cleanup(func() { defaultState.setOverrides(nil) })
+	core, logs := observer.New(zap.DebugLevel)
+	return zap.New(core, defaultState.levelHook(), zap.AddCaller()), logs
+}
+
+func TestOverrideName(t *testing.T) {
+	logger, logs := overrideLogger(t)
+	defaultState.setOverrides(map[string]zapcore.Level{
+		"db":            zap.WarnLevel,
+		"db.migrations": zap.InfoLevel,
+	})
+
+	db := logger.Named("db")
+	db.Info("db info")
+	db.Warn("db warn")
+	db.Error("db error")
+	// the longest match wins
+	db.Named("migrations").Info("migrations info")
+	db.Named("migrations").Debug("migrations debug")
+	// a prefix of the segment doesn't match
+	logger.Named("dbx").Debug("dbx debug")
+	logger.Debug("root debug")
+
+	var msgs []string
+	for _, entry := range logs.All() {
+		msgs = append(msgs, entry.Message)
+	}
+	tt.Equal(t, []string{"db warn", "db error", "migrations info",
+		"dbx debug", "root debug"}, msgs)
+}
+
+func TestOverrideCaller(t *testing.T) {
+	logger, logs := overrideLogger(t)
+
+	SetLevelFor("github.com/go-vgo/gt/zlog", zap.ErrorLevel)
+	logger.Warn("caller warn")
+	logger.Error("caller error")
+	tt.Equal(t, 1, logs.Len())
+
+	// the name wins by its length
+	SetLevelFor("github.com/go-vgo/gt/zlog/test/name", zap.DebugLevel)
+	logger.Named("github.com/go-vgo/gt/zlog/test/name").Debug("name debug")
+	tt.Equal(t, 1, logs.FilterMessage("name debug").Len())
+
+	// the runtime change applies to the same logger
+	SetLevelFor("github.com/go-vgo/gt/zlog", zap.DebugLevel)
+	logger.Warn("caller warn")
+	tt.Equal(t, 1, logs.FilterMessage("caller warn").Len())
+
+	tt.Equal(t, "github.com/go-vgo/gt/zlog", callerPackage(zapcore.EntryCaller{
+		Defined: true, Function: "github.com/go-vgo/gt/zlog.(*Zlog).Info"}))
+	tt.Equal(t, "main", callerPackage(zapcore.EntryCaller{
+		Defined: true, Function: "main.main"}))
+	tt.Equal(t, "", callerPackage(zapcore.EntryCaller{}))
+}
+
+func TestLevels(t *testing.T) {
+	t.Cleanup(func() { defaultState.setOverrides(nil) })
+	tt.NotNil(t, InitWithConfig(Config{Path: t.TempDir(),
+		Levels: map[string]string{"db": "loud"}}))
+
+	dir := initTest(t, `level = "debug"`, "[levels]", `"db" = "warn"`)
+	Named("db").Debug("db debug")
+	Named("db").Error("db error", errors.New("e1"))
+	Named("api").Debug("api debug")
+
+	SetLevelFor("api", zap.InfoLevel)
+	Named("api").Debug("api debug 2")
+	tt.Nil(t, Close())
+
+	entries := readEntries(t, filepath.Join(dir, "*", "test.json"))
+	tt.Equal(t, 0, len(msgEntries(entries, "db debug", "api debug 2")))
+	tt.Equal(t, 2, len(msgEntries(entries, "db error", "api debug")))
+
+	// Init replaces the runtime levels by the table
+	initTest(t)
+	defer Close()
+	tt.Equal(t, 0, len(defaultState.loadOverrides()))
+
+	cfg, logger := *getConfig(), L()
+	cfg.Levels = map[string]string{"db": "error"}
+	tt.Nil(t, apply(cfg))
+	tt.True(t, logger == L())
+	tt.Equal(t, zap.ErrorLevel, defaultState.loadOverrides()["db"])
+}
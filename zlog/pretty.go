@@ -0,0 +1,243 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// prettyTime the default time layout of Pretty
+const prettyTime = "2006-01-02 15:04:05.000"
+
+// the ANSI escapes of Pretty
+const (
+	ansiReset = "\x1b[0m"
+	ansiFaint = "\x1b[2m"
+)
+
+// PrettyOptions the filters and the format of Pretty
+type PrettyOptions struct {
+	// MinLevel the lowest level of the entries like "warn",
+	// default all the levels
+	MinLevel string
+	// Since the time of the first entries, the zero time
+	// doesn't filter
+	Since time.Time
+	// Grep the regexp of the lines, default all the lines
+	Grep string
+	// Color colors the levels and the keys with the ANSI escapes
+	Color bool
+	// TimeFormat the layout of the times, default
+	// "2006-01-02 15:04:05.000"
+	TimeFormat string
+}
+
+// Pretty renders the json entries of r to w as the aligned console
+// lines of the time, the level, the logger, the message and the
+// k=v fields, until the end of r. The keys and the time format of
+// the entries are the ones of the config. The lines which aren't
+// json entries are written as they are, Grep filters them as well.
+//
+//	err := zlog.Pretty(f, os.Stdout, zlog.PrettyOptions{MinLevel: "warn"})
+func Pretty(r io.Reader, w io.Writer, opts PrettyOptions) error {
+	q, err := getConfig().newQuery(QueryOptions{From: opts.Since, MinLevel: opts.MinLevel})
+	if err != nil {
+		return err
+	}
+
+	p := &prettyPrinter{q: q, w: w, color: opts.Color, layout: opts.TimeFormat}
+	if p.layout == "" {
+		p.layout = prettyTime
+	}
+	if opts.Grep != "" {
+		if p.grep, err = regexp.Compile(opts.Grep); err != nil {
+			return fmt.Errorf("zlog: grep %q: %v", opts.Grep, err)
+		}
+	}
+
+	br := bufio.NewReaderSize(r, queryBuf)
+	var line []byte
+	for {
+		line, err = readLine(br, line)
+		if len(line) > 0 {
+			if werr := p.print(line); werr != nil {
+				return werr
+			}
+		}
+
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// prettyPrinter the columns of Pretty, the time and the logger
+// columns widen to the longest values
+type prettyPrinter struct {
+	q      *query
+	w      io.Writer
+	grep   *regexp.Regexp
+	color  bool
+	layout string
+
+	timeWidth, nameWidth int
+	buf                  []byte
+}
+
+// print writes the line of an entry, or line if it isn't one
+func (p *prettyPrinter) print(line []byte) error {
+	raw := bytes.TrimRight(line, "\r\n")
+	if p.grep != nil && !p.grep.Match(raw) {
+		return nil
+	}
+
+	fields, ok := decodeEntry(raw)
+	if !ok {
+		p.buf = append(append(p.buf[:0], raw...), '\n')
+		_, err := p.w.Write(p.buf)
+		return err
+	}
+
+	lvl, _ := fields[p.q.lvlKey].(string)
+	var ent LogEntry
+	if !p.q.entry(fields, &ent) {
+		return nil
+	}
+
+	b := p.buf[:0]
+	if !ent.Time.IsZero() {
+		b = p.column(b, ent.Time.Format(p.layout), &p.timeWidth)
+	} else {
+		b = p.column(b, "", &p.timeWidth)
+	}
+	b = p.level(b, lvl)
+
+	name, _ := ent.Fields["logger"].(string)
+	delete(ent.Fields, "logger")
+	if name != "" || p.nameWidth > 0 {
+		b = p.column(b, name, &p.nameWidth)
+	}
+	b = append(b, ent.Message...)
+
+	stack, _ := ent.Fields["stacktrace"].(string)
+	delete(ent.Fields, "stacktrace")
+	b = p.fields(b, ent.Fields)
+	if stack != "" {
+		b = append(append(b, '\n'), stack...)
+	}
+
+	p.buf = append(b, '\n')
+	_, err := p.w.Write(p.buf)
+	return err
+}
+
+// column appends s and the spaces padding it to the width
+func (p *prettyPrinter) column(b []byte, s string, width *int) []byte {
+	if len(s) > *width {
+		*width = len(s)
+	}
+
+	b = append(b, s...)
+	return append(b, strings.Repeat(" ", *width-len(s)+1)...)
+}
+
+// level appends the capital level padded to 5, in the color of
+// the console encoder
+func (p *prettyPrinter) level(b []byte, s string) []byte {
+	lvl, err := zapcore.ParseLevel(s)
+	if err == nil {
+		s = lvl.CapitalString()
+	} else {
+		s = strings.ToUpper(s)
+	}
+
+	if p.color && err == nil {
+		b = append(b, levelColor(lvl)...)
+		b = append(b, s...)
+		b = append(b, ansiReset...)
+	} else {
+		b = append(b, s...)
+	}
+
+	if len(s) < 5 {
+		b = append(b, strings.Repeat(" ", 5-len(s))...)
+	}
+	return append(b, ' ')
+}
+
+// levelColor returns the ANSI color of lvl
+func levelColor(lvl zapcore.Level) string {
+	switch {
+	case lvl <= zapcore.DebugLevel:
+		return "\x1b[35m"
+	case lvl == zapcore.InfoLevel:
+		return "\x1b[34m"
+	case lvl == zapcore.WarnLevel:
+		return "\x1b[33m"
+	}
+	return "\x1b[31m"
+}
+
+// fields appends the k=v fields in the order of the keys
+func (p *prettyPrinter) fields(b []byte, fields map[string]interface{}) []byte {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for i, key := range keys {
+		if i == 0 {
+			b = append(b, "  "...)
+		} else {
+			b = append(b, ' ')
+		}
+
+		if p.color {
+			b = append(b, ansiFaint...)
+			b = append(b, key...)
+			b = append(b, '=')
+			b = append(b, ansiReset...)
+		} else {
+			b = append(append(b, key...), '=')
+		}
+		b = appendPretty(b, fields[key])
+	}
+	return b
+}
+
+// appendPretty appends v, quoted if it isn't a logfmt value
+func appendPretty(b []byte, v interface{}) []byte {
+	switch v := v.(type) {
+	case string:
+		if logfmtQuote(v) {
+			return strconv.AppendQuote(b, v)
+		}
+		return append(b, v...)
+	case json.Number:
+		return append(b, v...)
+	}
+	return append(b, jsonText(v)...)
+}
@@ -0,0 +1,86 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vcaesar/tt"
+)
+
+const prettyInput = `{"level":"info","time":"2018-05-01 08:30:00","logger":"db","caller":"zlog/log.go:12","msg":"connected","host":"db1","port":5432}
+not an entry
+{"level":"warn","time":"2018-05-01 08:31:00","msg":"slow query","took":"1.5s","sql":"select * from t"}
+{"level":"error","time":"2018-05-01 08:32:00","logger":"http","msg":"request failed","error":"timeout","ok":false,"tags":["a","b"],"stacktrace":"main.main\n\t/app/main.go:10"}
+{"level":"debug","time":"2018-05-01 08:33:00","msg":"poll"}
+`
+
+func TestPrettyGolden(t *testing.T) {
+	defer setConfig(*getConfig())
+	setConfig(Config{})
+
+	since := time.Date(2018, 5, 1, 8, 31, 0, 0, time.Local)
+	tests := []struct {
+		name string
+		opts PrettyOptions
+	}{
+		{"plain", PrettyOptions{}},
+		{"color", PrettyOptions{Color: true}},
+		{"filter", PrettyOptions{MinLevel: "warn", Since: since,
+			Grep: "query|failed|entry"}},
+	}
+
+	for _, test := range tests {
+		var buf bytes.Buffer
+		tt.Nil(t, Pretty(strings.NewReader(prettyInput), &buf, test.opts))
+
+		golden := filepath.Join("..", "testdata", "zlog_pretty_"+test.name+".golden")
+		if *update {
+			tt.Nil(t, ioutil.WriteFile(golden, buf.Bytes(), 0644))
+		}
+
+		want, err := ioutil.ReadFile(golden)
+		tt.Nil(t, err)
+		tt.Equal(t, string(want), buf.String())
+	}
+}
+
+func TestPrettyKeys(t *testing.T) {
+	defer setConfig(*getConfig())
+	setConfig(Config{Encoder: EncoderConfig{MessageKey: "message",
+		TimeFormat: "rfc3339", LevelFormat: "capital"}})
+
+	var buf bytes.Buffer
+	err := Pretty(strings.NewReader(`{"level":"WARN","time":"2018-05-01T08:30:00Z","message":"custom"}`),
+		&buf, PrettyOptions{TimeFormat: time.Kitchen})
+	tt.Nil(t, err)
+	tt.Equal(t, "8:30AM WARN  custom\n", buf.String())
+
+	// the last line without the newline
+	buf.Reset()
+	tt.Nil(t, Pretty(strings.NewReader("partial"), &buf, PrettyOptions{}))
+	tt.Equal(t, "partial\n", buf.String())
+}
+
+func TestPrettyErrors(t *testing.T) {
+	defer setConfig(*getConfig())
+	setConfig(Config{})
+
+	var buf bytes.Buffer
+	tt.NotNil(t, Pretty(strings.NewReader(prettyInput), &buf, PrettyOptions{Grep: "("}))
+	tt.NotNil(t, Pretty(strings.NewReader(prettyInput), &buf, PrettyOptions{MinLevel: "loud"}))
+	tt.Equal(t, 0, buf.Len())
+}
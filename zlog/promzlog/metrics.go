@@ -0,0 +1,90 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+// Package promzlog prometheus metrics of the zlog entries,
+// it is a separate package so zlog doesn't depend on prometheus.
+package promzlog
+
+import (
+	"sync"
+
+	"github.com/go-vgo/gt/zlog"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap/zapcore"
+)
+
+var (
+	hookOnce sync.Once
+
+	mu          sync.RWMutex
+	entries     *prometheus.CounterVec
+	writeErrors prometheus.Counter
+)
+
+// EnableMetrics registers the zlog_entries_total{level} and the
+// zlog_write_errors_total counters into reg and counts the entries
+// of zlog, the counters keep counting across zlog.Init, calling it
+// again with the same reg reuses the registered counters.
+//
+//	promzlog.EnableMetrics(prometheus.DefaultRegisterer)
+func EnableMetrics(reg prometheus.Registerer) error {
+	e := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "zlog_entries_total",
+		Help: "The number of the zlog entries by level.",
+	}, []string{"level"})
+
+	w := prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "zlog_write_errors_total",
+		Help: "The number of the zlog entries failed to write.",
+	})
+
+	if err := reg.Register(e); err != nil {
+		are, ok := err.(prometheus.AlreadyRegisteredError)
+		if !ok {
+			return err
+		}
+		e = are.ExistingCollector.(*prometheus.CounterVec)
+	}
+
+	if err := reg.Register(w); err != nil {
+		are, ok := err.(prometheus.AlreadyRegisteredError)
+		if !ok {
+			return err
+		}
+		w = are.ExistingCollector.(prometheus.Counter)
+	}
+
+	mu.Lock()
+	entries, writeErrors = e, w
+	mu.Unlock()
+
+	hookOnce.Do(func() {
+		zlog.OnEntry(countEntry)
+		zlog.OnWriteError(countWriteError)
+	})
+	return nil
+}
+
+func countEntry(ent zapcore.Entry) error {
+	mu.RLock()
+	e := entries
+	mu.RUnlock()
+
+	e.WithLabelValues(ent.Level.String()).Inc()
+	return nil
+}
+
+func countWriteError(error) {
+	mu.RLock()
+	w := writeErrors
+	mu.RUnlock()
+
+	w.Inc()
+}
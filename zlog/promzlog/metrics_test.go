@@ -0,0 +1,64 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package promzlog
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-vgo/gt/zlog"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/vcaesar/tt"
+)
+
+func TestMetrics(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	tt.Nil(t, EnableMetrics(reg))
+	// enabled twice and across Init
+	tt.Nil(t, EnableMetrics(reg))
+
+	tt.Nil(t, zlog.InitWithConfig(zlog.Config{Path: t.TempDir(), Mode: "stdout",
+		Level: "debug"}))
+	zlog.Debug("metrics debug")
+	zlog.Info("metrics info")
+	tt.Nil(t, zlog.InitWithConfig(zlog.Config{Path: t.TempDir(), Level: "debug"}))
+	zlog.Info("metrics info")
+	zlog.Warn("metrics warn")
+	zlog.Error("metrics error", errors.New("e1"))
+	tt.Nil(t, zlog.Close())
+
+	tt.Equal(t, float64(1), testutil.ToFloat64(entries.WithLabelValues("debug")))
+	// the old log sweep logs an info entry as well
+	tt.True(t, testutil.ToFloat64(entries.WithLabelValues("info")) >= 2)
+	tt.Equal(t, float64(1), testutil.ToFloat64(entries.WithLabelValues("warn")))
+	tt.Equal(t, float64(1), testutil.ToFloat64(entries.WithLabelValues("error")))
+	tt.Equal(t, float64(0), testutil.ToFloat64(writeErrors))
+
+	// the day dir is replaced by a file, the new files can't be created
+	dir := t.TempDir()
+	tt.Nil(t, zlog.InitWithConfig(zlog.Config{Path: dir}))
+	day := filepath.Join(dir, time.Now().Format(zlog.DayFormat))
+	tt.Nil(t, os.RemoveAll(day))
+	tt.Nil(t, ioutil.WriteFile(day, nil, 0644))
+	tt.NotNil(t, zlog.Rotate())
+	zlog.Warn("metrics write error")
+	tt.Nil(t, zlog.Close())
+	tt.True(t, testutil.ToFloat64(writeErrors) >= 1)
+
+	n, err := testutil.GatherAndCount(reg, "zlog_entries_total", "zlog_write_errors_total")
+	tt.Nil(t, err)
+	tt.Equal(t, 5, n)
+}
@@ -0,0 +1,555 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"container/heap"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/multierr"
+	"go.uber.org/zap/zapcore"
+)
+
+// queryBuf the size of the buffer of a file read by Query
+const queryBuf = 32 << 10
+
+// QueryOptions the entries read back by Query
+type QueryOptions struct {
+	// From and To the time range of the entries, From is included
+	// and To is excluded, a zero time doesn't bound the range
+	From, To time.Time
+	// MinLevel the lowest level of the entries like "warn",
+	// default all the levels
+	MinLevel string
+	// MessageContains the substring of the messages
+	MessageContains string
+	// Fields the values of the fields of the entries, the values
+	// which aren't strings match their json like "200" or "true"
+	Fields map[string]string
+	// Path the dir of the log files, default the path of the config
+	Path string
+	// Name the name of the log files, default the name of the config
+	Name string
+}
+
+// LogEntry an entry read back by Query
+type LogEntry struct {
+	Time    time.Time
+	Level   zapcore.Level
+	Message string
+	// Fields the other keys of the entry like the caller, the
+	// numbers are json.Number
+	Fields map[string]interface{}
+	// File the log file of the entry
+	File string
+}
+
+// EntryIterator the entries of Query, Next reads the next entry
+// and reports whether there's one, Err returns the error which
+// stopped it. Close closes the open files, it is needed when
+// the iterator isn't read to the end.
+type EntryIterator interface {
+	Next() bool
+	Entry() LogEntry
+	Err() error
+	Close() error
+}
+
+// Query reads back the entries of the json log files matching q,
+// the daily dirs of the time range are read with the rotated and
+// the compressed files, in the order of the entry times. The files
+// are streamed so only an entry per file is held, the entries
+// still buffered by Async or Buffer are read after a Sync.
+//
+//	iter, err := zlog.Query(zlog.QueryOptions{
+//		From: time.Now().Add(-time.Hour), MinLevel: "error",
+//	})
+//	if err != nil {
+//		return err
+//	}
+//	defer iter.Close()
+//
+//	for iter.Next() {
+//		fmt.Println(iter.Entry().Message)
+//	}
+//	return iter.Err()
+func Query(q QueryOptions) (EntryIterator, error) {
+	qc, err := getConfig().newQuery(q)
+	if err != nil {
+		return nil, err
+	}
+
+	groups, err := qc.groups()
+	if err != nil {
+		return nil, err
+	}
+	return &queryIter{q: qc, groups: groups}, nil
+}
+
+// query the options of Query with the keys and the files of
+// the config
+type query struct {
+	QueryOptions
+	hasMin  bool
+	min     zapcore.Level
+	daily   bool
+	names   []string
+	timeKey string
+	lvlKey  string
+	msgKey  string
+	format  string
+}
+
+func (c *Config) newQuery(q QueryOptions) (*query, error) {
+	encoding := c.FileEncoding
+	if encoding == "" {
+		encoding = c.Encoding
+	}
+	if encoding != "" && encoding != "json" {
+		return nil, fmt.Errorf("zlog: only the json log files are read back, not %q", encoding)
+	}
+
+	if !q.From.IsZero() && !q.To.IsZero() && q.To.Before(q.From) {
+		return nil, errors.New("zlog: the query ends before it starts")
+	}
+
+	qc := &query{
+		QueryOptions: q,
+		daily:        c.dailyDirs(),
+		timeKey:      "time",
+		lvlKey:       "level",
+		msgKey:       "msg",
+		format:       c.Encoder.TimeFormat,
+	}
+	if q.MinLevel != "" {
+		lvl, err := zapcore.ParseLevel(q.MinLevel)
+		if err != nil {
+			return nil, fmt.Errorf("zlog: unknown query level %q", q.MinLevel)
+		}
+		qc.hasMin, qc.min = true, lvl
+	}
+
+	for _, key := range []struct {
+		dst *string
+		val string
+	}{
+		{&qc.timeKey, c.Encoder.TimeKey},
+		{&qc.lvlKey, c.Encoder.LevelKey},
+		{&qc.msgKey, c.Encoder.MessageKey},
+	} {
+		if key.val != "" {
+			*key.dst = key.val
+		}
+	}
+
+	lpath, name := c.confPath()
+	if qc.Path == "" {
+		qc.Path = lpath
+	}
+	if qc.Name == "" {
+		qc.Name = name
+	}
+	qc.names = c.queryNames(qc.Name)
+
+	return qc, nil
+}
+
+// queryNames returns the log files of name holding every entry
+// once, the error file is skipped when it mirrors the main file
+func (c *Config) queryNames(name string) []string {
+	switch {
+	case c.SplitLevels:
+		return []string{name + "_debug.json", name + ".json",
+			name + "_warn.json", name + "_err.json"}
+	case c.mirrorErrors():
+		return []string{name + ".json"}
+	}
+	return []string{name + ".json", name + "_err.json"}
+}
+
+// groups returns the files of the daily dirs of the time range,
+// a group per dir in the order of the days
+func (q *query) groups() ([][]string, error) {
+	if !q.daily {
+		return [][]string{q.files(q.Path)}, nil
+	}
+
+	fis, err := ioutil.ReadDir(q.Path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	type dayDir struct {
+		day  time.Time
+		name string
+	}
+	var dirs []dayDir
+	for _, fi := range fis {
+		if !fi.IsDir() {
+			continue
+		}
+
+		day, err := time.ParseInLocation(DayFormat, fi.Name(), time.Local)
+		if err != nil || !q.hasDay(day) {
+			continue
+		}
+		dirs = append(dirs, dayDir{day, fi.Name()})
+	}
+	sort.Slice(dirs, func(i, j int) bool { return dirs[i].day.Before(dirs[j].day) })
+
+	groups := make([][]string, 0, len(dirs))
+	for _, dir := range dirs {
+		groups = append(groups, q.files(filepath.Join(q.Path, dir.name)))
+	}
+	return groups, nil
+}
+
+// hasDay reports whether the dir of day may hold the entries of
+// the time range, an entry of the end of a day may be written
+// in the dir of the next day
+func (q *query) hasDay(day time.Time) bool {
+	if !q.From.IsZero() && day.Before(startOfDay(q.From)) {
+		return false
+	}
+	return q.To.IsZero() || !day.After(startOfDay(q.To.Add(time.Minute)))
+}
+
+func startOfDay(t time.Time) time.Time {
+	t = t.Local()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+}
+
+// files returns the log files of dir, the rotated and the
+// compressed backups before the current file
+func (q *query) files(dir string) []string {
+	var files []string
+	for _, name := range q.names {
+		base := filepath.Join(dir, strings.TrimSuffix(name, ".json"))
+		backups, _ := filepath.Glob(base + "-*.json")
+		compressed, _ := filepath.Glob(base + "-*.json.gz")
+
+		backups = append(backups, compressed...)
+		sort.Strings(backups)
+		files = append(files, backups...)
+		files = append(files, filepath.Join(dir, name))
+	}
+	return files
+}
+
+// parse decodes the entry of line, it reports false for the lines
+// which aren't entries and the entries which don't match
+func (q *query) parse(line []byte, ent *LogEntry) bool {
+	fields, ok := decodeEntry(line)
+	return ok && q.entry(fields, ent)
+}
+
+// decodeEntry decodes the json object of line, the numbers
+// are json.Number
+func decodeEntry(line []byte) (map[string]interface{}, bool) {
+	fields := make(map[string]interface{})
+	dec := json.NewDecoder(bytes.NewReader(line))
+	dec.UseNumber()
+	if dec.Decode(&fields) != nil {
+		return nil, false
+	}
+	return fields, true
+}
+
+// entry sets ent to the decoded fields, it reports false for
+// the entries which don't match
+func (q *query) entry(fields map[string]interface{}, ent *LogEntry) bool {
+	tm, ok := q.entryTime(fields[q.timeKey])
+	if !ok && (!q.From.IsZero() || !q.To.IsZero()) {
+		return false
+	}
+	if !q.From.IsZero() && tm.Before(q.From) || !q.To.IsZero() && !tm.Before(q.To) {
+		return false
+	}
+
+	s, _ := fields[q.lvlKey].(string)
+	lvl, err := zapcore.ParseLevel(s)
+	if q.hasMin && (err != nil || lvl < q.min) {
+		return false
+	}
+
+	msg, _ := fields[q.msgKey].(string)
+	if !strings.Contains(msg, q.MessageContains) {
+		return false
+	}
+
+	for key, val := range q.Fields {
+		v, ok := fields[key]
+		if !ok || jsonText(v) != val {
+			return false
+		}
+	}
+
+	delete(fields, q.timeKey)
+	delete(fields, q.lvlKey)
+	delete(fields, q.msgKey)
+	ent.Time, ent.Level, ent.Message, ent.Fields = tm, lvl, msg, fields
+	return true
+}
+
+// entryTime parses the time of an entry in the time format
+// of the config
+func (q *query) entryTime(v interface{}) (time.Time, bool) {
+	if n, ok := v.(json.Number); ok {
+		f, err := n.Float64()
+		if err != nil {
+			return time.Time{}, false
+		}
+
+		switch q.format {
+		case "epoch":
+			return time.Unix(0, int64(f*float64(time.Second))), true
+		case "epochmillis":
+			return time.Unix(0, int64(f*float64(time.Millisecond))), true
+		case "epochnanos":
+			ns, err := n.Int64()
+			return time.Unix(0, ns), err == nil
+		}
+		return time.Time{}, false
+	}
+
+	s, ok := v.(string)
+	if !ok {
+		return time.Time{}, false
+	}
+
+	layout := q.format
+	switch layout {
+	case "":
+		layout = TimeFormat
+	case "rfc3339":
+		layout = time.RFC3339
+	case "rfc3339nano":
+		layout = time.RFC3339Nano
+	case "iso8601":
+		layout = "2006-01-02T15:04:05.000Z0700"
+	}
+
+	tm, err := time.ParseInLocation(layout, s, time.Local)
+	return tm, err == nil
+}
+
+// jsonText returns v as a string, or its json
+func jsonText(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+// queryFile a log file read by Query and its next entry
+type queryFile struct {
+	path  string
+	order int
+	f     *os.File
+	gz    *gzip.Reader
+	r     *bufio.Reader
+	line  []byte
+	ent   LogEntry
+}
+
+func openQueryFile(path string, order int) (*queryFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	qf := &queryFile{path: path, order: order, f: f}
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		if qf.gz, err = gzip.NewReader(f); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("zlog: query %s: %v", path, err)
+		}
+		r = qf.gz
+	}
+	qf.r = bufio.NewReaderSize(r, queryBuf)
+
+	return qf, nil
+}
+
+// next reads the next entry matching q, it reports false at the
+// end of the file
+func (f *queryFile) next(q *query) (bool, error) {
+	for {
+		line, err := readLine(f.r, f.line)
+		f.line = line
+		if len(line) > 0 && q.parse(line, &f.ent) {
+			f.ent.File = f.path
+			return true, nil
+		}
+
+		if err == io.EOF {
+			return false, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("zlog: query %s: %v", f.path, err)
+		}
+	}
+}
+
+// readLine reads a line of r into buf, the lines longer than
+// maxShipLine are skipped
+func readLine(r *bufio.Reader, buf []byte) ([]byte, error) {
+	buf = buf[:0]
+	long := false
+	for {
+		part, err := r.ReadSlice('\n')
+		long = long || len(buf)+len(part) > maxShipLine
+		if !long {
+			buf = append(buf, part...)
+		}
+
+		if err != bufio.ErrBufferFull {
+			if long {
+				return buf[:0], err
+			}
+			return buf, err
+		}
+	}
+}
+
+func (f *queryFile) Close() error {
+	if f.gz != nil {
+		f.gz.Close()
+	}
+	return f.f.Close()
+}
+
+// fileHeap the files of a day by the time of their next entry
+type fileHeap []*queryFile
+
+func (h fileHeap) Len() int { return len(h) }
+
+func (h fileHeap) Less(i, j int) bool {
+	ti, tj := h[i].ent.Time, h[j].ent.Time
+	if ti.Equal(tj) {
+		return h[i].order < h[j].order
+	}
+	return ti.Before(tj)
+}
+
+func (h fileHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *fileHeap) Push(x interface{}) {
+	*h = append(*h, x.(*queryFile))
+}
+
+func (h *fileHeap) Pop() interface{} {
+	old := *h
+	f := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return f
+}
+
+// queryIter merges the files of a day at a time
+type queryIter struct {
+	q      *query
+	groups [][]string
+	files  fileHeap
+	ent    LogEntry
+	err    error
+	closed bool
+}
+
+func (it *queryIter) Next() bool {
+	for it.err == nil && !it.closed {
+		if len(it.files) == 0 {
+			if len(it.groups) == 0 {
+				return false
+			}
+			it.openGroup()
+			continue
+		}
+
+		f := it.files[0]
+		it.ent = f.ent
+		ok, err := f.next(it.q)
+		if ok {
+			heap.Fix(&it.files, 0)
+		} else {
+			heap.Pop(&it.files)
+			f.Close()
+		}
+
+		// the entry is returned before the error
+		it.err = err
+		return true
+	}
+	return false
+}
+
+// openGroup opens the files of the next day and reads their
+// first entries, the files removed since are skipped
+func (it *queryIter) openGroup() {
+	group := it.groups[0]
+	it.groups = it.groups[1:]
+
+	for i, path := range group {
+		f, err := openQueryFile(path, i)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			it.err = err
+			return
+		}
+
+		ok, err := f.next(it.q)
+		if !ok {
+			f.Close()
+		} else {
+			heap.Push(&it.files, f)
+		}
+		if err != nil {
+			it.err = err
+			return
+		}
+	}
+}
+
+func (it *queryIter) Entry() LogEntry {
+	return it.ent
+}
+
+func (it *queryIter) Err() error {
+	return it.err
+}
+
+func (it *queryIter) Close() error {
+	var err error
+	for _, f := range it.files {
+		err = multierr.Append(err, f.Close())
+	}
+	it.files, it.groups, it.closed = nil, nil, true
+	return err
+}
@@ -0,0 +1,214 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vcaesar/tt"
+)
+
+// queryLine returns an entry of the default encoder at hour:min of day
+func queryLine(day time.Time, hour, min int, lvl, msg string, req int) string {
+	tm := day.Add(time.Duration(hour)*time.Hour + time.Duration(min)*time.Minute)
+	return fmt.Sprintf(`{"level":%q,"time":%q,"msg":%q,"req":%d}`,
+		lvl, tm.Format(TimeFormat), msg, req)
+}
+
+// writeQueryFile writes the lines to path, gzipped for a .gz path
+func writeQueryFile(t *testing.T, path string, lines ...string) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	data := []byte(strings.Join(lines, "\n") + "\n")
+	if strings.HasSuffix(path, ".gz") {
+		var b strings.Builder
+		zw := gzip.NewWriter(&b)
+		zw.Write(data)
+		zw.Close()
+		data = []byte(b.String())
+	}
+
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// queryMsgs returns the messages of the entries of q
+func queryMsgs(t *testing.T, q QueryOptions) []string {
+	iter, err := Query(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iter.Close()
+
+	var msgs []string
+	for iter.Next() {
+		msgs = append(msgs, iter.Entry().Message)
+	}
+	tt.Nil(t, iter.Err())
+
+	return msgs
+}
+
+// queryTree writes the log files of three days with the rotated,
+// the compressed and the error files
+func queryTree(t *testing.T) (string, time.Time) {
+	dir := t.TempDir()
+	day := time.Date(2018, 5, 1, 0, 0, 0, 0, time.Local)
+	dayDir := func(n int) string {
+		return filepath.Join(dir, day.AddDate(0, 0, n).Format(DayFormat))
+	}
+
+	writeQueryFile(t, filepath.Join(dayDir(0), "test-2018-05-01T12-00-00.000.json.gz"),
+		queryLine(day, 8, 0, "info", "start", 200),
+		queryLine(day, 9, 0, "debug", "poll", 200))
+	writeQueryFile(t, filepath.Join(dayDir(0), "test.json"),
+		queryLine(day, 13, 0, "warn", "slow", 200))
+	writeQueryFile(t, filepath.Join(dayDir(0), "test_err.json"),
+		queryLine(day, 8, 30, "error", "fail db", 500),
+		"not an entry")
+
+	day2 := day.AddDate(0, 0, 1)
+	writeQueryFile(t, filepath.Join(dayDir(1), "test-2018-05-02T10-30-00.000.json"),
+		queryLine(day2, 10, 0, "info", "second day", 200))
+	writeQueryFile(t, filepath.Join(dayDir(1), "test.json"),
+		queryLine(day2, 11, 0, "info", "later", 200))
+
+	writeQueryFile(t, filepath.Join(dayDir(2), "test.json"),
+		queryLine(day.AddDate(0, 0, 2), 9, 0, "info", "third", 200))
+
+	// not the log files
+	writeQueryFile(t, filepath.Join(dir, "tenants", "test.json"),
+		queryLine(day, 8, 0, "info", "tenants", 200))
+	writeQueryFile(t, filepath.Join(dayDir(2), "audit.json"),
+		queryLine(day, 8, 0, "info", "audit", 200))
+
+	return dir, day
+}
+
+func TestQuery(t *testing.T) {
+	defer setConfig(*getConfig())
+
+	mirror := false
+	setConfig(Config{Name: "test", MirrorErrors: &mirror})
+	dir, day := queryTree(t)
+
+	msgs := queryMsgs(t, QueryOptions{Path: dir})
+	tt.Equal(t, []string{"start", "fail db", "poll", "slow",
+		"second day", "later", "third"}, msgs)
+
+	msgs = queryMsgs(t, QueryOptions{Path: dir,
+		From: day.AddDate(0, 0, 1), To: day.AddDate(0, 0, 2)})
+	tt.Equal(t, []string{"second day", "later"}, msgs)
+
+	msgs = queryMsgs(t, QueryOptions{Path: dir, From: day.Add(9 * time.Hour),
+		To: day.AddDate(0, 0, 1).Add(11 * time.Hour)})
+	tt.Equal(t, []string{"poll", "slow", "second day"}, msgs)
+
+	msgs = queryMsgs(t, QueryOptions{Path: dir, MinLevel: "warn"})
+	tt.Equal(t, []string{"fail db", "slow"}, msgs)
+
+	msgs = queryMsgs(t, QueryOptions{Path: dir, MessageContains: "day"})
+	tt.Equal(t, []string{"second day"}, msgs)
+
+	iter, err := Query(QueryOptions{Path: dir, Fields: map[string]string{"req": "500"}})
+	tt.Nil(t, err)
+	tt.True(t, iter.Next())
+	ent := iter.Entry()
+	tt.Equal(t, "fail db", ent.Message)
+	tt.Equal(t, json.Number("500"), ent.Fields["req"])
+	tt.Equal(t, "test_err.json", filepath.Base(ent.File))
+	tt.True(t, ent.Time.Equal(day.Add(8*time.Hour+30*time.Minute)))
+	tt.False(t, iter.Next())
+	tt.Nil(t, iter.Close())
+
+	// closed before the end
+	iter, err = Query(QueryOptions{Path: dir})
+	tt.Nil(t, err)
+	tt.True(t, iter.Next())
+	tt.Nil(t, iter.Close())
+	tt.False(t, iter.Next())
+
+	// the error file mirrors the main file
+	setConfig(Config{Name: "test"})
+	msgs = queryMsgs(t, QueryOptions{Path: dir, To: day.AddDate(0, 0, 1)})
+	tt.Equal(t, []string{"start", "poll", "slow"}, msgs)
+
+	tt.Equal(t, 0, len(queryMsgs(t, QueryOptions{Path: filepath.Join(dir, "none")})))
+}
+
+func TestQueryFlat(t *testing.T) {
+	defer setConfig(*getConfig())
+
+	daily := false
+	setConfig(Config{Name: "test", DailyDirs: &daily,
+		Encoder: EncoderConfig{TimeFormat: "epochmillis", MessageKey: "message"}})
+
+	dir := t.TempDir()
+	day := time.Date(2018, 5, 1, 0, 0, 0, 0, time.UTC)
+	ms := func(hour int) int64 {
+		return day.Add(time.Duration(hour)*time.Hour).UnixNano() / int64(time.Millisecond)
+	}
+	writeQueryFile(t, filepath.Join(dir, "test-2018-05-01T12-00-00.000.json.gz"),
+		fmt.Sprintf(`{"level":"info","time":%d,"message":"old"}`, ms(8)))
+	writeQueryFile(t, filepath.Join(dir, "test.json"),
+		fmt.Sprintf(`{"level":"info","time":%d,"message":"new"}`, ms(13)),
+		`{"level":"info","time":1,"message":"`+strings.Repeat("x", maxShipLine)+`"}`)
+
+	msgs := queryMsgs(t, QueryOptions{Path: dir, From: day})
+	tt.Equal(t, []string{"old", "new"}, msgs)
+}
+
+func TestQueryLogs(t *testing.T) {
+	dir := initTest(t)
+	Info("query info")
+	Error("query error", errors.New("e1"))
+	getLogger().Sync()
+	getErrLogger().Sync()
+
+	iter, err := Query(QueryOptions{MinLevel: "error"})
+	tt.Nil(t, err)
+	defer iter.Close()
+
+	tt.True(t, iter.Next())
+	ent := iter.Entry()
+	tt.Equal(t, "query error", ent.Message)
+	tt.Equal(t, "e1", ent.Fields["error"])
+	tt.True(t, strings.HasPrefix(ent.File, dir))
+	tt.False(t, iter.Next())
+}
+
+func TestQueryErrors(t *testing.T) {
+	defer setConfig(*getConfig())
+
+	setConfig(Config{})
+	_, err := Query(QueryOptions{MinLevel: "loud"})
+	tt.NotNil(t, err)
+
+	now := time.Now()
+	_, err = Query(QueryOptions{From: now, To: now.Add(-time.Hour)})
+	tt.NotNil(t, err)
+
+	setConfig(Config{Encoding: "logfmt"})
+	_, err = Query(QueryOptions{})
+	tt.NotNil(t, err)
+}
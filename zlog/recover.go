@@ -0,0 +1,52 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"fmt"
+	"runtime/debug"
+
+	"go.uber.org/zap"
+)
+
+// Recover recovers a panic and logs it with the stack into the
+// error log, the panic is swallowed, it must be deferred directly.
+//
+//	defer zlog.Recover()
+func Recover() {
+	if rec := recover(); rec != nil {
+		logPanic(rec, nil)
+	}
+}
+
+// RecoverWith recovers a panic like Recover and logs the fields
+//
+//	defer zlog.RecoverWith(zlog.Str("job", name))
+func RecoverWith(fields ...zap.Field) {
+	if rec := recover(); rec != nil {
+		logPanic(rec, fields)
+	}
+}
+
+// Go runs fn in a goroutine, a panic of fn is logged with Recover
+func Go(fn func()) {
+	go func() {
+		defer Recover()
+		fn()
+	}()
+}
+
+func logPanic(rec interface{}, fields []zap.Field) {
+	getErrLogger().Error("zlog: recovered panic", append([]zap.Field{
+		zap.String("panic", fmt.Sprintf("%+v", rec)),
+		zap.String("stack", string(debug.Stack())),
+	}, fields...)...)
+}
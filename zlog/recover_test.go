@@ -0,0 +1,64 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vcaesar/tt"
+	"go.uber.org/zap"
+)
+
+func TestRecover(t *testing.T) {
+	_, errLogs := observeSplit(t)
+
+	func() {
+		defer Recover()
+		panic(errors.New("e1"))
+	}()
+
+	func() {
+		defer RecoverWith(Str("job", "sync"))
+		panic("boom")
+	}()
+
+	func() {
+		defer Recover()
+	}()
+
+	entries := errLogs.FilterMessage("zlog: recovered panic").AllUntimed()
+	tt.Equal(t, 2, len(entries))
+	tt.Equal(t, zap.ErrorLevel, entries[0].Level)
+	tt.Equal(t, "e1", entries[0].ContextMap()["panic"])
+	tt.True(t, strings.Contains(entries[0].ContextMap()["stack"].(string), "TestRecover"))
+	tt.Equal(t, "boom", entries[1].ContextMap()["panic"])
+	tt.Equal(t, "sync", entries[1].ContextMap()["job"])
+}
+
+func TestGo(t *testing.T) {
+	_, errLogs := observeSplit(t)
+
+	Go(func() {
+		panic("go boom")
+	})
+
+	for i := 0; i < 100 && errLogs.Len() == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	entries := errLogs.FilterMessage("zlog: recovered panic").AllUntimed()
+	tt.Equal(t, 1, len(entries))
+	tt.Equal(t, "go boom", entries[0].ContextMap()["panic"])
+	tt.True(t, strings.Contains(entries[0].ContextMap()["stack"].(string), "TestGo"))
+}
@@ -0,0 +1,231 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Redacted the value of the redacted fields
+const Redacted = "[REDACTED]"
+
+// redaction the redacted keys, lower cased, and the
+// key=value pattern of the messages
+type redaction struct {
+	keys map[string]struct{}
+	msg  *regexp.Regexp
+}
+
+// SetRedactedKeys replaces the values of the fields named like keys,
+// ignoring the case, by "[REDACTED]", also inside the zap.Any maps and
+// structs and the zap.Object fields, and the key=value of the messages.
+// It applies to the package loggers, the loggers of New redact the
+// keys of their Redact, no key turns it off.
+//
+//	zlog.SetRedactedKeys("password", "authorization", "token")
+func SetRedactedKeys(keys ...string) {
+	defaultState.setRedactedKeys(keys...)
+}
+
+// setRedactedKeys sets the redacted keys of st like SetRedactedKeys
+func (st *state) setRedactedKeys(keys ...string) {
+	if len(keys) == 0 {
+		st.redact.Store((*redaction)(nil))
+		return
+	}
+
+	r := &redaction{keys: make(map[string]struct{}, len(keys))}
+	quoted := make([]string, 0, len(keys))
+	for _, key := range keys {
+		r.keys[strings.ToLower(key)] = struct{}{}
+		quoted = append(quoted, regexp.QuoteMeta(key))
+	}
+
+	r.msg = regexp.MustCompile(`(?i)\b(` + strings.Join(quoted, "|") +
+		`)=("[^"]*"|[^\s,;&]*)`)
+	st.redact.Store(r)
+}
+
+func (st *state) loadRedaction() *redaction {
+	r, _ := st.redact.Load().(*redaction)
+	return r
+}
+
+func (r *redaction) match(key string) bool {
+	_, ok := r.keys[strings.ToLower(key)]
+	return ok
+}
+
+// message redacts the key=value of msg
+func (r *redaction) message(msg string) string {
+	return r.msg.ReplaceAllString(msg, "${1}="+Redacted)
+}
+
+// fields returns the redacted fields
+func (r *redaction) fields(fields []zapcore.Field) []zapcore.Field {
+	return mapFields(fields, r.field)
+}
+
+// field returns the redacted f and true if f changes
+func (r *redaction) field(f zapcore.Field) (zapcore.Field, bool) {
+	if f.Key != "" && r.match(f.Key) {
+		return zap.String(f.Key, Redacted), true
+	}
+
+	switch f.Type {
+	case zapcore.ObjectMarshalerType, zapcore.InlineMarshalerType:
+		f.Interface = redactObject{r, f.Interface.(zapcore.ObjectMarshaler)}
+		return f, true
+	case zapcore.ArrayMarshalerType:
+		f.Interface = redactArray{r, f.Interface.(zapcore.ArrayMarshaler)}
+		return f, true
+	case zapcore.ReflectType:
+		f.Interface = r.value(f.Interface)
+		return f, true
+	}
+	return f, false
+}
+
+// value returns the redacted copy of a zap.Any value, the maps,
+// structs and slices are rebuilt from their json
+func (r *redaction) value(v interface{}) interface{} {
+	if v == nil {
+		return v
+	}
+
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.Map, reflect.Struct, reflect.Slice, reflect.Array,
+		reflect.Ptr, reflect.Interface:
+	default:
+		return v
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var tree interface{}
+	if err := dec.Decode(&tree); err != nil {
+		return v
+	}
+	return r.tree(tree)
+}
+
+func (r *redaction) tree(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, val := range v {
+			if r.match(key) {
+				v[key] = Redacted
+			} else {
+				v[key] = r.tree(val)
+			}
+		}
+	case []interface{}:
+		for i, val := range v {
+			v[i] = r.tree(val)
+		}
+	}
+	return v
+}
+
+// redactObject marshals the object through a redactEncoder
+type redactObject struct {
+	r *redaction
+	m zapcore.ObjectMarshaler
+}
+
+func (o redactObject) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	return o.m.MarshalLogObject(redactEncoder{enc, o.r})
+}
+
+// redactArray marshals the array through a redactArrayEncoder
+type redactArray struct {
+	r *redaction
+	m zapcore.ArrayMarshaler
+}
+
+func (a redactArray) MarshalLogArray(enc zapcore.ArrayEncoder) error {
+	return a.m.MarshalLogArray(redactArrayEncoder{enc, a.r})
+}
+
+// redactEncoder redacts the keys of the objects
+type redactEncoder struct {
+	zapcore.ObjectEncoder
+	r *redaction
+}
+
+func (e redactEncoder) AddString(key, val string) {
+	if e.r.match(key) {
+		val = Redacted
+	}
+	e.ObjectEncoder.AddString(key, val)
+}
+
+func (e redactEncoder) AddByteString(key string, val []byte) {
+	if e.r.match(key) {
+		e.ObjectEncoder.AddString(key, Redacted)
+		return
+	}
+	e.ObjectEncoder.AddByteString(key, val)
+}
+
+func (e redactEncoder) AddObject(key string, m zapcore.ObjectMarshaler) error {
+	if e.r.match(key) {
+		e.ObjectEncoder.AddString(key, Redacted)
+		return nil
+	}
+	return e.ObjectEncoder.AddObject(key, redactObject{e.r, m})
+}
+
+func (e redactEncoder) AddArray(key string, m zapcore.ArrayMarshaler) error {
+	if e.r.match(key) {
+		e.ObjectEncoder.AddString(key, Redacted)
+		return nil
+	}
+	return e.ObjectEncoder.AddArray(key, redactArray{e.r, m})
+}
+
+func (e redactEncoder) AddReflected(key string, val interface{}) error {
+	if e.r.match(key) {
+		e.ObjectEncoder.AddString(key, Redacted)
+		return nil
+	}
+	return e.ObjectEncoder.AddReflected(key, e.r.value(val))
+}
+
+// redactArrayEncoder redacts the objects of the arrays
+type redactArrayEncoder struct {
+	zapcore.ArrayEncoder
+	r *redaction
+}
+
+func (e redactArrayEncoder) AppendObject(m zapcore.ObjectMarshaler) error {
+	return e.ArrayEncoder.AppendObject(redactObject{e.r, m})
+}
+
+func (e redactArrayEncoder) AppendArray(m zapcore.ArrayMarshaler) error {
+	return e.ArrayEncoder.AppendArray(redactArray{e.r, m})
+}
+
+func (e redactArrayEncoder) AppendReflected(val interface{}) error {
+	return e.ArrayEncoder.AppendReflected(e.r.value(val))
+}
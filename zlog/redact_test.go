@@ -0,0 +1,182 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/vcaesar/tt"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+type credentials struct {
+	User, Token string
+}
+
+func (c credentials) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("user", c.User)
+	enc.AddString("token", c.Token)
+	return enc.AddObject("inner", secret{c.Token})
+}
+
+type secret struct {
+	password string
+}
+
+func (c secret) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("Password", c.password)
+	return nil
+}
+
+// rewriteLogger returns a rewritten logger writing json lines to buf,
+// it redacts keys
+func rewriteLogger(t *testing.T, keys ...string) (*zap.Logger, *bytes.Buffer) {
+	SetRedactedKeys(keys...)
+	t.Cleanup(func() { SetRedactedKeys() })
+
+	buf := &bytes.Buffer{}
+	enc := zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig())
+	core := zapcore.NewCore(enc, zapcore.AddSync(buf), zap.DebugLevel)
+	return zap.New(core, defaultState.rewriteHook()), buf
+}
+
+func decodeLine(t *testing.T, buf *bytes.Buffer) map[string]interface{} {
+	line, err := buf.ReadBytes('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(line, &entry); err != nil {
+		t.Fatal(err)
+	}
+	return entry
+}
+
+func TestRedactFields(t *testing.T) {
+	logger, buf := rewriteLogger(t, "password", "TOKEN")
+
+	fields := []zap.Field{
+		zap.String("Password", "p1"),
+		zap.String("user", "bob"),
+		zap.Int("token", 42),
+	}
+	logger.Info("login", fields...)
+	tt.Equal(t, "p1", fields[0].String)
+	tt.Equal(t, int64(42), fields[2].Integer)
+
+	entry := decodeLine(t, buf)
+	tt.Equal(t, Redacted, entry["Password"])
+	tt.Equal(t, "bob", entry["user"])
+	tt.Equal(t, Redacted, entry["token"])
+
+	logger.With(zap.String("token", "t1")).Info("with")
+	entry = decodeLine(t, buf)
+	tt.Equal(t, Redacted, entry["token"])
+
+	logger.Info(`login user=bob password=p2 Token="t 2", ok`)
+	entry = decodeLine(t, buf)
+	tt.Equal(t, "login user=bob password="+Redacted+" Token="+Redacted+", ok",
+		entry["msg"])
+}
+
+func TestRedactNested(t *testing.T) {
+	logger, buf := rewriteLogger(t, "password", "token")
+
+	body := map[string]interface{}{
+		"user":  "bob",
+		"token": "t1",
+		"auth":  map[string]interface{}{"password": "p1"},
+		"list":  []interface{}{map[string]interface{}{"token": "t2"}},
+	}
+	req := struct {
+		User     string `json:"user"`
+		Password string `json:"password"`
+	}{"bob", "p2"}
+
+	logger.Info("nested", zap.Any("body", body), zap.Any("req", req),
+		zap.Object("creds", credentials{"bob", "t3"}))
+	tt.Equal(t, "t1", body["token"])
+
+	entry := decodeLine(t, buf)
+	got := entry["body"].(map[string]interface{})
+	tt.Equal(t, "bob", got["user"])
+	tt.Equal(t, Redacted, got["token"])
+	tt.Equal(t, Redacted, got["auth"].(map[string]interface{})["password"])
+	tt.Equal(t, Redacted,
+		got["list"].([]interface{})[0].(map[string]interface{})["token"])
+
+	tt.Equal(t, Redacted, entry["req"].(map[string]interface{})["password"])
+
+	creds := entry["creds"].(map[string]interface{})
+	tt.Equal(t, "bob", creds["user"])
+	tt.Equal(t, Redacted, creds["token"])
+	tt.Equal(t, Redacted, creds["inner"].(map[string]interface{})["Password"])
+}
+
+func TestRedactLevels(t *testing.T) {
+	SetRedactedKeys("token")
+	t.Cleanup(func() { SetRedactedKeys() })
+
+	// each wrapped core keeps its level
+	info, infoLogs := observer.New(zap.InfoLevel)
+	errCore, errLogs := observer.New(zap.ErrorLevel)
+	logger := zap.New(zapcore.NewTee(info, errCore), defaultState.rewriteHook())
+
+	logger.Debug("debug", zap.String("token", "t1"))
+	logger.Info("info", zap.String("token", "t1"))
+	logger.Error("error", zap.String("token", "t1"))
+	tt.Equal(t, 2, infoLogs.Len())
+	tt.Equal(t, 1, errLogs.Len())
+	tt.Equal(t, Redacted, errLogs.All()[0].ContextMap()["token"])
+}
+
+func TestRedactAllocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("the allocs vary with the race detector")
+	}
+
+	enc := zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig())
+	core := zapcore.NewCore(enc, zapcore.AddSync(ioutil.Discard), zap.DebugLevel)
+	plain, wrapped := zap.New(core), zap.New(core, defaultState.rewriteHook())
+
+	SetRedactedKeys()
+	allocs := func(logger *zap.Logger) float64 {
+		return testing.AllocsPerRun(100, func() {
+			logger.Info("allocs", zap.String("user", "bob"))
+		})
+	}
+	// no key adds no allocation
+	tt.Equal(t, allocs(plain), allocs(wrapped))
+}
+
+func TestRedactConfig(t *testing.T) {
+	t.Cleanup(func() { SetRedactedKeys() })
+
+	dir := initTest(t, `redact = ["password"]`)
+	InfoF("redact", zap.String("password", "p1"))
+	Info("redact password=p2")
+	tt.Nil(t, Close())
+
+	entries := msgEntries(readEntries(t, filepath.Join(dir, "*", "test.json")), "redact")
+	tt.Equal(t, 1, len(entries))
+	tt.Equal(t, Redacted, entries[0]["password"])
+
+	entries = msgEntries(readEntries(t, filepath.Join(dir, "*", "test.json")),
+		"redact password="+Redacted)
+	tt.Equal(t, 1, len(entries))
+}
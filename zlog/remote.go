@@ -0,0 +1,208 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultDialTimeout = 5 * time.Second
+	defaultBackoff     = time.Second
+	maxBackoff         = 30 * time.Second
+	defaultBufferSize  = 1024
+)
+
+var remoteDropped int64
+
+// RemoteDropped returns the number of the entries which
+// were dropped because the remote queue was full
+func RemoteDropped() int64 {
+	return atomic.LoadInt64(&remoteDropped)
+}
+
+// remoteWriter sends the entries to the remote in its goroutine,
+// the writes only queue the entries so the log calls never block.
+type remoteWriter struct {
+	protocol, addr       string
+	dialTimeout, backoff time.Duration
+
+	ch         chan []byte
+	stop, done chan struct{}
+	once       sync.Once
+}
+
+func durationConf(key, s string, def time.Duration) (time.Duration, error) {
+	if s == "" {
+		return def, nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("zlog: invalid %s %q", key, s)
+	}
+	return d, nil
+}
+
+func newRemoteWriter(cfg RemoteConfig) (*remoteWriter, error) {
+	w := &remoteWriter{
+		protocol: cfg.Protocol,
+		addr:     cfg.Address,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+
+	switch w.protocol {
+	case "":
+		w.protocol = "tcp"
+	case "tcp", "udp":
+	default:
+		return nil, fmt.Errorf("zlog: invalid remote protocol %q", cfg.Protocol)
+	}
+
+	var err error
+	if w.dialTimeout, err = durationConf("remote dial_timeout",
+		cfg.DialTimeout, defaultDialTimeout); err != nil {
+		return nil, err
+	}
+
+	if w.backoff, err = durationConf("remote reconnect_backoff",
+		cfg.ReconnectBackoff, defaultBackoff); err != nil {
+		return nil, err
+	}
+
+	size := cfg.BufferSize
+	if size <= 0 {
+		size = defaultBufferSize
+	}
+	w.ch = make(chan []byte, size)
+
+	go w.run()
+	return w, nil
+}
+
+// Write queues a copy of p, it is dropped if the queue is full
+func (w *remoteWriter) Write(p []byte) (int, error) {
+	select {
+	case <-w.stop:
+		atomic.AddInt64(&remoteDropped, 1)
+		return len(p), nil
+	default:
+	}
+
+	select {
+	case w.ch <- append([]byte(nil), p...):
+	default:
+		atomic.AddInt64(&remoteDropped, 1)
+	}
+	return len(p), nil
+}
+
+// Name returns the protocol and the address of the remote
+func (w *remoteWriter) Name() string {
+	return w.protocol + "://" + w.addr
+}
+
+// Sync the entries are sent in the background
+func (w *remoteWriter) Sync() error {
+	return nil
+}
+
+func (w *remoteWriter) run() {
+	defer close(w.done)
+
+	var conn net.Conn
+	defer func() {
+		if conn != nil {
+			conn.Close()
+		}
+	}()
+
+	for {
+		var msg []byte
+		select {
+		case msg = <-w.ch:
+		case <-w.stop:
+			w.drain(conn)
+			return
+		}
+
+		backoff := w.backoff
+		for !w.send(&conn, msg) {
+			select {
+			case <-time.After(backoff):
+			case <-w.stop:
+				atomic.AddInt64(&remoteDropped, 1)
+				w.drain(nil)
+				return
+			}
+
+			if backoff *= 2; backoff > maxBackoff {
+				backoff = maxBackoff
+			}
+		}
+	}
+}
+
+// send writes msg, it dials first if *conn is nil and closes
+// the connection if the write fails.
+func (w *remoteWriter) send(conn *net.Conn, msg []byte) bool {
+	if *conn == nil {
+		c, err := net.DialTimeout(w.protocol, w.addr, w.dialTimeout)
+		if err != nil {
+			writeFailed(w.Name(), 1, err)
+			return false
+		}
+		*conn = c
+	}
+
+	if _, err := (*conn).Write(msg); err != nil {
+		writeFailed(w.Name(), 1, err)
+		(*conn).Close()
+		*conn = nil
+		return false
+	}
+	return true
+}
+
+// drain writes the queued entries to conn without reconnecting,
+// they're dropped if conn is nil or a write fails.
+func (w *remoteWriter) drain(conn net.Conn) {
+	for {
+		select {
+		case msg := <-w.ch:
+			if conn != nil {
+				conn.SetWriteDeadline(time.Now().Add(w.dialTimeout))
+				if _, err := conn.Write(msg); err == nil {
+					continue
+				}
+				conn = nil
+			}
+			atomic.AddInt64(&remoteDropped, 1)
+		default:
+			return
+		}
+	}
+}
+
+// Close sends the queued entries if the remote is connected
+// and closes the connection
+func (w *remoteWriter) Close() error {
+	w.once.Do(func() {
+		close(w.stop)
+	})
+	<-w.done
+	return nil
+}
@@ -0,0 +1,143 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"bufio"
+	"errors"
+	"io/ioutil"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vcaesar/tt"
+)
+
+// acceptLine accepts a connection of ln and returns the
+// connection and the first line containing msg
+func acceptLine(t *testing.T, ln net.Listener, msg string) (net.Conn, string) {
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	r := bufio.NewReader(conn)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(line, msg) {
+			return conn, line
+		}
+	}
+}
+
+func TestRemote(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	tt.Nil(t, err)
+	defer ln.Close()
+
+	tt.Nil(t, InitWithConfig(Config{Path: t.TempDir(), Name: "test",
+		Remote: RemoteConfig{Address: ln.Addr().String(), ReconnectBackoff: "10ms"}}))
+	defer Close()
+
+	Info("remote first")
+	conn, line := acceptLine(t, ln, "remote first")
+	tt.True(t, strings.HasPrefix(line, "{"), line)
+	tt.True(t, strings.HasSuffix(line, "}\n"), line)
+
+	// the remote restarts, the writer reconnects
+	conn.Close()
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(10 * time.Millisecond):
+				Info("remote again")
+			}
+		}
+	}()
+
+	conn, _ = acceptLine(t, ln, "remote again")
+	conn.Close()
+}
+
+func TestRemoteShared(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	tt.Nil(t, err)
+	defer ln.Close()
+
+	tt.Nil(t, InitWithConfig(Config{Path: t.TempDir(), Name: "test",
+		Remote: RemoteConfig{Address: ln.Addr().String()}}))
+	Info("remote info")
+	Error("remote error", errors.New("e1"))
+	conn, err := ln.Accept()
+	tt.Nil(t, err)
+	defer conn.Close()
+	tt.Nil(t, Close())
+
+	// the main and the error logger share the connection
+	ln.(*net.TCPListener).SetDeadline(time.Now().Add(100 * time.Millisecond))
+	if c, err := ln.Accept(); err == nil {
+		c.Close()
+		t.Fatal("a second remote connection")
+	}
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	b, err := ioutil.ReadAll(conn)
+	tt.Nil(t, err)
+	tt.Equal(t, 1, strings.Count(string(b), "remote info"))
+	tt.Equal(t, 1, strings.Count(string(b), "remote error"))
+}
+
+func TestRemoteDrop(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	tt.Nil(t, err)
+	addr := ln.Addr().String()
+	// nothing listens on addr
+	ln.Close()
+
+	tt.Nil(t, InitWithConfig(Config{Path: t.TempDir(), Name: "test",
+		Remote: RemoteConfig{Address: addr, ReconnectBackoff: "1h", BufferSize: 2}}))
+
+	dropped := RemoteDropped()
+	for i := 0; i < 10; i++ {
+		Info("remote drop")
+	}
+	// a pending entry and the 2 queued ones are kept
+	tt.True(t, RemoteDropped()-dropped >= 7)
+
+	start := time.Now()
+	tt.Nil(t, Close())
+	tt.True(t, time.Since(start) < time.Second)
+}
+
+func TestRemoteConfig(t *testing.T) {
+	for _, cfg := range []RemoteConfig{
+		{Address: "127.0.0.1:1", Protocol: "http"},
+		{Address: "127.0.0.1:1", DialTimeout: "soon"},
+		{Address: "127.0.0.1:1", ReconnectBackoff: "-1s"},
+	} {
+		tt.NotNil(t, InitWithConfig(Config{Path: t.TempDir(), Remote: cfg}))
+	}
+
+	w, err := newRemoteWriter(RemoteConfig{Address: "127.0.0.1:1", Protocol: "udp"})
+	tt.Nil(t, err)
+	tt.Equal(t, defaultBufferSize, cap(w.ch))
+	tt.Equal(t, defaultDialTimeout, w.dialTimeout)
+	tt.Nil(t, w.Close())
+}
@@ -0,0 +1,93 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// rewriteHook wraps the cores of the loggers with the redaction and
+// the truncation of st, it is the last core option so it covers the
+// cores of AddCore.
+func (st *state) rewriteHook() zap.Option {
+	return zap.WrapCore(func(c zapcore.Core) zapcore.Core {
+		return rewriteCore{c, st}
+	})
+}
+
+// rewriteCore redacts and truncates the fields and the message before
+// the cores it wraps, it does nothing without the redacted keys and
+// MaxFieldLen of st
+type rewriteCore struct {
+	zapcore.Core
+	st *state
+}
+
+// rewriting reports whether the entries are rewritten
+func (st *state) rewriting() bool {
+	return st.loadRedaction() != nil || st.maxFieldLen() > 0
+}
+
+func (c rewriteCore) With(fields []zapcore.Field) zapcore.Core {
+	if r := c.st.loadRedaction(); r != nil {
+		fields = r.fields(fields)
+	}
+	if max := c.st.maxFieldLen(); max > 0 {
+		fields, _ = truncateFields(fields, max)
+	}
+	return rewriteCore{c.Core.With(fields), c.st}
+}
+
+func (c rewriteCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.st.rewriting() {
+		return c.Core.Check(ent, ce)
+	}
+
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write rewrites the entry, the wrapped cores are checked again so
+// each of them keeps its own level
+func (c rewriteCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if r := c.st.loadRedaction(); r != nil {
+		ent.Message = r.message(ent.Message)
+		fields = r.fields(fields)
+	}
+	if max := c.st.maxFieldLen(); max > 0 {
+		ent.Message, fields = truncateEntry(ent.Message, fields, max)
+	}
+
+	write(c.Core, ent, fields)
+	return nil
+}
+
+// mapFields returns the fields rewritten by fn, fields is copied on
+// the first change so the slice of the caller is never modified
+func mapFields(fields []zapcore.Field,
+	fn func(zapcore.Field) (zapcore.Field, bool)) []zapcore.Field {
+	out, copied := fields, false
+	for i, f := range fields {
+		rewritten, ok := fn(f)
+		if !ok {
+			continue
+		}
+
+		if !copied {
+			out, copied = append([]zapcore.Field(nil), fields...), true
+		}
+		out[i] = rewritten
+	}
+	return out
+}
@@ -0,0 +1,253 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// maxRingEntry the max bytes of an entry of the ring buffer, the
+// longer ones keep the truncated message without the fields
+const maxRingEntry = 4 << 10
+
+// ringEntry an encoded entry of the ring buffer
+type ringEntry struct {
+	level zapcore.Level
+	data  []byte
+}
+
+// ringBuffer the last entries of the loggers, the slots and their
+// bytes are reused so it holds at most size * maxRingEntry bytes
+type ringBuffer struct {
+	mu      sync.Mutex
+	entries []ringEntry
+	next    int
+	full    bool
+}
+
+func newRingBuffer(size int) *ringBuffer {
+	return &ringBuffer{entries: make([]ringEntry, size)}
+}
+
+// loadRing returns the ring buffer of the running config, nil
+// without RingBuffer
+func (st *state) loadRing() *ringBuffer {
+	r, _ := st.ring.Load().(*ringBuffer)
+	return r
+}
+
+// setRing sets the ring buffer of size entries, the entries are kept
+// if the size doesn't change, 0 drops it
+func (st *state) setRing(size int) {
+	r := st.loadRing()
+	switch {
+	case size <= 0:
+		st.ring.Store((*ringBuffer)(nil))
+	case r == nil || len(r.entries) != size:
+		st.ring.Store(newRingBuffer(size))
+	}
+}
+
+// checkRingBuffer returns the error of a negative RingBuffer
+func (c *Config) checkRingBuffer() error {
+	if c.RingBuffer < 0 {
+		return fmt.Errorf("zlog: invalid ring_buffer %d", c.RingBuffer)
+	}
+	return nil
+}
+
+func (r *ringBuffer) add(lvl zapcore.Level, data []byte) {
+	r.mu.Lock()
+	e := &r.entries[r.next]
+	e.level, e.data = lvl, append(e.data[:0], data...)
+
+	r.next++
+	if r.next == len(r.entries) {
+		r.next, r.full = 0, true
+	}
+	r.mu.Unlock()
+}
+
+// recent returns copies of the last n entries at lvl or above,
+// the oldest first, n <= 0 returns all of them
+func (r *ringBuffer) recent(n int, lvl zapcore.Level) [][]byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	size := r.next
+	if r.full {
+		size = len(r.entries)
+	}
+
+	var out [][]byte
+	// from the newest, reversed below
+	for i := 0; i < size && (n <= 0 || len(out) < n); i++ {
+		e := r.entries[(r.next-1-i+len(r.entries))%len(r.entries)]
+		if e.level >= lvl {
+			out = append(out, append([]byte(nil), e.data...))
+		}
+	}
+
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return out
+}
+
+// ringHook tees the cores of the loggers into the ring buffer of st
+// with RingBuffer, the entries are encoded as json at the level of
+// each core
+func (c *Config) ringHook(st *state) (zap.Option, error) {
+	if c.RingBuffer <= 0 {
+		return zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+			return core
+		}), nil
+	}
+
+	enc, err := c.newEncoder("json")
+	if err != nil {
+		return nil, err
+	}
+
+	return zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return zapcore.NewTee(core, ringCore{LevelEnabler: core, enc: enc, base: enc, st: st})
+	}), nil
+}
+
+// ringCore writes the entries to the ring buffer of st, it does
+// nothing without RingBuffer
+type ringCore struct {
+	zapcore.LevelEnabler
+	// base the encoder without the fields of With
+	enc, base zapcore.Encoder
+	st        *state
+}
+
+func (c ringCore) With(fields []zapcore.Field) zapcore.Core {
+	enc := c.enc.Clone()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	return ringCore{LevelEnabler: c.LevelEnabler, enc: enc, base: c.base, st: c.st}
+}
+
+func (c ringCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) && c.st.loadRing() != nil {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c ringCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	r := c.st.loadRing()
+	if r == nil {
+		return nil
+	}
+
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	defer buf.Free()
+
+	if buf.Len() <= maxRingEntry {
+		r.add(ent.Level, buf.Bytes())
+		return nil
+	}
+
+	ent.Message, _ = truncate(ent.Message, maxRingEntry/2)
+	short, err := c.base.EncodeEntry(ent, []zapcore.Field{zap.Bool("truncated", true)})
+	if err != nil {
+		return err
+	}
+	r.add(ent.Level, short.Bytes())
+	short.Free()
+	return nil
+}
+
+func (ringCore) Sync() error {
+	return nil
+}
+
+// Recent returns the last n entries of the ring buffer of the package
+// loggers encoded as json, the oldest first, n <= 0 returns all of
+// them. It's empty without RingBuffer.
+func Recent(n int) [][]byte {
+	return defaultState.recent(n)
+}
+
+// Recent returns the last n entries of the ring buffer of the logger
+// of New like Recent, the package ones for the others
+func (z *Zlog) Recent(n int) [][]byte {
+	return z.state().recent(n)
+}
+
+func (st *state) recent(n int) [][]byte {
+	r := st.loadRing()
+	if r == nil {
+		return nil
+	}
+	return r.recent(n, zapcore.DebugLevel)
+}
+
+// RecentHandler returns a http.Handler which serves the entries of
+// the ring buffer as ndjson, the oldest first, the level query keeps
+// the entries at the level or above and the limit query the last ones.
+//
+//	mux.Handle("/debug/logs", zlog.RecentHandler())
+//	// GET /debug/logs?level=error&limit=50
+func RecentHandler() http.Handler {
+	return http.HandlerFunc(serveRecent)
+}
+
+func serveRecent(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "zlog: only GET is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	lvl := zapcore.DebugLevel
+	if s := r.URL.Query().Get("level"); s != "" {
+		l, err := zapcore.ParseLevel(s)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("zlog: invalid level %q", s), http.StatusBadRequest)
+			return
+		}
+		lvl = l
+	}
+
+	limit := 0
+	if s := r.URL.Query().Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 {
+			http.Error(w, fmt.Sprintf("zlog: invalid limit %q", s), http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	ring := defaultState.loadRing()
+	if ring == nil {
+		http.Error(w, "zlog: ring_buffer is disabled", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	for _, entry := range ring.recent(limit, lvl) {
+		w.Write(entry)
+	}
+}
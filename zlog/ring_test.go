@@ -0,0 +1,158 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/vcaesar/tt"
+	"go.uber.org/zap/zapcore"
+)
+
+// decodeRecent decodes the json entries of the ring buffer
+func decodeRecent(t *testing.T, entries [][]byte) []map[string]interface{} {
+	out := make([]map[string]interface{}, 0, len(entries))
+	for _, data := range entries {
+		var entry map[string]interface{}
+		if err := json.Unmarshal(data, &entry); err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, entry)
+	}
+	return out
+}
+
+func TestRingBuffer(t *testing.T) {
+	r := newRingBuffer(3)
+	tt.Equal(t, 0, len(r.recent(0, zapcore.DebugLevel)))
+
+	for i := 0; i < 5; i++ {
+		lvl := zapcore.InfoLevel
+		if i%2 == 0 {
+			lvl = zapcore.ErrorLevel
+		}
+		r.add(lvl, []byte(fmt.Sprint(i)))
+	}
+
+	var got []string
+	for _, data := range r.recent(0, zapcore.DebugLevel) {
+		got = append(got, string(data))
+	}
+	tt.Equal(t, []string{"2", "3", "4"}, got)
+
+	got = got[:0]
+	for _, data := range r.recent(2, zapcore.DebugLevel) {
+		got = append(got, string(data))
+	}
+	tt.Equal(t, []string{"3", "4"}, got)
+
+	got = got[:0]
+	for _, data := range r.recent(0, zapcore.ErrorLevel) {
+		got = append(got, string(data))
+	}
+	tt.Equal(t, []string{"2", "4"}, got)
+}
+
+func TestRecent(t *testing.T) {
+	t.Cleanup(func() { defaultState.setRing(0) })
+	tt.NotNil(t, InitWithConfig(Config{Mode: "discard", RingBuffer: -1}))
+
+	tt.Nil(t, InitWithConfig(Config{Mode: "discard", RingBuffer: 4}))
+	defer Close()
+
+	for i := 0; i < 6; i++ {
+		Info(fmt.Sprint("recent ", i))
+	}
+	Error("recent error", errors.New("e1"))
+	With(Str("ctx", "c1")).Warn("recent with")
+	Debug("recent debug")
+
+	entries := decodeRecent(t, Recent(0))
+	tt.Equal(t, 4, len(entries))
+	tt.Equal(t, "recent 4", entries[0]["msg"])
+	tt.Equal(t, "recent 5", entries[1]["msg"])
+	tt.Equal(t, "recent error", entries[2]["msg"])
+	tt.Equal(t, "e1", entries[2]["error"])
+	tt.Equal(t, "c1", entries[3]["ctx"])
+
+	// the fields are dropped from the long entries
+	Info("long", strings.Repeat("x", 2*maxRingEntry))
+	long := Recent(1)
+	tt.True(t, len(long[0]) <= maxRingEntry)
+	entry := decodeRecent(t, long)[0]
+	tt.Equal(t, "long", entry["msg"])
+	tt.Equal(t, true, entry["truncated"])
+
+	// the entries are kept by the same size
+	tt.Nil(t, InitWithConfig(Config{Mode: "discard", RingBuffer: 4}))
+	tt.Equal(t, 4, len(Recent(0)))
+	tt.Nil(t, InitWithConfig(Config{Mode: "discard"}))
+	tt.Equal(t, 0, len(Recent(0)))
+}
+
+func TestRecentHandler(t *testing.T) {
+	t.Cleanup(func() { defaultState.setRing(0) })
+	h := RecentHandler()
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	tt.Equal(t, http.StatusNotFound, w.Code)
+
+	tt.Nil(t, InitWithConfig(Config{Mode: "discard", RingBuffer: 10}))
+	defer Close()
+	for i := 0; i < 12; i++ {
+		if i%3 == 0 {
+			Error(fmt.Sprint("handler ", i), errors.New("e1"))
+			continue
+		}
+		Info(fmt.Sprint("handler ", i))
+	}
+
+	serve := func(query string) []string {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/"+query, nil))
+		tt.Equal(t, http.StatusOK, w.Code)
+		tt.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+
+		var msgs []string
+		sc := bufio.NewScanner(w.Body)
+		for sc.Scan() {
+			var entry map[string]interface{}
+			tt.Nil(t, json.Unmarshal(sc.Bytes(), &entry))
+			msgs = append(msgs, entry["msg"].(string))
+		}
+		return msgs
+	}
+
+	msgs := serve("")
+	tt.Equal(t, 10, len(msgs))
+	tt.Equal(t, "handler 2", msgs[0])
+	tt.Equal(t, []string{"handler 3", "handler 6", "handler 9"}, serve("?level=error"))
+	tt.Equal(t, []string{"handler 9"}, serve("?level=error&limit=1"))
+	tt.Equal(t, []string{"handler 10", "handler 11"}, serve("?limit=2"))
+
+	for _, query := range []string{"?level=loud", "?limit=0", "?limit=x"} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/"+query, nil))
+		tt.Equal(t, http.StatusBadRequest, w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", nil))
+	tt.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
@@ -0,0 +1,44 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"io"
+
+	"go.uber.org/multierr"
+)
+
+// rotator a log file which can be rotated
+type rotator interface {
+	Rotate() error
+}
+
+// Rotate rotates the log files of the logger and the error logger,
+// the new entries are written to new files, it does nothing in dev
+// and stdout modes which write no file.
+func Rotate() error {
+	lg := load()
+	return rotateFiles(lg.closer, lg.errCloser)
+}
+
+// rotateFiles rotates the files of the closers of a logger and its
+// error logger
+func rotateFiles(closer, errCloser io.Closer) error {
+	var err error
+	if r, ok := closer.(rotator); ok {
+		err = multierr.Append(err, r.Rotate())
+	}
+
+	if r, ok := errCloser.(rotator); ok && errCloser != closer {
+		err = multierr.Append(err, r.Rotate())
+	}
+	return err
+}
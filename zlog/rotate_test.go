@@ -0,0 +1,83 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/vcaesar/tt"
+	"go.uber.org/multierr"
+)
+
+type failRotator struct{ err error }
+
+func (r failRotator) Rotate() error { return r.err }
+
+func (r failRotator) Close() error { return nil }
+
+func TestRotate(t *testing.T) {
+	dir := initTest(t)
+	defer Close()
+
+	Info("before rotate")
+	Error("before rotate", errors.New("e1"))
+	tt.Nil(t, Rotate())
+	Info("after rotate")
+	Error("after rotate", errors.New("e2"))
+
+	files, err := filepath.Glob(filepath.Join(dir, "*", "test*.json"))
+	tt.Nil(t, err)
+	// test.json, test_err.json and their backups
+	tt.Equal(t, 4, len(files))
+
+	entries := readEntries(t, filepath.Join(dir, "*", "test.json"))
+	tt.Equal(t, 0, len(msgEntries(entries, "before rotate")))
+	// the info and the mirrored error
+	tt.Equal(t, 2, len(msgEntries(entries, "after rotate")))
+}
+
+func TestRotateSplit(t *testing.T) {
+	dir := initTest(t, "split_levels = true", "level = \"debug\"")
+	defer Close()
+
+	Debug("before rotate")
+	Warn("before rotate")
+	tt.Nil(t, Rotate())
+	Debug("after rotate")
+
+	for _, name := range []string{"test_debug", "test_warn"} {
+		files, err := filepath.Glob(filepath.Join(dir, "*", name+"*.json"))
+		tt.Nil(t, err)
+		tt.Equal(t, 2, len(files))
+	}
+}
+
+func TestRotateModes(t *testing.T) {
+	for _, mode := range []string{"dev", "stdout", "discard"} {
+		tt.Nil(t, InitWithConfig(Config{Mode: mode, Path: t.TempDir()}))
+		tt.Nil(t, Rotate())
+	}
+	tt.Nil(t, InitNop())
+	tt.Nil(t, Rotate())
+}
+
+func TestRotateErrors(t *testing.T) {
+	e1, e2 := errors.New("e1"), errors.New("e2")
+	prev := load()
+	lg := *prev
+	lg.closer, lg.errCloser = failRotator{e1}, failRotator{e2}
+	current.Store(&lg)
+	defer current.Store(prev)
+
+	tt.Equal(t, []error{e1, e2}, multierr.Errors(Rotate()))
+}
@@ -0,0 +1,100 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"runtime"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// memStatsInterval the min interval of the reads of the memory stats
+const memStatsInterval = time.Second
+
+// readMemStats reads the memory stats, tests swap it
+var readMemStats = runtime.ReadMemStats
+
+// memStats the memory stats of the runtime fields, cached for
+// memStatsInterval since ReadMemStats stops the world
+type memStats struct {
+	mu   sync.Mutex
+	now  func() time.Time
+	read time.Time
+
+	heapAlloc, heapSys uint64
+	gcCount            uint32
+}
+
+var runtimeStats = &memStats{now: time.Now}
+
+// fields returns the runtime fields with the cached memory stats,
+// the stats are read again after memStatsInterval
+func (s *memStats) fields() []zapcore.Field {
+	s.mu.Lock()
+	if now := s.now(); s.read.IsZero() || now.Sub(s.read) >= memStatsInterval {
+		var m runtime.MemStats
+		readMemStats(&m)
+		s.heapAlloc, s.heapSys, s.gcCount, s.read = m.HeapAlloc, m.HeapSys, m.NumGC, now
+	}
+	heapAlloc, heapSys, gcCount := s.heapAlloc, s.heapSys, s.gcCount
+	s.mu.Unlock()
+
+	return []zapcore.Field{
+		zap.Int("goroutines", runtime.NumGoroutine()),
+		zap.Uint64("heap_alloc", heapAlloc),
+		zap.Uint64("heap_sys", heapSys),
+		zap.Uint32("gc_count", gcCount),
+	}
+}
+
+// runtimeHook adds the runtime fields to the error entries with
+// RuntimeFieldsOnError, before the rewrite so they reach the cores
+// of AddCore
+func (c *Config) runtimeHook() zap.Option {
+	return zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		if !c.RuntimeFieldsOnError {
+			return core
+		}
+		return runtimeCore{Core: core, stats: runtimeStats}
+	})
+}
+
+// runtimeCore adds the runtime fields to the error and above
+// entries, the lower ones go to the wrapped core as they are
+type runtimeCore struct {
+	zapcore.Core
+	stats *memStats
+}
+
+func (c runtimeCore) With(fields []zapcore.Field) zapcore.Core {
+	return runtimeCore{c.Core.With(fields), c.stats}
+}
+
+func (c runtimeCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if ent.Level < zapcore.ErrorLevel {
+		return c.Core.Check(ent, ce)
+	}
+
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write writes the entry with the runtime fields, the wrapped cores
+// are checked again so each of them keeps its own level
+func (c runtimeCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	write(c.Core, ent, append(fields[:len(fields):len(fields)], c.stats.fields()...))
+	return nil
+}
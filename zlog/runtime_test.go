@@ -0,0 +1,79 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"errors"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/vcaesar/tt"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestRuntimeFields(t *testing.T) {
+	clock := &fakeClock{tm: time.Date(2018, 5, 1, 8, 0, 0, 0, time.UTC)}
+	reads := 0
+	prev := readMemStats
+	readMemStats = func(m *runtime.MemStats) {
+		reads++
+		m.HeapAlloc, m.HeapSys, m.NumGC = uint64(reads), 100, uint32(reads)
+	}
+	defer func() { readMemStats = prev }()
+
+	core, logs := observer.New(zap.DebugLevel)
+	logger := zap.New(runtimeCore{Core: core, stats: &memStats{now: clock.now}}).
+		With(zap.String("app", "shop"))
+
+	logger.Info("info")
+	logger.Error("e1")
+	clock.set(clock.now().Add(500 * time.Millisecond))
+	logger.Error("e2")
+	clock.set(clock.now().Add(time.Second))
+	logger.Error("e3")
+	tt.Equal(t, 2, reads)
+
+	info := logs.FilterMessage("info").All()[0].ContextMap()
+	tt.Equal(t, map[string]interface{}{"app": "shop"}, info)
+
+	entries := logs.FilterLevelExact(zap.ErrorLevel).All()
+	tt.Equal(t, 3, len(entries))
+	for i, want := range []uint64{1, 1, 2} {
+		fields := entries[i].ContextMap()
+		tt.Equal(t, want, fields["heap_alloc"])
+		tt.Equal(t, uint64(100), fields["heap_sys"])
+		tt.Equal(t, uint32(want), fields["gc_count"])
+		tt.True(t, fields["goroutines"].(int64) > 0)
+		tt.Equal(t, "shop", fields["app"])
+	}
+}
+
+func TestRuntimeFieldsConf(t *testing.T) {
+	dir := initTest(t, "runtime_fields_on_error = true")
+	Info("runtime info")
+	Error("runtime error", errors.New("e1"))
+	tt.Nil(t, Close())
+
+	entries := readEntries(t, filepath.Join(dir, "*", "test.json"))
+	info := msgEntries(entries, "runtime info")
+	tt.Equal(t, 1, len(info))
+	_, ok := info[0]["goroutines"]
+	tt.False(t, ok)
+
+	errEntries := msgEntries(entries, "runtime error")
+	tt.Equal(t, 1, len(errEntries))
+	for _, key := range []string{"goroutines", "heap_alloc", "heap_sys", "gc_count"} {
+		tt.NotNil(t, errEntries[0][key])
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// checkSampling returns the error of a negative sampling
+func (c *Config) checkSampling() error {
+	s := c.Sampling
+	if s.Initial < 0 || s.Thereafter < 0 {
+		return fmt.Errorf("zlog: invalid sampling initial %d thereafter %d",
+			s.Initial, s.Thereafter)
+	}
+	return nil
+}
+
+// sampledHook counts the entries dropped by the sampling
+func sampledHook(ent zapcore.Entry, dec zapcore.SamplingDecision) {
+	if dec&zapcore.LogDropped != 0 {
+		atomic.AddInt64(&sampledEntries, 1)
+	}
+}
+
+// sampleCore wraps core in the sampler of the config
+func (c *Config) sampleCore(core zapcore.Core) zapcore.Core {
+	s := c.Sampling
+	if s.Initial == 0 {
+		return core
+	}
+
+	return sampledCore{
+		Core: zapcore.NewSamplerWithOptions(core, time.Second,
+			s.Initial, s.Thereafter, zapcore.SamplerHook(sampledHook)),
+		raw: core,
+	}
+}
+
+// sampledCore samples the entries below error, the error
+// and above entries skip the sampler
+type sampledCore struct {
+	zapcore.Core
+	raw zapcore.Core
+}
+
+func (c sampledCore) With(fields []zapcore.Field) zapcore.Core {
+	return sampledCore{c.Core.With(fields), c.raw.With(fields)}
+}
+
+func (c sampledCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if ent.Level >= zapcore.ErrorLevel {
+		return c.raw.Check(ent, ce)
+	}
+	return c.Core.Check(ent, ce)
+}
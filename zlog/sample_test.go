@@ -0,0 +1,62 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/vcaesar/tt"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestSampling(t *testing.T) {
+	sampled := Stats().SampledEntries
+	dir := initTest(t, "split_levels = true", "[sampling]", "initial = 10", "thereafter = 5")
+
+	for i := 0; i < 100; i++ {
+		Info("sampled info")
+		Warn("sampled warn")
+		Error("sampled error", errors.New("e1"))
+		ErrorF("sampled errorf")
+	}
+	tt.Nil(t, Close())
+
+	// 10 then 1 in 5 of the 90 others
+	entries := readEntries(t, filepath.Join(dir, "*", "test.json"))
+	tt.Equal(t, 28, len(msgEntries(entries, "sampled info")))
+	warns := readEntries(t, filepath.Join(dir, "*", "test_warn.json"))
+	tt.Equal(t, 28, len(msgEntries(warns, "sampled warn")))
+	tt.Equal(t, sampled+144, Stats().SampledEntries)
+
+	errEntries := readEntries(t, filepath.Join(dir, "*", "test_err.json"))
+	tt.Equal(t, 100, len(msgEntries(errEntries, "sampled error")))
+	tt.Equal(t, 100, len(msgEntries(errEntries, "sampled errorf")))
+}
+
+func TestSampledCore(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	config := Config{Sampling: SamplingConfig{Initial: 1}}
+
+	logger := zap.New(config.sampleCore(core)).With(zap.String("a", "b"))
+	for i := 0; i < 10; i++ {
+		logger.Info("sampled")
+		logger.DPanic("sampled")
+	}
+	tt.Equal(t, 1, logs.FilterLevelExact(zap.InfoLevel).Len())
+	tt.Equal(t, 10, logs.FilterLevelExact(zap.DPanicLevel).Len())
+	tt.Equal(t, "b", logs.All()[0].ContextMap()["a"])
+
+	tt.NotNil(t, InitWithConfig(Config{Path: t.TempDir(),
+		Sampling: SamplingConfig{Initial: -1}}))
+}
@@ -0,0 +1,591 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultShipLines    = 500
+	defaultShipInterval = 5 * time.Second
+	defaultShipPoll     = time.Second
+	defaultShipTimeout  = 10 * time.Second
+	defaultShipBackoff  = time.Second
+	maxShipBackoff      = 30 * time.Second
+	// shipBatchBytes the bytes of a batch sent before BatchLines
+	shipBatchBytes = 4 << 20
+	// maxShipLine the longer lines are cut
+	maxShipLine = 1 << 20
+	// shipChunk the bytes read at once from a file
+	shipChunk = 64 << 10
+	// fingerprintSize the head of a file identifying it in the checkpoint
+	fingerprintSize = 1024
+)
+
+// ShipperConfig the shipper of StartShipper
+type ShipperConfig struct {
+	// Path the log path, default "./log"
+	Path string `toml:"path"`
+	// Files the names of the shipped files in the daily dirs, default
+	// "log.json" which has the error entries too with MirrorErrors
+	Files []string `toml:"files"`
+	// DailyDirs false ships Path/<file>, default true
+	DailyDirs *bool `toml:"daily_dirs"`
+	// URL the http endpoint of the ndjson batches
+	URL string `toml:"url"`
+	// Token the bearer token of the requests, empty sends none
+	Token string `toml:"token"`
+	// Gzip compresses the body of the requests
+	Gzip bool `toml:"gzip"`
+	// BatchLines the lines of a batch, default 500
+	BatchLines int `toml:"batch_lines"`
+	// BatchInterval the max wait of a line before its batch is
+	// sent, default "5s"
+	BatchInterval string `toml:"batch_interval"`
+	// PollInterval the interval of the reads of the files, default "1s"
+	PollInterval string `toml:"poll_interval"`
+	// Timeout the timeout of a request, default "10s"
+	Timeout string `toml:"timeout"`
+	// RetryBackoff the first wait before the retry of a failed
+	// batch, it doubles on each failure up to 30s, default "1s"
+	RetryBackoff string `toml:"retry_backoff"`
+	// Checkpoint the file of the offsets of the sent lines,
+	// default Path/.shipper.json
+	Checkpoint string `toml:"checkpoint"`
+}
+
+// shipCheckpoint the end of the last sent line of a file, the head
+// of the file checks it's the same file after a rotation
+type shipCheckpoint struct {
+	Path        string `json:"path"`
+	Offset      int64  `json:"offset"`
+	Head        int64  `json:"head"`
+	Fingerprint string `json:"fingerprint"`
+}
+
+// tailFile a shipped file, data the bytes read after offset
+type tailFile struct {
+	f      *os.File
+	path   string
+	offset int64
+	data   []byte
+
+	head        int64
+	fingerprint string
+}
+
+// shipStream the files of a name of Files across the rotations
+type shipStream struct {
+	name string
+	file *tailFile
+}
+
+// shipLine a line of the batch and the checkpoint after it
+type shipLine struct {
+	stream *shipStream
+	line   []byte
+	ck     shipCheckpoint
+}
+
+// shipper tails the files and sends the batches in its goroutine,
+// a failed batch is retried before the files are read again so a
+// down endpoint pauses the tailing
+type shipper struct {
+	cfg                 ShipperConfig
+	lpath, ckPath, name string
+	daily               bool
+	batchLines          int
+	interval, poll      time.Duration
+	backoff             time.Duration
+	client              *http.Client
+	now                 func() time.Time
+
+	streams []*shipStream
+	// checkpoints the checkpoints of the sent lines by file name
+	checkpoints map[string]shipCheckpoint
+
+	batch      []shipLine
+	batchBytes int
+	first      time.Time
+
+	stop, done chan struct{}
+	once       sync.Once
+}
+
+// StartShipper starts shipping the lines of the json log files to
+// the url as the ndjson batches of BatchLines lines or BatchInterval,
+// the files are followed across the daily dirs and the rotations.
+// The offsets of the sent lines are saved to the checkpoint so a
+// restart sends the next lines, a line may be sent again if the
+// process stops between a batch and its checkpoint. Call stop to
+// send the read lines and stop.
+//
+//	stop, err := zlog.StartShipper(zlog.ShipperConfig{
+//		URL: "https://logs.example.com/ingest", Token: token, Gzip: true})
+//	defer stop()
+func StartShipper(cfg ShipperConfig) (stop func(), err error) {
+	s, err := newShipper(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	go s.run()
+	return s.close, nil
+}
+
+func newShipper(cfg ShipperConfig) (*shipper, error) {
+	if cfg.URL == "" {
+		return nil, errors.New("zlog: the url of the shipper is empty")
+	}
+	if cfg.BatchLines < 0 {
+		return nil, fmt.Errorf("zlog: invalid batch_lines %d", cfg.BatchLines)
+	}
+
+	s := &shipper{
+		cfg:        cfg,
+		lpath:      "./log",
+		name:       "shipper " + cfg.URL,
+		daily:      cfg.DailyDirs == nil || *cfg.DailyDirs,
+		batchLines: cfg.BatchLines,
+		now:        time.Now,
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	if cfg.Path != "" {
+		s.lpath = cfg.Path
+	}
+	if s.batchLines == 0 {
+		s.batchLines = defaultShipLines
+	}
+
+	var err error
+	for _, d := range []struct {
+		key, val string
+		def      time.Duration
+		dst      *time.Duration
+	}{
+		{"batch_interval", cfg.BatchInterval, defaultShipInterval, &s.interval},
+		{"poll_interval", cfg.PollInterval, defaultShipPoll, &s.poll},
+		{"retry_backoff", cfg.RetryBackoff, defaultShipBackoff, &s.backoff},
+	} {
+		if *d.dst, err = durationConf(d.key, d.val, d.def); err != nil {
+			return nil, err
+		}
+	}
+	timeout, err := durationConf("timeout", cfg.Timeout, defaultShipTimeout)
+	if err != nil {
+		return nil, err
+	}
+	s.client = &http.Client{Timeout: timeout}
+
+	files := cfg.Files
+	if len(files) == 0 {
+		files = []string{"log.json"}
+	}
+	for _, name := range files {
+		s.streams = append(s.streams, &shipStream{name: name})
+	}
+
+	s.ckPath = cfg.Checkpoint
+	if s.ckPath == "" {
+		s.ckPath = filepath.Join(s.lpath, ".shipper.json")
+	}
+	if s.checkpoints, err = readCheckpoints(s.ckPath); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// readCheckpoints reads the checkpoint file, none if it doesn't exist
+func readCheckpoints(path string) (map[string]shipCheckpoint, error) {
+	cks := make(map[string]shipCheckpoint)
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return cks, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(b, &cks); err != nil {
+		return nil, fmt.Errorf("zlog: invalid shipper checkpoint %s: %v", path, err)
+	}
+	return cks, nil
+}
+
+// close stops the shipper after sending the read lines once
+func (s *shipper) close() {
+	s.once.Do(func() {
+		close(s.stop)
+	})
+	<-s.done
+}
+
+func (s *shipper) run() {
+	defer close(s.done)
+	defer s.closeFiles()
+
+	for _, st := range s.streams {
+		s.restore(st)
+	}
+
+	ticker := time.NewTicker(s.poll)
+	defer ticker.Stop()
+
+	for {
+		s.tail()
+		if s.due() && !s.send(true) {
+			return
+		}
+		if s.full() {
+			continue
+		}
+
+		select {
+		case <-ticker.C:
+		case <-s.stop:
+			s.tail()
+			if len(s.batch) > 0 {
+				s.send(false)
+			}
+			return
+		}
+	}
+}
+
+// activePath returns the file of name written now
+func (s *shipper) activePath(name string) string {
+	return activeFile(s.lpath, name, s.daily, s.now())
+}
+
+// activeFile returns the file of name in lpath written at now
+func activeFile(lpath, name string, daily bool, now time.Time) string {
+	if !daily {
+		return filepath.Join(lpath, name)
+	}
+	return filepath.Join(lpath, now.Format(DayFormat), name)
+}
+
+// restore opens the file of the checkpoint of st at its offset, or
+// its rotated backup with the same head, or the active file
+func (s *shipper) restore(st *shipStream) {
+	ck, ok := s.checkpoints[st.name]
+	if !ok {
+		return
+	}
+
+	ext := filepath.Ext(ck.Path)
+	backups, _ := filepath.Glob(strings.TrimSuffix(ck.Path, ext) + "-*" + ext)
+	for _, path := range append([]string{ck.Path}, backups...) {
+		tf, err := openTail(path, ck.Offset)
+		if err != nil {
+			continue
+		}
+
+		if fp, err := tf.fingerprintAt(ck.Head); err == nil && fp == ck.Fingerprint {
+			st.file = tf
+			return
+		}
+		tf.f.Close()
+	}
+}
+
+func openTail(path string, offset int64) (*tailFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tailFile{f: f, path: path, offset: offset}, nil
+}
+
+// fingerprintAt returns the hash of the head bytes of the file
+func (tf *tailFile) fingerprintAt(head int64) (string, error) {
+	b := make([]byte, head)
+	if _, err := tf.f.ReadAt(b, 0); err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// checkpoint returns the checkpoint of the offset
+func (tf *tailFile) checkpoint() shipCheckpoint {
+	head := tf.offset
+	if head > fingerprintSize {
+		head = fingerprintSize
+	}
+	if head != tf.head || tf.fingerprint == "" {
+		// the bytes before the offset are written
+		tf.fingerprint, _ = tf.fingerprintAt(head)
+		tf.head = head
+	}
+
+	return shipCheckpoint{Path: tf.path, Offset: tf.offset,
+		Head: tf.head, Fingerprint: tf.fingerprint}
+}
+
+// readLine returns the next complete line, the line is cut at
+// maxShipLine
+func (tf *tailFile) readLine() ([]byte, bool) {
+	for {
+		if i := bytes.IndexByte(tf.data, '\n'); i >= 0 || len(tf.data) >= maxShipLine {
+			if i < 0 || i >= maxShipLine {
+				i = maxShipLine - 1
+			}
+			return tf.pop(i + 1), true
+		}
+
+		buf := make([]byte, shipChunk)
+		n, _ := tf.f.ReadAt(buf, tf.offset+int64(len(tf.data)))
+		if n == 0 {
+			return nil, false
+		}
+		tf.data = append(tf.data, buf[:n]...)
+	}
+}
+
+// pop consumes the first n bytes of data
+func (tf *tailFile) pop(n int) []byte {
+	line := append([]byte(nil), tf.data[:n]...)
+	tf.data = tf.data[n:]
+	tf.offset += int64(n)
+	return line
+}
+
+// tail reads the lines of the files until the batch is full
+func (s *shipper) tail() {
+	for _, st := range s.streams {
+		for !s.full() {
+			if !s.readStream(st) {
+				break
+			}
+		}
+	}
+}
+
+// readStream adds the next line of st to the batch
+func (s *shipper) readStream(st *shipStream) bool {
+	line, ok := st.next(s.activePath(st.name))
+	if ok {
+		s.add(st, line)
+	}
+	return ok
+}
+
+// next returns the next line of st, it switches to the active file
+// once the file is rotated or the day changes, after the lines
+// written before the switch
+func (st *shipStream) next(active string) ([]byte, bool) {
+	if st.file == nil {
+		tf, err := openTail(active, 0)
+		if err != nil {
+			return nil, false
+		}
+		st.file = tf
+	}
+
+	tf := st.file
+	if line, ok := tf.readLine(); ok {
+		return line, true
+	}
+
+	fi, err := os.Stat(active)
+	if err != nil {
+		return nil, false
+	}
+	if cur, err := tf.f.Stat(); err == nil && tf.path == active && os.SameFile(cur, fi) {
+		return nil, false
+	}
+
+	// the file isn't written after the switch, read it again
+	if line, ok := tf.readLine(); ok {
+		return line, true
+	}
+	if len(tf.data) > 0 {
+		return append(tf.pop(len(tf.data)), '\n'), true
+	}
+
+	next, err := openTail(active, 0)
+	if err != nil {
+		return nil, false
+	}
+	tf.f.Close()
+	st.file = next
+	return st.next(active)
+}
+
+func (s *shipper) add(st *shipStream, line []byte) {
+	if len(s.batch) == 0 {
+		s.first = s.now()
+	}
+	s.batch = append(s.batch, shipLine{stream: st, line: line, ck: st.file.checkpoint()})
+	s.batchBytes += len(line)
+}
+
+func (s *shipper) full() bool {
+	return len(s.batch) >= s.batchLines || s.batchBytes >= shipBatchBytes
+}
+
+// due reports whether the batch is sent
+func (s *shipper) due() bool {
+	return len(s.batch) > 0 && (s.full() || s.now().Sub(s.first) >= s.interval)
+}
+
+// send sends the batch, the failures which may pass later are retried
+// with backoff if retry until it is sent or the shipper stops, it
+// returns false if the shipper stops before.
+func (s *shipper) send(retry bool) bool {
+	body, err := s.body()
+	if err != nil {
+		writeFailed(s.name, int64(len(s.batch)), err)
+		s.commit()
+		return true
+	}
+
+	backoff := s.backoff
+	for failed := false; ; failed = true {
+		again, err := s.post(body)
+		if err == nil {
+			s.commit()
+			return true
+		}
+		if !again {
+			// the endpoint rejects the batch
+			writeFailed(s.name, int64(len(s.batch)), err)
+			s.commit()
+			return true
+		}
+		if !failed {
+			writeFailed(s.name, 0, err)
+		}
+		if !retry {
+			return false
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-s.stop:
+			return false
+		}
+		if backoff *= 2; backoff > maxShipBackoff {
+			backoff = maxShipBackoff
+		}
+	}
+}
+
+// body returns the ndjson of the batch, gzipped with Gzip
+func (s *shipper) body() ([]byte, error) {
+	var buf bytes.Buffer
+	var w io.Writer = &buf
+	var zw *gzip.Writer
+	if s.cfg.Gzip {
+		zw = gzip.NewWriter(&buf)
+		w = zw
+	}
+
+	for _, l := range s.batch {
+		if _, err := w.Write(l.line); err != nil {
+			return nil, err
+		}
+	}
+	if zw != nil {
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// post sends body and reports whether a failure may pass later
+func (s *shipper) post(body []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, s.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	if s.cfg.Gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	if s.cfg.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.cfg.Token)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+
+	if resp.StatusCode/100 == 2 {
+		return false, nil
+	}
+
+	err = fmt.Errorf("zlog: shipper status %s", resp.Status)
+	return resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests ||
+		resp.StatusCode == http.StatusRequestTimeout, err
+}
+
+// commit saves the checkpoints after the lines of the batch and
+// empties it
+func (s *shipper) commit() {
+	for _, l := range s.batch {
+		s.checkpoints[l.stream.name] = l.ck
+	}
+	s.batch, s.batchBytes = s.batch[:0], 0
+
+	if err := s.saveCheckpoints(); err != nil {
+		writeFailed(s.ckPath, 0, err)
+	}
+}
+
+// saveCheckpoints writes the checkpoint file by a rename, so it is
+// never half written
+func (s *shipper) saveCheckpoints() error {
+	b, err := json.Marshal(s.checkpoints)
+	if err != nil {
+		return err
+	}
+
+	if err := mkdirMode(filepath.Dir(s.ckPath), 0); err != nil {
+		return err
+	}
+	tmp := s.ckPath + ".tmp"
+	if err := ioutil.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.ckPath)
+}
+
+func (s *shipper) closeFiles() {
+	for _, st := range s.streams {
+		if st.file != nil {
+			st.file.f.Close()
+		}
+	}
+}
@@ -0,0 +1,230 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/vcaesar/tt"
+)
+
+// shipServer an endpoint collecting the lines of the batches, it
+// answers status while it isn't 0
+type shipServer struct {
+	*httptest.Server
+	mu     sync.Mutex
+	lines  []string
+	status int32
+	calls  int32
+}
+
+func newShipServer(t *testing.T) *shipServer {
+	s := &shipServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&s.calls, 1)
+		if status := atomic.LoadInt32(&s.status); status != 0 {
+			w.WriteHeader(int(status))
+			return
+		}
+
+		tt.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		tt.Equal(t, "application/x-ndjson", r.Header.Get("Content-Type"))
+		var body io.Reader = r.Body
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			zr, err := gzip.NewReader(r.Body)
+			tt.Nil(t, err)
+			body = zr
+		}
+
+		scanner := bufio.NewScanner(body)
+		s.mu.Lock()
+		for scanner.Scan() {
+			s.lines = append(s.lines, scanner.Text())
+		}
+		s.mu.Unlock()
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *shipServer) received() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.lines...)
+}
+
+// wait waits for n lines
+func (s *shipServer) wait(t *testing.T, n int) []string {
+	for i := 0; i < 500 && len(s.received()) < n; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	lines := s.received()
+	tt.Equal(t, n, len(lines))
+	return lines
+}
+
+func shipConfig(dir, url string) ShipperConfig {
+	return ShipperConfig{
+		Path: dir, URL: url, Token: "secret", Gzip: true,
+		BatchLines: 3, BatchInterval: "20ms", PollInterval: "5ms",
+		RetryBackoff: "5ms",
+	}
+}
+
+// appendLines appends the lines from to to-1 to path
+func appendLines(t *testing.T, path string, from, to int) {
+	tt.Nil(t, os.MkdirAll(filepath.Dir(path), 0755))
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	tt.Nil(t, err)
+	for i := from; i < to; i++ {
+		fmt.Fprintf(f, `{"msg":"line %d"}`+"\n", i)
+	}
+	tt.Nil(t, f.Close())
+}
+
+func wantLines(from, to int) []string {
+	var lines []string
+	for i := from; i < to; i++ {
+		lines = append(lines, fmt.Sprintf(`{"msg":"line %d"}`, i))
+	}
+	return lines
+}
+
+func startShipper(t *testing.T, cfg ShipperConfig, now func() time.Time) func() {
+	s, err := newShipper(cfg)
+	tt.Nil(t, err)
+	if now != nil {
+		s.now = now
+	}
+
+	go s.run()
+	return s.close
+}
+
+func TestShipperRotation(t *testing.T) {
+	dir, srv := t.TempDir(), newShipServer(t)
+	// the clock runs from 2018-05-01 23:00
+	shift := int64(time.Date(2018, 5, 1, 23, 0, 0, 0, time.Local).Sub(time.Now()))
+	now := func() time.Time {
+		return time.Now().Add(time.Duration(atomic.LoadInt64(&shift)))
+	}
+	day := filepath.Join(dir, "2018-05-01", "log.json")
+	appendLines(t, day, 0, 5)
+
+	stop := startShipper(t, shipConfig(dir, srv.URL), now)
+	defer stop()
+	srv.wait(t, 5)
+
+	// lumberjack renames the file and creates a new one
+	appendLines(t, day, 5, 7)
+	tt.Nil(t, os.Rename(day, filepath.Join(dir, "2018-05-01", "log-2018-05-01T23-30-00.000.json")))
+	appendLines(t, day, 7, 9)
+	srv.wait(t, 9)
+
+	// the next day
+	next := filepath.Join(dir, "2018-05-02", "log.json")
+	appendLines(t, day, 9, 10)
+	atomic.AddInt64(&shift, int64(2*time.Hour))
+	appendLines(t, next, 10, 12)
+	tt.Equal(t, wantLines(0, 12), srv.wait(t, 12))
+}
+
+func TestShipperCheckpoint(t *testing.T) {
+	dir, srv := t.TempDir(), newShipServer(t)
+	cfg := shipConfig(dir, srv.URL)
+	cfg.DailyDirs = new(bool)
+	cfg.Gzip = false
+	path := filepath.Join(dir, "log.json")
+	appendLines(t, path, 0, 4)
+
+	stop := startShipper(t, cfg, nil)
+	srv.wait(t, 4)
+	stop()
+
+	// the lines written while it is stopped, across a rotation
+	appendLines(t, path, 4, 6)
+	tt.Nil(t, os.Rename(path, filepath.Join(dir, "log-2018-05-01T08-00-00.000.json")))
+	appendLines(t, path, 6, 8)
+
+	stop = startShipper(t, cfg, nil)
+	tt.Equal(t, wantLines(0, 8), srv.wait(t, 8))
+	stop()
+
+	cks, err := readCheckpoints(filepath.Join(dir, ".shipper.json"))
+	tt.Nil(t, err)
+	fi, err := os.Stat(path)
+	tt.Nil(t, err)
+	tt.Equal(t, path, cks["log.json"].Path)
+	tt.Equal(t, fi.Size(), cks["log.json"].Offset)
+}
+
+func TestShipperDown(t *testing.T) {
+	dir, srv := t.TempDir(), newShipServer(t)
+	notices, _ := fakeNotices(t)
+	cfg := shipConfig(dir, srv.URL)
+	cfg.DailyDirs = new(bool)
+	path := filepath.Join(dir, "log.json")
+	atomic.StoreInt32(&srv.status, http.StatusServiceUnavailable)
+	appendLines(t, path, 0, 10)
+
+	stop := startShipper(t, cfg, nil)
+	for i := 0; i < 500 && atomic.LoadInt32(&srv.calls) < 5; i++ {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// the tailing is paused, nothing is checkpointed
+	_, err := os.Stat(filepath.Join(dir, ".shipper.json"))
+	tt.True(t, os.IsNotExist(err))
+
+	atomic.StoreInt32(&srv.status, 0)
+	tt.Equal(t, wantLines(0, 10), srv.wait(t, 10))
+
+	// a rejected batch is dropped
+	atomic.StoreInt32(&srv.status, http.StatusBadRequest)
+	calls := atomic.LoadInt32(&srv.calls)
+	appendLines(t, path, 10, 12)
+	for i := 0; i < 500 && atomic.LoadInt32(&srv.calls) == calls; i++ {
+		time.Sleep(5 * time.Millisecond)
+	}
+	atomic.StoreInt32(&srv.status, 0)
+	appendLines(t, path, 12, 13)
+	lines := srv.wait(t, 11)
+	tt.Equal(t, `{"msg":"line 12"}`, lines[10])
+
+	stop()
+	tt.True(t, strings.Contains(notices.String(), "503 Service Unavailable"))
+}
+
+func TestShipperConfig(t *testing.T) {
+	_, err := StartShipper(ShipperConfig{})
+	tt.NotNil(t, err)
+	_, err = StartShipper(ShipperConfig{URL: "http://localhost", BatchInterval: "x"})
+	tt.NotNil(t, err)
+
+	dir := t.TempDir()
+	ck := filepath.Join(dir, "ck.json")
+	tt.Nil(t, ioutil.WriteFile(ck, []byte("{"), 0644))
+	_, err = StartShipper(ShipperConfig{URL: "http://localhost", Checkpoint: ck})
+	tt.NotNil(t, err)
+}
@@ -0,0 +1,42 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+// +build !windows
+
+package zlog
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"go.uber.org/zap"
+)
+
+var signalOnce sync.Once
+
+// HandleSignals rotates the log files on SIGHUP, the logrotate
+// renames the files and sends SIGHUP so the new entries go to the
+// new files, it only installs the handler once.
+func HandleSignals() {
+	signalOnce.Do(func() {
+		ch := make(chan os.Signal, 1)
+		signal.Notify(ch, syscall.SIGHUP)
+
+		go func() {
+			for range ch {
+				if err := Rotate(); err != nil {
+					getErrLogger().Error("zlog: rotate error", zap.Error(err))
+				}
+			}
+		}()
+	})
+}
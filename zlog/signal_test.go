@@ -0,0 +1,45 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+// +build !windows
+
+package zlog
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/vcaesar/tt"
+)
+
+func TestHandleSignals(t *testing.T) {
+	dir := initTest(t)
+	defer Close()
+
+	HandleSignals()
+	HandleSignals()
+
+	Info("before hup")
+	// the logrotate renames the file before SIGHUP
+	day := filepath.Dir(load().closer.(*dailyWriter).lj.Filename)
+	tt.Nil(t, os.Rename(filepath.Join(day, "test.json"), filepath.Join(day, "test.json.1")))
+	tt.Nil(t, syscall.Kill(os.Getpid(), syscall.SIGHUP))
+
+	tt.True(t, waitFor(func() bool {
+		Info("after hup")
+		entries := readEntries(t, filepath.Join(dir, "*", "test.json"))
+		return len(msgEntries(entries, "after hup")) > 0
+	}))
+
+	entries := readEntries(t, filepath.Join(day, "test.json.1"))
+	tt.Equal(t, 1, len(msgEntries(entries, "before hup")))
+}
@@ -0,0 +1,151 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+// +build go1.21
+
+package zlog
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// slogHandler a slog handler writing the records through the
+// package loggers, the records of error go to the error log.
+type slogHandler struct {
+	// prefix the dotted groups of the attrs added after WithGroup
+	prefix string
+	fields []zap.Field
+}
+
+// SlogHandler returns a slog handler logging through zlog,
+// the groups of the attrs are flattened into dotted keys.
+//
+//	logger := slog.New(zlog.SlogHandler())
+func SlogHandler() slog.Handler {
+	return &slogHandler{}
+}
+
+// slogLevel maps the slog level to the zap level
+func slogLevel(l slog.Level) zapcore.Level {
+	switch {
+	case l < slog.LevelInfo:
+		return zapcore.DebugLevel
+	case l < slog.LevelWarn:
+		return zapcore.InfoLevel
+	case l < slog.LevelError:
+		return zapcore.WarnLevel
+	default:
+		return zapcore.ErrorLevel
+	}
+}
+
+func slogLogger(lvl zapcore.Level) *zap.Logger {
+	if lvl >= zapcore.ErrorLevel {
+		return getErrLogger()
+	}
+	return getLogger()
+}
+
+func (h *slogHandler) Enabled(_ context.Context, l slog.Level) bool {
+	lvl := slogLevel(l)
+	return slogLogger(lvl).Core().Enabled(lvl)
+}
+
+func (h *slogHandler) Handle(_ context.Context, r slog.Record) error {
+	lvl := slogLevel(r.Level)
+	ce := slogLogger(lvl).Check(lvl, r.Message)
+	if ce == nil {
+		return nil
+	}
+
+	if !r.Time.IsZero() {
+		ce.Time = r.Time
+	}
+
+	// the caller of the slog logger, not of the handler
+	if ce.Caller.Defined && r.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		ce.Caller = zapcore.NewEntryCaller(r.PC, frame.File, frame.Line, true)
+	}
+
+	fields := make([]zap.Field, len(h.fields), len(h.fields)+r.NumAttrs())
+	copy(fields, h.fields)
+	r.Attrs(func(a slog.Attr) bool {
+		fields = appendAttr(fields, h.prefix, a)
+		return true
+	})
+
+	ce.Write(fields...)
+	return nil
+}
+
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	fields := make([]zap.Field, len(h.fields), len(h.fields)+len(attrs))
+	copy(fields, h.fields)
+	for _, a := range attrs {
+		fields = appendAttr(fields, h.prefix, a)
+	}
+
+	return &slogHandler{prefix: h.prefix, fields: fields}
+}
+
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	return &slogHandler{prefix: h.prefix + name + ".", fields: h.fields}
+}
+
+// appendAttr appends the fields of the attr with the prefix,
+// the LogValuer values are resolved and the groups flattened.
+func appendAttr(fields []zap.Field, prefix string, a slog.Attr) []zap.Field {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return fields
+	}
+
+	key := prefix + a.Key
+	v := a.Value
+	switch v.Kind() {
+	case slog.KindGroup:
+		if a.Key != "" {
+			prefix = key + "."
+		}
+		for _, ga := range v.Group() {
+			fields = appendAttr(fields, prefix, ga)
+		}
+		return fields
+	case slog.KindString:
+		return append(fields, zap.String(key, v.String()))
+	case slog.KindInt64:
+		return append(fields, zap.Int64(key, v.Int64()))
+	case slog.KindUint64:
+		return append(fields, zap.Uint64(key, v.Uint64()))
+	case slog.KindFloat64:
+		return append(fields, zap.Float64(key, v.Float64()))
+	case slog.KindBool:
+		return append(fields, zap.Bool(key, v.Bool()))
+	case slog.KindDuration:
+		return append(fields, zap.Duration(key, v.Duration()))
+	case slog.KindTime:
+		return append(fields, zap.Time(key, v.Time()))
+	default:
+		if err, ok := v.Any().(error); ok {
+			return append(fields, zap.NamedError(key, err))
+		}
+		return append(fields, zap.Any(key, v.Any()))
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+// +build go1.21
+
+package zlog
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vcaesar/tt"
+	"go.uber.org/zap"
+)
+
+// token a LogValuer hiding its value
+type token string
+
+func (token) LogValue() slog.Value {
+	return slog.StringValue("***")
+}
+
+func TestSlogHandler(t *testing.T) {
+	logs, errLogs := observeSplit(t)
+	tm := time.Date(2018, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	logger := slog.New(SlogHandler()).With("app", "gt").WithGroup("req").With("id", 1)
+	logger.Info("slog info",
+		slog.Group("user", "name", "bob", slog.Group("role", "admin", true)),
+		"at", tm,
+		"took", 2*time.Second,
+		"token", token("secret"),
+		slog.Group("empty"),
+	)
+	logger.Debug("slog debug", "n", uint64(3))
+	slog.New(SlogHandler()).Error("slog error", "err", errors.New("e1"))
+
+	entries := logs.FilterMessage("slog info").AllUntimed()
+	tt.Equal(t, 1, len(entries))
+	tt.Equal(t, zap.InfoLevel, entries[0].Level)
+	tt.Equal(t, map[string]interface{}{
+		"app":                 "gt",
+		"req.id":              int64(1),
+		"req.user.name":       "bob",
+		"req.user.role.admin": true,
+		"req.at":              tm,
+		"req.took":            2 * time.Second,
+		"req.token":           "***",
+	}, entries[0].ContextMap())
+
+	debug := logs.FilterMessage("slog debug").AllUntimed()
+	tt.Equal(t, zap.DebugLevel, debug[0].Level)
+	tt.Equal(t, uint64(3), debug[0].ContextMap()["req.n"])
+
+	errEntries := errLogs.FilterMessage("slog error").AllUntimed()
+	tt.Equal(t, 1, len(errEntries))
+	tt.Equal(t, "e1", errEntries[0].ContextMap()["err"])
+	tt.Equal(t, 0, logs.FilterMessage("slog error").Len())
+}
+
+func TestSlogLevel(t *testing.T) {
+	tt.Equal(t, zap.DebugLevel, slogLevel(slog.LevelDebug))
+	tt.Equal(t, zap.InfoLevel, slogLevel(slog.LevelInfo+1))
+	tt.Equal(t, zap.WarnLevel, slogLevel(slog.LevelWarn))
+	tt.Equal(t, zap.ErrorLevel, slogLevel(slog.LevelError+4))
+
+	current.Store(nopLoggers())
+	tt.False(t, SlogHandler().Enabled(context.Background(), slog.LevelError))
+
+	observe(t)
+	tt.True(t, SlogHandler().Enabled(context.Background(), slog.LevelDebug))
+}
+
+func TestSlogCaller(t *testing.T) {
+	dir := t.TempDir()
+	tt.Nil(t, InitWithConfig(Config{Path: dir, Name: "slog", Caller: true}))
+	slog.New(SlogHandler()).Info("slog caller")
+	tt.Nil(t, Close())
+
+	entries := msgEntries(readEntries(t, filepath.Join(dir, "*", "slog.json")), "slog caller")
+	tt.Equal(t, 1, len(entries))
+	tt.True(t, strings.HasPrefix(entries[0]["caller"].(string), "zlog/slog_test.go:"))
+}
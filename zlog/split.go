@@ -0,0 +1,92 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"io"
+
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// closers closes all the closers, it's used by pointer
+// so swap can compare it
+type closers []io.Closer
+
+func (cs *closers) Close() error {
+	var err error
+	for _, c := range *cs {
+		err = multierr.Append(err, c.Close())
+	}
+	return err
+}
+
+// Rotate rotates the files of the closers which can be rotated
+func (cs *closers) Rotate() error {
+	var err error
+	for _, c := range *cs {
+		if r, ok := c.(rotator); ok {
+			err = multierr.Append(err, r.Rotate())
+		}
+	}
+	return err
+}
+
+// levelBand enables only lvl, if the live level enab enables it
+func levelBand(lvl zapcore.Level, enab zap.AtomicLevel) zapcore.LevelEnabler {
+	return zap.LevelEnablerFunc(func(l zapcore.Level) bool {
+		return l == lvl && enab.Enabled(l)
+	})
+}
+
+// splitCore returns the tee of a file per level of lvl, the error
+// and above entries are written to errWs if it isn't nil.
+func (c *Config) splitCore(lpath, name string, enc zapcore.Encoder,
+	errWs zapcore.WriteSyncer, lvl zap.AtomicLevel) (zapcore.Core, io.Closer, error) {
+	bands := []struct {
+		suffix string
+		lvl    zapcore.Level
+	}{
+		{"_debug.json", zapcore.DebugLevel},
+		{".json", zapcore.InfoLevel},
+		{"_warn.json", zapcore.WarnLevel},
+	}
+
+	var (
+		cores []zapcore.Core
+		cs    closers
+	)
+	for _, band := range bands {
+		ws, err := c.openFile(lpath, name+band.suffix)
+		if err != nil {
+			cs.Close()
+			return nil, nil, err
+		}
+
+		cores = append(cores, zapcore.NewCore(
+			enc,
+			hookWriter{ws},
+			levelBand(band.lvl, lvl),
+		))
+		cs = append(cs, ws)
+	}
+
+	if errWs != nil {
+		cores = append(cores, zapcore.NewCore(
+			enc,
+			hookWriter{errWs},
+			highPriority,
+		))
+	}
+
+	return zapcore.NewTee(cores...), &cs, nil
+}
@@ -0,0 +1,60 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/vcaesar/tt"
+)
+
+func TestSplitLevels(t *testing.T) {
+	dir := initTest(t, "split_levels = true", "level = \"debug\"")
+
+	Debug("debug msg")
+	Info("info msg")
+	Warn("warn msg")
+	Error("error msg", errors.New("e1"))
+	LogError("main error msg", errors.New("e2"))
+	tt.Nil(t, Close())
+
+	msgs := []string{"debug msg", "info msg", "warn msg", "error msg", "main error msg"}
+	files := map[string][]string{
+		"test_debug.json": {"debug msg"},
+		"test.json":       {"info msg"},
+		"test_warn.json":  {"warn msg"},
+		"test_err.json":   {"error msg", "main error msg"},
+	}
+
+	for file, want := range files {
+		entries := msgEntries(readEntries(t, filepath.Join(dir, "*", file)), msgs...)
+		var got []string
+		for _, entry := range entries {
+			got = append(got, entry["msg"].(string))
+		}
+		tt.Equal(t, want, got)
+	}
+}
+
+func TestSplitLevelsLevel(t *testing.T) {
+	dir := initTest(t, "split_levels = true", "level = \"warn\"")
+
+	Debug("debug msg")
+	Info("info msg")
+	Warn("warn msg")
+	tt.Nil(t, Close())
+
+	entries := readEntries(t, filepath.Join(dir, "*", "test*.json"))
+	tt.Equal(t, 0, len(msgEntries(entries, "debug msg", "info msg")))
+	tt.Equal(t, 1, len(msgEntries(entries, "warn msg")))
+}
@@ -0,0 +1,52 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// state the runtime state of the loggers built from a config, the
+// redacted keys, MaxFieldLen, the filters, the level overrides, the
+// ring buffer and the SlowThreshold. The package loggers use
+// defaultState, each logger of New has its own.
+type state struct {
+	// the int64 first, they're 64-bit aligned on the 32-bit platforms
+	fieldLen  int64
+	slowNanos int64
+
+	redact    atomic.Value // *redaction
+	filters   atomic.Value // *filters
+	overrides atomic.Value // map[string]zapcore.Level
+	ring      atomic.Value // *ringBuffer
+
+	filterLock, overrideLock sync.Mutex
+}
+
+// defaultState the state of the package loggers
+var defaultState = &state{}
+
+// set sets the state of the validated config c, the redacted keys
+// are kept without Redact
+func (st *state) set(c *Config) {
+	st.setMaxFieldLen(c.MaxFieldLen)
+	st.setRing(c.RingBuffer)
+	levels, _ := c.levelsConf()
+	st.setOverrides(levels)
+	f, _ := c.filtersConf()
+	st.setFilters(f)
+	slow, _ := c.slowThreshold()
+	st.setSlowThreshold(slow)
+	if len(c.Redact) > 0 {
+		st.setRedactedKeys(c.Redact...)
+	}
+}
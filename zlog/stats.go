@@ -0,0 +1,105 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// noticeInterval the min interval of the notices of the failed writes
+const noticeInterval = time.Minute
+
+var (
+	droppedEntries, sampledEntries, filteredEntries, writeErrors int64
+
+	// lastError the last write error, a string
+	lastError atomic.Value
+
+	noticeLock sync.Mutex
+	// notices the failed writes of each output since its last notice
+	notices = make(map[string]*failNotice)
+	// noticeOut and noticeNow the stderr of the notices and the clock,
+	// tests swap them
+	noticeOut io.Writer = os.Stderr
+	noticeNow           = time.Now
+)
+
+// failNotice the entries which couldn't be written to an output
+// since the last notice
+type failNotice struct {
+	entries int64
+	last    time.Time
+}
+
+// Statistics the counters of the log outputs
+type Statistics struct {
+	// DroppedEntries the entries dropped because the queue of
+	// an Async log file was full
+	DroppedEntries int64 `json:"dropped_entries"`
+	// SampledEntries the entries dropped by the sampling
+	SampledEntries int64 `json:"sampled_entries"`
+	// FilteredEntries the entries dropped by the Filters
+	// and AddDenyFilter
+	FilteredEntries int64 `json:"filtered_entries"`
+	// WriteErrors the writes and the syncs of the outputs which
+	// failed, like the writes of a full disk
+	WriteErrors int64 `json:"write_errors"`
+	// LastError the message of the last write error
+	LastError string `json:"last_error,omitempty"`
+}
+
+// Stats returns the counters of the log outputs
+func Stats() Statistics {
+	last, _ := lastError.Load().(string)
+	return Statistics{
+		DroppedEntries:  atomic.LoadInt64(&droppedEntries),
+		SampledEntries:  atomic.LoadInt64(&sampledEntries),
+		FilteredEntries: atomic.LoadInt64(&filteredEntries),
+		WriteErrors:     atomic.LoadInt64(&writeErrors),
+		LastError:       last,
+	}
+}
+
+// countFailure counts the write error of the n entries of output
+// and writes a notice to stderr at most once per minute for each
+// output, the notice has the entries lost since the previous one.
+func countFailure(output string, n int64, err error) {
+	atomic.AddInt64(&writeErrors, 1)
+	lastError.Store(err.Error())
+
+	noticeLock.Lock()
+	defer noticeLock.Unlock()
+
+	fn, ok := notices[output]
+	if !ok {
+		fn = &failNotice{}
+		notices[output] = fn
+	}
+	fn.entries += n
+
+	now := noticeNow()
+	if ok && now.Sub(fn.last) < noticeInterval {
+		return
+	}
+
+	if fn.entries == 0 {
+		fmt.Fprintf(noticeOut, "zlog: failed to sync %s: %v\n", output, err)
+	} else {
+		fmt.Fprintf(noticeOut, "zlog: failed to write %d entries to %s: %v\n",
+			fn.entries, output, err)
+	}
+	fn.entries, fn.last = 0, now
+}
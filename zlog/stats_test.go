@@ -0,0 +1,73 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vcaesar/tt"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// failWriter a log file whose writes fail
+type failWriter struct{}
+
+func (failWriter) Write([]byte) (int, error) { return 0, errors.New("no space left on device") }
+func (failWriter) Sync() error               { return nil }
+func (failWriter) Name() string              { return "/full/test.json" }
+
+// fakeNotices swaps in the stderr and the clock of the notices
+func fakeNotices(t *testing.T) (*bytes.Buffer, *fakeClock) {
+	buf, clock := &bytes.Buffer{}, &fakeClock{tm: time.Date(2018, 5, 1, 8, 0, 0, 0, time.UTC)}
+	prevOut, prevNow := noticeOut, noticeNow
+	noticeOut, noticeNow = buf, clock.now
+	t.Cleanup(func() {
+		noticeOut, noticeNow = prevOut, prevNow
+		noticeLock.Lock()
+		delete(notices, "/full/test.json")
+		noticeLock.Unlock()
+	})
+
+	return buf, clock
+}
+
+func TestWriteErrors(t *testing.T) {
+	buf, clock := fakeNotices(t)
+	errs := Stats().WriteErrors
+
+	logger := zap.New(zapcore.NewCore(zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()),
+		hookWriter{failWriter{}}, zap.InfoLevel))
+	for i := 0; i < 5; i++ {
+		logger.Info("lost")
+	}
+
+	stats := Stats()
+	tt.Equal(t, errs+5, stats.WriteErrors)
+	tt.Equal(t, "no space left on device", stats.LastError)
+
+	// the first one only
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	tt.Equal(t, []string{"zlog: failed to write 1 entries to /full/test.json: " +
+		"no space left on device"}, lines)
+
+	// the lost entries since the notice
+	clock.set(clock.now().Add(time.Minute))
+	logger.Info("lost")
+	lines = strings.Split(strings.TrimSpace(buf.String()), "\n")
+	tt.Equal(t, 2, len(lines))
+	tt.Equal(t, "zlog: failed to write 5 entries to /full/test.json: "+
+		"no space left on device", lines[1])
+}
@@ -0,0 +1,116 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// stdSource the source field of the entries of the std log
+var stdSource = zap.String("source", "stdlog")
+
+// stdLogger returns l for the std log, which counts its own frames,
+// without the frame skipped for the package functions.
+func stdLogger(l *zap.Logger) *zap.Logger {
+	return l.With(stdSource).WithOptions(zap.AddCallerSkip(-1))
+}
+
+// RedirectStdLog writes the output of the std log package as info
+// entries with the source "stdlog" field, the loggers of the time
+// it's called are used, it returns the func to restore the std log.
+//
+//	defer zlog.RedirectStdLog()()
+func RedirectStdLog() func() {
+	return zap.RedirectStdLog(stdLogger(getLogger()))
+}
+
+// StdLogger returns a std log logger writing entries at the level,
+// the entries of error and above go to the error log.
+//
+//	srv := &http.Server{ErrorLog: zlog.StdLogger(zapcore.ErrorLevel)}
+func StdLogger(lvl zapcore.Level) *log.Logger {
+	logger := getLogger()
+	if lvl >= zapcore.ErrorLevel {
+		logger = getErrLogger()
+	}
+
+	std, err := zap.NewStdLogAt(stdLogger(logger), lvl)
+	if err != nil {
+		return zap.NewStdLog(stdLogger(logger))
+	}
+	return std
+}
+
+// lineWriter logs every line written to it as an entry
+type lineWriter struct {
+	mu  sync.Mutex
+	lvl zapcore.Level
+	buf []byte
+}
+
+// Writer returns a writer logging every line written to it as an
+// entry at the level, a partial line is kept until the rest of it
+// is written or the writer is closed, empty lines are dropped.
+//
+//	cmd.Stdout = zlog.Writer(zapcore.InfoLevel)
+func Writer(lvl zapcore.Level) io.WriteCloser {
+	return &lineWriter{lvl: lvl}
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+
+		w.log(w.buf[:i])
+		w.buf = w.buf[i+1:]
+	}
+
+	return len(p), nil
+}
+
+// Close logs the partial line left
+func (w *lineWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.log(w.buf)
+	w.buf = nil
+	return nil
+}
+
+func (w *lineWriter) log(line []byte) {
+	line = bytes.TrimSuffix(line, []byte{'\r'})
+	if len(line) == 0 {
+		return
+	}
+
+	logger := getLogger()
+	if w.lvl >= zapcore.ErrorLevel {
+		logger = getErrLogger()
+	}
+
+	if ce := logger.Check(w.lvl, string(line)); ce != nil {
+		ce.Write()
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"log"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/vcaesar/tt"
+	"go.uber.org/zap"
+)
+
+func TestRedirectStdLog(t *testing.T) {
+	logs := observe(t)
+
+	restore := RedirectStdLog()
+	log.Print("std print")
+	restore()
+	log.Print("after restore")
+
+	entries := logs.FilterMessage("std print").AllUntimed()
+	tt.Equal(t, 1, len(entries))
+	tt.Equal(t, zap.InfoLevel, entries[0].Level)
+	tt.Equal(t, "stdlog", entries[0].ContextMap()["source"])
+	tt.Equal(t, 0, logs.FilterMessage("after restore").Len())
+}
+
+func TestStdLogger(t *testing.T) {
+	logs, errLogs := observeSplit(t)
+
+	StdLogger(zap.WarnLevel).Print("std warn")
+	StdLogger(zap.ErrorLevel).Printf("std %s", "error")
+
+	entries := logs.FilterMessage("std warn").AllUntimed()
+	tt.Equal(t, 1, len(entries))
+	tt.Equal(t, zap.WarnLevel, entries[0].Level)
+	tt.Equal(t, "stdlog", entries[0].ContextMap()["source"])
+
+	errEntries := errLogs.FilterMessage("std error").AllUntimed()
+	tt.Equal(t, 1, len(errEntries))
+	tt.Equal(t, zap.ErrorLevel, errEntries[0].Level)
+}
+
+func TestWriter(t *testing.T) {
+	logs, errLogs := observeSplit(t)
+
+	w := Writer(zap.InfoLevel)
+	for _, chunk := range []string{"first li", "ne\nsecond line\n\nthi", "rd line\r\n", "partial"} {
+		n, err := w.Write([]byte(chunk))
+		tt.Nil(t, err)
+		tt.Equal(t, len(chunk), n)
+	}
+	tt.Equal(t, 3, logs.Len())
+	tt.Nil(t, w.Close())
+
+	var msgs []string
+	for _, entry := range logs.AllUntimed() {
+		tt.Equal(t, zap.InfoLevel, entry.Level)
+		msgs = append(msgs, entry.Message)
+	}
+	tt.Equal(t, []string{"first line", "second line", "third line", "partial"}, msgs)
+
+	ew := Writer(zap.ErrorLevel)
+	ew.Write([]byte("error line\n"))
+	tt.Equal(t, 1, errLogs.FilterMessage("error line").Len())
+}
+
+func TestWriterConcurrent(t *testing.T) {
+	logs := observe(t)
+
+	w := Writer(zap.InfoLevel)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				w.Write([]byte("concurrent line\n"))
+			}
+		}()
+	}
+	wg.Wait()
+
+	tt.Equal(t, 1000, logs.FilterMessage("concurrent line").Len())
+}
+
+func TestStdLogCaller(t *testing.T) {
+	dir := t.TempDir()
+	tt.Nil(t, InitWithConfig(Config{Path: dir, Name: "std", Caller: true}))
+	restore := RedirectStdLog()
+	log.Print("std caller")
+	restore()
+	StdLogger(zap.InfoLevel).Print("std logger caller")
+	tt.Nil(t, Close())
+
+	entries := msgEntries(readEntries(t, filepath.Join(dir, "*", "std.json")),
+		"std caller", "std logger caller")
+	tt.Equal(t, 2, len(entries))
+	for _, entry := range entries {
+		tt.True(t, strings.HasPrefix(entry["caller"].(string), "zlog/std_test.go:"))
+	}
+}
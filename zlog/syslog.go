@@ -0,0 +1,213 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+// +build !windows,!plan9
+
+package zlog
+
+import (
+	"errors"
+	"fmt"
+	"log/syslog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// syslogBuffer the entries queued for syslog
+	syslogBuffer = 1024
+	// syslogRedial the min interval of the dials after a failure
+	syslogRedial = time.Second
+)
+
+var (
+	syslogFailures int64
+
+	errSyslogFull = errors.New("zlog: syslog queue is full, the entry is dropped")
+
+	facilities = map[string]syslog.Priority{
+		"kern": syslog.LOG_KERN, "user": syslog.LOG_USER,
+		"mail": syslog.LOG_MAIL, "daemon": syslog.LOG_DAEMON,
+		"auth": syslog.LOG_AUTH, "syslog": syslog.LOG_SYSLOG,
+		"lpr": syslog.LOG_LPR, "news": syslog.LOG_NEWS,
+		"uucp": syslog.LOG_UUCP, "cron": syslog.LOG_CRON,
+		"authpriv": syslog.LOG_AUTHPRIV, "ftp": syslog.LOG_FTP,
+		"local0": syslog.LOG_LOCAL0, "local1": syslog.LOG_LOCAL1,
+		"local2": syslog.LOG_LOCAL2, "local3": syslog.LOG_LOCAL3,
+		"local4": syslog.LOG_LOCAL4, "local5": syslog.LOG_LOCAL5,
+		"local6": syslog.LOG_LOCAL6, "local7": syslog.LOG_LOCAL7,
+	}
+)
+
+// SyslogFailures returns the number of the entries which
+// couldn't be written to syslog
+func SyslogFailures() int64 {
+	return atomic.LoadInt64(&syslogFailures)
+}
+
+func syslogFailed(err error) {
+	atomic.AddInt64(&syslogFailures, 1)
+	writeFailed("syslog", 1, err)
+}
+
+type syslogMsg struct {
+	lvl zapcore.Level
+	msg string
+}
+
+// syslogSink writes the entries to syslog in its goroutine, the
+// entries are dropped while syslog can't be reached or the queue
+// is full, so the log calls never block on syslog.
+type syslogSink struct {
+	cfg      SyslogConfig
+	facility syslog.Priority
+
+	ch   chan syslogMsg
+	done chan struct{}
+	once sync.Once
+}
+
+func newSyslogSink(cfg SyslogConfig) (*syslogSink, error) {
+	facility := syslog.LOG_USER
+	if cfg.Facility != "" {
+		var ok bool
+		if facility, ok = facilities[cfg.Facility]; !ok {
+			return nil, fmt.Errorf("zlog: invalid syslog facility %q", cfg.Facility)
+		}
+	}
+
+	s := &syslogSink{
+		cfg:      cfg,
+		facility: facility,
+		ch:       make(chan syslogMsg, syslogBuffer),
+		done:     make(chan struct{}),
+	}
+	go s.run()
+
+	return s, nil
+}
+
+func (s *syslogSink) send(lvl zapcore.Level, msg string) {
+	select {
+	case s.ch <- syslogMsg{lvl: lvl, msg: msg}:
+	default:
+		syslogFailed(errSyslogFull)
+	}
+}
+
+func (s *syslogSink) run() {
+	defer close(s.done)
+
+	var (
+		w        *syslog.Writer
+		lastDial time.Time
+	)
+	for m := range s.ch {
+		if w == nil {
+			if time.Since(lastDial) < syslogRedial {
+				syslogFailed(errors.New("zlog: syslog is not connected"))
+				continue
+			}
+
+			var err error
+			lastDial = time.Now()
+			w, err = syslog.Dial(s.cfg.Network, s.cfg.Address, s.facility, s.cfg.Tag)
+			if err != nil {
+				syslogFailed(err)
+				continue
+			}
+		}
+
+		if err := writeSyslog(w, m); err != nil {
+			syslogFailed(err)
+			w.Close()
+			w = nil
+		}
+	}
+
+	if w != nil {
+		w.Close()
+	}
+}
+
+// writeSyslog writes m with the severity of its level
+func writeSyslog(w *syslog.Writer, m syslogMsg) error {
+	switch m.lvl {
+	case zapcore.DebugLevel:
+		return w.Debug(m.msg)
+	case zapcore.InfoLevel:
+		return w.Info(m.msg)
+	case zapcore.WarnLevel:
+		return w.Warning(m.msg)
+	case zapcore.ErrorLevel:
+		return w.Err(m.msg)
+	}
+	return w.Crit(m.msg)
+}
+
+// Close writes the queued entries and closes the connection
+func (s *syslogSink) Close() error {
+	s.once.Do(func() {
+		close(s.ch)
+	})
+	<-s.done
+	return nil
+}
+
+// syslogCore encodes the entries with enc and sends them to the sink
+type syslogCore struct {
+	zapcore.LevelEnabler
+	enc  zapcore.Encoder
+	sink *syslogSink
+}
+
+func newSyslogCore(cfg SyslogConfig, enc zapcore.Encoder,
+	enab zapcore.LevelEnabler) (zapcore.Core, *syslogSink, error) {
+	sink, err := newSyslogSink(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &syslogCore{LevelEnabler: enab, enc: enc.Clone(), sink: sink}, sink, nil
+}
+
+func (c *syslogCore) With(fields []zapcore.Field) zapcore.Core {
+	enc := c.enc.Clone()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	return &syslogCore{LevelEnabler: c.LevelEnabler, enc: enc, sink: c.sink}
+}
+
+func (c *syslogCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *syslogCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+
+	c.sink.send(ent.Level, buf.String())
+	buf.Free()
+	return nil
+}
+
+// Sync the entries are sent in the background
+func (c *syslogCore) Sync() error {
+	return nil
+}
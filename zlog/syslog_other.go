@@ -0,0 +1,31 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+// +build windows plan9
+
+package zlog
+
+import (
+	"errors"
+	"io"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// SyslogFailures returns the number of the entries which
+// couldn't be written to syslog
+func SyslogFailures() int64 {
+	return 0
+}
+
+func newSyslogCore(cfg SyslogConfig, enc zapcore.Encoder,
+	enab zapcore.LevelEnabler) (zapcore.Core, io.Closer, error) {
+	return nil, nil, errors.New("zlog: syslog is not supported on this system")
+}
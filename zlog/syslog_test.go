@@ -0,0 +1,84 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+// +build !windows,!plan9
+
+package zlog
+
+import (
+	"errors"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vcaesar/tt"
+)
+
+func TestSyslog(t *testing.T) {
+	addr := filepath.Join(t.TempDir(), "syslog.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: addr, Net: "unixgram"})
+	tt.Nil(t, err)
+	defer conn.Close()
+
+	dir := t.TempDir()
+	tt.Nil(t, InitWithConfig(Config{Path: dir, Name: "test", Level: "debug",
+		Syslog: SyslogConfig{Enabled: true, Network: "unixgram", Address: addr,
+			Tag: "zlogtest", Facility: "local0"}}))
+	Debug("syslog debug")
+	Error("syslog error", errors.New("e1"))
+	tt.Nil(t, Close())
+
+	// local0 is 16, debug 7 and err 3
+	prefixes := map[string]string{"syslog debug": "<135>", "syslog error": "<131>"}
+	buf := make([]byte, 4096)
+	for len(prefixes) > 0 {
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		n, err := conn.Read(buf)
+		if err != nil {
+			t.Fatal(err, prefixes)
+		}
+
+		msg := string(buf[:n])
+		for text, prefix := range prefixes {
+			if strings.Contains(msg, `"msg":"`+text+`"`) {
+				tt.True(t, strings.HasPrefix(msg, prefix), msg)
+				tt.True(t, strings.Contains(msg, "zlogtest["), msg)
+				delete(prefixes, text)
+			}
+		}
+	}
+
+	entries := readEntries(t, filepath.Join(dir, "*", "test.json"))
+	tt.Equal(t, 1, len(msgEntries(entries, "syslog debug")))
+}
+
+func TestSyslogDown(t *testing.T) {
+	dir := t.TempDir()
+	addr := filepath.Join(dir, "not_exist.sock")
+	tt.Nil(t, InitWithConfig(Config{Path: dir, Name: "test",
+		Syslog: SyslogConfig{Enabled: true, Network: "unixgram", Address: addr}}))
+
+	failures := SyslogFailures()
+	Info("syslog down")
+	Info("syslog down")
+	tt.Nil(t, Close())
+	// the old log sweep may log as well
+	tt.True(t, SyslogFailures() >= failures+2)
+
+	// the files keep logging
+	entries := readEntries(t, filepath.Join(dir, "*", "test.json"))
+	tt.Equal(t, 2, len(msgEntries(entries, "syslog down")))
+
+	err := InitWithConfig(Config{Path: dir,
+		Syslog: SyslogConfig{Enabled: true, Facility: "nope"}})
+	tt.NotNil(t, err)
+}
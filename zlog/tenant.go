@@ -0,0 +1,252 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"container/list"
+	"errors"
+	"path/filepath"
+	"sync"
+
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// tenantKey the field of ForTenant
+	tenantKey = "tenant"
+	// tenantDir the dir of the tenant files in the daily dirs
+	tenantDir = "tenants"
+
+	defaultMaxTenantFiles = 64
+)
+
+var errTenantsClosed = errors.New("zlog: the tenant files are closed")
+
+// ForTenant returns a child logger adding the tenant field, with
+// TenantFiles its entries are also written to the file of the tenant.
+//
+//	log := zlog.ForTenant(tenantID)
+//	log.Info("export done")
+func ForTenant(id string) *Zlog {
+	return (&Zlog{}).ForTenant(id)
+}
+
+// ForTenant returns a child logger of z adding the tenant field,
+// like ForTenant
+func (z *Zlog) ForTenant(id string) *Zlog {
+	return z.With(zap.String(tenantKey, id))
+}
+
+// tenantID returns the id of the last tenant field of fields
+func tenantID(fields []zapcore.Field) (string, bool) {
+	id, ok := "", false
+	for _, f := range fields {
+		if f.Key == tenantKey && f.Type == zapcore.StringType {
+			id, ok = f.String, true
+		}
+	}
+	return id, ok
+}
+
+// tenantName returns the file name of the tenant id, the chars
+// other than the letters, the digits, "-", "_" and "." are
+// replaced so the id can't leave the tenants dir
+func tenantName(id string) string {
+	b := []byte(id)
+	for i, ch := range b {
+		switch {
+		case ch >= 'a' && ch <= 'z', ch >= 'A' && ch <= 'Z',
+			ch >= '0' && ch <= '9', ch == '-', ch == '_', ch == '.':
+		default:
+			b[i] = '_'
+		}
+	}
+
+	name := string(b)
+	if name == "" || name == "." || name == ".." {
+		name = "_" + name
+	}
+	return name + ".json"
+}
+
+// tenantFile an open tenant file of the lru
+type tenantFile struct {
+	id string
+	w  *dailyWriter
+}
+
+// tenantFiles the files of the tenants of the loggers of a config,
+// at most max files are open, the least recently used one is closed
+// to open another, it reopens on the next entry of its tenant
+type tenantFiles struct {
+	lpath  string
+	rotate rotateConfig
+	max    int
+	// st the state of the rewrite of the files
+	st *state
+
+	mu     sync.Mutex
+	lru    *list.List // of *tenantFile, the most recent first
+	files  map[string]*list.Element
+	closed bool
+}
+
+func (c *Config) newTenantFiles(st *state) *tenantFiles {
+	lpath, _ := c.confPath()
+	max := c.MaxTenantFiles
+	if max <= 0 {
+		max = defaultMaxTenantFiles
+	}
+
+	return &tenantFiles{
+		lpath:  lpath,
+		rotate: c.rotateConf(),
+		max:    max,
+		st:     st,
+		lru:    list.New(),
+		files:  make(map[string]*list.Element),
+	}
+}
+
+// write writes p to the file of id, opened if it isn't open, the
+// write is under the lock so the file isn't closed during it
+func (t *tenantFiles) write(id string, p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.closed {
+		return 0, errTenantsClosed
+	}
+
+	if el, ok := t.files[id]; ok {
+		t.lru.MoveToFront(el)
+		return el.Value.(*tenantFile).w.Write(p)
+	}
+
+	for t.lru.Len() >= t.max {
+		oldest := t.lru.Remove(t.lru.Back()).(*tenantFile)
+		delete(t.files, oldest.id)
+		oldest.w.Close()
+	}
+
+	f := &tenantFile{
+		id: id,
+		w:  newDailyWriter(t.lpath, filepath.Join(tenantDir, tenantName(id)), t.rotate),
+	}
+	t.files[id] = t.lru.PushFront(f)
+	return f.w.Write(p)
+}
+
+// name returns the path of the file of id
+func (t *tenantFiles) name(id string) string {
+	return newDailyWriter(t.lpath, filepath.Join(tenantDir, tenantName(id)), t.rotate).Name()
+}
+
+// Rotate rotates the open tenant files
+func (t *tenantFiles) Rotate() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var err error
+	for el := t.lru.Front(); el != nil; el = el.Next() {
+		err = multierr.Append(err, el.Value.(*tenantFile).w.Rotate())
+	}
+	return err
+}
+
+// Close closes the tenant files, the entries of the tenants fail after it
+func (t *tenantFiles) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var err error
+	for el := t.lru.Front(); el != nil; el = el.Next() {
+		err = multierr.Append(err, el.Value.(*tenantFile).w.Close())
+	}
+	t.lru.Init()
+	t.files = make(map[string]*list.Element)
+	t.closed = true
+	return err
+}
+
+// tenantWriter the output of the file of a tenant
+type tenantWriter struct {
+	files *tenantFiles
+	id    string
+}
+
+func (w tenantWriter) Write(p []byte) (int, error) {
+	return w.files.write(w.id, p)
+}
+
+func (w tenantWriter) Sync() error {
+	return nil
+}
+
+func (w tenantWriter) Name() string {
+	return w.files.name(w.id)
+}
+
+// hook returns the option routing the entries of the tenant loggers
+// of enab to their files with enc
+func (t *tenantFiles) hook(enc zapcore.Encoder, enab zapcore.LevelEnabler) zap.Option {
+	return zap.WrapCore(func(c zapcore.Core) zapcore.Core {
+		return &tenantCore{Core: c, files: t, enc: enc, enab: enab}
+	})
+}
+
+// tenantCore adds the core of the tenant file once a tenant field
+// is added by With, the fields of the previous With are kept for it
+type tenantCore struct {
+	zapcore.Core
+	files *tenantFiles
+	enc   zapcore.Encoder
+	enab  zapcore.LevelEnabler
+
+	fields []zapcore.Field
+	// file the core of the tenant file, nil without a tenant
+	file zapcore.Core
+}
+
+func (c *tenantCore) With(fields []zapcore.Field) zapcore.Core {
+	next := *c
+	next.Core = c.Core.With(fields)
+	next.fields = append(c.fields[:len(c.fields):len(c.fields)], fields...)
+
+	if id, ok := tenantID(fields); ok {
+		// redacted and truncated like the other outputs
+		file := rewriteCore{zapcore.NewCore(c.enc, hookWriter{tenantWriter{c.files, id}}, c.enab), c.files.st}
+		next.file = file.With(next.fields)
+	} else if c.file != nil {
+		next.file = c.file.With(fields)
+	}
+	return &next
+}
+
+// Check adds the tenant file if the entry isn't dropped by the
+// cores of the logger, so the filters and the sampling apply to it
+func (c *tenantCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	checked := c.Core.Check(ent, ce)
+	if c.file == nil || checked == nil {
+		return checked
+	}
+	return c.file.Check(ent, checked)
+}
+
+func (c *tenantCore) Sync() error {
+	err := c.Core.Sync()
+	if c.file != nil {
+		err = multierr.Append(err, c.file.Sync())
+	}
+	return err
+}
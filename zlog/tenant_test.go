@@ -0,0 +1,114 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/vcaesar/tt"
+)
+
+func TestForTenant(t *testing.T) {
+	dir := t.TempDir()
+	tt.Nil(t, InitWithConfig(Config{Path: dir, Name: "test", TenantFiles: true}))
+
+	var wg sync.WaitGroup
+	for _, id := range []string{"acme", "globex"} {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			log := With(Str("req", "r-"+id)).ForTenant(id)
+			for i := 0; i < 100; i++ {
+				log.Info("tenant info", fmt.Sprint(i))
+			}
+			log.Error("tenant error", errors.New(id))
+		}(id)
+	}
+	wg.Wait()
+	Info("no tenant")
+	tt.Nil(t, Close())
+
+	for _, id := range []string{"acme", "globex"} {
+		entries := readEntries(t, filepath.Join(dir, "*", tenantDir, id+".json"))
+		tt.Equal(t, 101, len(entries))
+		for _, entry := range entries {
+			tt.Equal(t, id, entry["tenant"])
+			tt.Equal(t, "r-"+id, entry["req"])
+		}
+		tt.Equal(t, id, msgEntries(entries, "tenant error")[0]["error"])
+	}
+
+	// the main file has all the entries
+	entries := readEntries(t, filepath.Join(dir, "*", "test.json"))
+	tt.Equal(t, 200, len(msgEntries(entries, "tenant info")))
+	tt.Equal(t, 1, len(msgEntries(entries, "no tenant")))
+
+	// the tenant field without the files
+	logs, _ := observeSplit(t)
+	ForTenant("acme").Info("observed")
+	tt.Equal(t, "acme", logs.All()[0].ContextMap()["tenant"])
+}
+
+func TestTenantLRU(t *testing.T) {
+	dir := t.TempDir()
+	files := (&Config{Path: dir, MaxTenantFiles: 2}).newTenantFiles(defaultState)
+	defer files.Close()
+
+	for _, id := range []string{"a", "b", "c"} {
+		_, err := tenantWriter{files, id}.Write([]byte(`{"msg":"` + id + `"}` + "\n"))
+		tt.Nil(t, err)
+	}
+	tt.Equal(t, 2, files.lru.Len())
+	_, ok := files.files["a"]
+	tt.False(t, ok)
+
+	// a is opened again and b is closed
+	_, err := tenantWriter{files, "a"}.Write([]byte(`{"msg":"a"}` + "\n"))
+	tt.Nil(t, err)
+	_, ok = files.files["b"]
+	tt.False(t, ok)
+	tt.Equal(t, 2, files.lru.Len())
+
+	entries := readEntries(t, filepath.Join(dir, "*", tenantDir, "a.json"))
+	tt.Equal(t, 2, len(entries))
+
+	tt.Nil(t, files.Close())
+	_, err = tenantWriter{files, "a"}.Write([]byte("{}\n"))
+	tt.Equal(t, errTenantsClosed, err)
+}
+
+func TestTenantName(t *testing.T) {
+	tt.Equal(t, "acme-1.json", tenantName("acme-1"))
+	tt.Equal(t, ".._.._etc.json", tenantName("../../etc"))
+	tt.Equal(t, "_...json", tenantName(".."))
+	tt.Equal(t, "_.json", tenantName(""))
+}
+
+func TestTenantRetention(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	old := filepath.Join(dir, now.AddDate(0, 0, -40).Format(DayFormat), tenantDir)
+	tt.Nil(t, os.MkdirAll(old, 0755))
+	tt.Nil(t, ioutil.WriteFile(filepath.Join(old, "acme.json"), []byte("{}\n"), 0644))
+
+	removed, err := deleteOldLog(dir, 28, now)
+	tt.Nil(t, err)
+	tt.Equal(t, 1, removed)
+	_, err = os.Stat(filepath.Dir(old))
+	tt.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,127 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// slowThreshold returns the SlowThreshold, 0 without it
+func (c *Config) slowThreshold() (time.Duration, error) {
+	return durationConf("slow_threshold", c.SlowThreshold, 0)
+}
+
+// loadSlowThreshold returns the SlowThreshold of the running config
+func (st *state) loadSlowThreshold() time.Duration {
+	return time.Duration(atomic.LoadInt64(&st.slowNanos))
+}
+
+func (st *state) setSlowThreshold(d time.Duration) {
+	atomic.StoreInt64(&st.slowNanos, int64(d))
+}
+
+// TimeTrack info log of the time since start with the duration
+// field, at warn above the SlowThreshold.
+//
+//	defer zlog.TimeTrack(time.Now(), "rebuild index")
+func TimeTrack(start time.Time, name string, fields ...zap.Field) {
+	d := time.Since(start)
+	if ce := getLogger().Check(slowLevel(d, defaultState.loadSlowThreshold()), name); ce != nil {
+		writeDuration(ce, d, nil, fields)
+	}
+}
+
+// TimeTrackSlow is TimeTrack logging at warn above slow
+// instead of the SlowThreshold
+func TimeTrackSlow(start time.Time, name string, slow time.Duration,
+	fields ...zap.Field) {
+	d := time.Since(start)
+	if ce := getLogger().Check(slowLevel(d, slow), name); ce != nil {
+		writeDuration(ce, d, nil, fields)
+	}
+}
+
+// Timed runs fn and logs its duration like TimeTrack, the error of
+// fn is logged to the error log with the duration and returned.
+//
+//	err := zlog.Timed("migrate", db.Migrate)
+func Timed(name string, fn func() error) error {
+	start := time.Now()
+	err := fn()
+	d := time.Since(start)
+
+	if err != nil {
+		if ce := getErrLogger().Check(zap.ErrorLevel, name); ce != nil {
+			writeDuration(ce, d, err, nil)
+		}
+		return err
+	}
+
+	if ce := getLogger().Check(slowLevel(d, defaultState.loadSlowThreshold()), name); ce != nil {
+		writeDuration(ce, d, nil, nil)
+	}
+	return nil
+}
+
+// TimeTrack logs the time since start like TimeTrack, at warn above
+// the SlowThreshold of the logger of New
+func (z *Zlog) TimeTrack(start time.Time, name string, fields ...zap.Field) {
+	d := time.Since(start)
+	logger, _ := z.get()
+	if ce := logger.Check(slowLevel(d, z.state().loadSlowThreshold()), name); ce != nil {
+		writeDuration(ce, d, nil, fields)
+	}
+}
+
+// Timed runs fn and logs its duration like Timed, with the
+// SlowThreshold of the logger of New
+func (z *Zlog) Timed(name string, fn func() error) error {
+	start := time.Now()
+	err := fn()
+	d := time.Since(start)
+
+	logger, errLogger := z.get()
+	if err != nil {
+		if ce := errLogger.Check(zap.ErrorLevel, name); ce != nil {
+			writeDuration(ce, d, err, nil)
+		}
+		return err
+	}
+
+	if ce := logger.Check(slowLevel(d, z.state().loadSlowThreshold()), name); ce != nil {
+		writeDuration(ce, d, nil, nil)
+	}
+	return nil
+}
+
+// slowLevel returns warn for a duration above slow, or info
+func slowLevel(d, slow time.Duration) zapcore.Level {
+	if slow > 0 && d > slow {
+		return zap.WarnLevel
+	}
+	return zap.InfoLevel
+}
+
+// writeDuration writes ce with the duration and the error in
+// front of the fields in a pooled slice
+func writeDuration(ce *zapcore.CheckedEntry, d time.Duration, err error,
+	fields []zap.Field) {
+	p := fieldSlices.Get().(*[]zap.Field)
+	fs := append((*p)[:0], zap.Duration("duration", d))
+	if err != nil {
+		fs = append(fs, zap.Error(err))
+	}
+	putFields(p, ce, append(fs, fields...))
+}
@@ -0,0 +1,96 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/vcaesar/tt"
+	"go.uber.org/zap"
+)
+
+func TestTimeTrack(t *testing.T) {
+	logs, _ := observeSplit(t)
+	t.Cleanup(func() { defaultState.setSlowThreshold(0) })
+
+	// no threshold is never slow
+	TimeTrack(time.Now().Add(-time.Hour), "no threshold")
+	entry := logs.FilterMessage("no threshold").All()[0]
+	tt.Equal(t, zap.InfoLevel, entry.Level)
+	tt.True(t, entry.ContextMap()["duration"].(time.Duration) >= time.Hour)
+
+	defaultState.setSlowThreshold(time.Second)
+	TimeTrack(time.Now(), "fast", Str("index", "i1"))
+	TimeTrack(time.Now().Add(-2*time.Second), "slow")
+	TimeTrackSlow(time.Now().Add(-2*time.Second), "slow call", time.Minute)
+
+	entry = logs.FilterMessage("fast").All()[0]
+	tt.Equal(t, zap.InfoLevel, entry.Level)
+	tt.Equal(t, "i1", entry.ContextMap()["index"])
+	tt.Equal(t, zap.WarnLevel, logs.FilterMessage("slow").All()[0].Level)
+	tt.Equal(t, zap.InfoLevel, logs.FilterMessage("slow call").All()[0].Level)
+}
+
+func TestTimed(t *testing.T) {
+	logs, errLogs := observeSplit(t)
+
+	ran := false
+	tt.Nil(t, Timed("timed ok", func() error {
+		ran = true
+		return nil
+	}))
+	tt.True(t, ran)
+	tt.Equal(t, 1, logs.FilterMessage("timed ok").Len())
+
+	e1 := errors.New("e1")
+	tt.Equal(t, e1, Timed("timed error", func() error { return e1 }))
+	tt.Equal(t, 0, logs.FilterMessage("timed error").Len())
+
+	entry := errLogs.FilterMessage("timed error").All()[0]
+	tt.Equal(t, zap.ErrorLevel, entry.Level)
+	tt.Equal(t, "e1", entry.ContextMap()["error"])
+	_, ok := entry.ContextMap()["duration"]
+	tt.True(t, ok)
+}
+
+func TestSlowThreshold(t *testing.T) {
+	t.Cleanup(func() { defaultState.setSlowThreshold(0) })
+	tt.NotNil(t, InitWithConfig(Config{Path: t.TempDir(), SlowThreshold: "slow"}))
+
+	dir := initTest(t, `slow_threshold = "10ms"`)
+	tt.Equal(t, 10*time.Millisecond, defaultState.loadSlowThreshold())
+	TimeTrack(time.Now().Add(-time.Second), "slow track")
+	tt.Nil(t, Close())
+
+	entries := msgEntries(readEntries(t, filepath.Join(dir, "*", "test.json")), "slow track")
+	tt.Equal(t, 1, len(entries))
+	tt.Equal(t, "warn", entries[0]["level"])
+}
+
+func TestNewTimeTrack(t *testing.T) {
+	dir := t.TempDir()
+	z, err := New(Config{Path: dir, Name: "inst", SlowThreshold: "10ms"})
+	tt.Nil(t, err)
+
+	z.TimeTrack(time.Now().Add(-time.Second), "slow track")
+	tt.Nil(t, z.Timed("timed fast", func() error { return nil }))
+	tt.Equal(t, time.Duration(0), defaultState.loadSlowThreshold())
+	tt.Nil(t, z.Close())
+
+	entries := readEntries(t, filepath.Join(dir, "*", "inst.json"))
+	slow := msgEntries(entries, "slow track")
+	tt.Equal(t, 1, len(slow))
+	tt.Equal(t, "warn", slow[0]["level"])
+	tt.Equal(t, "info", msgEntries(entries, "timed fast")[0]["level"])
+}
@@ -0,0 +1,91 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"fmt"
+	"sync/atomic"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// maxFieldLen returns the MaxFieldLen of the running config
+func (st *state) maxFieldLen() int {
+	return int(atomic.LoadInt64(&st.fieldLen))
+}
+
+func (st *state) setMaxFieldLen(max int) {
+	atomic.StoreInt64(&st.fieldLen, int64(max))
+}
+
+// checkFieldLen returns the error of a negative MaxFieldLen
+func (c *Config) checkFieldLen() error {
+	if c.MaxFieldLen < 0 {
+		return fmt.Errorf("zlog: invalid max_field_len %d", c.MaxFieldLen)
+	}
+	return nil
+}
+
+// truncate cuts s to at most max bytes without splitting a rune
+// and appends the number of the removed bytes
+func truncate(s string, max int) (string, bool) {
+	if len(s) <= max {
+		return s, false
+	}
+
+	cut := max
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return fmt.Sprintf("%s...(truncated %d bytes)", s[:cut], len(s)-cut), true
+}
+
+// truncateEntry truncates the message and the string fields, the
+// field truncated: true is added if any of them is truncated
+func truncateEntry(msg string, fields []zapcore.Field,
+	max int) (string, []zapcore.Field) {
+	msg, msgCut := truncate(msg, max)
+	fields, fieldCut := truncateFields(fields, max)
+
+	if msgCut || fieldCut {
+		fields = append(fields[:len(fields):len(fields)], zap.Bool("truncated", true))
+	}
+	return msg, fields
+}
+
+// truncateFields truncates the string and the byte string fields,
+// the errors keep their type and their verbose fields
+func truncateFields(fields []zapcore.Field, max int) ([]zapcore.Field, bool) {
+	var cut bool
+	fields = mapFields(fields, func(f zapcore.Field) (zapcore.Field, bool) {
+		var s string
+		switch f.Type {
+		case zapcore.StringType:
+			s = f.String
+		case zapcore.ByteStringType:
+			if b := f.Interface.([]byte); len(b) > max {
+				s = string(b)
+			}
+		default:
+			return f, false
+		}
+
+		s, ok := truncate(s, max)
+		if ok {
+			cut = true
+			return zap.String(f.Key, s), true
+		}
+		return f, false
+	})
+	return fields, cut
+}
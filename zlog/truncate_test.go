@@ -0,0 +1,97 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/vcaesar/tt"
+	"go.uber.org/zap"
+)
+
+func TestTruncate(t *testing.T) {
+	for _, c := range []struct {
+		s    string
+		max  int
+		want string
+	}{
+		{"hello", 5, "hello"},
+		{"hello", 4, "hell...(truncated 1 bytes)"},
+		// é is 2 bytes from the byte 1
+		{"héllo", 3, "hé...(truncated 3 bytes)"},
+		{"héllo", 2, "h...(truncated 5 bytes)"},
+		{"日本語", 4, "日...(truncated 6 bytes)"},
+		{"日本語", 6, "日本...(truncated 3 bytes)"},
+		{"日本語", 2, "...(truncated 9 bytes)"},
+		{"a🙂b", 4, "a...(truncated 5 bytes)"},
+		{"a🙂b", 5, "a🙂...(truncated 1 bytes)"},
+	} {
+		got, cut := truncate(c.s, c.max)
+		tt.Equal(t, c.want, got)
+		tt.Equal(t, c.want != c.s, cut)
+		tt.True(t, utf8.ValidString(got))
+	}
+}
+
+func TestTruncateEntry(t *testing.T) {
+	logger, buf := rewriteLogger(t)
+	defaultState.setMaxFieldLen(8)
+	t.Cleanup(func() { defaultState.setMaxFieldLen(0) })
+
+	long := strings.Repeat("é", 10)
+	fields := []zap.Field{
+		zap.String("body", long),
+		zap.ByteString("raw", []byte("0123456789")),
+		zap.String("short", "ok"),
+		zap.Error(errors.New("a long error message")),
+	}
+	logger.Info("a long message", fields...)
+	tt.Equal(t, long, fields[0].String)
+	tt.Equal(t, 4, len(fields))
+
+	entry := decodeLine(t, buf)
+	tt.Equal(t, "a long m...(truncated 6 bytes)", entry["msg"])
+	tt.Equal(t, "éééé...(truncated 12 bytes)", entry["body"])
+	tt.Equal(t, "01234567...(truncated 2 bytes)", entry["raw"])
+	tt.Equal(t, "ok", entry["short"])
+	tt.Equal(t, "a long error message", entry["error"])
+	tt.Equal(t, true, entry["truncated"])
+
+	logger.Info("short", zap.String("short", "ok"))
+	entry = decodeLine(t, buf)
+	tt.Equal(t, "short", entry["msg"])
+	_, ok := entry["truncated"]
+	tt.False(t, ok)
+
+	// the fields of With are truncated without the flag
+	logger.With(zap.String("ctx", long)).Info("with")
+	entry = decodeLine(t, buf)
+	tt.Equal(t, "éééé...(truncated 12 bytes)", entry["ctx"])
+}
+
+func TestMaxFieldLen(t *testing.T) {
+	t.Cleanup(func() { defaultState.setMaxFieldLen(0) })
+	tt.NotNil(t, InitWithConfig(Config{Path: t.TempDir(), MaxFieldLen: -1}))
+	tt.Equal(t, 0, defaultState.maxFieldLen())
+
+	dir := initTest(t, "max_field_len = 16")
+	Info("truncate", strings.Repeat("x", 1<<10))
+	tt.Nil(t, Close())
+
+	entries := msgEntries(readEntries(t, filepath.Join(dir, "*", "test.json")), "truncate")
+	tt.Equal(t, 1, len(entries))
+	tt.Equal(t, strings.Repeat("x", 16)+"...(truncated 1008 bytes)", entries[0]["info"])
+	tt.Equal(t, true, entries[0]["truncated"])
+}
@@ -0,0 +1,251 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"go.uber.org/zap"
+)
+
+var (
+	// reloadDelay waits for the writes of the config to settle,
+	// a truncated file would decode to the default config
+	reloadDelay = 100 * time.Millisecond
+	// pollInterval the interval of the mtime polling used
+	// when the config file can't be watched
+	pollInterval = time.Second
+)
+
+// Watch watches the config file tpath and applies its changes, the
+// level, the redaction, the truncation and the old log sweep change
+// in place, the other changes reopen the log files with InitWithConfig.
+// A config which can't be read or applied is logged by the error
+// logger and the previous config is kept. It falls back to polling
+// the mtime of the file if the file can't be watched, stop stops the
+// watching.
+//
+//	stop, err := zlog.Watch("zlog.toml")
+//	defer stop()
+func Watch(tpath string) (stop func(), err error) {
+	fi, err := os.Stat(tpath)
+	if err != nil {
+		return nil, err
+	}
+
+	done, stopped := make(chan struct{}), make(chan struct{})
+	var once sync.Once
+	stop = func() {
+		once.Do(func() {
+			close(done)
+			<-stopped
+		})
+	}
+
+	// the dir is watched so the renames of the editors are seen
+	watcher, err := fsnotify.NewWatcher()
+	if err == nil {
+		if err = watcher.Add(filepath.Dir(tpath)); err != nil {
+			watcher.Close()
+		}
+	}
+
+	if err != nil {
+		go pollConf(tpath, fi.ModTime(), done, stopped)
+		return stop, nil
+	}
+
+	go watchConf(watcher, tpath, done, stopped)
+	return stop, nil
+}
+
+func watchConf(watcher *fsnotify.Watcher, tpath string, done, stopped chan struct{}) {
+	defer close(stopped)
+	defer watcher.Close()
+
+	name := filepath.Clean(tpath)
+	timer := time.NewTimer(reloadDelay)
+	timer.Stop()
+	defer timer.Stop()
+
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+
+			if filepath.Clean(event.Name) != name ||
+				event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) == 0 {
+				continue
+			}
+			timer.Reset(reloadDelay)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			reloadError(tpath, err)
+		case <-timer.C:
+			reload(tpath)
+		case <-done:
+			return
+		}
+	}
+}
+
+func pollConf(tpath string, mtime time.Time, done, stopped chan struct{}) {
+	defer close(stopped)
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			fi, err := os.Stat(tpath)
+			if err != nil || fi.ModTime().Equal(mtime) {
+				continue
+			}
+
+			mtime = fi.ModTime()
+			reload(tpath)
+		case <-done:
+			return
+		}
+	}
+}
+
+// reload decodes the config file and applies it
+func reload(tpath string) {
+	cfg, unknown, err := loadConfig(tpath)
+	if err == nil {
+		err = checkUnknown(cfg, unknown)
+	}
+	if err != nil {
+		reloadError(tpath, err)
+		return
+	}
+
+	if err := apply(cfg); err != nil {
+		reloadError(tpath, err)
+		return
+	}
+	warnUnknown(unknown)
+}
+
+func reloadError(tpath string, err error) {
+	getErrLogger().Error("zlog: reload config error",
+		zap.String("path", tpath), zap.Error(err))
+}
+
+// apply applies the delta of cfg to the running config, the
+// previous config is kept if it returns an error.
+func apply(cfg Config) (err error) {
+	configLock.Lock()
+	defer configLock.Unlock()
+
+	config := &cfg
+	prev := *getConfig()
+	rotate, keep := prev.rotateConf(), prev.retentionConf()
+	if reflect.DeepEqual(cfg, prev) {
+		return nil
+	}
+
+	inPlace := prev
+	inPlace.Level, inPlace.CleanInterval = cfg.Level, cfg.CleanInterval
+	inPlace.MaxDays, inPlace.MaxTotalSizeMB = cfg.MaxDays, cfg.MaxTotalSizeMB
+	inPlace.ArchiveOldDays, inPlace.CompressAfterDays = cfg.ArchiveOldDays, cfg.CompressAfterDays
+	inPlace.Redact, inPlace.MaxFieldLen = cfg.Redact, cfg.MaxFieldLen
+	inPlace.StrictConfig, inPlace.SlowThreshold = cfg.StrictConfig, cfg.SlowThreshold
+	inPlace.Levels, inPlace.Filters = cfg.Levels, cfg.Filters
+
+	if prev.dailyDirs() == (cfg.DailyDirs == nil || *cfg.DailyDirs) {
+		// the same layout from another pointer
+		inPlace.DailyDirs = cfg.DailyDirs
+	}
+
+	if err := config.validate(); err != nil {
+		return err
+	}
+
+	// MaxDays is also the max age of the rotated files by default
+	if !reflect.DeepEqual(inPlace, cfg) || config.rotateConf() != rotate {
+		return initWithConfig(cfg)
+	}
+
+	setConfig(cfg)
+	defer func() {
+		if err != nil {
+			setConfig(prev)
+		}
+	}()
+
+	lvl, err := config.logLevel()
+	if err != nil {
+		return err
+	}
+
+	interval, err := config.cleanInterval()
+	if err != nil {
+		return err
+	}
+
+	if cfg.Level != prev.Level {
+		if cfg.Mode == "dev" && cfg.Level == "" {
+			lvl = zap.DebugLevel
+		}
+		SetLevel(lvl)
+	}
+
+	if err := config.checkFieldLen(); err != nil {
+		return err
+	}
+	defaultState.setMaxFieldLen(cfg.MaxFieldLen)
+
+	slow, err := cfg.slowThreshold()
+	if err != nil {
+		return err
+	}
+	defaultState.setSlowThreshold(slow)
+
+	if !reflect.DeepEqual(cfg.Levels, prev.Levels) {
+		levels, err := cfg.levelsConf()
+		if err != nil {
+			return err
+		}
+		defaultState.setOverrides(levels)
+	}
+
+	if !reflect.DeepEqual(cfg.Filters, prev.Filters) {
+		f, err := cfg.filtersConf()
+		if err != nil {
+			return err
+		}
+		defaultState.setFilters(f)
+	}
+
+	// an empty Redact keeps the keys of SetRedactedKeys
+	if len(cfg.Redact) > 0 && !reflect.DeepEqual(cfg.Redact, prev.Redact) {
+		defaultState.setRedactedKeys(cfg.Redact...)
+	}
+
+	if (config.retentionConf() != keep || cfg.CleanInterval != prev.CleanInterval) && cleaning() {
+		fileDir, _ := config.confPath()
+		startCleaner(fileDir, config.retentionConf(), interval)
+	}
+
+	return nil
+}
@@ -0,0 +1,203 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vcaesar/tt"
+	"go.uber.org/zap"
+)
+
+// waitFor polls cond until it is true or the timeout
+func waitFor(cond func() bool) bool {
+	for i := 0; i < 500; i++ {
+		if cond() {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return false
+}
+
+func rewrite(t *testing.T, tpath, content string) {
+	if err := ioutil.WriteFile(tpath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	base := "path = \"" + filepath.ToSlash(dir) + "\"\nname = \"test\"\n"
+	tpath := writeConf(t, base+"level = \"info\"\n")
+	tt.Nil(t, Init(tpath))
+	defer Close()
+
+	stop, err := Watch(tpath)
+	tt.Nil(t, err)
+	defer stop()
+
+	logger := L()
+	rewrite(t, tpath, base+"level = \"debug\"\n")
+	tt.True(t, waitFor(func() bool { return GetLevel() == zap.DebugLevel }))
+	// the level changed in place
+	tt.True(t, logger == L())
+
+	// the bad config is logged and the old one is kept
+	rewrite(t, tpath, base+"level = \"bogus\"\n")
+	tt.True(t, waitFor(func() bool {
+		entries := readEntries(t, filepath.Join(dir, "*", "test_err.json"))
+		return len(msgEntries(entries, "zlog: reload config error")) == 1
+	}))
+	tt.Equal(t, zap.DebugLevel, GetLevel())
+
+	// a new path opens the new files
+	newDir := t.TempDir()
+	rewrite(t, tpath, "path = \""+filepath.ToSlash(newDir)+"\"\nname = \"test\"\n")
+	tt.True(t, waitFor(func() bool { return logger != L() }))
+	tt.Equal(t, zap.InfoLevel, GetLevel())
+
+	Info("watch new path")
+	tt.Nil(t, Sync())
+	entries := readEntries(t, filepath.Join(newDir, "*", "test.json"))
+	tt.Equal(t, 1, len(msgEntries(entries, "watch new path")))
+}
+
+func TestWatchPoll(t *testing.T) {
+	prev := pollInterval
+	pollInterval = 10 * time.Millisecond
+	defer func() { pollInterval = prev }()
+
+	base := "mode = \"discard\"\n"
+	tpath := writeConf(t, base)
+	tt.Nil(t, Init(tpath))
+	defer Close()
+
+	done, stopped := make(chan struct{}), make(chan struct{})
+	go pollConf(tpath, time.Time{}, done, stopped)
+	defer func() {
+		close(done)
+		<-stopped
+	}()
+
+	rewrite(t, tpath, base+"level = \"warn\"\n")
+	tt.True(t, waitFor(func() bool { return GetLevel() == zap.WarnLevel }))
+}
+
+func TestApplyRetention(t *testing.T) {
+	initTest(t)
+	defer Close()
+
+	cfg := *getConfig()
+	cfg.MaxAge = 28
+	tt.Nil(t, apply(cfg))
+
+	// the max age of the rotated files is set, MaxDays is swept only
+	cfg.MaxDays, cfg.CleanInterval = 7, "1h"
+	logger := L()
+	tt.Nil(t, apply(cfg))
+	tt.True(t, logger == L())
+	tt.True(t, cleaning())
+	tt.Equal(t, int64(7), getConfig().maxDays())
+
+	// the same layout from the decoded pointer
+	daily := true
+	cfg.DailyDirs = &daily
+	tt.Nil(t, apply(cfg))
+	tt.True(t, logger == L())
+
+	cfg.CleanInterval = "-1h"
+	tt.NotNil(t, apply(cfg))
+	tt.Equal(t, "1h", getConfig().CleanInterval)
+}
+
+func TestApplyRedact(t *testing.T) {
+	initTest(t)
+	defer Close()
+	t.Cleanup(func() { SetRedactedKeys() })
+
+	cfg, logger := *getConfig(), L()
+	cfg.Redact = []string{"token"}
+	tt.Nil(t, apply(cfg))
+	tt.True(t, logger == L())
+	tt.True(t, defaultState.loadRedaction().match("Token"))
+
+	// the keys are kept without Redact
+	cfg.Redact = nil
+	tt.Nil(t, apply(cfg))
+	tt.True(t, logger == L())
+	tt.True(t, defaultState.loadRedaction().match("Token"))
+}
+
+func TestWatchRedact(t *testing.T) {
+	dir := t.TempDir()
+	base := "path = \"" + filepath.ToSlash(dir) + "\"\nname = \"test\"\n"
+	tpath := writeConf(t, base+"redact = [\"token\"]\n")
+	tt.Nil(t, Init(tpath))
+	defer Close()
+	t.Cleanup(func() { SetRedactedKeys() })
+	SetRedactedKeys("secret")
+
+	stop, err := Watch(tpath)
+	tt.Nil(t, err)
+	defer stop()
+
+	// the reload without redact keeps the keys of SetRedactedKeys
+	rewrite(t, tpath, base+"level = \"debug\"\n")
+	tt.True(t, waitFor(func() bool { return GetLevel() == zap.DebugLevel }))
+	InfoF("watch redact", zap.String("secret", "s1"))
+	tt.Nil(t, Sync())
+
+	entries := msgEntries(readEntries(t, filepath.Join(dir, "*", "test.json")), "watch redact")
+	tt.Equal(t, 1, len(entries))
+	tt.Equal(t, Redacted, entries[0]["secret"])
+}
+
+func TestWatchMissing(t *testing.T) {
+	_, err := Watch(filepath.Join(t.TempDir(), "not_exist.toml"))
+	tt.NotNil(t, err)
+}
+
+func TestApplyRace(t *testing.T) {
+	initTest(t)
+	defer Close()
+
+	cfg := *getConfig()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 50; i++ {
+			// in place, then with the files reopened
+			cfg.MaxDays = int64(i%7 + 1)
+			tt.Nil(t, apply(cfg))
+			cfg.MaxSize = i%3 + 1
+			tt.Nil(t, apply(cfg))
+		}
+	}()
+
+	hammer(20, func() {
+		tt.NotEqual(t, "", EffectiveConfig().Path)
+		iter, err := Query(QueryOptions{MinLevel: "error"})
+		tt.Nil(t, err)
+		iter.Close()
+		tt.Nil(t, Pretty(strings.NewReader(`{"msg":"race"}`), ioutil.Discard, PrettyOptions{}))
+		h, err := AddWriter(ioutil.Discard, zap.InfoLevel, "json")
+		tt.Nil(t, err)
+		h.Remove()
+		SetGlobalFields(Str("race", "1"))
+	})
+	<-done
+	SetGlobalFields()
+}
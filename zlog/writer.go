@@ -0,0 +1,273 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+const (
+	// DayFormat the name format of the daily log directory
+	DayFormat = "2006-01-02"
+
+	defaultDirMode os.FileMode = 0755
+)
+
+// rotateConfig the lumberjack rotation of the log files
+type rotateConfig struct {
+	maxSize    int // megabytes
+	maxBackups int
+	maxAge     int // days
+	compress   bool
+	every      time.Duration // 0 rotates by the day only
+	flat       bool          // writes lpath/name without the daily dirs
+	dirMode    os.FileMode   // 0 is 0755
+	fileMode   os.FileMode   // 0 is the mode of lumberjack
+}
+
+// dailyWriter writes to lpath/<date>/name, it switches to the
+// directory of the new day when the date changes, lumberjack
+// still rotates the file by size inside each day, with every
+// it writes to name-<period>.json and switches on each period,
+// the flat writer writes to lpath/name.
+type dailyWriter struct {
+	mu sync.Mutex
+
+	lpath, name string
+	rotate      rotateConfig
+	now         func() time.Time
+	// held leaves the switch to the file of a new period to the
+	// layer buffering the writes, it calls switchTo before writing
+	// the first entry of the period
+	held bool
+
+	file string
+	lj   *lumberjack.Logger
+}
+
+func newDailyWriter(lpath, name string, rotate rotateConfig) *dailyWriter {
+	return &dailyWriter{
+		lpath:  lpath,
+		name:   name,
+		rotate: rotate,
+		now:    time.Now,
+	}
+}
+
+// Write writes an entry into the file of the current day,
+// the whole entry is written under the lock so a rollover
+// never splits or interleaves lines.
+func (w *dailyWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if file := w.filename(w.now()); w.lj == nil || !w.held && file != w.file {
+		if err := w.rollover(file); err != nil {
+			return 0, err
+		}
+	}
+
+	return w.lj.Write(p)
+}
+
+// nowFile returns the file of the entries written now
+func (w *dailyWriter) nowFile() string {
+	return w.filename(w.now())
+}
+
+// switchTo switches to file if it isn't the current one, the held
+// writer is switched by the buffering layer once its entries of the
+// previous period are written.
+func (w *dailyWriter) switchTo(file string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if file == w.file {
+		return nil
+	}
+	return w.rollover(file)
+}
+
+// open creates the dir and the file of now,
+// so Init returns the error of a bad path.
+func (w *dailyWriter) open() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.rollover(w.filename(w.now())); err != nil {
+		return err
+	}
+
+	// lumberjack opens the file on the first write
+	_, err := w.lj.Write(nil)
+	return err
+}
+
+// filename returns the file of tm, all the log file paths
+// are built here
+func (w *dailyWriter) filename(tm time.Time) string {
+	name := w.name
+	if every := w.rotate.every; every > 0 {
+		ext := filepath.Ext(name)
+		name = strings.TrimSuffix(name, ext) + "-" +
+			periodStart(tm, every).Format(periodFormat(every)) + ext
+	}
+
+	if w.rotate.flat {
+		return filepath.Join(w.lpath, name)
+	}
+	return filepath.Join(w.lpath, tm.Format(DayFormat), name)
+}
+
+// periodStart returns the start of the period of tm,
+// the periods start from the local midnight
+func periodStart(tm time.Time, every time.Duration) time.Time {
+	day := time.Date(tm.Year(), tm.Month(), tm.Day(), 0, 0, 0, 0, tm.Location())
+	return day.Add(tm.Sub(day) / every * every)
+}
+
+// periodFormat returns the name format of the periods of every
+func periodFormat(every time.Duration) string {
+	switch {
+	case every%(24*time.Hour) == 0:
+		return DayFormat
+	case every%time.Hour == 0:
+		return "2006-01-02T15"
+	}
+	return "2006-01-02T15-04"
+}
+
+// rollover creates the dir of file and switches to it, the
+// previous file is kept if the dir can't be created.
+func (w *dailyWriter) rollover(file string) error {
+	if err := w.mkdir(filepath.Dir(file)); err != nil {
+		return err
+	}
+
+	// lumberjack appends to the existing file and creates the
+	// rotated files with the mode of the old one
+	if mode := w.rotate.fileMode; mode != 0 {
+		if err := createFile(file, mode); err != nil {
+			return err
+		}
+	}
+
+	if w.lj != nil {
+		w.lj.Close()
+	}
+
+	w.file = file
+	w.lj = &lumberjack.Logger{
+		Filename:   file,
+		MaxSize:    w.rotate.maxSize,
+		MaxBackups: w.rotate.maxBackups,
+		MaxAge:     w.rotate.maxAge,
+		Compress:   w.rotate.compress,
+	}
+	return nil
+}
+
+// mkdir creates dir and its missing parents if it doesn't exist, the
+// new dirs are set to the dir mode regardless of the umask
+func (w *dailyWriter) mkdir(dir string) error {
+	return mkdirMode(dir, w.rotate.dirMode)
+}
+
+// mkdirMode creates dir with mode like mkdir, 0 is 0755
+func mkdirMode(dir string, mode os.FileMode) error {
+	if _, err := os.Stat(dir); err == nil {
+		return nil
+	}
+
+	if mode == 0 {
+		mode = defaultDirMode
+	}
+
+	// the dirs created by MkdirAll, dir first
+	var created []string
+	for d := filepath.Clean(dir); ; d = filepath.Dir(d) {
+		if _, err := os.Stat(d); err == nil {
+			break
+		}
+		created = append(created, d)
+		if filepath.Dir(d) == d {
+			break
+		}
+	}
+
+	if err := os.MkdirAll(dir, mode); err != nil {
+		return err
+	}
+	for _, d := range created {
+		if err := os.Chmod(d, mode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createFile creates file if it doesn't exist and sets its mode
+func createFile(file string, mode os.FileMode) error {
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_APPEND, mode)
+	if err != nil {
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Chmod(file, mode)
+}
+
+// Rotate closes the file of the current day and opens a new one,
+// lumberjack renames the old file to a backup if it still exists.
+func (w *dailyWriter) Rotate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.lj == nil {
+		return nil
+	}
+	return w.lj.Rotate()
+}
+
+// Name returns the path of the current file
+func (w *dailyWriter) Name() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == "" {
+		return w.filename(w.now())
+	}
+	return w.file
+}
+
+// Sync lumberjack writes to the file without buffer
+func (w *dailyWriter) Sync() error {
+	return nil
+}
+
+// Close close the file of the current day
+func (w *dailyWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.lj == nil {
+		return nil
+	}
+	return w.lj.Close()
+}
@@ -0,0 +1,209 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/vcaesar/tt"
+)
+
+// fakeClock a settable clock for the writers
+type fakeClock struct {
+	mu sync.Mutex
+	tm time.Time
+}
+
+func (c *fakeClock) now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.tm
+}
+
+func (c *fakeClock) set(tm time.Time) {
+	c.mu.Lock()
+	c.tm = tm
+	c.mu.Unlock()
+}
+
+func readLines(t *testing.T, name string) []string {
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+}
+
+func TestDailyWriterRollover(t *testing.T) {
+	dir := t.TempDir()
+	clock := &fakeClock{tm: time.Date(2018, 5, 1, 23, 59, 59, 0, time.Local)}
+
+	w := newDailyWriter(dir, "test.json", rotateConfig{maxSize: 500})
+	w.now = clock.now
+	defer w.Close()
+
+	w.Write([]byte("before midnight\n"))
+	clock.set(clock.now().Add(2 * time.Second))
+	w.Write([]byte("after midnight\n"))
+
+	tt.Equal(t, []string{"before midnight"},
+		readLines(t, filepath.Join(dir, "2018-05-01", "test.json")))
+	tt.Equal(t, []string{"after midnight"},
+		readLines(t, filepath.Join(dir, "2018-05-02", "test.json")))
+}
+
+func TestDailyWriterConcurrent(t *testing.T) {
+	dir := t.TempDir()
+	clock := &fakeClock{tm: time.Date(2018, 5, 1, 23, 59, 59, 0, time.Local)}
+
+	w := newDailyWriter(dir, "test.json", rotateConfig{maxSize: 500})
+	w.now = clock.now
+	defer w.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				w.Write([]byte(fmt.Sprintf("writer %d line %d\n", i, j)))
+			}
+		}(i)
+	}
+
+	clock.set(clock.now().Add(2 * time.Second))
+	wg.Wait()
+
+	files, err := filepath.Glob(filepath.Join(dir, "*", "test.json"))
+	tt.Nil(t, err)
+
+	var lines []string
+	for _, name := range files {
+		lines = append(lines, readLines(t, name)...)
+	}
+
+	count := 0
+	for _, line := range lines {
+		var i, j int
+		if line == "" {
+			continue
+		}
+		_, err := fmt.Sscanf(line, "writer %d line %d", &i, &j)
+		tt.Nil(t, err)
+		count++
+	}
+	tt.Equal(t, 1000, count)
+}
+
+func TestDailyWriterRotate(t *testing.T) {
+	dir := t.TempDir()
+	w := newDailyWriter(dir, "test.json", rotateConfig{maxSize: 1, maxBackups: 2})
+	defer w.Close()
+
+	line := []byte(strings.Repeat("x", 1023) + "\n")
+	for i := 0; i < 4*1024; i++ {
+		w.Write(line)
+	}
+
+	// lumberjack removes the old backups in the background
+	var backups []string
+	for i := 0; i < 100; i++ {
+		backups, _ = filepath.Glob(filepath.Join(dir, "*", "test-*.json"))
+		if len(backups) == 2 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	tt.Equal(t, 2, len(backups))
+}
+
+func TestDailyWriterEvery(t *testing.T) {
+	dir := t.TempDir()
+	clock := &fakeClock{tm: time.Date(2018, 5, 1, 13, 59, 59, 0, time.Local)}
+
+	w := newDailyWriter(dir, "test.json", rotateConfig{maxSize: 500, every: time.Hour})
+	w.now = clock.now
+	defer w.Close()
+
+	w.Write([]byte("before 14\n"))
+	clock.set(clock.now().Add(2 * time.Second))
+	w.Write([]byte("after 14\n"))
+	clock.set(clock.now().Add(10 * time.Minute))
+	w.Write([]byte("still 14\n"))
+
+	day := filepath.Join(dir, "2018-05-01")
+	tt.Equal(t, []string{"before 14"}, readLines(t, filepath.Join(day, "test-2018-05-01T13.json")))
+	tt.Equal(t, []string{"after 14", "still 14"},
+		readLines(t, filepath.Join(day, "test-2018-05-01T14.json")))
+}
+
+func TestPeriod(t *testing.T) {
+	tm := time.Date(2018, 5, 1, 14, 47, 10, 0, time.Local)
+
+	tests := []struct {
+		every time.Duration
+		name  string
+	}{
+		{time.Hour, "2018-05-01T14"},
+		{24 * time.Hour, "2018-05-01"},
+		{15 * time.Minute, "2018-05-01T14-45"},
+		{6 * time.Hour, "2018-05-01T12"},
+	}
+	for _, test := range tests {
+		tt.Equal(t, test.name, periodStart(tm, test.every).Format(periodFormat(test.every)))
+	}
+}
+
+func TestRotateEvery(t *testing.T) {
+	var config Config
+
+	tests := []struct {
+		every string
+		want  time.Duration
+		err   bool
+	}{
+		{"", 0, false},
+		{"hour", time.Hour, false},
+		{"day", 24 * time.Hour, false},
+		{"30m", 30 * time.Minute, false},
+		{"10s", 0, true},
+		{"48h", 0, true},
+		{"weekly", 0, true},
+	}
+	for _, test := range tests {
+		config = Config{RotateEvery: test.every}
+		every, err := config.rotateEvery()
+		tt.Equal(t, test.want, every, test.every)
+		tt.Equal(t, test.err, err != nil, test.every)
+	}
+
+	tt.NotNil(t, InitWithConfig(Config{Path: t.TempDir(), RotateEvery: "10s"}))
+}
+
+func TestRotateConf(t *testing.T) {
+	var config Config
+	tt.Equal(t, rotateConfig{maxSize: 500, maxBackups: 3, maxAge: 28, dirMode: 0755},
+		config.rotateConf())
+
+	config = Config{MaxDays: 7, MaxSize: 10, MaxBackups: 5, Compress: true, DirMode: "0700"}
+	tt.Equal(t, rotateConfig{maxSize: 10, maxBackups: 5, maxAge: 7, compress: true,
+		dirMode: 0700}, config.rotateConf())
+
+	config = Config{MaxAge: 3}
+	tt.Equal(t, int64(3), config.maxDays())
+}
@@ -0,0 +1,233 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"strings"
+
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+)
+
+// Zlog zlog struct, a logger carrying its own fields or built by New,
+// the zero value logs through the package loggers.
+type Zlog struct {
+	logger, errLogger *zap.Logger
+	// access the logger of the access file, nil without AccessFile
+	access *zap.Logger
+
+	// inst the instance of New, nil for the package loggers
+	inst *instance
+}
+
+// With returns a child logger which adds the fields to every entry
+// of the logger and the error logger, the child keeps the loggers
+// of the time it's created, so create it after Init.
+//
+//	log := zlog.With(zlog.Str("request_id", id))
+//	log.Info("handle request")
+func With(fields ...zap.Field) *Zlog {
+	return (&Zlog{}).With(fields...)
+}
+
+// With returns a child logger with the fields added to the fields of z
+func (z *Zlog) With(fields ...zap.Field) *Zlog {
+	return z.child(func(l *zap.Logger) *zap.Logger {
+		return l.With(fields...)
+	})
+}
+
+// Named returns a child logger with the name, the names of
+// nested Named loggers are joined with a dot.
+//
+//	zlog.Named("db").Named("migrations") // db.migrations
+func Named(name string) *Zlog {
+	return (&Zlog{}).Named(name)
+}
+
+// Named returns a child logger with the name added to the name of z
+func (z *Zlog) Named(name string) *Zlog {
+	return z.child(func(l *zap.Logger) *zap.Logger {
+		return l.Named(name)
+	})
+}
+
+// WithCallerSkip returns a child logger skipping n more frames
+// for the caller, for the helpers wrapping the zlog functions.
+//
+//	func logErr(msg string, err error) {
+//		zlog.WithCallerSkip(1).Error(msg, err)
+//	}
+func WithCallerSkip(n int) *Zlog {
+	return (&Zlog{}).WithCallerSkip(n)
+}
+
+// WithCallerSkip returns a child logger skipping n more frames
+func (z *Zlog) WithCallerSkip(n int) *Zlog {
+	return z.child(func(l *zap.Logger) *zap.Logger {
+		return l.WithOptions(zap.AddCallerSkip(n))
+	})
+}
+
+// child returns a child logger of z with the loggers of z passed
+// to fn
+func (z *Zlog) child(fn func(*zap.Logger) *zap.Logger) *Zlog {
+	logger, errLogger := z.get()
+	c := &Zlog{logger: fn(logger), errLogger: fn(errLogger), inst: z.inst}
+	if access := z.getAccess(); access != nil {
+		c.access = fn(access)
+	}
+	return c
+}
+
+// get returns the loggers of z, the package loggers for the zero
+// value, or the no-op loggers once the instance of New is closed
+func (z *Zlog) get() (logger, errLogger *zap.Logger) {
+	if z.logger == nil {
+		lg := load()
+		return lg.logger, lg.errLogger
+	}
+
+	if z.inst != nil && z.inst.isClosed() {
+		return nopLogger, nopLogger
+	}
+
+	return z.logger, z.errLogger
+}
+
+// getAccess returns the access logger of z like get, nil without
+// AccessFile or once the instance of New is closed
+func (z *Zlog) getAccess() *zap.Logger {
+	if z.logger == nil {
+		return getAccess()
+	}
+
+	if z.inst != nil && z.inst.isClosed() {
+		return nil
+	}
+	return z.access
+}
+
+// Info info log, the info strings are joined with a space
+func (z *Zlog) Info(msg string, info ...string) {
+	logger, _ := z.get()
+	if ce := logger.Check(zap.InfoLevel, msg); ce != nil {
+		writeField(ce, zap.String("info", strings.Join(info, " ")))
+	}
+}
+
+// InfoF info log with the fields
+func (z *Zlog) InfoF(msg string, fields ...zap.Field) {
+	logger, _ := z.get()
+	logger.Info(msg, fields...)
+}
+
+// Warn warn log, the warn strings are joined with a space
+func (z *Zlog) Warn(msg string, warn ...string) {
+	logger, _ := z.get()
+	if ce := logger.Check(zap.WarnLevel, msg); ce != nil {
+		writeField(ce, zap.String("warn", strings.Join(warn, " ")))
+	}
+}
+
+// WarnF warn log with the fields
+func (z *Zlog) WarnF(msg string, fields ...zap.Field) {
+	logger, _ := z.get()
+	logger.Warn(msg, fields...)
+}
+
+// Debug debug log, the debug strings are joined with a space
+func (z *Zlog) Debug(msg string, debug ...string) {
+	logger, _ := z.get()
+	if ce := logger.Check(zap.DebugLevel, msg); ce != nil {
+		writeField(ce, zap.String("debug", strings.Join(debug, " ")))
+	}
+}
+
+// DebugF debug log with the fields
+func (z *Zlog) DebugF(msg string, fields ...zap.Field) {
+	logger, _ := z.get()
+	logger.Debug(msg, fields...)
+}
+
+// Error error log, the errors are combined into one error field
+func (z *Zlog) Error(msg string, err ...error) {
+	_, errLogger := z.get()
+	if ce := errLogger.Check(zap.ErrorLevel, msg); ce != nil {
+		writeField(ce, zap.Error(multierr.Combine(err...)))
+	}
+}
+
+// ErrorF error log with the fields
+func (z *Zlog) ErrorF(msg string, fields ...zap.Field) {
+	_, errLogger := z.get()
+	errLogger.Error(msg, fields...)
+}
+
+// Fatal fatal log
+func (z *Zlog) Fatal(msg string, err ...error) {
+	_, errLogger := z.get()
+	errLogger.Fatal(msg, zap.Error(multierr.Combine(err...)))
+}
+
+// Panic panic log
+func (z *Zlog) Panic(msg string, err ...error) {
+	defer z.Sync()
+	_, errLogger := z.get()
+	errLogger.Panic(msg, zap.Error(multierr.Combine(err...)))
+}
+
+// DPanic dpanic log, it panics in dev mode and with Strict
+func (z *Zlog) DPanic(msg string, err ...error) {
+	defer z.Sync()
+	_, errLogger := z.get()
+	if ce := errLogger.Check(zap.DPanicLevel, msg); ce != nil {
+		writeField(ce, zap.Error(multierr.Combine(err...)))
+	}
+}
+
+// ErrorWith error log with the error and the fields
+func (z *Zlog) ErrorWith(msg string, err error, fields ...zap.Field) {
+	_, errLogger := z.get()
+	if ce := errLogger.Check(zap.ErrorLevel, msg); ce != nil {
+		writeWithErr(ce, err, fields)
+	}
+}
+
+// WarnWith warn log with the error and the fields
+func (z *Zlog) WarnWith(msg string, err error, fields ...zap.Field) {
+	logger, _ := z.get()
+	if ce := logger.Check(zap.WarnLevel, msg); ce != nil {
+		writeWithErr(ce, err, fields)
+	}
+}
+
+// FatalWith fatal log with the error and the fields
+func (z *Zlog) FatalWith(msg string, err error, fields ...zap.Field) {
+	_, errLogger := z.get()
+	errLogger.Fatal(msg, withErr(err, fields)...)
+}
+
+// DPanicWith dpanic log with the error and the fields
+func (z *Zlog) DPanicWith(msg string, err error, fields ...zap.Field) {
+	defer z.Sync()
+	_, errLogger := z.get()
+	if ce := errLogger.Check(zap.DPanicLevel, msg); ce != nil {
+		writeWithErr(ce, err, fields)
+	}
+}
+
+// PanicWith panic log with the error and the fields
+func (z *Zlog) PanicWith(msg string, err error, fields ...zap.Field) {
+	defer z.Sync()
+	_, errLogger := z.get()
+	errLogger.Panic(msg, withErr(err, fields)...)
+}
@@ -0,0 +1,117 @@
+// Copyright 2017 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/gt/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package zlog
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/vcaesar/tt"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// observeSplit swaps in separate observer cores for the logger
+// and the error logger, like the two files of the prod mode.
+func observeSplit(t *testing.T) (logs, errLogs *observer.ObservedLogs) {
+	core, logs := observer.New(zap.DebugLevel)
+	errCore, errLogs := observer.New(zap.ErrorLevel)
+	logger, errLogger := zap.New(core), zap.New(errCore)
+
+	prev := load()
+	current.Store(&loggers{
+		logger:    logger,
+		errLogger: errLogger,
+		sugar:     logger.Sugar(),
+		errSugar:  errLogger.Sugar(),
+	})
+	t.Cleanup(func() { current.Store(prev) })
+
+	return logs, errLogs
+}
+
+func TestWith(t *testing.T) {
+	logs, errLogs := observeSplit(t)
+
+	req := With(Str("request_id", "r1"))
+	user := req.With(Str("user_id", "u1"))
+
+	req.Info("req info", "a", "b")
+	user.InfoF("user info", Int("n", 1))
+	user.Warn("user warn")
+	user.DebugF("user debug")
+	user.Error("user error", errors.New("e1"))
+	user.ErrorF("user errorf", Str("k", "v"))
+	Info("plain")
+
+	entries := logs.AllUntimed()
+	tt.Equal(t, 5, len(entries))
+	tt.Equal(t, map[string]interface{}{"request_id": "r1", "info": "a b"},
+		entries[0].ContextMap())
+	tt.Equal(t, map[string]interface{}{"request_id": "r1", "user_id": "u1", "n": int64(1)},
+		entries[1].ContextMap())
+	tt.Equal(t, "u1", entries[2].ContextMap()["user_id"])
+	tt.Equal(t, "u1", entries[3].ContextMap()["user_id"])
+	tt.Equal(t, map[string]interface{}{"info": ""}, entries[4].ContextMap())
+
+	errEntries := errLogs.AllUntimed()
+	tt.Equal(t, 2, len(errEntries))
+	tt.Equal(t, map[string]interface{}{"request_id": "r1", "user_id": "u1", "error": "e1"},
+		errEntries[0].ContextMap())
+	tt.Equal(t, "v", errEntries[1].ContextMap()["k"])
+	tt.Equal(t, "r1", errEntries[1].ContextMap()["request_id"])
+}
+
+func TestZlogZero(t *testing.T) {
+	_, errLogs := observeSplit(t)
+
+	z := &Zlog{}
+	z.Error("zero", errors.New("e1"))
+	tt.Equal(t, 1, errLogs.FilterMessage("zero").Len())
+
+	tt.Equal(t, true, func() (panicked bool) {
+		defer func() { panicked = recover() != nil }()
+		z.Panic("zero panic")
+		return
+	}())
+	tt.Equal(t, 1, errLogs.FilterMessage("zero panic").Len())
+}
+
+func TestNamed(t *testing.T) {
+	logs, errLogs := observeSplit(t)
+
+	Named("http").Info("http info")
+	db := Named("db")
+	db.Named("migrations").Warn("migrations warn")
+	db.With(Str("table", "user")).Error("db error", errors.New("e1"))
+
+	entries := logs.AllUntimed()
+	tt.Equal(t, 2, len(entries))
+	tt.Equal(t, "http", entries[0].LoggerName)
+	tt.Equal(t, "db.migrations", entries[1].LoggerName)
+
+	errEntries := errLogs.AllUntimed()
+	tt.Equal(t, 1, len(errEntries))
+	tt.Equal(t, "db", errEntries[0].LoggerName)
+	tt.Equal(t, "user", errEntries[0].ContextMap()["table"])
+}
+
+func TestNamedFile(t *testing.T) {
+	dir := initTest(t)
+	Named("db").Info("named file")
+	tt.Nil(t, Close())
+
+	entries := msgEntries(readEntries(t, filepath.Join(dir, "*", "test.json")),
+		"named file")
+	tt.Equal(t, 1, len(entries))
+	tt.Equal(t, "db", entries[0]["logger"])
+}